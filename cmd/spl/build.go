@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/codegen/amd64"
+	"github.com/lukasmalkmus/spl/internal/app/spl/codegen/golang"
+	"github.com/lukasmalkmus/spl/internal/app/spl/codegen/js"
+	"github.com/lukasmalkmus/spl/internal/app/spl/codegen/llvm"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+// target describes a target platform of the build command.
+type target struct {
+	// ext is the extension of the output file.
+	ext string
+	// perm are the permissions of the output file.
+	perm os.FileMode
+	// compile is the compiler of the target.
+	compile compileFunc
+	// html, if not nil, compiles the type checked program into a standalone
+	// HTML page.
+	html compileFunc
+}
+
+// compileFunc compiles the type checked program and writes it to w.
+type compileFunc func(w io.Writer, prog *ast.Program, info *types.Info) error
+
+// targets are the supported target platforms, indexed by name.
+var targets = map[string]target{
+	amd64.Target:  {"", 0755, amd64.Compile, nil},
+	golang.Target: {".go", 0644, golang.Compile, nil},
+	js.Target:     {".js", 0644, js.Compile, js.CompileHTML},
+	llvm.Target:   {".ll", 0644, llvm.Compile, nil},
+}
+
+// buildCmd represents the build command.
+var buildCmd = &cobra.Command{
+	Use:   "build [flags] files or directory",
+	Short: "Compile a program",
+	Long: `Compile a program for the target platform given by the target flag.
+
+The program is given as list of source files or as directory, whose source
+files except for test files make up the program. The files share their
+declarations. The output file is named after the first source file or the
+directory unless it is explicitly given.
+The js target optionally produces a standalone HTML page instead of an ES
+module. Supported targets: ` + strings.Join(targetNames(), ", "),
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := diagnosticsFormat(cmd); err != nil {
+			return err
+		}
+		name, _ := cmd.Flags().GetString("target")
+		t, ok := targets[name]
+		if !ok {
+			return fmt.Errorf("unsupported target %q", name)
+		}
+
+		compile, ext := t.compile, t.ext
+		if html, _ := cmd.Flags().GetBool("html"); html {
+			if t.html == nil {
+				return fmt.Errorf("target %s doesn't support HTML output", name)
+			}
+			compile, ext = t.html, ".html"
+		}
+
+		files, err := sourceFiles(args)
+		if err != nil {
+			return err
+		}
+		prog, info, err := load(files...)
+		if err != nil {
+			return err
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+		if output == "" {
+			name, err := filepath.Abs(args[0])
+			if err != nil {
+				return err
+			}
+			output = strings.TrimSuffix(filepath.Base(name), ".spl") + ext
+		}
+		f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, t.perm)
+		if err != nil {
+			return err
+		}
+		if err := compile(f, prog, info); err != nil {
+			_ = f.Close()
+			_ = os.Remove(output)
+			return err
+		}
+		return f.Close()
+	},
+}
+
+// targetNames returns the sorted names of the supported targets.
+func targetNames() []string {
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	buildCmd.Flags().StringP("output", "o", "", "name of the output file")
+	buildCmd.Flags().String("target", amd64.Target, "target platform")
+	buildCmd.Flags().Bool("html", false, "write a standalone HTML page (js target only)")
+
+	addDiagnosticsFlag(buildCmd)
+
+	rootCmd.AddCommand(buildCmd)
+}
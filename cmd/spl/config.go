@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"text/template"
 
 	"github.com/spf13/cobra"
@@ -11,19 +13,43 @@ import (
 // configTemplate is the configuration file template.
 const configTemplate = `# SPL COMPILER TOOLCHAIN CONFIGURATION
 
+# Language extensions beyond SPL 1.2 which are enabled, e.g. ["imports"].
+ext = {{ list "ext" }}
+
+# Language version, "spl1.2" rejects constructs beyond the specification.
+lang = {{ printf "%q" .lang }}
+
 # Source code formatter configuration.
 [format]
 # Indentation width used.
 indent = {{ .format.indent }}
+
+# Module import configuration.
+[import]
+# Directories searched for imported modules. Modules are searched relative to
+# the importing source file first.
+path = {{ list "import.path" }}
 `
 
+// configFuncs are the functions available to the configuration template.
+var configFuncs = template.FuncMap{
+	// list formats a configuration value as TOML array of strings.
+	"list": func(key string) string {
+		list := viper.GetStringSlice(key)
+		for i, s := range list {
+			list[i] = strconv.Quote(s)
+		}
+		return "[" + strings.Join(list, ", ") + "]"
+	},
+}
+
 // configCmd represents the config command.
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Print the configuration",
 	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if t, err := template.New("config").Parse(configTemplate); err != nil {
+		if t, err := template.New("config").Funcs(configFuncs).Parse(configTemplate); err != nil {
 			return fmt.Errorf("invalid configuration template: %w", err)
 		} else if err := t.Execute(cmd.OutOrStdout(), viper.AllSettings()); err != nil {
 			return fmt.Errorf("execute configuration template: %w", err)
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/cover"
+)
+
+// coverCmd represents the cover command.
+var coverCmd = &cobra.Command{
+	Use:   "cover [flags]",
+	Short: "Analyze a coverage profile",
+	Long: `Analyze a coverage profile written by "spl run -coverprofile".
+
+The func flag prints the percentage of executed statements per procedure. The
+html flag writes a HTML report which shows the source code with executed
+statements in green and statements which were never executed in red. It is
+written to standard output unless an output file is given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		funcProfile, _ := cmd.Flags().GetString("func")
+		htmlProfile, _ := cmd.Flags().GetString("html")
+		switch {
+		case funcProfile != "" && htmlProfile == "":
+			profiles, err := readProfiles(funcProfile)
+			if err != nil {
+				return err
+			}
+			return coverFuncs(cmd.OutOrStdout(), profiles)
+		case htmlProfile != "" && funcProfile == "":
+			profiles, err := readProfiles(htmlProfile)
+			if err != nil {
+				return err
+			}
+			output, _ := cmd.Flags().GetString("output")
+			if output == "" {
+				return cover.WriteHTML(cmd.OutOrStdout(), profiles, ioutil.ReadFile)
+			}
+			return writeFile(output, func(w io.Writer) error { return cover.WriteHTML(w, profiles, ioutil.ReadFile) })
+		}
+		return errors.New("exactly one of the func and html flags is required")
+	},
+}
+
+// readProfiles reads the coverage profiles from the file.
+func readProfiles(name string) ([]*cover.Profile, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return cover.ParseProfiles(f)
+}
+
+// coverFuncs prints the coverage of the procedures of the profiles and the
+// total coverage in the format of go tool cover -func.
+func coverFuncs(w io.Writer, profiles []*cover.Profile) error {
+	tw := tabwriter.NewWriter(w, 1, 8, 1, '\t', 0)
+	covered, total := 0, 0
+	for _, p := range profiles {
+		prog, _, err := load(p.FileName)
+		if err != nil {
+			return err
+		}
+		for _, f := range cover.Funcs(p, prog) {
+			fmt.Fprintf(tw, "%s:%d:\t%s\t%.1f%%\n", f.Pos.Filename, f.Pos.Line, f.Name, f.Percent())
+			covered += f.Covered
+			total += f.Total
+		}
+	}
+	percent := 100.0
+	if total > 0 {
+		percent = 100 * float64(covered) / float64(total)
+	}
+	fmt.Fprintf(tw, "total:\t(statements)\t%.1f%%\n", percent)
+	return tw.Flush()
+}
+
+func init() {
+	coverCmd.Flags().String("func", "", "print the coverage of each procedure of the profile")
+	coverCmd.Flags().String("html", "", "write a HTML report of the profile")
+	coverCmd.Flags().StringP("output", "o", "", "name of the HTML report")
+
+	addDiagnosticsFlag(coverCmd)
+
+	rootCmd.AddCommand(coverCmd)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io"
+	"net"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/dap"
+)
+
+// dapCmd represents the dap command.
+var dapCmd = &cobra.Command{
+	Use:   "dap",
+	Short: "Run a Debug Adapter Protocol server",
+	Long: `Run a Debug Adapter Protocol server which lets editors like VS Code debug
+programs.
+
+The server communicates over standard input and output unless an address to
+listen on is given by the listen flag, in which case clients are served one
+after another. Program output is shown in the debug console of the editor.
+While the program runs, lines entered in the debug console are passed to it as
+input; while it is stopped they are evaluated as expressions.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addr, _ := cmd.Flags().GetString("listen")
+		if addr == "" {
+			return dap.Serve(struct {
+				io.Reader
+				io.Writer
+			}{os.Stdin, os.Stdout}, loadFile)
+		}
+
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		defer l.Close()
+		cmd.PrintErrf("DAP server listening at: %s\n", l.Addr())
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return err
+			}
+			if err := dap.Serve(conn, loadFile); err != nil {
+				cmd.PrintErrln("Error:", err)
+			}
+			_ = conn.Close()
+		}
+	},
+}
+
+func init() {
+	dapCmd.Flags().String("listen", "", "address to listen on for TCP connections (e.g. 127.0.0.1:4711)")
+
+	addDiagnosticsFlag(dapCmd)
+
+	rootCmd.AddCommand(dapCmd)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/debugger"
+)
+
+// debugCmd represents the debug command.
+var debugCmd = &cobra.Command{
+	Use:   "debug file.spl",
+	Short: "Debug a program",
+	Long: `Debug a program interactively.
+
+The debugger provides a gdb like prompt. Breakpoints are set on lines or
+procedures, watchpoints on expressions. The program is started with "run" and
+reads its input from the same terminal as the debugger. Type "help" for a list
+of commands.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, err := loaderConfig()
+		if err != nil {
+			return err
+		}
+		prog, info, err := conf.Load(args[0])
+		if err != nil {
+			return err
+		}
+		d, err := debugger.New(prog, info, conf.Fset, os.Stdin, cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
+		return d.Run()
+	},
+}
+
+func init() {
+	addDiagnosticsFlag(debugCmd)
+
+	rootCmd.AddCommand(debugCmd)
+}
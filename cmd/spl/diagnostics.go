@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/diag"
+)
+
+// addDiagnosticsFlag adds the flag selecting the output format of diagnostics
+// to the command.
+func addDiagnosticsFlag(cmd *cobra.Command) {
+	cmd.Flags().String("diagnostics-format", string(diag.Text), "output format of diagnostics (text, json, sarif, checkstyle)")
+}
+
+// diagnosticsFormat returns the output format of diagnostics selected for the
+// command. It is Text for commands without the diagnostics-format flag.
+func diagnosticsFormat(cmd *cobra.Command) (diag.Format, error) {
+	if cmd == nil || cmd.Flags().Lookup("diagnostics-format") == nil {
+		return diag.Text, nil
+	}
+	name, _ := cmd.Flags().GetString("diagnostics-format")
+	return diag.ParseFormat(name)
+}
+
+// writeDiagnostics writes the diagnostics to w in the given format. Text is
+// rendered with source code and colorized as configured by the color flag,
+// machine-readable formats use paths relative to the repository root.
+func writeDiagnostics(w io.Writer, format diag.Format, l diag.List) error {
+	if format == diag.Text {
+		color, err := colorEnabled(os.Stderr)
+		if err != nil {
+			return err
+		}
+		p := &diag.Printer{Color: color}
+		return p.Fprint(w, l)
+	}
+	enc := &diag.Encoder{Format: format, Root: repoRoot()}
+	return enc.Encode(w, l)
+}
+
+// repoRoot returns the root directory of the repository containing the
+// working directory: the closest directory holding a .git entry. It is the
+// working directory itself outside of a repository.
+func repoRoot() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for dir := wd; ; {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return wd
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/ext"
+	"github.com/lukasmalkmus/spl/internal/app/spl/loader"
+	"github.com/lukasmalkmus/spl/internal/app/spl/tester"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+// load parses and type checks the source files as a single program. Errors in
+// the source code are returned as parser.ErrorList.
+func load(filenames ...string) (*ast.Program, *types.Info, error) {
+	conf, err := loaderConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	return conf.Load(filenames...)
+}
+
+// loaderConfig returns the loader configuration given by the language version,
+// the enabled language extensions and the module search path.
+func loaderConfig() (*loader.Config, error) {
+	lang, err := ext.ParseLang(viper.GetString("lang"))
+	if err != nil {
+		return nil, err
+	}
+	exts, err := ext.Parse(viper.GetStringSlice("ext")...)
+	if err != nil {
+		return nil, err
+	}
+	if err := lang.Check(exts); err != nil {
+		return nil, err
+	}
+	return &loader.Config{Extensions: exts, Lang: lang, Path: viper.GetStringSlice("import.path")}, nil
+}
+
+// sourceFiles returns the source files of a program given as list of files or
+// as a single directory. The source files of a directory are the files with
+// the extension .spl, except for test files.
+func sourceFiles(args []string) ([]string, error) {
+	if len(args) != 1 {
+		return args, nil
+	}
+	if fi, err := os.Stat(args[0]); err != nil || !fi.IsDir() {
+		return args, nil
+	}
+	names, err := filepath.Glob(filepath.Join(args[0], "*.spl"))
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, name := range names {
+		if !strings.HasSuffix(name, tester.Suffix) {
+			files = append(files, name)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no source files in %s", args[0])
+	}
+	return files, nil
+}
+
+// loadFile parses and type checks a program consisting of a single source
+// file.
+func loadFile(filename string) (*ast.Program, *types.Info, error) { return load(filename) }
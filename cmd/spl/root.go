@@ -9,6 +9,10 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/diag"
+	"github.com/lukasmalkmus/spl/internal/app/spl/interp"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
 )
 
 // rootCmd represents the base command when called without any subcommands.
@@ -33,7 +37,11 @@ func main() {
 	// specified in the configuration file and environment. Only available to
 	// the root command.
 	rootCmd.PersistentFlags().String("config", "", "configuration file to use")
+	rootCmd.PersistentFlags().String("color", "auto", "colorize diagnostics (always, never, auto)")
+	rootCmd.PersistentFlags().StringSlice("ext", nil, "language extensions to enable (imports, functions, bool, records, strings)")
 	rootCmd.PersistentFlags().Uint("format.indent", 4, "indentation used by the formatter")
+	rootCmd.PersistentFlags().StringSlice("import.path", nil, "directories searched for imported modules")
+	rootCmd.PersistentFlags().String("lang", "", "language version (spl1.2 rejects constructs beyond the specification)")
 
 	// Bind the configuration flags to viper expect for the config flag.
 	rootCmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
@@ -51,12 +59,87 @@ func main() {
 	// On initialization, the configuration is loaded.
 	cobra.OnInitialize(initConfig(rootCmd))
 
-	// Silence the usage message of the root command.
+	// Silence the usage message of the root command. Errors are printed
+	// below, so lists of source code errors are printed one per line.
 	rootCmd.SilenceUsage = true
+	rootCmd.SilenceErrors = true
 
-	if err := rootCmd.Execute(); err != nil {
+	rootCmd.SetArgs(goStyleFlags(rootCmd, os.Args[1:]))
+	cmd, err := rootCmd.ExecuteC()
+	format, ferr := diagnosticsFormat(cmd)
+	if ferr != nil {
+		rootCmd.PrintErrln("Error:", ferr)
 		os.Exit(1)
 	}
+	list, isList := err.(parser.ErrorList)
+	if format != diag.Text && (err == nil || isList || err == errTestsFailed) {
+		// Machine-readable reports are written even if there are no
+		// diagnostics, so tools always find one.
+		if werr := writeDiagnostics(rootCmd.ErrOrStderr(), format, list); werr != nil {
+			rootCmd.PrintErrln("Error:", werr)
+			os.Exit(1)
+		}
+	}
+	if err != nil {
+		if err == errTestsFailed {
+			os.Exit(1)
+		}
+		switch err.(type) {
+		case parser.ErrorList:
+			if format == diag.Text {
+				if werr := writeDiagnostics(rootCmd.ErrOrStderr(), format, list); werr != nil {
+					rootCmd.PrintErrln("Error:", werr)
+				}
+			}
+		case *interp.RuntimeError:
+			rootCmd.PrintErrln(err)
+		default:
+			rootCmd.PrintErrln("Error:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// colorEnabled reports whether diagnostics written to f are colorized as
+// configured by the color flag. In auto mode, they are colorized if f is a
+// terminal and the NO_COLOR environment variable is not set.
+func colorEnabled(f *os.File) (bool, error) {
+	switch mode := viper.GetString("color"); mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto", "":
+		return isTerminal(f.Fd()) && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb", nil
+	default:
+		return false, fmt.Errorf("invalid color mode %q, must be always, never or auto", mode)
+	}
+}
+
+// goStyleFlags rewrites long flags given with a single dash, like the flags of
+// the go tool (e.g. -target=llvm), into their double dash form. Shorthand flags
+// and arguments following the "--" terminator are left untouched.
+func goStyleFlags(root *cobra.Command, args []string) []string {
+	cmd, _, err := root.Find(args)
+	if err != nil {
+		return args
+	}
+	res := make([]string, len(args))
+	for i, arg := range args {
+		res[i] = arg
+		if arg == "--" {
+			copy(res[i:], args[i:])
+			break
+		}
+		if len(arg) < 3 || arg[0] != '-' || arg[1] == '-' {
+			continue
+		}
+		name := strings.SplitN(arg[1:], "=", 2)[0]
+		if cmd.Flags().Lookup(name) != nil || cmd.InheritedFlags().Lookup(name) != nil {
+			res[i] = "-" + arg
+		}
+	}
+	return res
 }
 
 // initConfig reads the configuration using the provider viper instance.
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestGoStyleFlags(t *testing.T) {
+	root := &cobra.Command{Use: "spl"}
+	root.PersistentFlags().String("config", "", "")
+	build := &cobra.Command{Use: "build", Run: func(*cobra.Command, []string) {}}
+	build.Flags().StringP("output", "o", "", "")
+	build.Flags().String("target", "", "")
+	root.AddCommand(build)
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"build", "-target=llvm", "a.spl"}, []string{"build", "--target=llvm", "a.spl"}},
+		{[]string{"build", "-target", "llvm"}, []string{"build", "--target", "llvm"}},
+		{[]string{"build", "--target=llvm"}, []string{"build", "--target=llvm"}},
+		{[]string{"build", "-config=spl.toml"}, []string{"build", "--config=spl.toml"}},
+		{[]string{"build", "-o", "prog"}, []string{"build", "-o", "prog"}},
+		{[]string{"build", "-oprog"}, []string{"build", "-oprog"}},
+		{[]string{"build", "-unknown"}, []string{"build", "-unknown"}},
+		{[]string{"build", "--", "-target=llvm"}, []string{"build", "--", "-target=llvm"}},
+		{[]string{"missing", "-target=llvm"}, []string{"missing", "-target=llvm"}},
+	}
+	for _, tt := range tests {
+		if got := goStyleFlags(root, tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("goStyleFlags(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
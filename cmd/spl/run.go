@@ -0,0 +1,102 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/cover"
+	"github.com/lukasmalkmus/spl/internal/app/spl/interp"
+	"github.com/lukasmalkmus/spl/internal/app/spl/profile"
+)
+
+// runCmd represents the run command.
+var runCmd = &cobra.Command{
+	Use:   "run [flags] files or directory",
+	Short: "Interpret a program",
+	Long: `Interpret a program without compiling it.
+
+The program is given as list of source files or as directory, whose source
+files except for test files make up the program. The files share their
+declarations.
+
+The program reads from standard input and writes to standard output. Runtime
+errors are reported on standard error. The graphics library is not supported.
+
+The cpuprofile flag writes a profile of the execution in the format of pprof,
+which is analyzed by "go tool pprof". Its samples count the time spent and the
+statements executed per procedure call stack and source line.
+
+The coverprofile flag writes a coverage profile which records how often each
+statement was executed. It is analyzed by "spl cover".`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := diagnosticsFormat(cmd); err != nil {
+			return err
+		}
+		files, err := sourceFiles(args)
+		if err != nil {
+			return err
+		}
+		prog, info, err := load(files...)
+		if err != nil {
+			return err
+		}
+		m, err := interp.New(prog, info, os.Stdin, cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
+
+		var (
+			p *profile.Profiler
+			r *cover.Recorder
+		)
+		cpuprofile, _ := cmd.Flags().GetString("cpuprofile")
+		if cpuprofile != "" {
+			p = profile.Start(m)
+		}
+		coverprofile, _ := cmd.Flags().GetString("coverprofile")
+		if coverprofile != "" {
+			r = cover.Start(m, prog)
+		}
+
+		err = m.Run()
+		if p != nil {
+			p.Stop()
+			if perr := writeFile(cpuprofile, p.Write); perr != nil {
+				return perr
+			}
+		}
+		if r != nil {
+			r.Stop()
+			write := func(w io.Writer) error { return cover.WriteProfiles(w, r.Profiles()) }
+			if cerr := writeFile(coverprofile, write); cerr != nil {
+				return cerr
+			}
+		}
+		return err
+	},
+}
+
+// writeFile creates the file and writes to it using write.
+func writeFile(name string, write func(w io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func init() {
+	runCmd.Flags().String("cpuprofile", "", "write a pprof profile of the execution to the file")
+	runCmd.Flags().String("coverprofile", "", "write a coverage profile of the execution to the file")
+
+	addDiagnosticsFlag(runCmd)
+
+	rootCmd.AddCommand(runCmd)
+}
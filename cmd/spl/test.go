@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"regexp"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/diag"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+	"github.com/lukasmalkmus/spl/internal/app/spl/tester"
+)
+
+// errTestsFailed is returned by the test command if a test failed. The
+// failures are already reported in the output.
+var errTestsFailed = errors.New("tests failed")
+
+// testCmd represents the test command.
+var testCmd = &cobra.Command{
+	Use:   "test [flags] [files or directories]",
+	Short: "Test programs",
+	Long: `Run the tests of source files named *_test.spl and the examples.
+
+Files are given by name or by directory. A directory followed by "/..." also
+includes its subdirectories. Without arguments the source files in the current
+directory are run. Test files are checked together with the other source files
+of their directory, like the files of a Go package, and don't need a main
+procedure. Only the tests of the test file itself are run.
+
+Every parameterless procedure whose name starts with "test" is a test. Tests
+use the procedures assertEq(got, want) which reports a failure if got and want
+differ, and fail() which reports a failure and stops the test. Failures and
+runtime errors are reported together with their source line.
+
+Other source files are examples if they end with a comment block which starts
+with a line "// Output:". Their main procedure is run as test named main and
+its output is compared with the following comment lines. The lines of a block
+starting with "// Input:" are passed as input to the program:
+
+	// Input:
+	// 21
+	// Output:
+	// 42
+
+Trailing blanks are ignored. Differences are reported as diff. Source files
+which are neither tests nor examples are skipped.
+
+The output resembles the one of go test. The run flag selects the tests whose
+name matches a regular expression, the v flag also reports passing tests. The
+json flag writes the output as stream of events in the format of
+go tool test2json, which is understood by tools like gotestsum.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts tester.Options
+		if run, _ := cmd.Flags().GetString("run"); run != "" {
+			re, err := regexp.Compile(run)
+			if err != nil {
+				return err
+			}
+			opts.Run = re
+		}
+		opts.Verbose, _ = cmd.Flags().GetBool("verbose")
+		opts.JSON, _ = cmd.Flags().GetBool("json")
+		conf, err := loaderConfig()
+		if err != nil {
+			return err
+		}
+		opts.Config = *conf
+
+		// Files which don't type check are reported in the output. In a
+		// machine-readable format, their errors are also returned to be
+		// written as report.
+		format, err := diagnosticsFormat(cmd)
+		if err != nil {
+			return err
+		}
+		color, err := colorEnabled(os.Stdout)
+		if err != nil {
+			return err
+		}
+		opts.Printer = &diag.Printer{Color: color && !opts.JSON}
+		var diags parser.ErrorList
+		if format != diag.Text {
+			opts.Diagnostics = func(l parser.ErrorList) { diags = append(diags, l...) }
+		}
+
+		files, err := tester.Find(args)
+		if err != nil {
+			return err
+		}
+		ok, err := tester.Run(cmd.OutOrStdout(), files, opts)
+		if err != nil {
+			return err
+		}
+		if len(diags) > 0 {
+			return diags
+		}
+		if !ok {
+			return errTestsFailed
+		}
+		return nil
+	},
+}
+
+func init() {
+	testCmd.Flags().String("run", "", "run only tests matching the regular expression")
+	testCmd.Flags().BoolP("verbose", "v", false, "report all tests, not only failed ones")
+	testCmd.Flags().Bool("json", false, "write the output as JSON event stream")
+
+	addDiagnosticsFlag(testCmd)
+
+	rootCmd.AddCommand(testCmd)
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package main
+
+// isTerminal reports whether the file descriptor refers to a terminal. Without
+// support for the platform, it never does.
+func isTerminal(fd uintptr) bool { return false }
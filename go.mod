@@ -10,5 +10,6 @@ require (
 	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.6.1
 	golang.org/x/perf v0.0.0-20191209155426-36b577b0eb03
+	golang.org/x/sys v0.0.0-20190922100055-0a153f010e69
 	gotest.tools/gotestsum v0.4.0
 )
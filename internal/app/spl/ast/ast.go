@@ -22,15 +22,18 @@ type Expr interface {
 	exprNode()
 }
 
-func (*BadExpr) exprNode()    {}
-func (*Ident) exprNode()      {}
-func (*IntLit) exprNode()     {}
-func (*ParenExpr) exprNode()  {}
-func (*UnaryExpr) exprNode()  {}
-func (*BinaryExpr) exprNode() {}
-func (*IndexExpr) exprNode()  {}
-func (*CallExpr) exprNode()   {}
-func (*ArrayType) exprNode()  {}
+func (*BadExpr) exprNode()      {}
+func (*Ident) exprNode()        {}
+func (*IntLit) exprNode()       {}
+func (*StringLit) exprNode()    {}
+func (*ParenExpr) exprNode()    {}
+func (*UnaryExpr) exprNode()    {}
+func (*BinaryExpr) exprNode()   {}
+func (*IndexExpr) exprNode()    {}
+func (*SelectorExpr) exprNode() {}
+func (*CallExpr) exprNode()     {}
+func (*ArrayType) exprNode()    {}
+func (*RecordType) exprNode()   {}
 
 // Stmt is a simple programing language (SPL) statement.
 type Stmt interface {
@@ -43,11 +46,13 @@ type Stmt interface {
 
 func (*BadStmt) stmtNode()    {}
 func (*DeclStmt) stmtNode()   {}
+func (*EmptyStmt) stmtNode()  {}
 func (*BlockStmt) stmtNode()  {}
 func (*ExprStmt) stmtNode()   {}
 func (*AssignStmt) stmtNode() {}
 func (*WhileStmt) stmtNode()  {}
 func (*IfStmt) stmtNode()     {}
+func (*ReturnStmt) stmtNode() {}
 
 // Decl is a simple programing language (SPL) declaration.
 type Decl interface {
@@ -58,16 +63,17 @@ type Decl interface {
 	declNode()
 }
 
-func (*BadDecl) declNode()  {}
-func (*VarDecl) declNode()  {}
-func (*TypeDecl) declNode() {}
-func (*ProcDecl) declNode() {}
+func (*BadDecl) declNode()    {}
+func (*ImportDecl) declNode() {}
+func (*VarDecl) declNode()    {}
+func (*TypeDecl) declNode()   {}
+func (*ProcDecl) declNode()   {}
 
 // -----------------------------------------------------------------------------
 // Expressions and types
 
-// Field represents a Field declaration list in a parameter declaration in a
-// signature.
+// Field represents a parameter declaration in a signature or a field
+// declaration in a record type.
 type Field struct {
 	Ref  token.Position
 	Name *Ident
@@ -96,7 +102,7 @@ type FieldList struct {
 func (f *FieldList) Pos() token.Position { return f.Opening }
 
 // End implements the Node interface.
-func (f *FieldList) End() token.Position { return f.Closing }
+func (f *FieldList) End() token.Position { return after(f.Closing) }
 
 // An expression is represented by a tree consisting of one or more of the
 // following concrete expression nodes.
@@ -121,6 +127,13 @@ type (
 		Value    string
 	}
 
+	// StringLit represents a string literal node. The value is the literal
+	// including the quotation marks and escape sequences.
+	StringLit struct {
+		ValuePos token.Position
+		Value    string
+	}
+
 	// ParenExpr represents a parenthesized expression node.
 	ParenExpr struct {
 		Lparen token.Position
@@ -151,6 +164,13 @@ type (
 		Rbrack token.Position
 	}
 
+	// SelectorExpr represents an expression node followed by a selector,
+	// e.g. a qualified identifier or the field of a record.
+	SelectorExpr struct {
+		X   Expr
+		Sel *Ident
+	}
+
 	// CallExpr represents an expression node followed by an argument list.
 	CallExpr struct {
 		Pro    Expr
@@ -170,19 +190,40 @@ func (x *BadExpr) End() token.Position { return x.To }
 func (x *Ident) Pos() token.Position { return x.NamePos }
 
 // End implements the Node interface.
-func (x *Ident) End() token.Position { pos := x.NamePos; pos.Line += len(x.Name); return pos }
+func (x *Ident) End() token.Position {
+	pos := x.NamePos
+	pos.Column += len(x.Name)
+	pos.Char += len(x.Name)
+	return pos
+}
 
 // Pos implements the Node interface.
 func (x *IntLit) Pos() token.Position { return x.ValuePos }
 
 // End implements the Node interface.
-func (x *IntLit) End() token.Position { pos := x.ValuePos; pos.Line += len(x.Value); return pos }
+func (x *IntLit) End() token.Position {
+	pos := x.ValuePos
+	pos.Column += len(x.Value)
+	pos.Char += len(x.Value)
+	return pos
+}
+
+// Pos implements the Node interface.
+func (x *StringLit) Pos() token.Position { return x.ValuePos }
+
+// End implements the Node interface.
+func (x *StringLit) End() token.Position {
+	pos := x.ValuePos
+	pos.Column += len(x.Value)
+	pos.Char += len(x.Value)
+	return pos
+}
 
 // Pos implements the Node interface.
 func (x *ParenExpr) Pos() token.Position { return x.Lparen }
 
 // End implements the Node interface.
-func (x *ParenExpr) End() token.Position { return x.Rparen }
+func (x *ParenExpr) End() token.Position { return after(x.Rparen) }
 
 // Pos implements the Node interface.
 func (x *UnaryExpr) Pos() token.Position { return x.OpPos }
@@ -197,16 +238,22 @@ func (x *BinaryExpr) Pos() token.Position { return x.X.Pos() }
 func (x *BinaryExpr) End() token.Position { return x.Y.End() }
 
 // Pos implements the Node interface.
-func (x *IndexExpr) Pos() token.Position { return x.Lbrack }
+func (x *IndexExpr) Pos() token.Position { return x.X.Pos() }
+
+// End implements the Node interface.
+func (x *IndexExpr) End() token.Position { return after(x.Rbrack) }
+
+// Pos implements the Node interface.
+func (x *SelectorExpr) Pos() token.Position { return x.X.Pos() }
 
 // End implements the Node interface.
-func (x *IndexExpr) End() token.Position { return x.Rbrack }
+func (x *SelectorExpr) End() token.Position { return x.Sel.End() }
 
 // Pos implements the Node interface.
 func (x *CallExpr) Pos() token.Position { return x.Pro.Pos() }
 
 // End implements the Node interface.
-func (x *CallExpr) End() token.Position { return x.Rparen }
+func (x *CallExpr) End() token.Position { return after(x.Rparen) }
 
 func (x *Ident) String() string {
 	if x != nil {
@@ -225,13 +272,25 @@ type (
 		Of    token.Position
 		Elt   Expr
 	}
+
+	// RecordType represents a record type node of the records extension.
+	RecordType struct {
+		Record token.Position
+		Fields *FieldList // fields enclosed by braces
+	}
 )
 
 // Pos implements the Node interface.
 func (x *ArrayType) Pos() token.Position { return x.Array }
 
 // End implements the Node interface.
-func (x *ArrayType) End() token.Position { return x.Elt.Pos() }
+func (x *ArrayType) End() token.Position { return x.Elt.End() }
+
+// Pos implements the Node interface.
+func (x *RecordType) Pos() token.Position { return x.Record }
+
+// End implements the Node interface.
+func (x *RecordType) End() token.Position { return x.Fields.End() }
 
 // -----------------------------------------------------------------------------
 // Statements
@@ -251,6 +310,11 @@ type (
 		Decl Decl
 	}
 
+	// EmptyStmt represents an empty statement node.
+	EmptyStmt struct {
+		Semicolon token.Position
+	}
+
 	// BlockStmt represents a braced statement list node.
 	BlockStmt struct {
 		Lbrace token.Position
@@ -285,6 +349,13 @@ type (
 		Body Stmt
 		Else Stmt
 	}
+
+	// ReturnStmt represents a return node. It is part of the functions
+	// extension.
+	ReturnStmt struct {
+		Return token.Position
+		Result Expr // result value; or nil
+	}
 )
 
 // Pos implements the Node interface.
@@ -299,11 +370,17 @@ func (s *DeclStmt) Pos() token.Position { return s.Decl.Pos() }
 // End implements the Node interface.
 func (s *DeclStmt) End() token.Position { return s.Decl.End() }
 
+// Pos implements the Node interface.
+func (s *EmptyStmt) Pos() token.Position { return s.Semicolon }
+
+// End implements the Node interface.
+func (s *EmptyStmt) End() token.Position { return after(s.Semicolon) }
+
 // Pos implements the Node interface.
 func (s *BlockStmt) Pos() token.Position { return s.Lbrace }
 
 // End implements the Node interface.
-func (s *BlockStmt) End() token.Position { return s.Rbrace }
+func (s *BlockStmt) End() token.Position { return after(s.Rbrace) }
 
 // Pos implements the Node interface.
 func (s *ExprStmt) Pos() token.Position { return s.X.Pos() }
@@ -315,7 +392,7 @@ func (s *ExprStmt) End() token.Position { return s.X.End() }
 func (s *AssignStmt) Pos() token.Position { return s.Left.Pos() }
 
 // End implements the Node interface.
-func (s *AssignStmt) End() token.Position { return s.Right.Pos() }
+func (s *AssignStmt) End() token.Position { return s.Right.End() }
 
 // Pos implements the Node interface.
 func (s *WhileStmt) Pos() token.Position { return s.While }
@@ -334,6 +411,20 @@ func (s *IfStmt) End() token.Position {
 	return s.Body.End()
 }
 
+// Pos implements the Node interface.
+func (s *ReturnStmt) Pos() token.Position { return s.Return }
+
+// End implements the Node interface.
+func (s *ReturnStmt) End() token.Position {
+	if s.Result != nil {
+		return s.Result.End()
+	}
+	pos := s.Return
+	pos.Column += len("return")
+	pos.Char += len("return")
+	return pos
+}
+
 // -----------------------------------------------------------------------------
 // Declarations
 
@@ -346,10 +437,22 @@ type (
 		To   token.Position
 	}
 
-	// VarDecl represents a variable declaration node.
+	// ImportDecl represents the import declaration of a module node. It is
+	// part of the imports extension.
+	ImportDecl struct {
+		Import token.Position
+		Path   *StringLit
+		As     token.Position
+		Name   *Ident
+	}
+
+	// VarDecl represents a variable declaration node. The initial value is
+	// part of the strings extension.
 	VarDecl struct {
-		Name *Ident
-		Type Expr
+		Name   *Ident
+		Type   Expr
+		Assign token.Position // position of ":="; or invalid
+		Value  Expr           // initial value; or nil
 	}
 
 	// TypeDecl represents a type declaration node.
@@ -359,11 +462,13 @@ type (
 		Type   Expr
 	}
 
-	// ProcDecl represents a procedure declaration node.
+	// ProcDecl represents a procedure declaration node. The result type is
+	// part of the functions extension.
 	ProcDecl struct {
 		Name   *Ident
 		Proc   token.Position
 		Params *FieldList
+		Result Expr // result type; or nil
 		Body   *BlockStmt
 	}
 )
@@ -374,11 +479,22 @@ func (d *BadDecl) Pos() token.Position { return d.From }
 // End implements the Node interface.
 func (d *BadDecl) End() token.Position { return d.To }
 
+// Pos implements the Node interface.
+func (d *ImportDecl) Pos() token.Position { return d.Import }
+
+// End implements the Node interface.
+func (d *ImportDecl) End() token.Position { return d.Name.End() }
+
 // Pos implements the Node interface.
 func (d *VarDecl) Pos() token.Position { return d.Name.NamePos }
 
 // End implements the Node interface.
-func (d *VarDecl) End() token.Position { return d.Type.End() }
+func (d *VarDecl) End() token.Position {
+	if d.Value != nil {
+		return d.Value.End()
+	}
+	return d.Type.End()
+}
 
 // Pos implements the Node interface.
 func (d *TypeDecl) Pos() token.Position { return d.Name.NamePos }
@@ -392,14 +508,39 @@ func (d *ProcDecl) Pos() token.Position { return d.Proc }
 // End implements the Node interface.
 func (d *ProcDecl) End() token.Position { return d.Body.End() }
 
+// -----------------------------------------------------------------------------
+// Comments
+
+// Comment represents a single //-style comment. Text is the comment including
+// the leading slashes.
+type Comment struct {
+	Slash token.Position
+	Text  string
+}
+
+// Pos implements the Node interface.
+func (c *Comment) Pos() token.Position { return c.Slash }
+
+// End implements the Node interface.
+func (c *Comment) End() token.Position {
+	end := c.Slash
+	end.Column += len(c.Text)
+	end.Char += len(c.Text)
+	return end
+}
+
 // -----------------------------------------------------------------------------
 // Helpers
 
 // Program represents a simple programing language (SPL) program AST.
+// Unresolved holds the identifiers which denote no declared or predeclared
+// object. Comments holds the comments of the source files in source order if
+// they were parsed with the ParseComments mode.
 type Program struct {
 	Name       string
 	Decls      []Decl
 	Unresolved []*Ident
+	Comments   []*Comment
 }
 
 // Pos implements the Node interface.
@@ -412,3 +553,13 @@ func (p *Program) End() token.Position {
 	}
 	return token.Position{Filename: p.Name, Line: 1, Column: 1}
 }
+
+// after returns the position immediately after the single character token at
+// pos.
+func after(pos token.Position) token.Position {
+	if pos.IsValid() {
+		pos.Column++
+		pos.Char++
+	}
+	return pos
+}
@@ -42,7 +42,7 @@ func (s *Scope) Insert(obj *Object) *Object {
 // The Data field contains object-specific data:
 //
 //	Kind    Data type         Data value
-//	Pkg     *Scope            package scope
+//	Mod     *Scope            module scope
 type Object struct {
 	Kind ObjKind
 	Name string
@@ -68,6 +68,18 @@ func (obj *Object) Pos() token.Position {
 		if d.Name.Name == name {
 			return d.Name.Pos()
 		}
+	case *TypeDecl:
+		if d.Name.Name == name {
+			return d.Name.Pos()
+		}
+	case *VarDecl:
+		if d.Name.Name == name {
+			return d.Name.Pos()
+		}
+	case *ImportDecl:
+		if d.Name.Name == name {
+			return d.Name.Pos()
+		}
 	}
 	return token.NoPos
 }
@@ -78,16 +90,20 @@ type ObjKind int
 // List of possible Object kinds.
 const (
 	Bad ObjKind = iota
+	Con
 	Typ
 	Var
 	Pro
+	Mod
 )
 
 var objKindStrings = [...]string{
 	Bad: "bad",
+	Con: "const",
 	Typ: "type",
 	Var: "var",
 	Pro: "proc",
+	Mod: "module",
 }
 
 func (kind ObjKind) String() string { return objKindStrings[kind] }
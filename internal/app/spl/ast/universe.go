@@ -0,0 +1,71 @@
+package ast
+
+import "github.com/lukasmalkmus/spl/internal/app/spl/ext"
+
+// Predeclared describes an object which is implicitly declared before all user
+// declarations.
+type Predeclared struct {
+	Kind ObjKind
+	Name string
+
+	// Ext is the language extension predeclaring the object, or 0 if it is
+	// always predeclared. Test is set for the library procedures which are
+	// only available to tests.
+	Ext  ext.Set
+	Test bool
+
+	// Type and Value are the type and value of a constant. Params describes
+	// the parameters of a library procedure by their names. Names prefixed by
+	// "ref " denote reference parameters. Parameters are of type int unless
+	// the name is followed by another type, e.g. "s string".
+	Type   string
+	Value  int32
+	Params []string
+}
+
+// Universe is the table of the predeclared objects: the type int, the library
+// procedures of the runtime, the objects of language extensions and the
+// procedures of tests. The universe scopes of the parser and the predeclared
+// objects of the type checker are built from it.
+var Universe = []Predeclared{
+	{Kind: Typ, Name: "int"},
+	{Kind: Pro, Name: "printi", Params: []string{"i"}},
+	{Kind: Pro, Name: "printc", Params: []string{"i"}},
+	{Kind: Pro, Name: "readi", Params: []string{"ref i"}},
+	{Kind: Pro, Name: "readc", Params: []string{"ref i"}},
+	{Kind: Pro, Name: "exit"},
+	{Kind: Pro, Name: "time", Params: []string{"ref i"}},
+	{Kind: Pro, Name: "clearAll", Params: []string{"color"}},
+	{Kind: Pro, Name: "setPixel", Params: []string{"x", "y", "color"}},
+	{Kind: Pro, Name: "drawLine", Params: []string{"x1", "y1", "x2", "y2", "color"}},
+	{Kind: Pro, Name: "drawCircle", Params: []string{"x0", "y0", "radius", "color"}},
+	{Kind: Typ, Name: "bool", Ext: ext.Bool},
+	{Kind: Con, Name: "true", Ext: ext.Bool, Type: "bool", Value: 1},
+	{Kind: Con, Name: "false", Ext: ext.Bool, Type: "bool", Value: 0},
+	{Kind: Pro, Name: "prints", Ext: ext.Strings, Params: []string{"s string"}},
+	{Kind: Pro, Name: "assertEq", Test: true, Params: []string{"got", "want"}},
+	{Kind: Pro, Name: "fail", Test: true},
+}
+
+// universe holds the scope objects of the entries of Universe, which are
+// shared by all universe scopes.
+var universe = make([]*Object, len(Universe))
+
+func init() {
+	for i, p := range Universe {
+		universe[i] = NewObj(p.Kind, p.Name)
+	}
+}
+
+// UniverseScope returns the outermost scope of programs using the language
+// extensions. It holds the predeclared objects of the extensions and, if test
+// is set, the procedures of tests. Predeclared objects have no declaration.
+func UniverseScope(exts ext.Set, test bool) *Scope {
+	s := NewScope(nil)
+	for i, p := range Universe {
+		if exts.Has(p.Ext) && (test || !p.Test) {
+			s.Insert(universe[i])
+		}
+	}
+	return s
+}
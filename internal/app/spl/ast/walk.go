@@ -0,0 +1,162 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is invoked for each node encountered by Walk. If
+// the result visitor w is not nil, Walk visits each of the children of node
+// with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order: It starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor w for
+// each of the non-nil children of node, followed by a call of w.Visit(nil).
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	// Expressions and types
+	case *Field:
+		Walk(v, n.Name)
+		Walk(v, n.Type)
+
+	case *FieldList:
+		for _, f := range n.List {
+			Walk(v, f)
+		}
+
+	case *BadExpr, *Ident, *IntLit, *StringLit:
+		// nothing to do
+
+	case *ParenExpr:
+		Walk(v, n.X)
+
+	case *UnaryExpr:
+		Walk(v, n.X)
+
+	case *BinaryExpr:
+		Walk(v, n.X)
+		Walk(v, n.Y)
+
+	case *IndexExpr:
+		Walk(v, n.X)
+		Walk(v, n.Index)
+
+	case *SelectorExpr:
+		Walk(v, n.X)
+		Walk(v, n.Sel)
+
+	case *CallExpr:
+		Walk(v, n.Pro)
+		for _, x := range n.Args {
+			Walk(v, x)
+		}
+
+	case *ArrayType:
+		Walk(v, n.Len)
+		Walk(v, n.Elt)
+
+	case *RecordType:
+		Walk(v, n.Fields)
+
+	// Statements
+	case *BadStmt, *EmptyStmt:
+		// nothing to do
+
+	case *DeclStmt:
+		Walk(v, n.Decl)
+
+	case *BlockStmt:
+		for _, s := range n.List {
+			Walk(v, s)
+		}
+
+	case *ExprStmt:
+		Walk(v, n.X)
+
+	case *AssignStmt:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+
+	case *WhileStmt:
+		Walk(v, n.Cond)
+		Walk(v, n.Body)
+
+	case *IfStmt:
+		Walk(v, n.Cond)
+		Walk(v, n.Body)
+		if n.Else != nil {
+			Walk(v, n.Else)
+		}
+
+	case *ReturnStmt:
+		if n.Result != nil {
+			Walk(v, n.Result)
+		}
+
+	// Declarations
+	case *BadDecl:
+		// nothing to do
+
+	case *ImportDecl:
+		Walk(v, n.Path)
+		Walk(v, n.Name)
+
+	case *VarDecl:
+		Walk(v, n.Name)
+		Walk(v, n.Type)
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	case *TypeDecl:
+		Walk(v, n.Name)
+		Walk(v, n.Type)
+
+	case *ProcDecl:
+		Walk(v, n.Name)
+		if n.Params != nil {
+			Walk(v, n.Params)
+		}
+		if n.Result != nil {
+			Walk(v, n.Result)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	case *Comment:
+		// nothing to do
+
+	case *Program:
+		for _, d := range n.Decls {
+			Walk(v, d)
+		}
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a call
+// of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,6 @@
+// Package amd64 implements the native code generator for x86-64 Linux. It
+// translates a type checked program into machine code, links it with a small
+// runtime which talks to the kernel through system calls and writes the result
+// as a statically linked ELF executable. No external assembler or linker is
+// required.
+package amd64
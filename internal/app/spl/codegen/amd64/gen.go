@@ -0,0 +1,446 @@
+package amd64
+
+import (
+	"debug/elf"
+	"fmt"
+	"io"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/diag"
+	splelf "github.com/lukasmalkmus/spl/internal/app/spl/elf"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+	"github.com/lukasmalkmus/spl/internal/app/spl/x86"
+)
+
+// Target is the name of the target platform of the code generator.
+const Target = "linux-amd64"
+
+// textAddr is the virtual address the code is loaded to.
+const textAddr = 0x401000
+
+// maxFrameSize is the maximum size of the local variables of a procedure.
+const maxFrameSize = 1 << 30
+
+// Compile compiles the type checked program into a statically linked x86-64
+// Linux executable and writes it to w. Procedures of the graphics library are
+// not supported by the target and reported as error.
+func Compile(w io.Writer, prog *ast.Program, info *types.Info) error {
+	g := &generator{
+		info:  info,
+		procs: make(map[*types.Proc]x86.Label),
+	}
+	g.rt = newRuntime(&g.asm)
+	for _, proc := range info.Procs {
+		g.procs[proc] = g.asm.NewLabel()
+	}
+
+	g.entry(info.Procs)
+	for _, proc := range info.Procs {
+		g.proc(proc)
+	}
+	g.rt.emit()
+	if err := g.errors.Err(); err != nil {
+		return err
+	}
+
+	text, err := g.asm.Bytes()
+	if err != nil {
+		return err
+	}
+	dataAddr := textAddr + alignUp(uint64(len(text)), splelf.PageSize)
+	for _, r := range g.asm.Relocs() {
+		x86.Patch(text, r.Offset, int32(dataAddr+uint64(r.Add)-(textAddr+uint64(r.Offset)+4)))
+	}
+
+	f := &splelf.File{
+		Entry: textAddr,
+		Segments: []*splelf.Segment{
+			{Addr: textAddr, Flags: elf.PF_R | elf.PF_X, Data: text},
+			{Addr: dataAddr, Flags: elf.PF_R | elf.PF_W, MemSize: dataSize},
+		},
+	}
+	_, err = f.WriteTo(w)
+	return err
+}
+
+// generator maintains the state of the code generator.
+type generator struct {
+	asm    x86.Assembler
+	info   *types.Info
+	rt     *runtime
+	errors parser.ErrorList
+
+	// procs maps procedures to the labels of their entry points.
+	procs map[*types.Proc]x86.Label
+
+	// frame maps the variables of the current procedure to their offsets
+	// relative to the frame pointer.
+	frame map[*types.Var]int32
+
+	// ret is the label of the epilogue of the current procedure.
+	ret x86.Label
+}
+
+// entry emits the entry point of the executable. It initializes the runtime,
+// calls the main procedure and exits the process.
+func (g *generator) entry(procs []*types.Proc) {
+	g.rt.init()
+	for _, proc := range procs {
+		if proc.LinkName() == "main" {
+			g.asm.Call(g.procs[proc])
+		}
+	}
+	g.asm.Call(g.rt.exit)
+}
+
+// -----------------------------------------------------------------------------
+// Procedures
+
+// proc emits a procedure. Arguments are pushed onto the stack from left to
+// right, each occupying eight bytes, and are removed by the caller. The result
+// is returned in EAX. Local variables are zero initialized, character buffers
+// with an initial value are then filled by copying the constant characters.
+func (g *generator) proc(proc *types.Proc) {
+	g.frame = make(map[*types.Var]int32)
+	params := proc.Params()
+	for i, v := range params {
+		g.frame[v] = int32(16 + 8*(len(params)-1-i))
+	}
+	var size int64
+	for _, v := range proc.Locals() {
+		size += types.Sizeof(v.Type())
+		g.frame[v] = int32(-size)
+	}
+	size = int64(alignUp(uint64(size), 16))
+	if size > maxFrameSize {
+		g.errorf(proc.Decl().Name, "local variables of procedure %s exceed the maximum frame size", proc.Name())
+		return
+	}
+
+	g.ret = g.asm.NewLabel()
+	g.asm.Bind(g.procs[proc])
+	g.asm.Push(x86.RBP)
+	g.asm.Mov(x86.Q, x86.RBP, x86.RSP)
+	if size > 0 {
+		g.asm.Sub(x86.Q, x86.RSP, x86.Imm(size))
+		g.asm.Mov(x86.Q, x86.RDI, x86.RSP)
+		g.asm.Mov(x86.L, x86.RCX, x86.Imm(size/4))
+		g.asm.Xor(x86.L, x86.RAX, x86.RAX)
+		g.asm.RepStosl()
+	}
+	for _, v := range proc.Locals() {
+		if init := v.Init(); init != nil && len(g.info.Strings[init]) > 0 {
+			s := g.info.Strings[init]
+			g.asm.Lea(x86.RDI, x86.Mem{Base: x86.RBP, Disp: g.frame[v]})
+			g.asm.LeaLabel(x86.RSI, g.rt.chars(s))
+			g.asm.Mov(x86.L, x86.RCX, x86.Imm(len(s)))
+			g.asm.RepMovsl()
+		}
+	}
+	g.stmtList(proc.Decl().Body.List)
+	g.asm.Bind(g.ret)
+	g.asm.Leave()
+	g.asm.Ret()
+}
+
+// -----------------------------------------------------------------------------
+// Statements
+
+func (g *generator) stmtList(list []ast.Stmt) {
+	for _, s := range list {
+		g.stmt(s)
+	}
+}
+
+func (g *generator) stmt(s ast.Stmt) {
+	switch s := s.(type) {
+	case *ast.BlockStmt:
+		g.stmtList(s.List)
+	case *ast.ExprStmt:
+		g.call(unparen(s.X).(*ast.CallExpr))
+	case *ast.AssignStmt:
+		// The left hand side is evaluated before the right hand side.
+		g.addr(s.Left)
+		g.asm.Push(x86.RAX)
+		g.expr(s.Right)
+		g.asm.Pop(x86.RCX)
+		g.asm.Mov(x86.L, x86.Mem{Base: x86.RCX}, x86.RAX)
+	case *ast.IfStmt:
+		els, end := g.asm.NewLabel(), g.asm.NewLabel()
+		g.cond(s.Cond, els)
+		g.stmt(s.Body)
+		if s.Else != nil {
+			g.asm.Jmp(end)
+		}
+		g.asm.Bind(els)
+		if s.Else != nil {
+			g.stmt(s.Else)
+		}
+		g.asm.Bind(end)
+	case *ast.WhileStmt:
+		top, end := g.asm.NewLabel(), g.asm.NewLabel()
+		g.asm.Bind(top)
+		g.cond(s.Cond, end)
+		g.stmt(s.Body)
+		g.asm.Jmp(top)
+		g.asm.Bind(end)
+	case *ast.ReturnStmt:
+		if s.Result != nil {
+			g.expr(s.Result)
+		}
+		g.asm.Jmp(g.ret)
+	}
+}
+
+// call emits a procedure call. Reference parameters are passed as addresses,
+// value parameters as values. The result is left in EAX.
+func (g *generator) call(x *ast.CallExpr) {
+	proc := g.info.Callee(x)
+	target, ok := g.procs[proc]
+	if proc.Builtin() {
+		target, ok = g.rt.procs[proc.Name()]
+	}
+	if !ok {
+		g.errorf(x, "procedure %s is not supported by target %s", proc.Name(), Target)
+		return
+	}
+
+	params := proc.Params()
+	for i, arg := range x.Args {
+		if params[i].IsRef() {
+			g.addr(arg)
+		} else {
+			g.expr(arg)
+		}
+		g.asm.Push(x86.RAX)
+	}
+	g.asm.Call(target)
+	if n := len(x.Args); n > 0 {
+		g.asm.Add(x86.Q, x86.RSP, x86.Imm(8*n))
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Expressions
+
+// conds maps comparison operators to condition codes.
+var conds = map[token.Token]x86.Cond{
+	token.EQL: x86.CondE,
+	token.NOT: x86.CondNE,
+	token.LSS: x86.CondL,
+	token.LEQ: x86.CondLE,
+	token.GTR: x86.CondG,
+	token.GEQ: x86.CondGE,
+}
+
+// cond emits a jump to the label which is taken if the condition is false.
+func (g *generator) cond(e ast.Expr, f x86.Label) { g.branch(e, f, false) }
+
+// branch emits a jump to the label which is taken if the condition evaluates
+// to taken. The right operand of a logical operator is evaluated only if it
+// determines the result.
+func (g *generator) branch(e ast.Expr, l x86.Label, taken bool) {
+	switch e := unparen(e).(type) {
+	case *ast.UnaryExpr:
+		if e.Op == token.LNOT {
+			g.branch(e.X, l, !taken)
+			return
+		}
+	case *ast.BinaryExpr:
+		if e.Op == token.LAND || e.Op == token.LOR {
+			// The left operand of and jumps if it is false, the one of
+			// or if it is true, either way skipping the right operand.
+			if (e.Op == token.LOR) == taken {
+				g.branch(e.X, l, taken)
+				g.branch(e.Y, l, taken)
+				return
+			}
+			skip := g.asm.NewLabel()
+			g.branch(e.X, skip, !taken)
+			g.branch(e.Y, l, taken)
+			g.asm.Bind(skip)
+			return
+		}
+		if cc, ok := conds[e.Op]; ok {
+			g.operands(e)
+			g.asm.Cmp(x86.L, x86.RAX, x86.RCX)
+			if !taken {
+				cc = cc.Not()
+			}
+			g.asm.Jcc(cc, l)
+			return
+		}
+	}
+	g.expr(e)
+	g.asm.Test(x86.L, x86.RAX, x86.RAX)
+	if taken {
+		g.asm.Jcc(x86.CondNE, l)
+	} else {
+		g.asm.Jcc(x86.CondE, l)
+	}
+}
+
+// operands evaluates the operands of a binary expression from left to right.
+// The left operand is left in EAX, the right one in ECX.
+func (g *generator) operands(b *ast.BinaryExpr) {
+	g.expr(b.X)
+	g.asm.Push(x86.RAX)
+	g.expr(b.Y)
+	g.asm.Mov(x86.L, x86.RCX, x86.RAX)
+	g.asm.Pop(x86.RAX)
+}
+
+// expr evaluates an expression and leaves its value in EAX. Logical values are
+// represented as 0 (false) or 1 (true). The value of a string literal is the
+// address of its constant data in RAX.
+func (g *generator) expr(e ast.Expr) {
+	if v, ok := g.info.Values[e]; ok {
+		g.asm.Mov(x86.L, x86.RAX, x86.Imm(v))
+		return
+	}
+	if s, ok := g.info.Strings[e]; ok {
+		g.asm.LeaLabel(x86.RAX, g.rt.text(s))
+		return
+	}
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		g.expr(e.X)
+	case *ast.UnaryExpr:
+		g.expr(e.X)
+		if e.Op == token.LNOT {
+			g.asm.Xor(x86.L, x86.RAX, x86.Imm(1))
+		} else {
+			g.asm.Neg(x86.L, x86.RAX)
+		}
+	case *ast.BinaryExpr:
+		if e.Op == token.LAND || e.Op == token.LOR {
+			g.logical(e)
+		} else {
+			g.binary(e)
+		}
+	case *ast.Ident:
+		v := g.info.Uses[e].(*types.Var)
+		if v.IsRef() {
+			g.asm.Mov(x86.Q, x86.RAX, x86.Mem{Base: x86.RBP, Disp: g.frame[v]})
+			g.asm.Mov(x86.L, x86.RAX, x86.Mem{Base: x86.RAX})
+		} else {
+			g.asm.Mov(x86.L, x86.RAX, x86.Mem{Base: x86.RBP, Disp: g.frame[v]})
+		}
+	case *ast.IndexExpr, *ast.SelectorExpr:
+		g.addr(e)
+		g.asm.Mov(x86.L, x86.RAX, x86.Mem{Base: x86.RAX})
+	case *ast.CallExpr:
+		g.call(e)
+	}
+}
+
+func (g *generator) binary(b *ast.BinaryExpr) {
+	g.operands(b)
+	switch b.Op {
+	case token.ADD:
+		g.asm.Add(x86.L, x86.RAX, x86.RCX)
+	case token.SUB:
+		g.asm.Sub(x86.L, x86.RAX, x86.RCX)
+	case token.MUL:
+		g.asm.Imul(x86.L, x86.RAX, x86.RCX)
+	case token.QUO:
+		// Division by zero is a runtime error. Division of the smallest
+		// integer by -1 would trap, so negation is used instead which wraps
+		// around like the other arithmetic operations.
+		ok, neg, end := g.asm.NewLabel(), g.asm.NewLabel(), g.asm.NewLabel()
+		g.asm.Test(x86.L, x86.RCX, x86.RCX)
+		g.asm.Jcc(x86.CondNE, ok)
+		g.trap(g.rt.divideError, b.OpPos)
+		g.asm.Bind(ok)
+		g.asm.Cmp(x86.L, x86.RCX, x86.Imm(-1))
+		g.asm.Jcc(x86.CondE, neg)
+		g.asm.Cdq()
+		g.asm.Idiv(x86.L, x86.RCX)
+		g.asm.Jmp(end)
+		g.asm.Bind(neg)
+		g.asm.Neg(x86.L, x86.RAX)
+		g.asm.Bind(end)
+	default:
+		g.asm.Cmp(x86.L, x86.RAX, x86.RCX)
+		g.asm.Setcc(conds[b.Op], x86.RAX)
+		g.asm.Movzxb(x86.RAX, x86.RAX)
+	}
+}
+
+// logical evaluates an expression of a logical operator by branching on it.
+func (g *generator) logical(b *ast.BinaryExpr) {
+	f, end := g.asm.NewLabel(), g.asm.NewLabel()
+	g.cond(b, f)
+	g.asm.Mov(x86.L, x86.RAX, x86.Imm(1))
+	g.asm.Jmp(end)
+	g.asm.Bind(f)
+	g.asm.Mov(x86.L, x86.RAX, x86.Imm(0))
+	g.asm.Bind(end)
+}
+
+// addr computes the address of a variable, array element or record field and
+// leaves it in RAX. Array indices are bounds checked.
+func (g *generator) addr(e ast.Expr) {
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		g.addr(e.X)
+	case *ast.Ident:
+		v := g.info.Uses[e].(*types.Var)
+		if v.IsRef() {
+			g.asm.Mov(x86.Q, x86.RAX, x86.Mem{Base: x86.RBP, Disp: g.frame[v]})
+		} else {
+			g.asm.Lea(x86.RAX, x86.Mem{Base: x86.RBP, Disp: g.frame[v]})
+		}
+	case *ast.IndexExpr:
+		a := g.info.Types[e.X].(*types.Array)
+		g.addr(e.X)
+		g.asm.Push(x86.RAX)
+		g.expr(e.Index)
+		ok := g.asm.NewLabel()
+		g.asm.Cmp(x86.L, x86.RAX, x86.Imm(a.Len()))
+		g.asm.Jcc(x86.CondB, ok)
+		g.trap(g.rt.indexError, e.Lbrack)
+		g.asm.Bind(ok)
+		g.asm.Imul(x86.Q, x86.RAX, x86.Imm(types.Sizeof(a.Elem())))
+		g.asm.Pop(x86.RCX)
+		g.asm.Add(x86.Q, x86.RAX, x86.RCX)
+	case *ast.SelectorExpr:
+		r := g.info.Types[e.X].(*types.Record)
+		g.addr(e.X)
+		if off := r.Offset(g.info.Uses[e.Sel].(*types.Var)); off != 0 {
+			g.asm.Add(x86.Q, x86.RAX, x86.Imm(off))
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Helpers
+
+// unparen returns e with any enclosing parentheses stripped.
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		p, ok := e.(*ast.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.X
+	}
+}
+
+// alignUp rounds x up to a multiple of a.
+func alignUp(x, a uint64) uint64 { return (x + a - 1) &^ (a - 1) }
+
+func (g *generator) errorf(at ast.Node, format string, args ...interface{}) {
+	g.errors.Report(&diag.Diagnostic{Code: diag.Unsupported, Pos: at.Pos(), End: at.End(), Msg: fmt.Sprintf(format, args...)})
+}
+
+// trap calls the runtime error handler with the line of the source position in
+// EDI and its file name in R8 (address) and R9 (length).
+func (g *generator) trap(handler x86.Label, pos token.Position) {
+	g.asm.Mov(x86.L, x86.RDI, x86.Imm(pos.Line))
+	g.asm.LeaLabel(x86.R8, g.rt.str(pos.Filename))
+	g.asm.Mov(x86.L, x86.R9, x86.Imm(len(pos.Filename)))
+	g.asm.Call(handler)
+}
@@ -0,0 +1,211 @@
+package amd64_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/codegen/amd64"
+	"github.com/lukasmalkmus/spl/internal/app/spl/ext"
+	"github.com/lukasmalkmus/spl/internal/app/spl/internal/testutil"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+// exts are the language extensions the test programs may use.
+const exts = ext.Functions | ext.Bool | ext.Records | ext.Strings
+
+func TestCompile_FullValidProgram(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	exe := build(t, "../../testdata/valid.spl", dir)
+
+	out, err := exec.Command(exe).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(out), "\n\n"); n != 92 {
+		t.Errorf("got %d solutions, want 92", n)
+	}
+	if want := " 0 . . . . . . .\n . . . . 0 . . .\n"; !strings.HasPrefix(string(out), want) {
+		t.Errorf("got output starting with %q, want %q", out[:len(want)], want)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		input    string
+		output   string
+		stderr   string
+		exitCode int
+	}{
+		{
+			"print",
+			"proc main() { printi(-42); printc(' '); printi(-2147483647 - 1); printc('\\n'); }",
+			"",
+			"-42 -2147483648\n",
+			"",
+			0,
+		},
+		{
+			"arithmetic",
+			"proc main() { printi(7 / 2); printc(' '); printi(-7 / 2); printc(' '); printi(3 * -5 + 1); }",
+			"",
+			"3 -3 -14",
+			"",
+			0,
+		},
+		{
+			"read",
+			"proc main() { var i: int; readi(i); printi(i * 2); readc(i); printi(i); readc(i); printi(i); }",
+			"  -17\nZ",
+			"-3490-1",
+			"",
+			0,
+		},
+		{
+			"functions",
+			"proc fib(n: int): int { if (n < 2) return n; return fib(n - 1) + fib(n - 2); }\nproc hello(n: int) { if (n = 0) return; printc('h'); }\nproc main() { printi(fib(10) * 2); hello(0); hello(1); }",
+			"",
+			"110h",
+			"",
+			0,
+		},
+		{
+			"bool",
+			"proc inRange(i: int, n: int): bool { return 0 <= i and i < n; }\nproc flip(ref b: bool) { b := not b; }\nproc main() { var a: array [3] of bool; var b: bool; var i: int; a[1] := true; flip(b); while (inRange(i, 3)) { if (a[i] or i = 2 and b) printi(i); i := i + 1; } b := i < 0 and 1 / 0 = 0; if (not b or 1 / 0 = 0) printc('x'); }",
+			"",
+			"12x",
+			"",
+			0,
+		},
+		{
+			"records",
+			"type point = record { x: int; y: int; };\ntype line = record { from: point; to: point; };\nproc move(ref p: point, d: int) { p.x := p.x + d; p.y := (p).y - d; }\nproc main() { var l: line; var ps: array [2] of point; l.to.x := 5; move(l.to, 2); ps[1].y := l.to.x; move(ps[1], 1); printi(l.from.x); printi(l.to.x); printi(l.to.y); printi(ps[1].x); printi(ps[1].y); }",
+			"",
+			"07-216",
+			"",
+			0,
+		},
+		{
+			"strings",
+			"proc main() { var b: array [4] of int := \"ok\"; prints(\"a\\tb\\n\"); printc(b[0]); printc(b[1]); printi(b[2]); prints((\"\\\"\\\\\")); }",
+			"",
+			"a\tb\nok0\"\\",
+			"",
+			0,
+		},
+		{
+			"exit",
+			"proc main() { printi(1); exit(); printi(2); }",
+			"",
+			"1",
+			"",
+			0,
+		},
+		{
+			"index out of range",
+			"type A = array [3] of int;\nproc main() {\n  var a: A;\n  a[3] := 1;\n}",
+			"",
+			"",
+			"test.spl:4: runtime error: index out of range\n",
+			1,
+		},
+		{
+			"divide by zero",
+			"proc main() {\n  var i: int;\n  printi(1 / i);\n}",
+			"",
+			"",
+			"test.spl:3: runtime error: integer divide by zero\n",
+			1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testutil.TempDir(t)
+			defer cleanup()
+			filename := filepath.Join(dir, "test.spl")
+			if err := ioutil.WriteFile(filename, []byte(tt.src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			exe := build(t, filename, dir)
+
+			var stdout, stderr bytes.Buffer
+			cmd := exec.Command(exe)
+			cmd.Stdin = strings.NewReader(tt.input)
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			err := cmd.Run()
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				if code := exitErr.ExitCode(); code != tt.exitCode {
+					t.Errorf("got exit code %d, want %d", code, tt.exitCode)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if tt.exitCode != 0 {
+				t.Errorf("got exit code 0, want %d", tt.exitCode)
+			}
+			if got := stdout.String(); got != tt.output {
+				t.Errorf("got output %q, want %q", got, tt.output)
+			}
+			if got := strings.TrimPrefix(stderr.String(), filepath.Dir(filename)+string(filepath.Separator)); got != tt.stderr {
+				t.Errorf("got stderr %q, want %q", got, tt.stderr)
+			}
+		})
+	}
+}
+
+func TestCompile_UnsupportedProcedure(t *testing.T) {
+	prog, err := parser.ParseFile(token.NewFileSet(), "", "proc main() { clearAll(0); }", parser.DeclarationErrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := types.Check(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = amd64.Compile(&bytes.Buffer{}, prog, info)
+	if err == nil || !strings.Contains(err.Error(), "procedure clearAll is not supported by target linux-amd64") {
+		t.Errorf("got error %v, want unsupported procedure", err)
+	}
+}
+
+// build compiles the source file into an executable inside the directory and
+// returns its path. The test is skipped if the executable can't be run on
+// the host.
+func build(tb testing.TB, filename, dir string) string {
+	tb.Helper()
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		tb.Skipf("can't run %s executables on %s/%s", amd64.Target, runtime.GOOS, runtime.GOARCH)
+	}
+
+	prog, err := parser.ParseFiles(token.NewFileSet(), []string{filename}, exts, parser.DeclarationErrors)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	info, err := types.Check(prog)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	exe := filepath.Join(dir, "prog")
+	out, err := os.OpenFile(exe, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := amd64.Compile(out, prog, info); err != nil {
+		tb.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	return exe
+}
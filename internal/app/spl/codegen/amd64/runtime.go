@@ -0,0 +1,497 @@
+package amd64
+
+import (
+	"encoding/binary"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/x86"
+)
+
+// Linux system call numbers.
+const (
+	sysRead         = 0
+	sysWrite        = 1
+	sysClockGettime = 228
+	sysExitGroup    = 231
+)
+
+// clockMonotonic is the clock used to measure the time since the program was
+// started.
+const clockMonotonic = 1
+
+// The runtime keeps its state in a zero initialized data segment which is the
+// only relocation symbol. Output is buffered and flushed when the buffer is
+// full, before input is read and when the program terminates.
+const (
+	symData = iota
+)
+
+// Layout of the data segment.
+const (
+	dataOutLen = 0  // Number of bytes in the output buffer (8 bytes)
+	dataStart  = 8  // Start time of the program (struct timespec, 16 bytes)
+	dataOutBuf = 32 // Output buffer
+	outBufSize = 4096
+	dataSize   = dataOutBuf + outBufSize
+)
+
+// Runtime error messages and the exit status of a program terminated by a
+// runtime error.
+const (
+	msgIndex    = "index out of range"
+	msgDivide   = "integer divide by zero"
+	msgRuntime  = ": runtime error: "
+	exitFailure = 1
+)
+
+// runtime emits the runtime library: the library procedures of the language
+// and the support routines used by the generated code. Library procedures
+// follow the calling convention of the generated code. Support routines take
+// their arguments in registers.
+type runtime struct {
+	asm *x86.Assembler
+
+	// procs maps the names of the supported library procedures to their
+	// entry points.
+	procs map[string]x86.Label
+
+	// Entry points of support routines.
+	exit        x86.Label
+	indexError  x86.Label
+	divideError x86.Label
+	flush       x86.Label
+	write       x86.Label
+	ewrite      x86.Label
+	itoa        x86.Label
+	fatal       x86.Label
+
+	// Constant data.
+	strings    map[string]x86.Label
+	stringList []string
+}
+
+func newRuntime(asm *x86.Assembler) *runtime {
+	rt := &runtime{
+		asm:     asm,
+		procs:   make(map[string]x86.Label),
+		strings: make(map[string]x86.Label),
+	}
+	for _, name := range []string{"printi", "printc", "prints", "readi", "readc", "exit", "time"} {
+		rt.procs[name] = asm.NewLabel()
+	}
+	for _, l := range []*x86.Label{
+		&rt.exit, &rt.indexError, &rt.divideError, &rt.flush,
+		&rt.write, &rt.ewrite, &rt.itoa, &rt.fatal,
+	} {
+		*l = asm.NewLabel()
+	}
+	return rt
+}
+
+// str returns the label of a constant string.
+func (rt *runtime) str(s string) x86.Label {
+	l, ok := rt.strings[s]
+	if !ok {
+		l = rt.asm.NewLabel()
+		rt.strings[s] = l
+		rt.stringList = append(rt.stringList, s)
+	}
+	return l
+}
+
+// text returns the label of a string value: the length of s as 32-bit integer
+// followed by the characters of s.
+func (rt *runtime) text(s string) x86.Label {
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(len(s)))
+	return rt.str(string(n[:]) + s)
+}
+
+// chars returns the label of the characters of s stored like the elements of
+// an array of int.
+func (rt *runtime) chars(s string) x86.Label {
+	b := make([]byte, 4*len(s))
+	for i := range s {
+		binary.LittleEndian.PutUint32(b[4*i:], uint32(s[i]))
+	}
+	return rt.str(string(b))
+}
+
+// mem returns a memory operand.
+func mem(base x86.Reg, disp int32) x86.Mem { return x86.Mem{Base: base, Disp: disp} }
+
+// init emits the initialization of the runtime which records the start time of
+// the program.
+func (rt *runtime) init() {
+	a := rt.asm
+	a.Mov(x86.L, x86.RAX, x86.Imm(sysClockGettime))
+	a.Mov(x86.L, x86.RDI, x86.Imm(clockMonotonic))
+	a.LeaSym(x86.RSI, symData, dataStart)
+	a.Syscall()
+}
+
+// emit emits the runtime routines and constant data.
+func (rt *runtime) emit() {
+	rt.emitPrinti()
+	rt.emitPrintc()
+	rt.emitPrints()
+	rt.emitReadi()
+	rt.emitReadc()
+	rt.emitTime()
+	rt.emitExit()
+	rt.emitErrors()
+	rt.emitWrite()
+	rt.emitFlush()
+	rt.emitItoa()
+
+	a := rt.asm
+	for _, s := range rt.stringList {
+		a.Bind(rt.strings[s])
+		a.Data([]byte(s)...)
+	}
+}
+
+// prologue emits the standard procedure prologue which reserves size bytes of
+// stack space.
+func (rt *runtime) prologue(size int32) {
+	rt.asm.Push(x86.RBP)
+	rt.asm.Mov(x86.Q, x86.RBP, x86.RSP)
+	if size > 0 {
+		rt.asm.Sub(x86.Q, x86.RSP, x86.Imm(size))
+	}
+}
+
+// epilogue emits the standard procedure epilogue.
+func (rt *runtime) epilogue() {
+	rt.asm.Leave()
+	rt.asm.Ret()
+}
+
+// printi(i: int) writes the decimal representation of i.
+func (rt *runtime) emitPrinti() {
+	a := rt.asm
+	a.Bind(rt.procs["printi"])
+	rt.prologue(16)
+	a.Mov(x86.L, x86.RDI, mem(x86.RBP, 16))
+	a.Mov(x86.Q, x86.RSI, x86.RBP)
+	a.Call(rt.itoa)
+	a.Mov(x86.Q, x86.RDX, x86.RBP)
+	a.Sub(x86.Q, x86.RDX, x86.RSI)
+	a.Call(rt.write)
+	rt.epilogue()
+}
+
+// printc(i: int) writes the character with the ASCII code i.
+func (rt *runtime) emitPrintc() {
+	a := rt.asm
+	a.Bind(rt.procs["printc"])
+	rt.prologue(0)
+	a.Lea(x86.RSI, mem(x86.RBP, 16))
+	a.Mov(x86.L, x86.RDX, x86.Imm(1))
+	a.Call(rt.write)
+	rt.epilogue()
+}
+
+// prints(s: string) writes the characters of s. The argument is the address of
+// a string value, see text.
+func (rt *runtime) emitPrints() {
+	a := rt.asm
+	a.Bind(rt.procs["prints"])
+	rt.prologue(0)
+	a.Mov(x86.Q, x86.RSI, mem(x86.RBP, 16))
+	a.Mov(x86.L, x86.RDX, mem(x86.RSI, 0))
+	a.Add(x86.Q, x86.RSI, x86.Imm(4))
+	a.Call(rt.write)
+	rt.epilogue()
+}
+
+// readi(ref i: int) reads a line from standard input and stores the integer at
+// its beginning in i. Leading blanks and a sign are accepted, everything after
+// the digits is ignored. If there are no digits, 0 is stored.
+func (rt *runtime) emitReadi() {
+	const (
+		value = -8  // Value read so far
+		neg   = -12 // Negative sign seen
+		state = -16 // 0: leading blanks, 1: sign or digits, 2: rest of line
+		char  = -17 // Character read
+	)
+	a := rt.asm
+	loop, notDigit, notMinus, stop, end, positive := a.NewLabel(), a.NewLabel(), a.NewLabel(), a.NewLabel(), a.NewLabel(), a.NewLabel()
+
+	a.Bind(rt.procs["readi"])
+	rt.prologue(32)
+	a.Call(rt.flush)
+	a.Mov(x86.L, mem(x86.RBP, value), x86.Imm(0))
+	a.Mov(x86.L, mem(x86.RBP, neg), x86.Imm(0))
+	a.Mov(x86.L, mem(x86.RBP, state), x86.Imm(0))
+
+	a.Bind(loop)
+	a.Mov(x86.L, x86.RAX, x86.Imm(sysRead))
+	a.Xor(x86.L, x86.RDI, x86.RDI)
+	a.Lea(x86.RSI, mem(x86.RBP, char))
+	a.Mov(x86.L, x86.RDX, x86.Imm(1))
+	a.Syscall()
+	a.Cmp(x86.Q, x86.RAX, x86.Imm(1))
+	a.Jcc(x86.CondNE, end)
+	a.Movzxb(x86.RAX, mem(x86.RBP, char))
+	a.Cmp(x86.L, x86.RAX, x86.Imm('\n'))
+	a.Jcc(x86.CondE, end)
+	a.Mov(x86.L, x86.RCX, mem(x86.RBP, state))
+	a.Cmp(x86.L, x86.RCX, x86.Imm(2))
+	a.Jcc(x86.CondE, loop)
+
+	a.Mov(x86.L, x86.RDX, x86.RAX)
+	a.Sub(x86.L, x86.RDX, x86.Imm('0'))
+	a.Cmp(x86.L, x86.RDX, x86.Imm(9))
+	a.Jcc(x86.CondA, notDigit)
+	a.Mov(x86.L, x86.RAX, mem(x86.RBP, value))
+	a.Imul(x86.L, x86.RAX, x86.Imm(10))
+	a.Add(x86.L, x86.RAX, x86.RDX)
+	a.Mov(x86.L, mem(x86.RBP, value), x86.RAX)
+	a.Mov(x86.L, mem(x86.RBP, state), x86.Imm(1))
+	a.Jmp(loop)
+
+	a.Bind(notDigit)
+	a.Test(x86.L, x86.RCX, x86.RCX)
+	a.Jcc(x86.CondNE, stop)
+	a.Cmp(x86.L, x86.RAX, x86.Imm(' '))
+	a.Jcc(x86.CondE, loop)
+	a.Cmp(x86.L, x86.RAX, x86.Imm('\t'))
+	a.Jcc(x86.CondE, loop)
+	a.Mov(x86.L, mem(x86.RBP, state), x86.Imm(1))
+	a.Cmp(x86.L, x86.RAX, x86.Imm('-'))
+	a.Jcc(x86.CondNE, notMinus)
+	a.Mov(x86.L, mem(x86.RBP, neg), x86.Imm(1))
+	a.Jmp(loop)
+	a.Bind(notMinus)
+	a.Cmp(x86.L, x86.RAX, x86.Imm('+'))
+	a.Jcc(x86.CondE, loop)
+	a.Bind(stop)
+	a.Mov(x86.L, mem(x86.RBP, state), x86.Imm(2))
+	a.Jmp(loop)
+
+	a.Bind(end)
+	a.Mov(x86.L, x86.RAX, mem(x86.RBP, value))
+	a.Cmp(x86.L, mem(x86.RBP, neg), x86.Imm(0))
+	a.Jcc(x86.CondE, positive)
+	a.Neg(x86.L, x86.RAX)
+	a.Bind(positive)
+	a.Mov(x86.Q, x86.RCX, mem(x86.RBP, 16))
+	a.Mov(x86.L, mem(x86.RCX, 0), x86.RAX)
+	rt.epilogue()
+}
+
+// readc(ref i: int) reads a single character from standard input and stores
+// its ASCII code in i. At the end of the input -1 is stored.
+func (rt *runtime) emitReadc() {
+	a := rt.asm
+	ok, store := a.NewLabel(), a.NewLabel()
+
+	a.Bind(rt.procs["readc"])
+	rt.prologue(16)
+	a.Call(rt.flush)
+	a.Mov(x86.L, x86.RAX, x86.Imm(sysRead))
+	a.Xor(x86.L, x86.RDI, x86.RDI)
+	a.Lea(x86.RSI, mem(x86.RBP, -1))
+	a.Mov(x86.L, x86.RDX, x86.Imm(1))
+	a.Syscall()
+	a.Cmp(x86.Q, x86.RAX, x86.Imm(1))
+	a.Jcc(x86.CondE, ok)
+	a.Mov(x86.L, x86.RAX, x86.Imm(-1))
+	a.Jmp(store)
+	a.Bind(ok)
+	a.Movzxb(x86.RAX, mem(x86.RBP, -1))
+	a.Bind(store)
+	a.Mov(x86.Q, x86.RCX, mem(x86.RBP, 16))
+	a.Mov(x86.L, mem(x86.RCX, 0), x86.RAX)
+	rt.epilogue()
+}
+
+// time(ref i: int) stores the number of seconds since the start of the program
+// in i.
+func (rt *runtime) emitTime() {
+	a := rt.asm
+	a.Bind(rt.procs["time"])
+	rt.prologue(16)
+	a.Mov(x86.L, x86.RAX, x86.Imm(sysClockGettime))
+	a.Mov(x86.L, x86.RDI, x86.Imm(clockMonotonic))
+	a.Lea(x86.RSI, mem(x86.RBP, -16))
+	a.Syscall()
+	a.Mov(x86.Q, x86.RAX, mem(x86.RBP, -16))
+	a.LeaSym(x86.R8, symData, 0)
+	a.Sub(x86.Q, x86.RAX, mem(x86.R8, dataStart))
+	a.Mov(x86.Q, x86.RCX, mem(x86.RBP, 16))
+	a.Mov(x86.L, mem(x86.RCX, 0), x86.RAX)
+	rt.epilogue()
+}
+
+// exit() flushes the output and terminates the program. It is also called
+// after the main procedure returned.
+func (rt *runtime) emitExit() {
+	a := rt.asm
+	a.Bind(rt.procs["exit"])
+	a.Bind(rt.exit)
+	a.Call(rt.flush)
+	a.Mov(x86.L, x86.RAX, x86.Imm(sysExitGroup))
+	a.Xor(x86.L, x86.RDI, x86.RDI)
+	a.Syscall()
+}
+
+// emitErrors emits the runtime error handlers. They take the source line in
+// EDI and the file name in R8 (address) and R9 (length), print an error
+// message to standard error and terminate the program with a non-zero exit
+// status.
+func (rt *runtime) emitErrors() {
+	a := rt.asm
+	for _, e := range []struct {
+		l   x86.Label
+		msg string
+	}{
+		{rt.indexError, msgIndex},
+		{rt.divideError, msgDivide},
+	} {
+		a.Bind(e.l)
+		a.LeaLabel(x86.RSI, rt.str(e.msg))
+		a.Mov(x86.L, x86.RDX, x86.Imm(len(e.msg)))
+		a.Jmp(rt.fatal)
+	}
+
+	// fatal takes the line in EDI, the file name in R8 and R9 and the
+	// message in RSI (address) and RDX (length). It prints
+	// "file:line: runtime error: message".
+	const (
+		line    = -8
+		msg     = -16
+		msgLen  = -24
+		file    = -32
+		fileLen = -40
+		buf     = -48
+	)
+	a.Bind(rt.fatal)
+	rt.prologue(64)
+	a.Mov(x86.L, mem(x86.RBP, line), x86.RDI)
+	a.Mov(x86.Q, mem(x86.RBP, msg), x86.RSI)
+	a.Mov(x86.Q, mem(x86.RBP, msgLen), x86.RDX)
+	a.Mov(x86.Q, mem(x86.RBP, file), x86.R8)
+	a.Mov(x86.Q, mem(x86.RBP, fileLen), x86.R9)
+	a.Call(rt.flush)
+	a.Mov(x86.Q, x86.RSI, mem(x86.RBP, file))
+	a.Mov(x86.Q, x86.RDX, mem(x86.RBP, fileLen))
+	a.Call(rt.ewrite)
+	a.LeaLabel(x86.RSI, rt.str(":"))
+	a.Mov(x86.L, x86.RDX, x86.Imm(1))
+	a.Call(rt.ewrite)
+	a.Mov(x86.L, x86.RDI, mem(x86.RBP, line))
+	a.Lea(x86.RSI, mem(x86.RBP, buf))
+	a.Call(rt.itoa)
+	a.Lea(x86.RDX, mem(x86.RBP, buf))
+	a.Sub(x86.Q, x86.RDX, x86.RSI)
+	a.Call(rt.ewrite)
+	a.LeaLabel(x86.RSI, rt.str(msgRuntime))
+	a.Mov(x86.L, x86.RDX, x86.Imm(len(msgRuntime)))
+	a.Call(rt.ewrite)
+	a.Mov(x86.Q, x86.RSI, mem(x86.RBP, msg))
+	a.Mov(x86.Q, x86.RDX, mem(x86.RBP, msgLen))
+	a.Call(rt.ewrite)
+	a.LeaLabel(x86.RSI, rt.str("\n"))
+	a.Mov(x86.L, x86.RDX, x86.Imm(1))
+	a.Call(rt.ewrite)
+	a.Mov(x86.L, x86.RAX, x86.Imm(sysExitGroup))
+	a.Mov(x86.L, x86.RDI, x86.Imm(exitFailure))
+	a.Syscall()
+
+	// ewrite writes RDX bytes at RSI to standard error, unbuffered.
+	a.Bind(rt.ewrite)
+	a.Mov(x86.L, x86.RAX, x86.Imm(sysWrite))
+	a.Mov(x86.L, x86.RDI, x86.Imm(2))
+	a.Syscall()
+	a.Ret()
+}
+
+// write appends RDX bytes at RSI to the output buffer. The buffer is flushed
+// whenever it is full.
+func (rt *runtime) emitWrite() {
+	a := rt.asm
+	loop, notFull, done := a.NewLabel(), a.NewLabel(), a.NewLabel()
+
+	a.Bind(rt.write)
+	a.Bind(loop)
+	a.Test(x86.Q, x86.RDX, x86.RDX)
+	a.Jcc(x86.CondE, done)
+	a.LeaSym(x86.R8, symData, 0)
+	a.Mov(x86.Q, x86.RCX, mem(x86.R8, dataOutLen))
+	a.Cmp(x86.Q, x86.RCX, x86.Imm(outBufSize))
+	a.Jcc(x86.CondNE, notFull)
+	a.Push(x86.RSI)
+	a.Push(x86.RDX)
+	a.Call(rt.flush)
+	a.Pop(x86.RDX)
+	a.Pop(x86.RSI)
+	a.LeaSym(x86.R8, symData, 0)
+	a.Mov(x86.Q, x86.RCX, mem(x86.R8, dataOutLen))
+	a.Bind(notFull)
+	a.Movzxb(x86.RAX, mem(x86.RSI, 0))
+	a.Lea(x86.RDI, mem(x86.R8, dataOutBuf))
+	a.Add(x86.Q, x86.RDI, x86.RCX)
+	a.Mov(x86.B, mem(x86.RDI, 0), x86.RAX)
+	a.Add(x86.Q, x86.RCX, x86.Imm(1))
+	a.Mov(x86.Q, mem(x86.R8, dataOutLen), x86.RCX)
+	a.Add(x86.Q, x86.RSI, x86.Imm(1))
+	a.Sub(x86.Q, x86.RDX, x86.Imm(1))
+	a.Jmp(loop)
+	a.Bind(done)
+	a.Ret()
+}
+
+// flush writes the contents of the output buffer to standard output.
+func (rt *runtime) emitFlush() {
+	a := rt.asm
+	done := a.NewLabel()
+
+	a.Bind(rt.flush)
+	a.LeaSym(x86.R8, symData, 0)
+	a.Mov(x86.Q, x86.RDX, mem(x86.R8, dataOutLen))
+	a.Test(x86.Q, x86.RDX, x86.RDX)
+	a.Jcc(x86.CondE, done)
+	a.Mov(x86.L, x86.RAX, x86.Imm(sysWrite))
+	a.Mov(x86.L, x86.RDI, x86.Imm(1))
+	a.Lea(x86.RSI, mem(x86.R8, dataOutBuf))
+	a.Syscall()
+	a.LeaSym(x86.R8, symData, 0)
+	a.Mov(x86.Q, mem(x86.R8, dataOutLen), x86.Imm(0))
+	a.Bind(done)
+	a.Ret()
+}
+
+// itoa converts the integer in EDI to its decimal representation. The digits
+// are stored in front of the address in RSI, which is updated to point to the
+// first character.
+func (rt *runtime) emitItoa() {
+	a := rt.asm
+	positive, loop, done := a.NewLabel(), a.NewLabel(), a.NewLabel()
+
+	a.Bind(rt.itoa)
+	a.Mov(x86.L, x86.RAX, x86.RDI)
+	a.Xor(x86.L, x86.R8, x86.R8)
+	a.Test(x86.L, x86.RAX, x86.RAX)
+	a.Jcc(x86.CondNS, positive)
+	// The absolute value of the smallest integer is representable as
+	// unsigned integer, so an unsigned division is used.
+	a.Neg(x86.L, x86.RAX)
+	a.Mov(x86.L, x86.R8, x86.Imm(1))
+	a.Bind(positive)
+	a.Mov(x86.L, x86.RCX, x86.Imm(10))
+	a.Bind(loop)
+	a.Xor(x86.L, x86.RDX, x86.RDX)
+	a.Div(x86.L, x86.RCX)
+	a.Add(x86.L, x86.RDX, x86.Imm('0'))
+	a.Sub(x86.Q, x86.RSI, x86.Imm(1))
+	a.Mov(x86.B, mem(x86.RSI, 0), x86.RDX)
+	a.Test(x86.L, x86.RAX, x86.RAX)
+	a.Jcc(x86.CondNE, loop)
+	a.Test(x86.L, x86.R8, x86.R8)
+	a.Jcc(x86.CondE, done)
+	a.Sub(x86.Q, x86.RSI, x86.Imm(1))
+	a.Mov(x86.B, mem(x86.RSI, 0), x86.Imm('-'))
+	a.Bind(done)
+	a.Ret()
+}
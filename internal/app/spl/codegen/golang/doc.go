@@ -0,0 +1,11 @@
+// Package golang implements a code generator which translates a type checked
+// program into a standalone Go program. Procedures become functions, reference
+// parameters become pointers and arrays become Go arrays, so index checks are
+// done by the Go runtime. The library procedures used by the program are
+// emitted as Go source code, too.
+//
+// Identifiers which clash with Go keywords, predeclared identifiers or names
+// used by the generated runtime are suffixed with an underscore. This never
+// introduces new clashes because identifiers of the simple programming language
+// must not end with an underscore.
+package golang
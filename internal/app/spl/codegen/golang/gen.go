@@ -0,0 +1,486 @@
+package golang
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/diag"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+// Target is the name of the target platform of the code generator.
+const Target = "go"
+
+// reserved contains the Go keywords, the predeclared identifiers of Go and the
+// package level names of the generated code.
+var reserved = map[string]bool{}
+
+func init() {
+	for _, name := range strings.Fields(`
+		break case chan const continue default defer else fallthrough for
+		func go goto if import interface map package range return select
+		struct switch type var
+		any bool byte comparable complex64 complex128 error float32 float64
+		int int8 int16 int32 int64 rune string uint uint8 uint16 uint32
+		uint64 uintptr true false iota nil append cap clear close complex
+		copy delete imag len make max min new panic print println real
+		recover
+		init bufio os strconv strings time out in start flush fail idx div`) {
+		reserved[name] = true
+	}
+}
+
+// Compile compiles the type checked program into the source code of a Go
+// program and writes it to w. Procedures of the graphics library are not
+// supported by the target and reported as error.
+func Compile(w io.Writer, prog *ast.Program, info *types.Info) error {
+	g := &generator{
+		info:    info,
+		runtime: map[string]bool{"out": true},
+		aliases: make(map[types.Object]bool),
+	}
+	for _, decl := range prog.Decls {
+		switch d := decl.(type) {
+		case *ast.TypeDecl:
+			g.printf("\ntype %s = %s\n", name(d.Name.Name), g.typ(d.Type))
+			g.aliases[info.Defs[d.Name]] = true
+		case *ast.ProcDecl:
+			g.proc(info.Defs[d.Name].(*types.Proc))
+		}
+	}
+	for _, proc := range info.Procs {
+		if proc.Module() != "" {
+			g.proc(proc)
+		}
+	}
+	if err := g.errors.Err(); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by spl from %s. DO NOT EDIT.\n\n", prog.Name)
+	fmt.Fprintf(&buf, "package main\n")
+	g.emitRuntime(&buf)
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting generated code: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// generator maintains the state of the code generator.
+type generator struct {
+	buf    bytes.Buffer
+	info   *types.Info
+	errors parser.ErrorList
+
+	// runtime holds the names of the runtime snippets required by the
+	// program.
+	runtime map[string]bool
+
+	// aliases holds the types declared by the program, which are emitted as
+	// type aliases.
+	aliases map[types.Object]bool
+
+	// used holds the local variables of the current procedure which are used
+	// in the sense of the Go compiler, that is read at least once.
+	used map[*types.Var]bool
+}
+
+// emitRuntime writes the imports and the runtime to the buffer which already
+// holds the package clause. The generated program is appended afterwards.
+func (g *generator) emitRuntime(buf *bytes.Buffer) {
+	var names []string
+	for name := range g.runtime {
+		names = append(names, name)
+		for _, dep := range runtime[name].deps {
+			if !g.runtime[dep] {
+				names = append(names, dep)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	imports := map[string]bool{}
+	var src bytes.Buffer
+	for i, name := range names {
+		if i > 0 && names[i-1] == name {
+			continue
+		}
+		for _, imp := range runtime[name].imports {
+			imports[imp] = true
+		}
+		src.WriteString(runtime[name].src)
+	}
+
+	paths := make([]string, 0, len(imports))
+	for imp := range imports {
+		paths = append(paths, fmt.Sprintf("%q", imp))
+	}
+	sort.Strings(paths)
+	fmt.Fprintf(buf, "\nimport (\n%s\n)\n", strings.Join(paths, "\n"))
+	_, _ = g.buf.WriteTo(buf)
+	fmt.Fprintf(buf, "\n// Runtime\n")
+	_, _ = src.WriteTo(buf)
+}
+
+// -----------------------------------------------------------------------------
+// Procedures
+
+// proc emits a procedure as Go function. The main procedure becomes the main
+// function of the Go program which flushes the output when it returns.
+func (g *generator) proc(proc *types.Proc) {
+	decl := proc.Decl()
+	g.used = make(map[*types.Var]bool)
+
+	params := make([]string, 0, len(decl.Params.List))
+	for _, f := range decl.Params.List {
+		t := g.typ(f.Type)
+		if f.Ref != token.NoPos {
+			t = "*" + t
+		}
+		params = append(params, name(f.Name.Name)+" "+t)
+	}
+
+	// The body is generated first to find the unused local variables.
+	head := g.buf
+	g.buf = bytes.Buffer{}
+	var locals []*types.Var
+	for _, s := range decl.Body.List {
+		if d, ok := s.(*ast.DeclStmt); ok {
+			if v, ok := d.Decl.(*ast.VarDecl); ok {
+				locals = append(locals, g.info.Defs[v.Name].(*types.Var))
+				continue
+			}
+		}
+		g.stmt(s)
+	}
+	body := g.buf
+	g.buf = head
+
+	result := ""
+	if proc.Result() != nil {
+		result = " " + goType(proc.Result())
+	}
+	if proc.LinkName() == "main" {
+		g.printf("\nfunc main() {\n")
+		g.printf("defer flush()\n")
+	} else {
+		g.printf("\nfunc %s(%s)%s {\n", name(proc.LinkName()), strings.Join(params, ", "), result)
+	}
+	for _, s := range decl.Body.List {
+		if d, ok := s.(*ast.DeclStmt); ok {
+			if v, ok := d.Decl.(*ast.VarDecl); ok {
+				g.varDecl(v)
+			}
+		}
+	}
+	for _, v := range locals {
+		if !g.used[v] {
+			g.printf("_ = %s\n", name(v.Name()))
+		}
+	}
+	_, _ = body.WriteTo(&g.buf)
+	g.printf("}\n")
+}
+
+// varDecl emits the declaration of a local variable. The characters of the
+// initial value of a character buffer are written as array literal.
+func (g *generator) varDecl(d *ast.VarDecl) {
+	init := g.info.Defs[d.Name].(*types.Var).Init()
+	if init == nil {
+		g.printf("var %s %s\n", name(d.Name.Name), g.typ(d.Type))
+		return
+	}
+	s := g.info.Strings[init]
+	chars := make([]string, len(s))
+	for i := range s {
+		chars[i] = strconv.QuoteRuneToASCII(rune(s[i]))
+	}
+	g.printf("var %s = %s{%s}\n", name(d.Name.Name), g.typ(d.Type), strings.Join(chars, ", "))
+}
+
+// -----------------------------------------------------------------------------
+// Statements
+
+func (g *generator) stmt(s ast.Stmt) {
+	switch s := s.(type) {
+	case *ast.BlockStmt:
+		for _, s := range s.List {
+			g.stmt(s)
+		}
+	case *ast.ExprStmt:
+		g.printf("%s\n", g.call(unparen(s.X).(*ast.CallExpr)))
+	case *ast.AssignStmt:
+		g.printf("%s = %s\n", g.lvalue(s.Left), g.expr(s.Right))
+	case *ast.IfStmt:
+		g.printf("if %s {\n", g.expr(s.Cond))
+		g.stmt(s.Body)
+		if s.Else != nil {
+			g.printf("} else {\n")
+			g.stmt(s.Else)
+		}
+		g.printf("}\n")
+	case *ast.WhileStmt:
+		g.printf("for %s {\n", g.expr(s.Cond))
+		g.stmt(s.Body)
+		g.printf("}\n")
+	case *ast.ReturnStmt:
+		if s.Result != nil {
+			g.printf("return %s\n", g.expr(s.Result))
+		} else {
+			g.printf("return\n")
+		}
+	}
+}
+
+// call returns the Go expression of a procedure call. Reference parameters are
+// passed as pointers, value parameters as values.
+func (g *generator) call(x *ast.CallExpr) string {
+	proc := g.info.Callee(x)
+	if proc.Builtin() {
+		if _, ok := runtime[proc.Name()]; !ok {
+			g.errorf(x, "procedure %s is not supported by target %s", proc.Name(), Target)
+			return ""
+		}
+		g.runtime[proc.Name()] = true
+	}
+
+	params := proc.Params()
+	args := make([]string, 0, len(x.Args))
+	for i, arg := range x.Args {
+		if params[i].IsRef() {
+			args = append(args, g.ref(arg))
+		} else {
+			args = append(args, g.expr(arg))
+		}
+	}
+	return name(proc.LinkName()) + "(" + strings.Join(args, ", ") + ")"
+}
+
+// -----------------------------------------------------------------------------
+// Expressions
+
+// ops maps operators to Go operators.
+var ops = map[token.Token]string{
+	token.ADD:  "+",
+	token.SUB:  "-",
+	token.MUL:  "*",
+	token.QUO:  "/",
+	token.EQL:  "==",
+	token.NOT:  "!=",
+	token.LSS:  "<",
+	token.LEQ:  "<=",
+	token.GTR:  ">",
+	token.GEQ:  ">=",
+	token.LAND: "&&",
+	token.LOR:  "||",
+}
+
+// expr returns the Go expression of an expression. Character literals are
+// kept as such. Constant integer expressions are replaced by their values
+// folded with wraparound, because Go rejects constant expressions which
+// overflow int32.
+func (g *generator) expr(e ast.Expr) string {
+	if lit, ok := e.(*ast.IntLit); ok && strings.HasPrefix(lit.Value, "'") {
+		return strconv.QuoteRuneToASCII(rune(g.info.Values[e]))
+	}
+	if s, ok := g.info.Strings[e]; ok {
+		return strconv.Quote(s)
+	}
+	if v, ok := g.info.Values[e]; ok {
+		if types.IsBoolean(g.info.Types[e]) {
+			return strconv.FormatBool(v != 0)
+		}
+		return fmt.Sprint(v)
+	}
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return "(" + g.expr(e.X) + ")"
+	case *ast.UnaryExpr:
+		if e.Op == token.LNOT {
+			return "!" + g.expr(e.X)
+		}
+		// Nested negations must not be emitted as decrement operator.
+		x := g.expr(e.X)
+		if strings.HasPrefix(x, "-") {
+			return "-(" + x + ")"
+		}
+		return "-" + x
+	case *ast.BinaryExpr:
+		// Divisors other than non-zero constants are checked by the
+		// runtime, which reports the source line of a division by zero.
+		if v, ok := g.info.Values[e.Y]; e.Op == token.QUO && (!ok || v == 0) {
+			g.runtime["div"] = true
+			return fmt.Sprintf("div(%s, %s, %s)", g.expr(e.X), g.expr(e.Y), pos(e.OpPos))
+		}
+		return g.expr(e.X) + " " + ops[e.Op] + " " + g.expr(e.Y)
+	case *ast.Ident:
+		v := g.info.Uses[e].(*types.Var)
+		g.used[v] = true
+		if v.IsRef() {
+			return "*" + name(e.Name)
+		}
+		return name(e.Name)
+	case *ast.IndexExpr:
+		return g.index(e)
+	case *ast.SelectorExpr:
+		return g.field(e)
+	case *ast.CallExpr:
+		return g.call(e)
+	}
+	panic(fmt.Sprintf("golang: unexpected expression %T", e))
+}
+
+// lvalue returns the Go expression of the left hand side of an assignment.
+// Variables which are only assigned are not used.
+func (g *generator) lvalue(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return g.lvalue(e.X)
+	case *ast.Ident:
+		if g.info.Uses[e].(*types.Var).IsRef() {
+			return "*" + name(e.Name)
+		}
+		return name(e.Name)
+	case *ast.IndexExpr:
+		return g.index(e)
+	case *ast.SelectorExpr:
+		return g.field(e)
+	}
+	panic(fmt.Sprintf("golang: unexpected operand %T", e))
+}
+
+// index returns the Go expression of an indexed array. Indices other than
+// constants within the bounds of the array are checked by the runtime, which
+// reports the source line of an index out of range.
+func (g *generator) index(e *ast.IndexExpr) string {
+	n := g.info.Types[e.X].(*types.Array).Len()
+	i := g.expr(e.Index)
+	if v, ok := g.info.Values[e.Index]; !ok || v < 0 || int64(v) >= n {
+		g.runtime["idx"] = true
+		i = fmt.Sprintf("idx(%s, %d, %s)", i, n, pos(e.Lbrack))
+	}
+	return g.operand(e.X) + "[" + i + "]"
+}
+
+// field returns the Go expression of a selected record field.
+func (g *generator) field(e *ast.SelectorExpr) string {
+	return g.operand(e.X) + "." + name(e.Sel.Name)
+}
+
+// operand returns the Go expression of an indexed array or a record whose
+// field is selected. Go indexes pointers to arrays like arrays and selects the
+// fields of pointers to structs like those of structs, so reference
+// parameters are not dereferenced. The variable is used, even if the element
+// or field is only assigned.
+func (g *generator) operand(e ast.Expr) string {
+	if id, ok := unparen(e).(*ast.Ident); ok {
+		g.used[g.info.Uses[id].(*types.Var)] = true
+		return name(id.Name)
+	}
+	return g.lvalue(e)
+}
+
+// ref returns a pointer to the variable, array element or record field passed
+// as reference argument.
+func (g *generator) ref(e ast.Expr) string {
+	e = unparen(e)
+	if id, ok := e.(*ast.Ident); ok {
+		v := g.info.Uses[id].(*types.Var)
+		g.used[v] = true
+		if v.IsRef() {
+			return name(id.Name)
+		}
+		return "&" + name(id.Name)
+	}
+	return "&" + g.lvalue(e)
+}
+
+// typ returns the Go type of a type expression. Types declared by the program
+// are referred to by their alias, other types are spelled out.
+func (g *generator) typ(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.Ident:
+		if g.aliases[g.info.Uses[e]] {
+			return name(e.Name)
+		}
+		return goType(g.info.Types[e])
+	case *ast.SelectorExpr:
+		return goType(g.info.Types[e])
+	case *ast.ArrayType:
+		return fmt.Sprintf("[%d]%s", g.info.Types[e].(*types.Array).Len(), g.typ(e.Elt))
+	case *ast.RecordType:
+		fields := make([]string, len(e.Fields.List))
+		for i, f := range e.Fields.List {
+			fields[i] = name(f.Name.Name) + " " + g.typ(f.Type)
+		}
+		return "struct{ " + strings.Join(fields, "; ") + " }"
+	}
+	panic(fmt.Sprintf("golang: unexpected type %T", e))
+}
+
+// goType returns the Go type of a type.
+func goType(t types.Type) string {
+	switch t := t.(type) {
+	case *types.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), goType(t.Elem()))
+	case *types.Record:
+		fields := make([]string, len(t.Fields()))
+		for i, f := range t.Fields() {
+			fields[i] = name(f.Name()) + " " + goType(f.Type())
+		}
+		return "struct{ " + strings.Join(fields, "; ") + " }"
+	}
+	if types.IsBoolean(t) {
+		return "bool"
+	}
+	return "int32"
+}
+
+// -----------------------------------------------------------------------------
+// Helpers
+
+// name returns the Go identifier for an identifier of the program. Reserved
+// words and identifiers ending with an underscore, which include the blank
+// identifier, are suffixed with an underscore. This never clashes with other
+// identifiers.
+func name(s string) string {
+	if reserved[s] || strings.HasSuffix(s, "_") {
+		return s + "_"
+	}
+	return s
+}
+
+// pos returns the quoted source position of runtime errors, the file and the
+// line.
+func pos(p token.Position) string { return strconv.Quote(fmt.Sprintf("%s:%d", p.Filename, p.Line)) }
+
+// unparen returns e with any enclosing parentheses stripped.
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		p, ok := e.(*ast.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.X
+	}
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) errorf(at ast.Node, format string, args ...interface{}) {
+	g.errors.Report(&diag.Diagnostic{Code: diag.Unsupported, Pos: at.Pos(), End: at.End(), Msg: fmt.Sprintf(format, args...)})
+}
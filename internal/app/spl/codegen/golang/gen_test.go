@@ -0,0 +1,215 @@
+package golang_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/codegen/golang"
+	"github.com/lukasmalkmus/spl/internal/app/spl/ext"
+	"github.com/lukasmalkmus/spl/internal/app/spl/internal/testutil"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+// exts are the language extensions the test programs may use.
+const exts = ext.Functions | ext.Bool | ext.Records | ext.Strings
+
+func TestCompile_FullValidProgram(t *testing.T) {
+	out, err := run(t, "../../testdata/valid.spl", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out, "\n\n"); n != 92 {
+		t.Errorf("got %d solutions, want 92", n)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		input  string
+		output string
+	}{
+		{
+			"print",
+			"proc main() { printi(-42); printc(' '); printi(-2147483647 - 1); printc('\\n'); }",
+			"",
+			"-42 -2147483648\n",
+		},
+		{
+			"arithmetic",
+			"proc main() { printi(7 / 2); printc(' '); printi(- -7 / 2); printc(' '); printi(2147483647 + 1); }",
+			"",
+			"3 3 -2147483648",
+		},
+		{
+			"read",
+			"proc main() { var i: int; readi(i); printi(i * 2); readc(i); printi(i); readc(i); printi(i); }",
+			"  -17\nZ",
+			"-3490-1",
+		},
+		{
+			"reference parameters",
+			"type A = array [2] of int; proc f(ref a: A, ref i: int) { a[1] := 3; i := a[1] + 1; } proc main() { var a: A; var i: int; f(a, i); printi(a[1]); printi(i); }",
+			"",
+			"34",
+		},
+		{
+			"reserved names",
+			"proc init(ref len: int) { var int32: int; var out: int; int32 := len; len := int32 + 1; } proc main() { var x: int; init(x); printi(x); }",
+			"",
+			"1",
+		},
+		{
+			"functions",
+			"proc fib(n: int): int { if (n < 2) return n; return fib(n - 1) + fib(n - 2); }\nproc hello(n: int) { if (n = 0) return; printc('h'); }\nproc main() { printi(fib(10) * 2); hello(0); hello(1); }",
+			"",
+			"110h",
+		},
+		{
+			"bool",
+			"proc inRange(i: int, n: int): bool { return 0 <= i and i < n; }\nproc flip(ref b: bool) { b := not b; }\nproc main() { var a: array [3] of bool; var b: bool; var i: int; a[1] := true; flip(b); while (inRange(i, 3)) { if (a[i] or i = 2 and b) printi(i); i := i + 1; } b := i < 0 and 1 / 0 = 0; if (not b or 1 / 0 = 0) printc('x'); }",
+			"",
+			"12x",
+		},
+		{
+			"records",
+			"type point = record { x: int; y: int; };\ntype line = record { from: point; to: point; };\nproc move(ref p: point, d: int) { p.x := p.x + d; p.y := (p).y - d; }\nproc main() { var l: line; var ps: array [2] of point; l.to.x := 5; move(l.to, 2); ps[1].y := l.to.x; move(ps[1], 1); printi(l.from.x); printi(l.to.x); printi(l.to.y); printi(ps[1].x); printi(ps[1].y); }",
+			"",
+			"07-216",
+		},
+		{
+			"strings",
+			"proc main() { var b: array [4] of int := \"ok\"; prints(\"a\\tb\\n\"); printc(b[0]); printc(b[1]); printi(b[2]); prints((\"\\\"\\\\\")); }",
+			"",
+			"a\tb\nok0\"\\",
+		},
+		{
+			"exit",
+			"proc main() { printi(1); exit(); printi(2); }",
+			"",
+			"1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testutil.TempDir(t)
+			defer cleanup()
+			filename := filepath.Join(dir, "test.spl")
+			if err := ioutil.WriteFile(filename, []byte(tt.src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			out, err := run(t, filename, tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tt.output {
+				t.Errorf("got output %q, want %q", out, tt.output)
+			}
+		})
+	}
+}
+
+func TestCompile_DivideByZero(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	filename := filepath.Join(dir, "test.spl")
+	src := "proc main() {\n  var z: int;\n  printi(4 / 2 / 2);\n  printi(1 / z);\n}"
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := run(t, filename, "")
+	if want := "exit status 1: " + filename + ":4: runtime error: integer divide by zero\n"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+	if out != "1" {
+		t.Errorf("got output %q, want output to be flushed", out)
+	}
+}
+
+func TestCompile_IndexOutOfRange(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	filename := filepath.Join(dir, "test.spl")
+	src := "type A = array [3] of int;\nproc main() {\n  var a: A;\n  a[2] := 1;\n  printi(a[2]);\n  a[3] := 1;\n}"
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := run(t, filename, "")
+	if want := "exit status 1: " + filename + ":6: runtime error: index out of range\n"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+	if out != "1" {
+		t.Errorf("got output %q, want output to be flushed", out)
+	}
+}
+
+func TestCompile_UnsupportedProcedure(t *testing.T) {
+	prog, err := parser.ParseFile(token.NewFileSet(), "", "proc main() { clearAll(0); }", parser.DeclarationErrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := types.Check(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = golang.Compile(&bytes.Buffer{}, prog, info)
+	if err == nil || !strings.Contains(err.Error(), "procedure clearAll is not supported by target go") {
+		t.Errorf("got error %v, want unsupported procedure", err)
+	}
+}
+
+// run compiles the source file into a Go program, vets it and runs it with
+// the given input. It returns the output of the program. The test is skipped
+// if the go tool is not available.
+func run(tb testing.TB, filename, input string) (string, error) {
+	tb.Helper()
+	gotool, err := exec.LookPath("go")
+	if err != nil {
+		tb.Skip("go tool not available")
+	}
+
+	prog, err := parser.ParseFiles(token.NewFileSet(), []string{filename}, exts, parser.DeclarationErrors)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	info, err := types.Check(prog)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	var src bytes.Buffer
+	if err := golang.Compile(&src, prog, info); err != nil {
+		tb.Fatal(err)
+	}
+
+	dir, cleanup := testutil.TempDir(tb)
+	defer cleanup()
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), src.Bytes(), 0644); err != nil {
+		tb.Fatal(err)
+	}
+	env := append(os.Environ(), "GOFLAGS=", "GO111MODULE=off")
+	for _, args := range [][]string{{"vet", "main.go"}, {"build", "-o", "prog", "main.go"}} {
+		cmd := exec.Command(gotool, args...)
+		cmd.Dir, cmd.Env = dir, env
+		if out, err := cmd.CombinedOutput(); err != nil {
+			tb.Fatalf("go %s: %v\n%s\n%s", args[0], err, out, src.Bytes())
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(filepath.Join(dir, "prog"))
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("%v: %s", err, stderr.Bytes())
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,148 @@
+package golang
+
+// snippet is a piece of runtime source code together with the imports and
+// other snippets it requires.
+type snippet struct {
+	imports []string
+	deps    []string
+	src     string
+}
+
+// runtime contains the snippets of the runtime, indexed by name. The names of
+// library procedures are the names of the procedures they implement.
+var runtime = map[string]snippet{
+	"out": {
+		imports: []string{"bufio", "os"},
+		src: `
+// out buffers the output of the program. It is flushed before input is read
+// and when the program terminates.
+var out = bufio.NewWriter(os.Stdout)
+
+func flush() { _ = out.Flush() }
+`,
+	},
+	"in": {
+		imports: []string{"bufio", "os"},
+		src: `
+var in = bufio.NewReader(os.Stdin)
+`,
+	},
+	"fail": {
+		imports: []string{"os"},
+		src: `
+// fail reports a runtime error at the source position pos and terminates the
+// program with exit status 1.
+func fail(pos, msg string) {
+	flush()
+	_, _ = os.Stderr.WriteString(pos + ": runtime error: " + msg + "\n")
+	os.Exit(1)
+}
+`,
+	},
+	"idx": {
+		deps: []string{"fail"},
+		src: `
+// idx returns i if it is an index of an array of length n. Otherwise it
+// reports a runtime error at pos.
+func idx(i, n int32, pos string) int32 {
+	if uint32(i) >= uint32(n) {
+		fail(pos, "index out of range")
+	}
+	return i
+}
+`,
+	},
+	"div": {
+		deps: []string{"fail"},
+		src: `
+// div returns x / y truncated towards zero. A division by zero is reported as
+// runtime error at pos.
+func div(x, y int32, pos string) int32 {
+	if y == 0 {
+		fail(pos, "integer divide by zero")
+	}
+	return x / y
+}
+`,
+	},
+	"printi": {
+		imports: []string{"strconv"},
+		src: `
+// printi writes the decimal representation of i.
+func printi(i int32) { _, _ = out.WriteString(strconv.Itoa(int(i))) }
+`,
+	},
+	"printc": {
+		src: `
+// printc writes the character with the ASCII code i.
+func printc(i int32) { _ = out.WriteByte(byte(i)) }
+`,
+	},
+	"prints": {
+		src: `
+// prints writes the string s.
+func prints(s string) { _, _ = out.WriteString(s) }
+`,
+	},
+	"readi": {
+		imports: []string{"strings"},
+		deps:    []string{"in"},
+		src: `
+// readi reads a line and stores the integer at its beginning in i. Leading
+// blanks and a sign are accepted, everything after the digits is ignored. If
+// there are no digits, 0 is stored.
+func readi(i *int32) {
+	flush()
+	line, _ := in.ReadString('\n')
+	line = strings.TrimLeft(line, " \t")
+	neg := strings.HasPrefix(line, "-")
+	if neg || strings.HasPrefix(line, "+") {
+		line = line[1:]
+	}
+	var v int32
+	for ; line != "" && '0' <= line[0] && line[0] <= '9'; line = line[1:] {
+		v = v*10 + int32(line[0]-'0')
+	}
+	if neg {
+		v = -v
+	}
+	*i = v
+}
+`,
+	},
+	"readc": {
+		deps: []string{"in"},
+		src: `
+// readc reads a single character and stores its ASCII code in i. At the end
+// of the input -1 is stored.
+func readc(i *int32) {
+	flush()
+	c, err := in.ReadByte()
+	if err != nil {
+		*i = -1
+		return
+	}
+	*i = int32(c)
+}
+`,
+	},
+	"exit": {
+		imports: []string{"os"},
+		src: `
+// exit flushes the output and terminates the program.
+func exit() {
+	flush()
+	os.Exit(0)
+}
+`,
+	},
+	"time": {
+		imports: []string{"time"},
+		src: `
+var start = time.Now()
+
+// time_ stores the number of seconds since the start of the program in i.
+func time_(i *int32) { *i = int32(time.Since(start) / time.Second) }
+`,
+	},
+}
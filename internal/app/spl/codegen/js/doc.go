@@ -0,0 +1,25 @@
+// Package js implements a code generator which translates a type checked
+// program into an ES module or a standalone HTML page running in the browser.
+//
+// The module exports the functions run and terminal. run executes the program
+// and returns a promise which resolves to its exit status:
+//
+//	import { run, terminal } from "./prog.js";
+//
+//	run({
+//		...terminal(document.getElementById("terminal")),
+//		canvas: document.getElementById("canvas"),
+//	});
+//
+// The environment passed to run must provide a write(text) method for the
+// output of the program and an async read() method which resolves to the next
+// chunk of input or null at the end of the input. The graphics procedures
+// draw on the optional 640x480 canvas. terminal creates such an environment
+// for a DOM element: output is appended to the element and keyboard input is
+// echoed and passed to the program line by line.
+//
+// Integers are JavaScript numbers truncated to 32 bits after each operation.
+// Arrays and variables passed as reference are stored in Int32Arrays, so
+// reference parameters are passed as pairs of array and offset. Procedures
+// which (transitively) read input are lowered to async functions.
+package js
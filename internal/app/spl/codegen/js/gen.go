@@ -0,0 +1,417 @@
+package js
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+// Target is the name of the target platform of the code generator.
+const Target = "js"
+
+// reserved contains the reserved words of JavaScript, identifiers which can't
+// be bound in strict mode code and the globals used by the generated code, the
+// runtime and its environments, which must not be shadowed by the program.
+var reserved = map[string]bool{}
+
+func init() {
+	for _, name := range strings.Fields(`
+		await break case catch class const continue debugger default delete
+		do else enum export extends false finally for function if implements
+		import in instanceof interface let new null package private protected
+		public return static super switch this throw true try typeof var void
+		while with yield arguments eval undefined NaN Infinity
+		Array Error Int32Array Math Number Object Promise String document
+		globalThis performance process`) {
+		reserved[name] = true
+	}
+}
+
+// Compile compiles the type checked program into an ES module and writes it to
+// w.
+func Compile(w io.Writer, prog *ast.Program, info *types.Info) error {
+	_, err := io.WriteString(w, module(prog, info))
+	return err
+}
+
+// CompileHTML compiles the type checked program into a standalone HTML page
+// which runs the program on load and writes it to w.
+func CompileHTML(w io.Writer, prog *ast.Program, info *types.Info) error {
+	src := strings.Replace(module(prog, info), "</script", `<\/script`, -1)
+	_, err := fmt.Fprintf(w, page, html.EscapeString(prog.Name), src)
+	return err
+}
+
+// module returns the source code of the ES module of the program.
+func module(prog *ast.Program, info *types.Info) string {
+	g := &generator{
+		info:  info,
+		async: asyncProcs(info),
+	}
+	g.printf("// Code generated by spl from %s. DO NOT EDIT.\n", prog.Name)
+	for _, proc := range info.Procs {
+		g.proc(proc)
+	}
+	g.printf("\nfunction $main() {\n")
+	for _, proc := range info.Procs {
+		if proc.LinkName() == "main" {
+			g.printf("  return %s();\n", name(proc.LinkName()))
+		}
+	}
+	g.printf("}\n")
+	g.buf.WriteString(runtime)
+	return g.buf.String()
+}
+
+// generator maintains the state of the code generator.
+type generator struct {
+	buf    bytes.Buffer
+	info   *types.Info
+	indent int
+
+	// async holds the procedures which are lowered to async functions.
+	async map[*types.Proc]bool
+
+	// boxed holds the integer and boolean variables of the current
+	// procedure which are stored in an Int32Array of length 1, because
+	// they are passed as reference.
+	boxed map[*types.Var]bool
+}
+
+// asyncProcs returns the procedures which read input, directly or by calling
+// other such procedures.
+func asyncProcs(info *types.Info) map[*types.Proc]bool {
+	async := map[*types.Proc]bool{
+		types.Lookup("readi").(*types.Proc): true,
+		types.Lookup("readc").(*types.Proc): true,
+	}
+	calls := make(map[*types.Proc][]*types.Proc)
+	for _, proc := range info.Procs {
+		ast.Inspect(proc.Decl().Body, func(n ast.Node) bool {
+			if x, ok := n.(*ast.CallExpr); ok {
+				if callee := info.Callee(x); callee != nil {
+					calls[proc] = append(calls[proc], callee)
+				}
+			}
+			return true
+		})
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, proc := range info.Procs {
+			for _, callee := range calls[proc] {
+				if async[callee] && !async[proc] {
+					async[proc], changed = true, true
+				}
+			}
+		}
+	}
+	return async
+}
+
+// -----------------------------------------------------------------------------
+// Procedures
+
+// proc emits a procedure as function. Reference parameters are passed as two
+// parameters, the array holding the value and the offset of the value.
+// Integer and boolean variables passed as reference are boxed.
+func (g *generator) proc(proc *types.Proc) {
+	g.boxed = make(map[*types.Var]bool)
+	ast.Inspect(proc.Decl().Body, func(n ast.Node) bool {
+		if x, ok := n.(*ast.CallExpr); ok {
+			callee := g.info.Callee(x)
+			for i, arg := range x.Args {
+				if id, ok := unparen(arg).(*ast.Ident); ok && callee.Params()[i].IsRef() {
+					if v := g.info.Uses[id].(*types.Var); !v.IsRef() && types.IsBasic(v.Type()) {
+						g.boxed[v] = true
+					}
+				}
+			}
+		}
+		return true
+	})
+
+	params := make([]string, 0, len(proc.Params()))
+	for _, v := range proc.Params() {
+		switch {
+		case v.IsRef():
+			params = append(params, name(v.Name()), name(v.Name())+"$")
+		case g.boxed[v]:
+			params = append(params, name(v.Name())+"$")
+		default:
+			params = append(params, name(v.Name()))
+		}
+	}
+	async := ""
+	if g.async[proc] {
+		async = "async "
+	}
+	g.printf("\n%sfunction %s(%s) {\n", async, name(proc.LinkName()), strings.Join(params, ", "))
+	g.indent++
+	for _, v := range proc.Params() {
+		if g.boxed[v] && !v.IsRef() {
+			g.line("const %s = Int32Array.of(%s$);", name(v.Name()), name(v.Name()))
+		}
+	}
+	for _, v := range proc.Locals() {
+		switch {
+		case g.boxed[v]:
+			g.line("const %s = new Int32Array(1);", name(v.Name()))
+		case types.IsInteger(v.Type()):
+			g.line("let %s = 0;", name(v.Name()))
+		case types.IsBoolean(v.Type()):
+			g.line("let %s = false;", name(v.Name()))
+		default:
+			g.line("const %s = new Int32Array(%d);", name(v.Name()), types.Sizeof(v.Type())/4)
+			if init := v.Init(); init != nil {
+				g.line("%s.set($chars(%s));", name(v.Name()), g.expr(init))
+			}
+		}
+	}
+	g.stmtList(proc.Decl().Body.List)
+	g.indent--
+	g.printf("}\n")
+}
+
+// -----------------------------------------------------------------------------
+// Statements
+
+func (g *generator) stmtList(list []ast.Stmt) {
+	for _, s := range list {
+		g.stmt(s)
+	}
+}
+
+func (g *generator) stmt(s ast.Stmt) {
+	switch s := s.(type) {
+	case *ast.BlockStmt:
+		g.stmtList(s.List)
+	case *ast.ExprStmt:
+		g.line("%s;", g.call(unparen(s.X).(*ast.CallExpr)))
+	case *ast.AssignStmt:
+		// The left hand side is evaluated before the right hand side.
+		lhs := g.lvalue(s.Left)
+		g.line("%s = %s;", lhs, g.expr(s.Right))
+	case *ast.IfStmt:
+		g.line("if %s {", g.cond(s.Cond))
+		g.block(s.Body)
+		if s.Else != nil {
+			g.line("} else {")
+			g.block(s.Else)
+		}
+		g.line("}")
+	case *ast.WhileStmt:
+		g.line("while %s {", g.cond(s.Cond))
+		g.block(s.Body)
+		g.line("}")
+	case *ast.ReturnStmt:
+		if s.Result != nil {
+			g.line("return %s;", g.expr(s.Result))
+		} else {
+			g.line("return;")
+		}
+	}
+}
+
+// block emits an indented statement.
+func (g *generator) block(s ast.Stmt) {
+	g.indent++
+	g.stmt(s)
+	g.indent--
+}
+
+// call returns the JavaScript expression of a procedure call. Calls of async
+// procedures are awaited.
+func (g *generator) call(x *ast.CallExpr) string {
+	proc := g.info.Callee(x)
+	params := proc.Params()
+	args := make([]string, 0, len(x.Args))
+	for i, arg := range x.Args {
+		if params[i].IsRef() {
+			b, o := g.addr(arg)
+			args = append(args, b, o)
+		} else {
+			args = append(args, g.expr(arg))
+		}
+	}
+	await := ""
+	if g.async[proc] {
+		await = "await "
+	}
+	fn := name(proc.LinkName())
+	if proc.Builtin() {
+		fn = "$" + proc.Name()
+	}
+	return fmt.Sprintf("%s%s(%s)", await, fn, strings.Join(args, ", "))
+}
+
+// -----------------------------------------------------------------------------
+// Expressions
+
+// ops maps operators to JavaScript operators.
+var ops = map[token.Token]string{
+	token.ADD:  "+",
+	token.SUB:  "-",
+	token.EQL:  "===",
+	token.NOT:  "!==",
+	token.LSS:  "<",
+	token.LEQ:  "<=",
+	token.GTR:  ">",
+	token.GEQ:  ">=",
+	token.LAND: "&&",
+	token.LOR:  "||",
+}
+
+// cond returns the JavaScript expression of a condition enclosed in
+// parentheses.
+func (g *generator) cond(e ast.Expr) string {
+	x := g.expr(e)
+	if _, ok := g.info.Values[e]; !ok {
+		switch unparen(e).(type) {
+		case *ast.UnaryExpr, *ast.BinaryExpr, *ast.CallExpr:
+			return x
+		}
+	}
+	return "(" + x + ")"
+}
+
+// expr returns the JavaScript expression of an expression. Results of
+// arithmetic operations are truncated to 32 bits. Operations are enclosed in
+// parentheses, so operator precedence doesn't matter. Booleans stored in an
+// Int32Array read as 0 or 1, which is only used for its truthiness.
+func (g *generator) expr(e ast.Expr) string {
+	if s, ok := g.info.Strings[e]; ok {
+		return quote(s)
+	}
+	if v, ok := g.info.Values[e]; ok {
+		if types.IsBoolean(g.info.Types[e]) {
+			return strconv.FormatBool(v != 0)
+		}
+		return fmt.Sprint(v)
+	}
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return g.expr(e.X)
+	case *ast.UnaryExpr:
+		if e.Op == token.LNOT {
+			return fmt.Sprintf("(!%s)", g.expr(e.X))
+		}
+		return fmt.Sprintf("(-%s | 0)", g.expr(e.X))
+	case *ast.BinaryExpr:
+		x, y := g.expr(e.X), g.expr(e.Y)
+		switch e.Op {
+		case token.MUL:
+			return fmt.Sprintf("Math.imul(%s, %s)", x, y)
+		case token.QUO:
+			return fmt.Sprintf("$div(%s, %s, %s)", x, y, pos(e.OpPos))
+		case token.ADD, token.SUB:
+			return fmt.Sprintf("(%s %s %s | 0)", x, ops[e.Op], y)
+		}
+		return fmt.Sprintf("(%s %s %s)", x, ops[e.Op], y)
+	case *ast.Ident, *ast.IndexExpr, *ast.SelectorExpr:
+		return g.lvalue(e)
+	case *ast.CallExpr:
+		return "(" + g.call(e) + ")"
+	}
+	panic(fmt.Sprintf("js: unexpected expression %T", e))
+}
+
+// lvalue returns the JavaScript expression denoting a variable, array element
+// or record field.
+func (g *generator) lvalue(e ast.Expr) string {
+	if id, ok := unparen(e).(*ast.Ident); ok {
+		if v := g.info.Uses[id].(*types.Var); !v.IsRef() && !g.boxed[v] {
+			return name(id.Name)
+		}
+	}
+	b, o := g.addr(e)
+	return b + "[" + o + "]"
+}
+
+// addr returns the array holding a variable, array element or record field and
+// its offset. Array indices are bounds checked.
+func (g *generator) addr(e ast.Expr) (string, string) {
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return g.addr(e.X)
+	case *ast.Ident:
+		if g.info.Uses[e].(*types.Var).IsRef() {
+			return name(e.Name), name(e.Name) + "$"
+		}
+		return name(e.Name), "0"
+	case *ast.IndexExpr:
+		a := g.info.Types[e.X].(*types.Array)
+		b, o := g.addr(e.X)
+		i := fmt.Sprintf("$idx(%s, %d, %s)", g.expr(e.Index), a.Len(), pos(e.Lbrack))
+		if stride := types.Sizeof(a.Elem()) / 4; stride != 1 {
+			i = fmt.Sprintf("%s * %d", i, stride)
+		}
+		if o == "0" {
+			return b, i
+		}
+		return b, o + " + " + i
+	case *ast.SelectorExpr:
+		r := g.info.Types[e.X].(*types.Record)
+		b, o := g.addr(e.X)
+		off := r.Offset(g.info.Uses[e.Sel].(*types.Var)) / 4
+		switch {
+		case off == 0:
+			return b, o
+		case o == "0":
+			return b, strconv.FormatInt(off, 10)
+		}
+		return b, fmt.Sprintf("%s + %d", o, off)
+	}
+	panic(fmt.Sprintf("js: unexpected operand %T", e))
+}
+
+// -----------------------------------------------------------------------------
+// Helpers
+
+// name returns the JavaScript identifier for an identifier of the program.
+// Reserved words and identifiers ending with an underscore are suffixed with an
+// underscore. This never clashes with other identifiers.
+func name(s string) string {
+	if reserved[s] || strings.HasSuffix(s, "_") {
+		return s + "_"
+	}
+	return s
+}
+
+// quote returns s as JavaScript string literal.
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// pos returns the file and line of the position as JavaScript string literal,
+// which is used in runtime error messages.
+func pos(p token.Position) string { return quote(fmt.Sprintf("%s:%d", p.Filename, p.Line)) }
+
+// unparen returns e with any enclosing parentheses stripped.
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		p, ok := e.(*ast.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.X
+	}
+}
+
+// line emits an indented line.
+func (g *generator) line(format string, args ...interface{}) {
+	g.printf("%s%s\n", strings.Repeat("  ", g.indent), fmt.Sprintf(format, args...))
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
@@ -0,0 +1,306 @@
+package js_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/codegen/js"
+	"github.com/lukasmalkmus/spl/internal/app/spl/ext"
+	"github.com/lukasmalkmus/spl/internal/app/spl/internal/testutil"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+// exts are the language extensions the test programs may use.
+const exts = ext.Functions | ext.Bool | ext.Records | ext.Strings
+
+// driver runs the compiled module prog.mjs with node. The input is read from
+// standard input and passed to the program at once. The drawing operations on
+// the canvas are written to standard error.
+const driver = `
+import { run } from "./prog.mjs";
+import { readFileSync } from "fs";
+
+let input = readFileSync(0, "latin1");
+const ctx = {
+  fillRect(x, y, w, h) {
+    process.stderr.write(this.fillStyle + " " + [x, y, w, h].join(",") + "\n");
+  },
+};
+process.exitCode = await run({
+  canvas: { getContext: () => ctx },
+  write: (text) => process.stdout.write(text, "latin1"),
+  error: (text) => process.stderr.write(text),
+  read: async () => {
+    const chunk = input;
+    input = null;
+    return chunk === "" ? null : chunk;
+  },
+});
+`
+
+func TestCompile_FullValidProgram(t *testing.T) {
+	out, _, code := run(t, compile(t, "../../testdata/valid.spl"), "")
+	if code != 0 {
+		t.Errorf("got exit code %d, want 0", code)
+	}
+	if n := strings.Count(out, "\n\n"); n != 92 {
+		t.Errorf("got %d solutions, want 92", n)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		input    string
+		output   string
+		stderr   string
+		exitCode int
+	}{
+		{
+			"print",
+			"proc main() { printi(-42); printc(' '); printi(-2147483647 - 1); printc('\\n'); }",
+			"",
+			"-42 -2147483648\n",
+			"",
+			0,
+		},
+		{
+			"arithmetic",
+			"proc main() { printi(7 / 2); printc(' '); printi(-7 / 2); printc(' '); printi(2147483647 + 1); printc(' '); printi(65536 * 65536 + 3); }",
+			"",
+			"3 -3 -2147483648 3",
+			"",
+			0,
+		},
+		{
+			"read",
+			"proc main() { var i: int; readi(i); printi(i * 2); readc(i); printi(i); readc(i); printi(i); }",
+			"  -17\nZ",
+			"-3490-1",
+			"",
+			0,
+		},
+		{
+			"reference parameters",
+			"type A = array [2] of array [3] of int; proc f(ref a: A, i: int) { g(i); a[1][2] := i; } proc g(ref i: int) { i := i + 1; } proc main() { var a: A; f(a, 4); printi(a[1][2]); }",
+			"",
+			"5",
+			"",
+			0,
+		},
+		{
+			"reserved names",
+			"proc delete(ref this: int) { var new: int; new := this; this := new + 1; } proc main() { var let: int; delete(let); printi(let); }",
+			"",
+			"1",
+			"",
+			0,
+		},
+		{
+			"reserved globals",
+			"proc Int32Array() { printi(2); } proc main() { var Math: int; var a: array [2] of int; Math := 3; a[1] := Math * 7; printi(a[1]); Int32Array(); }",
+			"",
+			"212",
+			"",
+			0,
+		},
+		{
+			"functions",
+			"proc fib(n: int): int { if (n < 2) return n; return fib(n - 1) + fib(n - 2); }\nproc hello(n: int) { if (n = 0) return; printc('h'); }\nproc main() { printi(fib(10) * 2); hello(0); hello(1); }",
+			"",
+			"110h",
+			"",
+			0,
+		},
+		{
+			"bool",
+			"proc inRange(i: int, n: int): bool { return 0 <= i and i < n; }\nproc flip(ref b: bool) { b := not b; }\nproc main() { var a: array [3] of bool; var b: bool; var i: int; a[1] := true; flip(b); while (inRange(i, 3)) { if (a[i] or i = 2 and b) printi(i); i := i + 1; } b := i < 0 and 1 / 0 = 0; if (not b or 1 / 0 = 0) printc('x'); }",
+			"",
+			"12x",
+			"",
+			0,
+		},
+		{
+			"records",
+			"type point = record { x: int; y: int; };\ntype line = record { from: point; to: point; };\nproc move(ref p: point, d: int) { p.x := p.x + d; p.y := (p).y - d; }\nproc main() { var l: line; var ps: array [2] of point; l.to.x := 5; move(l.to, 2); ps[1].y := l.to.x; move(ps[1], 1); printi(l.from.x); printi(l.to.x); printi(l.to.y); printi(ps[1].x); printi(ps[1].y); }",
+			"",
+			"07-216",
+			"",
+			0,
+		},
+		{
+			"strings",
+			"proc main() { var b: array [4] of int := \"ok\"; prints(\"a\\tb\\n\"); printc(b[0]); printc(b[1]); printi(b[2]); prints((\"\\\"\\\\\")); }",
+			"",
+			"a\tb\nok0\"\\",
+			"",
+			0,
+		},
+		{
+			"exit",
+			"proc main() { printi(1); exit(); printi(2); }",
+			"",
+			"1",
+			"",
+			0,
+		},
+		{
+			"graphics",
+			"proc main() { clearAll(255); setPixel(1, 2, 65280); drawLine(0, 0, 2, 1, 16711680); }",
+			"",
+			"",
+			"#0000ff 0,0,640,480\n#00ff00 1,2,1,1\n#ff0000 0,0,1,1\n#ff0000 1,1,1,1\n#ff0000 2,1,1,1\n",
+			0,
+		},
+		{
+			"index out of range",
+			"type A = array [3] of int;\nproc main() {\n  var a: A;\n  printi(1);\n  a[3] := 1;\n}",
+			"",
+			"1",
+			"test.spl:5: runtime error: index out of range\n",
+			1,
+		},
+		{
+			"divide by zero",
+			"proc main() {\n  var i: int;\n  printi(1 / i);\n}",
+			"",
+			"",
+			"test.spl:3: runtime error: integer divide by zero\n",
+			1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testutil.TempDir(t)
+			defer cleanup()
+			filename := filepath.Join(dir, "test.spl")
+			if err := ioutil.WriteFile(filename, []byte(tt.src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			out, stderr, code := run(t, compile(t, filename), tt.input)
+			if code != tt.exitCode {
+				t.Errorf("got exit code %d, want %d", code, tt.exitCode)
+			}
+			if out != tt.output {
+				t.Errorf("got output %q, want %q", out, tt.output)
+			}
+			if stderr = strings.TrimPrefix(stderr, dir+string(filepath.Separator)); stderr != tt.stderr {
+				t.Errorf("got stderr %q, want %q", stderr, tt.stderr)
+			}
+		})
+	}
+}
+
+func TestCompile_Async(t *testing.T) {
+	src := `
+proc main() { var i: int; a(i); b(); }
+proc a(ref i: int) { c(i); }
+proc b() { printi(1); }
+proc c(ref i: int) { readi(i); }
+`
+	mod := compileSource(t, src, js.Compile)
+	for _, want := range []string{
+		"async function main(",
+		"async function a(",
+		"\nfunction b(",
+		"async function c(",
+		"await a(i, 0);",
+		"  b();",
+		"await $readi(i, i$);",
+	} {
+		if !strings.Contains(mod, want) {
+			t.Errorf("expected module to contain %q", want)
+		}
+	}
+}
+
+func TestCompileHTML(t *testing.T) {
+	page := compileSource(t, "proc main() { printi(1); }", js.CompileHTML)
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		`<canvas id="canvas" width="640" height="480">`,
+		`<pre id="terminal"`,
+		"function main() {",
+		"$run({ ...$terminal(element)",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("expected page to contain %q", want)
+		}
+	}
+}
+
+// compile compiles the source file into an ES module.
+func compile(tb testing.TB, filename string) string {
+	tb.Helper()
+	prog, err := parser.ParseFiles(token.NewFileSet(), []string{filename}, exts, parser.DeclarationErrors)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	info, err := types.Check(prog)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := js.Compile(&buf, prog, info); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.String()
+}
+
+// compileSource compiles the source code using the compile function.
+func compileSource(tb testing.TB, src string, compile func(w io.Writer, prog *ast.Program, info *types.Info) error) string {
+	tb.Helper()
+	prog, err := parser.ParseFile(token.NewFileSet(), "", src, parser.DeclarationErrors)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	info, err := types.Check(prog)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := compile(&buf, prog, info); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.String()
+}
+
+// run runs the module with node and returns its output, the output to
+// standard error and the exit code. The test is skipped if node is not
+// available.
+func run(tb testing.TB, mod, input string) (string, string, int) {
+	tb.Helper()
+	node, err := exec.LookPath("node")
+	if err != nil {
+		tb.Skip("node not available")
+	}
+
+	dir, cleanup := testutil.TempDir(tb)
+	defer cleanup()
+	for name, src := range map[string]string{"prog.mjs": mod, "driver.mjs": driver} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(node, filepath.Join(dir, "driver.mjs"))
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err = cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return stdout.String(), stderr.String(), exitErr.ExitCode()
+	} else if err != nil {
+		tb.Fatal(err)
+	}
+	return stdout.String(), stderr.String(), 0
+}
@@ -0,0 +1,285 @@
+package js
+
+// runtime is the JavaScript source code of the runtime. All of its names start
+// with a dollar sign which is not allowed in identifiers of the program. The
+// function $main is provided by the generated code. Runtime errors carry the
+// source position "file:line" of the failing operation.
+const runtime = `
+// Runtime
+
+class $RuntimeError extends Error {
+  constructor(pos, message) {
+    super(message);
+    this.pos = pos;
+  }
+}
+
+class $Exit extends Error {}
+
+const $WIDTH = 640, $HEIGHT = 480;
+
+let $io, $out, $in, $start, $ctx;
+
+function $flush() {
+  if ($out !== "") {
+    $io.write($out);
+    $out = "";
+  }
+}
+
+// $getc returns the next character of the input or -1 at its end. The output
+// is flushed before the program waits for input.
+async function $getc() {
+  while ($in.pos >= $in.buf.length) {
+    if ($in.eof) {
+      return -1;
+    }
+    $flush();
+    const buf = await $io.read();
+    if (buf === null) {
+      $in.eof = true;
+      return -1;
+    }
+    $in.buf = buf;
+    $in.pos = 0;
+  }
+  return $in.buf.charCodeAt($in.pos++) & 0xFF;
+}
+
+function $idx(i, len, pos) {
+  if (i >>> 0 >= len) {
+    throw new $RuntimeError(pos, "index out of range");
+  }
+  return i;
+}
+
+function $div(x, y, pos) {
+  if (y === 0) {
+    throw new $RuntimeError(pos, "integer divide by zero");
+  }
+  return x / y | 0;
+}
+
+function $color(c) {
+  return "#" + ((c & 0xFFFFFF) | 0x1000000).toString(16).slice(1);
+}
+
+// printi(i: int) writes the decimal representation of i.
+function $printi(i) {
+  $out += String(i);
+}
+
+// printc(i: int) writes the character with the ASCII code i.
+function $printc(i) {
+  $out += String.fromCharCode(i & 0xFF);
+}
+
+// prints(s: string) writes the string s.
+function $prints(s) {
+  $out += s;
+}
+
+// $chars returns the ASCII codes of the characters of s, which initialize a
+// character buffer.
+function $chars(s) {
+  return Array.from(s, c => c.charCodeAt(0));
+}
+
+// readi(ref i: int) reads a line and stores the integer at its beginning in i.
+// Leading blanks and a sign are accepted, everything after the digits is
+// ignored. If there are no digits, 0 is stored.
+async function $readi(b, o) {
+  let v = 0, neg = false, state = 0, c;
+  while ((c = await $getc()) !== -1 && c !== 10) {
+    if (state === 2) {
+      continue;
+    }
+    if (c >= 48 && c <= 57) {
+      v = Math.imul(v, 10) + c - 48 | 0;
+      state = 1;
+    } else if (state === 1) {
+      state = 2;
+    } else if (c !== 32 && c !== 9) {
+      state = c === 45 || c === 43 ? 1 : 2;
+      neg = c === 45;
+    }
+  }
+  b[o] = neg ? -v : v;
+}
+
+// readc(ref i: int) reads a single character and stores its ASCII code in i.
+// At the end of the input -1 is stored.
+async function $readc(b, o) {
+  b[o] = await $getc();
+}
+
+// exit() terminates the program.
+function $exit() {
+  throw new $Exit();
+}
+
+// time(ref i: int) stores the number of seconds since the start of the program
+// in i.
+function $time(b, o) {
+  b[o] = (performance.now() - $start) / 1000;
+}
+
+// clearAll(color: int) fills the canvas with the color.
+function $clearAll(color) {
+  if ($ctx) {
+    $ctx.fillStyle = $color(color);
+    $ctx.fillRect(0, 0, $WIDTH, $HEIGHT);
+  }
+}
+
+// setPixel(x: int, y: int, color: int) sets the color of a pixel.
+function $setPixel(x, y, color) {
+  if ($ctx) {
+    $ctx.fillStyle = $color(color);
+    $ctx.fillRect(x, y, 1, 1);
+  }
+}
+
+// drawLine(x1: int, y1: int, x2: int, y2: int, color: int) draws a line using
+// Bresenham's algorithm.
+function $drawLine(x1, y1, x2, y2, color) {
+  const dx = Math.abs(x2 - x1), sx = x1 < x2 ? 1 : -1;
+  const dy = -Math.abs(y2 - y1), sy = y1 < y2 ? 1 : -1;
+  let err = dx + dy;
+  for (;;) {
+    $setPixel(x1, y1, color);
+    if (x1 === x2 && y1 === y2) {
+      break;
+    }
+    const e2 = 2 * err;
+    if (e2 >= dy) {
+      err += dy;
+      x1 += sx;
+    }
+    if (e2 <= dx) {
+      err += dx;
+      y1 += sy;
+    }
+  }
+}
+
+// drawCircle(x0: int, y0: int, radius: int, color: int) draws a circle using
+// the midpoint circle algorithm.
+function $drawCircle(x0, y0, radius, color) {
+  let x = radius, y = 0, err = 1 - radius;
+  while (x >= y) {
+    for (const [px, py] of [[x, y], [y, x], [-y, x], [-x, y], [-x, -y], [-y, -x], [y, -x], [x, -y]]) {
+      $setPixel(x0 + px, y0 + py, color);
+    }
+    y++;
+    if (err < 0) {
+      err += 2 * y + 1;
+    } else {
+      x--;
+      err += 2 * (y - x) + 1;
+    }
+  }
+}
+
+// $run executes the program in the environment and returns its exit status.
+// Runtime errors are reported on the output.
+async function $run(env) {
+  $io = env;
+  $out = "";
+  $in = { buf: "", pos: 0, eof: false };
+  $start = performance.now();
+  $ctx = env.canvas ? env.canvas.getContext("2d") : null;
+  try {
+    await $main();
+  } catch (e) {
+    if (e instanceof $RuntimeError) {
+      $flush();
+      (env.error || env.write)(e.pos + ": runtime error: " + e.message + "\n");
+      return 1;
+    }
+    if (!(e instanceof $Exit)) {
+      throw e;
+    }
+  } finally {
+    $flush();
+  }
+  return 0;
+}
+
+// $terminal returns an environment which appends the output to the element
+// and reads the input from keyboard events of the element. Input is echoed and
+// passed to the program line by line. Ctrl+D ends the input.
+function $terminal(element) {
+  const lines = [];
+  let line = "", waiting = null;
+
+  const write = (text) => {
+    element.textContent += text;
+    element.scrollTop = element.scrollHeight;
+  };
+  const push = (text) => {
+    if (waiting) {
+      const resolve = waiting;
+      waiting = null;
+      resolve(text);
+    } else {
+      lines.push(text);
+    }
+  };
+  element.addEventListener("keydown", (e) => {
+    if (e.key === "Enter") {
+      write("\n");
+      push(line + "\n");
+      line = "";
+    } else if (e.key === "Backspace") {
+      if (line !== "") {
+        line = line.slice(0, -1);
+        element.textContent = element.textContent.slice(0, -1);
+      }
+    } else if (e.ctrlKey && e.key === "d") {
+      push(line !== "" ? line : null);
+      line = "";
+    } else if (e.key.length === 1 && !e.ctrlKey && !e.metaKey) {
+      line += e.key;
+      write(e.key);
+    } else {
+      return;
+    }
+    e.preventDefault();
+  });
+
+  return {
+    write,
+    read: () => lines.length > 0 ? Promise.resolve(lines.shift()) : new Promise((resolve) => { waiting = resolve; }),
+  };
+}
+
+export { $run as run, $terminal as terminal };
+`
+
+// page is the HTML page the module is embedded in. It is formatted with the
+// title and the source code of the module.
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+  body { background: #222; color: #ddd; font-family: monospace; }
+  canvas { background: #000; display: block; margin: 1em auto; }
+  pre { width: 640px; height: 200px; margin: 1em auto; padding: 0.5em; overflow: auto; background: #000; outline: none; }
+  pre:focus { box-shadow: 0 0 0 1px #888; }
+</style>
+</head>
+<body>
+<canvas id="canvas" width="640" height="480"></canvas>
+<pre id="terminal" tabindex="0"></pre>
+<script type="module">
+%s
+const element = document.getElementById("terminal");
+element.focus();
+$run({ ...$terminal(element), canvas: document.getElementById("canvas") });
+</script>
+</body>
+</html>
+`
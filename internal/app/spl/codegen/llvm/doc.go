@@ -0,0 +1,18 @@
+// Package llvm implements a code generator which translates a type checked
+// program into textual LLVM IR. The output can be optimized and compiled by the
+// LLVM toolchain, e.g. clang, and must be linked with a runtime providing the
+// library procedures.
+//
+// Procedures are emitted with the "spl." prefix, so they don't clash with the
+// symbols of the C library. A C compatible main function calls the main
+// procedure of the program. Library procedures are declared as external
+// functions with the "spl_" prefix and take int parameters as i32 and ref
+// parameters as pointers to i32:
+//
+//	void spl_printi(int32_t i);
+//	void spl_readi(int32_t *i);
+//
+// Runtime errors are reported by calling the noreturn trap handlers
+// spl_index_error and spl_divide_error with the source line of the erroneous
+// operation.
+package llvm
@@ -0,0 +1,485 @@
+package llvm
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+// Target is the name of the target platform of the code generator.
+const Target = "llvm"
+
+// Names of the trap handlers of the runtime.
+const (
+	indexError  = "spl_index_error"
+	divideError = "spl_divide_error"
+)
+
+// library lists the library procedures in the order they are declared.
+var library = []string{
+	"printi", "printc", "prints", "readi", "readc", "exit", "time",
+	"clearAll", "setPixel", "drawLine", "drawCircle",
+}
+
+// Compile compiles the type checked program into textual LLVM IR and writes it
+// to w.
+func Compile(w io.Writer, prog *ast.Program, info *types.Info) error {
+	g := &generator{info: info}
+	g.printf("; Code generated by spl. DO NOT EDIT.\n")
+	g.printf("source_filename = %s\n", quote(prog.Name))
+	for _, proc := range info.Procs {
+		g.proc(proc)
+	}
+	g.entry(info.Procs)
+	g.declarations()
+	g.constants()
+
+	_, err := g.buf.WriteTo(w)
+	return err
+}
+
+// generator maintains the state of the code generator.
+type generator struct {
+	buf  bytes.Buffer
+	info *types.Info
+
+	// tmps and labels count the temporary values and basic block labels of
+	// the current procedure.
+	tmps   int
+	labels int
+
+	// cur is the label of the current basic block. The unlabeled entry
+	// block is numbered 0.
+	cur string
+
+	// addrs maps the variables of the current procedure to the pointers
+	// holding their values.
+	addrs map[*types.Var]string
+
+	// strings holds the values of the string literals in the order they are
+	// used. The constant of the n-th string is named @str.n.
+	strings []string
+}
+
+// entry emits the C compatible main function which calls the main procedure.
+func (g *generator) entry(procs []*types.Proc) {
+	g.printf("\ndefine i32 @main() {\n")
+	for _, proc := range procs {
+		if proc.LinkName() == "main" {
+			g.printf("  call void %s()\n", procName(proc))
+		}
+	}
+	g.printf("  ret i32 0\n")
+	g.printf("}\n")
+}
+
+// declarations emits the declarations of the library procedures and the trap
+// handlers provided by the runtime.
+func (g *generator) declarations() {
+	g.printf("\n")
+	for _, name := range library {
+		proc := types.Lookup(name).(*types.Proc)
+		params := make([]string, 0, len(proc.Params()))
+		for _, v := range proc.Params() {
+			params = append(params, paramType(v))
+		}
+		g.printf("declare void %s(%s)\n", procName(proc), strings.Join(params, ", "))
+	}
+	g.printf("declare void @%s(i32) noreturn\n", indexError)
+	g.printf("declare void @%s(i32) noreturn\n", divideError)
+}
+
+// constants emits the string constants, which are terminated by a zero byte
+// like the strings of C.
+func (g *generator) constants() {
+	if len(g.strings) > 0 {
+		g.printf("\n")
+	}
+	for i, s := range g.strings {
+		g.printf("@str.%d = private unnamed_addr constant [%d x i8] c%s\n", i+1, len(s)+1, quote(s+"\x00"))
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Procedures
+
+// proc emits a procedure. Value parameters are copied into stack slots, so
+// they can be assigned like local variables. Local variables are zero
+// initialized, the characters of the initial value of a character buffer are
+// stored over its first elements. The entry block is left unlabeled. The end of a procedure with
+// result is unreachable, as it returns on every path.
+func (g *generator) proc(proc *types.Proc) {
+	g.tmps, g.labels, g.cur = 0, 0, "0"
+	g.addrs = make(map[*types.Var]string)
+
+	params := make([]string, 0, len(proc.Params()))
+	for _, v := range proc.Params() {
+		params = append(params, fmt.Sprintf("%s %%%s", paramType(v), v.Name()))
+	}
+	g.printf("\ndefine %s %s(%s) {\n", resultType(proc), procName(proc), strings.Join(params, ", "))
+	for _, v := range proc.Params() {
+		if v.IsRef() {
+			g.addrs[v] = "%" + v.Name()
+			continue
+		}
+		g.addrs[v] = "%" + v.Name() + ".addr"
+		g.printf("  %s = alloca i32\n", g.addrs[v])
+		g.printf("  store i32 %%%s, ptr %s\n", v.Name(), g.addrs[v])
+	}
+	for _, v := range proc.Locals() {
+		g.addrs[v] = "%" + v.Name() + ".addr"
+		t, zero := typ(v.Type()), "0"
+		if !types.IsBasic(v.Type()) {
+			zero = "zeroinitializer"
+		}
+		g.printf("  %s = alloca %s\n", g.addrs[v], t)
+		g.printf("  store %s %s, ptr %s\n", t, zero, g.addrs[v])
+		if init := v.Init(); init != nil && len(g.info.Strings[init]) > 0 {
+			s := g.info.Strings[init]
+			chars := make([]string, len(s))
+			for i := range s {
+				chars[i] = fmt.Sprintf("i32 %d", s[i])
+			}
+			g.printf("  store [%d x i32] [%s], ptr %s\n", len(s), strings.Join(chars, ", "), g.addrs[v])
+		}
+	}
+	g.stmtList(proc.Decl().Body.List)
+	if proc.Result() != nil {
+		g.printf("  unreachable\n")
+	} else {
+		g.printf("  ret void\n")
+	}
+	g.printf("}\n")
+}
+
+// -----------------------------------------------------------------------------
+// Statements
+
+func (g *generator) stmtList(list []ast.Stmt) {
+	for _, s := range list {
+		g.stmt(s)
+	}
+}
+
+func (g *generator) stmt(s ast.Stmt) {
+	switch s := s.(type) {
+	case *ast.BlockStmt:
+		g.stmtList(s.List)
+	case *ast.ExprStmt:
+		g.call(unparen(s.X).(*ast.CallExpr))
+	case *ast.AssignStmt:
+		// The left hand side is evaluated before the right hand side.
+		addr := g.addr(s.Left)
+		v := g.expr(s.Right)
+		g.printf("  store i32 %s, ptr %s\n", v, addr)
+	case *ast.IfStmt:
+		then, els, end := g.label("if.then"), g.label("if.else"), g.label("if.end")
+		if s.Else == nil {
+			els = end
+		}
+		g.printf("  br i1 %s, label %%%s, label %%%s\n", g.cond(s.Cond), then, els)
+		g.block(then)
+		g.stmt(s.Body)
+		g.printf("  br label %%%s\n", end)
+		if s.Else != nil {
+			g.block(els)
+			g.stmt(s.Else)
+			g.printf("  br label %%%s\n", end)
+		}
+		g.block(end)
+	case *ast.WhileStmt:
+		cond, body, end := g.label("while.cond"), g.label("while.body"), g.label("while.end")
+		g.printf("  br label %%%s\n", cond)
+		g.block(cond)
+		g.printf("  br i1 %s, label %%%s, label %%%s\n", g.cond(s.Cond), body, end)
+		g.block(body)
+		g.stmt(s.Body)
+		g.printf("  br label %%%s\n", cond)
+		g.block(end)
+	case *ast.ReturnStmt:
+		if s.Result != nil {
+			g.printf("  ret i32 %s\n", g.expr(s.Result))
+		} else {
+			g.printf("  ret void\n")
+		}
+		// Statements following the return statement are unreachable, but
+		// must be placed in a basic block nonetheless.
+		g.block(g.label("return.dead"))
+	}
+}
+
+// call emits a procedure call and returns the resulting i32 value, which is
+// empty for procedures without result. Reference parameters are passed as
+// pointers, value parameters as values.
+func (g *generator) call(x *ast.CallExpr) string {
+	proc := g.info.Callee(x)
+	params := proc.Params()
+	args := make([]string, 0, len(x.Args))
+	for i, arg := range x.Args {
+		if params[i].IsRef() {
+			args = append(args, "ptr "+g.addr(arg))
+		} else {
+			args = append(args, paramType(params[i])+" "+g.expr(arg))
+		}
+	}
+	if proc.Result() == nil {
+		g.printf("  call void %s(%s)\n", procName(proc), strings.Join(args, ", "))
+		return ""
+	}
+	return g.tmp("call i32 %s(%s)", procName(proc), strings.Join(args, ", "))
+}
+
+// -----------------------------------------------------------------------------
+// Expressions
+
+// conds maps comparison operators to the predicates of the icmp instruction.
+var conds = map[token.Token]string{
+	token.EQL: "eq",
+	token.NOT: "ne",
+	token.LSS: "slt",
+	token.LEQ: "sle",
+	token.GTR: "sgt",
+	token.GEQ: "sge",
+}
+
+// ops maps arithmetic operators to instructions. Arithmetic wraps around, so
+// the instructions don't carry the nsw flag.
+var ops = map[token.Token]string{
+	token.ADD: "add",
+	token.SUB: "sub",
+	token.MUL: "mul",
+}
+
+// cond evaluates a boolean expression and returns the resulting i1 value.
+// Booleans are stored as i32 values 0 and 1.
+func (g *generator) cond(e ast.Expr) string {
+	if v, ok := g.info.Values[e]; ok {
+		return fmt.Sprint(v != 0)
+	}
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return g.cond(e.X)
+	case *ast.UnaryExpr:
+		return g.tmp("xor i1 %s, true", g.cond(e.X))
+	case *ast.BinaryExpr:
+		if e.Op == token.LAND || e.Op == token.LOR {
+			return g.logical(e)
+		}
+		x, y := g.expr(e.X), g.expr(e.Y)
+		return g.tmp("icmp %s i32 %s, %s", conds[e.Op], x, y)
+	}
+	return g.tmp("icmp ne i32 %s, 0", g.expr(e))
+}
+
+// logical evaluates the right operand of a logical operator only if the left
+// one doesn't determine the result and returns the resulting i1 value.
+func (g *generator) logical(e *ast.BinaryExpr) string {
+	rhs, end := g.label("logical.rhs"), g.label("logical.end")
+	x := g.cond(e.X)
+	short, from := "false", g.cur
+	if e.Op == token.LAND {
+		g.printf("  br i1 %s, label %%%s, label %%%s\n", x, rhs, end)
+	} else {
+		short = "true"
+		g.printf("  br i1 %s, label %%%s, label %%%s\n", x, end, rhs)
+	}
+	g.block(rhs)
+	y := g.cond(e.Y)
+	g.printf("  br label %%%s\n", end)
+	to := g.cur
+	g.block(end)
+	return g.tmp("phi i1 [ %s, %%%s ], [ %s, %%%s ]", short, from, y, to)
+}
+
+// expr evaluates an integer or boolean expression and returns the resulting
+// i32 value. A string literal evaluates to the pointer to its constant.
+func (g *generator) expr(e ast.Expr) string {
+	if s, ok := g.info.Strings[e]; ok {
+		g.strings = append(g.strings, s)
+		return fmt.Sprintf("@str.%d", len(g.strings))
+	}
+	if v, ok := g.info.Values[e]; ok {
+		return fmt.Sprint(v)
+	}
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return g.expr(e.X)
+	case *ast.UnaryExpr:
+		if e.Op == token.LNOT {
+			return g.tmp("zext i1 %s to i32", g.cond(e))
+		}
+		return g.tmp("sub i32 0, %s", g.expr(e.X))
+	case *ast.BinaryExpr:
+		if types.IsBoolean(g.info.Types[e]) {
+			return g.tmp("zext i1 %s to i32", g.cond(e))
+		}
+		x, y := g.expr(e.X), g.expr(e.Y)
+		if e.Op == token.QUO {
+			return g.quo(x, y, e.OpPos.Line)
+		}
+		return g.tmp("%s i32 %s, %s", ops[e.Op], x, y)
+	case *ast.Ident, *ast.IndexExpr, *ast.SelectorExpr:
+		return g.tmp("load i32, ptr %s", g.addr(e))
+	case *ast.CallExpr:
+		return g.call(e)
+	}
+	panic(fmt.Sprintf("llvm: unexpected expression %T", e))
+}
+
+// quo emits a division. Division by zero is a runtime error. The division of
+// the smallest integer by -1 is undefined in LLVM, so the divisor is replaced
+// by 1 and the negated dividend is selected instead, which wraps around like
+// the other arithmetic operations.
+func (g *generator) quo(x, y string, line int) string {
+	fail, ok := g.label("div.fail"), g.label("div.ok")
+	zero := g.tmp("icmp eq i32 %s, 0", y)
+	g.printf("  br i1 %s, label %%%s, label %%%s\n", zero, fail, ok)
+	g.trap(fail, divideError, line)
+	g.block(ok)
+	minus := g.tmp("icmp eq i32 %s, -1", y)
+	d := g.tmp("select i1 %s, i32 1, i32 %s", minus, y)
+	q := g.tmp("sdiv i32 %s, %s", x, d)
+	neg := g.tmp("sub i32 0, %s", x)
+	return g.tmp("select i1 %s, i32 %s, i32 %s", minus, neg, q)
+}
+
+// addr returns the pointer to a variable, array element or record field. Array
+// indices are bounds checked.
+func (g *generator) addr(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return g.addr(e.X)
+	case *ast.Ident:
+		return g.addrs[g.info.Uses[e].(*types.Var)]
+	case *ast.IndexExpr:
+		a := g.info.Types[e.X].(*types.Array)
+		base := g.addr(e.X)
+		i := g.expr(e.Index)
+		in := g.tmp("icmp ult i32 %s, %d", i, a.Len())
+		fail, ok := g.label("index.fail"), g.label("index.ok")
+		g.printf("  br i1 %s, label %%%s, label %%%s\n", in, ok, fail)
+		g.trap(fail, indexError, e.Lbrack.Line)
+		g.block(ok)
+		return g.tmp("getelementptr %s, ptr %s, i32 0, i32 %s", typ(a), base, i)
+	case *ast.SelectorExpr:
+		r := g.info.Types[e.X].(*types.Record)
+		base := g.addr(e.X)
+		f := g.info.Uses[e.Sel].(*types.Var)
+		for i, field := range r.Fields() {
+			if field == f {
+				return g.tmp("getelementptr %s, ptr %s, i32 0, i32 %d", typ(r), base, i)
+			}
+		}
+	}
+	panic(fmt.Sprintf("llvm: unexpected operand %T", e))
+}
+
+// trap emits a basic block which calls the trap handler with the source line.
+func (g *generator) trap(label, handler string, line int) {
+	g.block(label)
+	g.printf("  call void @%s(i32 %d)\n", handler, line)
+	g.printf("  unreachable\n")
+}
+
+// -----------------------------------------------------------------------------
+// Helpers
+
+// tmp emits an instruction which assigns its result to a new temporary value
+// and returns the name of the value. Names of temporary values, basic blocks
+// and variable pointers contain a dot and therefore never clash with the
+// identifiers of the program.
+func (g *generator) tmp(format string, args ...interface{}) string {
+	g.tmps++
+	name := fmt.Sprintf("%%t.%d", g.tmps)
+	g.printf("  %s = %s\n", name, fmt.Sprintf(format, args...))
+	return name
+}
+
+// label returns a new, unique basic block label with the given prefix.
+func (g *generator) label(prefix string) string {
+	g.labels++
+	return fmt.Sprintf("%s.%d", prefix, g.labels)
+}
+
+// block starts a new basic block.
+func (g *generator) block(label string) {
+	g.cur = label
+	g.printf("\n%s:\n", label)
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// procName returns the global name of a procedure.
+func procName(proc *types.Proc) string {
+	if proc.Builtin() {
+		return "@spl_" + proc.Name()
+	}
+	return "@spl." + proc.LinkName()
+}
+
+// resultType returns the LLVM type of the result of a procedure.
+func resultType(proc *types.Proc) string {
+	if proc.Result() == nil {
+		return "void"
+	}
+	return "i32"
+}
+
+// paramType returns the LLVM type a parameter is passed as. Strings are passed
+// as pointer to their characters terminated by a zero byte.
+func paramType(v *types.Var) string {
+	if v.IsRef() || v.Type() == types.Typ[types.String] {
+		return "ptr"
+	}
+	return "i32"
+}
+
+// typ returns the LLVM type of a value of type t. Records are structures, which
+// are not padded because all their fields are aligned like i32.
+func typ(t types.Type) string {
+	switch t := t.(type) {
+	case *types.Array:
+		return fmt.Sprintf("[%d x %s]", t.Len(), typ(t.Elem()))
+	case *types.Record:
+		fields := make([]string, len(t.Fields()))
+		for i, f := range t.Fields() {
+			fields[i] = typ(f.Type())
+		}
+		return "{ " + strings.Join(fields, ", ") + " }"
+	}
+	return "i32"
+}
+
+// quote returns s as LLVM string literal. Quotes, backslashes and non-printable
+// characters are escaped as hexadecimal byte values.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < ' ' || c > '~' || c == '"' || c == '\\' {
+			fmt.Fprintf(&b, "\\%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// unparen returns e with any enclosing parentheses stripped.
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		p, ok := e.(*ast.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.X
+	}
+}
@@ -0,0 +1,76 @@
+package llvm_test
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/codegen/llvm"
+	"github.com/lukasmalkmus/spl/internal/app/spl/ext"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+// exts are the language extensions the test programs may use.
+const exts = ext.Functions | ext.Bool | ext.Records | ext.Strings
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestCompile(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.spl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files = append(files, "../../testdata/valid.spl")
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".spl")
+		t.Run(name, func(t *testing.T) {
+			prog, err := parser.ParseFiles(token.NewFileSet(), []string{file}, exts, parser.DeclarationErrors)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := types.Check(prog)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := llvm.Compile(&buf, prog, info); err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", name+".ll")
+			if *update {
+				if err := ioutil.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal("failed to read golden file:", err)
+			}
+			equals(t, buf.String(), string(want))
+		})
+	}
+}
+
+// equals fails the test if got is not equal to want. The first differing line
+// is reported.
+func equals(tb testing.TB, got, want string) {
+	tb.Helper()
+	if got == want {
+		return
+	}
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(want, "\n")
+	for i := 0; i < len(gotLines) && i < len(wantLines); i++ {
+		if gotLines[i] != wantLines[i] {
+			tb.Errorf("line %d:\n\tgot:  %q\n\twant: %q", i+1, gotLines[i], wantLines[i])
+			return
+		}
+	}
+	tb.Errorf("got %d lines, want %d", len(gotLines), len(wantLines))
+}
@@ -0,0 +1,158 @@
+package constant
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+)
+
+// MaxInt is the largest value of an integer literal.
+const MaxInt = math.MaxInt32
+
+var (
+	// ErrOverflow is returned by UnaryOp and BinaryOp if the exact result
+	// isn't representable as int32. The value returned along with it is the
+	// result wrapped around like at run time.
+	ErrOverflow = errors.New("constant overflow")
+
+	// ErrDivByZero is returned by BinaryOp for a division by zero.
+	ErrDivByZero = errors.New("division by zero")
+)
+
+// Int decodes the value of an integer literal: a decimal literal, a
+// hexadecimal literal with the prefix "0x" or a character literal, which
+// denotes the ASCII code of a printable character or of the newline escape
+// sequence \n enclosed in apostrophes.
+func Int(lit string) (int32, error) {
+	if strings.HasPrefix(lit, "'") {
+		return char(lit)
+	}
+	base, s := 10, lit
+	if strings.HasPrefix(lit, "0x") {
+		base, s = 16, lit[2:]
+	}
+	v, err := strconv.ParseUint(s, base, 64)
+	if err != nil {
+		if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+			return 0, fmt.Errorf("%s exceeds 2^31-1", lit)
+		}
+		return 0, fmt.Errorf("malformed integer literal %s", lit)
+	}
+	if v > MaxInt {
+		return 0, fmt.Errorf("%s exceeds 2^31-1", lit)
+	}
+	return int32(v), nil
+}
+
+// char decodes a character literal.
+func char(lit string) (int32, error) {
+	n := len(lit)
+	if n < 3 || lit[n-1] != '\'' {
+		return 0, fmt.Errorf("malformed character literal %s", lit)
+	}
+	switch s := lit[1 : n-1]; {
+	case s == `\n`:
+		return '\n', nil
+	case len(s) == 1 && s[0] >= ' ' && s[0] <= '~':
+		return int32(s[0]), nil
+	case len(s) == 2 && s[0] == '\\':
+		return 0, fmt.Errorf("invalid escape sequence %s", s)
+	}
+	return 0, fmt.Errorf("malformed character literal %s", lit)
+}
+
+// String decodes the value of a string literal: printable ASCII characters
+// and the escape sequences \n, \t, \" and \\ enclosed in quotation marks.
+func String(lit string) (string, error) {
+	n := len(lit)
+	if n < 2 || lit[0] != '"' || lit[n-1] != '"' {
+		return "", fmt.Errorf("malformed string literal %s", lit)
+	}
+	var b strings.Builder
+	for s := lit[1 : n-1]; s != ""; s = s[1:] {
+		c := s[0]
+		switch {
+		case c == '\\' && len(s) > 1:
+			esc, ok := escapes[s[1]]
+			if !ok {
+				return "", fmt.Errorf("invalid escape sequence %s", s[:2])
+			}
+			_ = b.WriteByte(esc)
+			s = s[1:]
+		case c == '\\' || c == '"':
+			return "", fmt.Errorf("malformed string literal %s", lit)
+		case c < ' ' || c > '~':
+			r, _ := utf8.DecodeRuneInString(s)
+			return "", fmt.Errorf("illegal character %#U in string literal", r)
+		default:
+			_ = b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// escapes maps the escape sequences of string literals to the characters they
+// denote.
+var escapes = map[byte]byte{'n': '\n', 't': '\t', '"': '"', '\\': '\\'}
+
+// UnaryOp returns the value of the unary operation op x. The only unary
+// operator is the negation token.SUB.
+func UnaryOp(op token.Token, x int32) (int32, error) {
+	if op != token.SUB {
+		return 0, fmt.Errorf("invalid unary operator %s", op)
+	}
+	return exact(-int64(x))
+}
+
+// BinaryOp returns the value of the arithmetic operation x op y. Division
+// truncates towards zero.
+func BinaryOp(x int32, op token.Token, y int32) (int32, error) {
+	a, b := int64(x), int64(y)
+	switch op {
+	case token.ADD:
+		return exact(a + b)
+	case token.SUB:
+		return exact(a - b)
+	case token.MUL:
+		return exact(a * b)
+	case token.QUO:
+		if b == 0 {
+			return 0, ErrDivByZero
+		}
+		return exact(a / b)
+	}
+	return 0, fmt.Errorf("invalid binary operator %s", op)
+}
+
+// Compare returns the result of the comparison x op y.
+func Compare(x int32, op token.Token, y int32) (bool, error) {
+	switch op {
+	case token.EQL:
+		return x == y, nil
+	case token.NOT:
+		return x != y, nil
+	case token.LSS:
+		return x < y, nil
+	case token.LEQ:
+		return x <= y, nil
+	case token.GTR:
+		return x > y, nil
+	case token.GEQ:
+		return x >= y, nil
+	}
+	return false, fmt.Errorf("invalid comparison operator %s", op)
+}
+
+// exact converts v to int32. If v isn't representable, the wrapped value is
+// returned together with ErrOverflow.
+func exact(v int64) (int32, error) {
+	if v < math.MinInt32 || v > math.MaxInt32 {
+		return int32(v), ErrOverflow
+	}
+	return int32(v), nil
+}
@@ -0,0 +1,133 @@
+package constant_test
+
+import (
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/constant"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+)
+
+func TestInt(t *testing.T) {
+	tests := []struct {
+		lit     string
+		want    int32
+		wantErr string
+	}{
+		{"0", 0, ""},
+		{"42", 42, ""},
+		{"2147483647", 2147483647, ""},
+		{"0x1a2f", 0x1a2f, ""},
+		{"0x7FFFFFFF", 2147483647, ""},
+		{"'a'", 'a', ""},
+		{"' '", ' ', ""},
+		{"'''", '\'', ""},
+		{`'\n'`, '\n', ""},
+		{"2147483648", 0, "2147483648 exceeds 2^31-1"},
+		{"99999999999999999999", 0, "99999999999999999999 exceeds 2^31-1"},
+		{"0x80000000", 0, "0x80000000 exceeds 2^31-1"},
+		{"0XAB", 0, "malformed integer literal 0XAB"},
+		{"12x", 0, "malformed integer literal 12x"},
+		{`'\q'`, 0, `invalid escape sequence \q`},
+		{"'ab'", 0, "malformed character literal 'ab'"},
+		{"'a", 0, "malformed character literal 'a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lit, func(t *testing.T) {
+			got, err := constant.Int(tt.lit)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("got error %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("got %d, %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		lit     string
+		want    string
+		wantErr string
+	}{
+		{`""`, "", ""},
+		{`"Hello, World!"`, "Hello, World!", ""},
+		{`"a\tb\n"`, "a\tb\n", ""},
+		{`"\"q\" \\"`, `"q" \`, ""},
+		{`"\q"`, "", `invalid escape sequence \q`},
+		{`"a"b"`, "", `malformed string literal "a"b"`},
+		{`"a\"`, "", `malformed string literal "a\"`},
+		{`"abc`, "", `malformed string literal "abc`},
+		{`"ä"`, "", "illegal character U+00E4 'ä' in string literal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lit, func(t *testing.T) {
+			got, err := constant.String(tt.lit)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("got error %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("got %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBinaryOp(t *testing.T) {
+	tests := []struct {
+		x    int32
+		op   token.Token
+		y    int32
+		want int32
+		err  error
+	}{
+		{1, token.ADD, 2, 3, nil},
+		{1, token.SUB, 2, -1, nil},
+		{6, token.MUL, 7, 42, nil},
+		{7, token.QUO, 2, 3, nil},
+		{-7, token.QUO, 2, -3, nil},
+		{2147483647, token.ADD, 1, -2147483648, constant.ErrOverflow},
+		{-2147483648, token.SUB, 1, 2147483647, constant.ErrOverflow},
+		{65536, token.MUL, 65536, 0, constant.ErrOverflow},
+		{-2147483648, token.QUO, -1, -2147483648, constant.ErrOverflow},
+		{1, token.QUO, 0, 0, constant.ErrDivByZero},
+	}
+	for _, tt := range tests {
+		got, err := constant.BinaryOp(tt.x, tt.op, tt.y)
+		if got != tt.want || err != tt.err {
+			t.Errorf("%d %s %d = %d, %v, want %d, %v", tt.x, tt.op, tt.y, got, err, tt.want, tt.err)
+		}
+	}
+	if _, err := constant.BinaryOp(1, token.LSS, 2); err == nil {
+		t.Error("BinaryOp with comparison succeeded")
+	}
+}
+
+func TestUnaryOp(t *testing.T) {
+	if v, err := constant.UnaryOp(token.SUB, 5); v != -5 || err != nil {
+		t.Errorf("got %d, %v, want -5", v, err)
+	}
+	if v, err := constant.UnaryOp(token.SUB, -2147483648); v != -2147483648 || err != constant.ErrOverflow {
+		t.Errorf("got %d, %v, want overflow", v, err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	for _, tt := range []struct {
+		op   token.Token
+		want bool
+	}{
+		{token.EQL, false}, {token.NOT, true}, {token.LSS, true},
+		{token.LEQ, true}, {token.GTR, false}, {token.GEQ, false},
+	} {
+		if got, err := constant.Compare(1, tt.op, 2); got != tt.want || err != nil {
+			t.Errorf("1 %s 2 = %t, %v, want %t", tt.op, got, err, tt.want)
+		}
+	}
+}
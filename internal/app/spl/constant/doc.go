@@ -0,0 +1,5 @@
+// Package constant implements the values of constant expressions of the simple
+// programming language (SPL). It decodes integer and string literals and folds
+// arithmetic on constant values with the int32 semantics of the language,
+// reporting overflows and divisions by zero.
+package constant
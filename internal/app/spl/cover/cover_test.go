@@ -0,0 +1,139 @@
+package cover_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/cover"
+	"github.com/lukasmalkmus/spl/internal/app/spl/internal/testutil"
+	"github.com/lukasmalkmus/spl/internal/app/spl/interp"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+const src = `proc abs(ref x: int) {
+  if (x < 0) x := -x;
+}
+
+proc unused() {
+  printi(1);
+}
+
+proc main() {
+  var i: int;
+  i := 2;
+  while (i > 0) {
+    abs(i);
+    i := i - 1;
+  }
+}
+`
+
+func TestRecorder(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	prog, profiles := record(t, filepath.Join(dir, "test.spl"))
+
+	var buf bytes.Buffer
+	if err := cover.WriteProfiles(&buf, profiles); err != nil {
+		t.Fatal(err)
+	}
+	want := `mode: count
+test.spl:2.3,2.14 1 2
+test.spl:2.14,2.21 1 0
+test.spl:6.3,6.12 1 0
+test.spl:11.3,11.9 1 1
+test.spl:12.3,12.17 1 3
+test.spl:13.5,13.11 1 2
+test.spl:14.5,14.15 1 2
+`
+	if got := strings.ReplaceAll(buf.String(), dir+string(filepath.Separator), ""); got != want {
+		t.Errorf("got profile\n%s\nwant\n%s", got, want)
+	}
+
+	parsed, err := cover.ParseProfiles(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range cover.Funcs(parsed[0], prog) {
+		got = append(got, fmt.Sprintf("%s %.0f", f.Name, f.Percent()))
+	}
+	if want := "abs 50 unused 0 main 100"; strings.Join(got, " ") != want {
+		t.Errorf("got funcs %q, want %q", strings.Join(got, " "), want)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	filename := filepath.Join(dir, "test.spl")
+	_, profiles := record(t, filename)
+
+	var buf bytes.Buffer
+	if err := cover.WriteHTML(&buf, profiles, ioutil.ReadFile); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`(71.4%)</option>`,
+		`  <span class="cov1" title="2">if (x &lt; 0) </span><span class="cov0" title="0">x := -x</span>;`,
+		`  <span class="cov0" title="0">printi(1)</span>;`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("got report\n%s\nwant it to contain %q", buf.String(), want)
+		}
+	}
+}
+
+func TestParseProfiles(t *testing.T) {
+	profiles, err := cover.ParseProfiles(strings.NewReader("mode: count\nb.spl:1.1,1.5 1 0\na.spl:2.1,2.5 1 1\nb.spl:1.1,1.5 1 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 2 || profiles[0].FileName != "a.spl" || profiles[1].FileName != "b.spl" {
+		t.Fatalf("got profiles %v, want a.spl and b.spl", profiles)
+	}
+	if b := profiles[1].Blocks; len(b) != 1 || b[0].Count != 3 {
+		t.Errorf("got blocks %v, want merged block with count 3", b)
+	}
+
+	for _, in := range []string{"", "b.spl:1.1,1.5 1 0\n", "mode: count\nb.spl 1 0\n"} {
+		if _, err := cover.ParseProfiles(strings.NewReader(in)); err == nil {
+			t.Errorf("parsing %q: got no error", in)
+		}
+	}
+}
+
+// record runs the test program written to the file and returns its coverage
+// profiles.
+func record(tb testing.TB, filename string) (*ast.Program, []*cover.Profile) {
+	tb.Helper()
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		tb.Fatal(err)
+	}
+	prog, err := parser.ParseFile(token.NewFileSet(), filename, nil, parser.DeclarationErrors)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	info, err := types.Check(prog)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	m, err := interp.New(prog, info, strings.NewReader(""), &bytes.Buffer{})
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	r := cover.Start(m, prog)
+	if err := m.Run(); err != nil {
+		tb.Fatal(err)
+	}
+	r.Stop()
+	return prog, r.Profiles()
+}
@@ -0,0 +1,4 @@
+// Package cover records which statements of a program are executed and how
+// often. The recording is written as coverage profile in the format of go test
+// -coverprofile, which is summarized per procedure or rendered as HTML report.
+package cover
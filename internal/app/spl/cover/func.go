@@ -0,0 +1,53 @@
+package cover
+
+import (
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+)
+
+// Func is the coverage of a procedure.
+type Func struct {
+	Name    string
+	Pos     token.Position
+	Covered int // number of executed statements
+	Total   int // number of statements
+}
+
+// Percent returns the percentage of executed statements. Procedures without
+// statements are fully covered.
+func (f Func) Percent() float64 {
+	if f.Total == 0 {
+		return 100
+	}
+	return 100 * float64(f.Covered) / float64(f.Total)
+}
+
+// Funcs returns the coverage of the procedures declared by the program in the
+// source file of the profile, in source order.
+func Funcs(p *Profile, prog *ast.Program) []Func {
+	var funcs []Func
+	for _, decl := range prog.Decls {
+		d, ok := decl.(*ast.ProcDecl)
+		if !ok || d.Body == nil || d.Pos().Filename != p.FileName {
+			continue
+		}
+		f := Func{Name: d.Name.Name, Pos: d.Pos()}
+		start, end := d.Pos(), d.Body.End()
+		for _, b := range p.Blocks {
+			if before(b.StartLine, b.StartCol, start) || !before(b.StartLine, b.StartCol, end) {
+				continue
+			}
+			f.Total += b.NumStmt
+			if b.Count > 0 {
+				f.Covered += b.NumStmt
+			}
+		}
+		funcs = append(funcs, f)
+	}
+	return funcs
+}
+
+// before reports whether the line and column are before the position.
+func before(line, col int, pos token.Position) bool {
+	return line < pos.Line || line == pos.Line && col < pos.Column
+}
@@ -0,0 +1,132 @@
+package cover
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+)
+
+// WriteHTML writes a HTML report which shows the source files of the profiles
+// with the executed statements in green and the statements which were never
+// executed in red. The source files are read by readFile.
+func WriteHTML(w io.Writer, profiles []*Profile, readFile func(name string) ([]byte, error)) error {
+	var data struct {
+		Files []file
+	}
+	for _, p := range profiles {
+		src, err := readFile(p.FileName)
+		if err != nil {
+			return err
+		}
+		covered, total := 0, 0
+		for _, b := range p.Blocks {
+			total += b.NumStmt
+			if b.Count > 0 {
+				covered += b.NumStmt
+			}
+		}
+		percent := 100.0
+		if total > 0 {
+			percent = 100 * float64(covered) / float64(total)
+		}
+		data.Files = append(data.Files, file{
+			Name:    p.FileName,
+			Percent: percent,
+			Body:    template.HTML(annotate(src, p.Blocks)),
+		})
+	}
+	return page.Execute(w, data)
+}
+
+type file struct {
+	Name    string
+	Percent float64
+	Body    template.HTML
+}
+
+// annotate escapes the source code and wraps the blocks into spans. The blocks
+// must be sorted and must not overlap.
+func annotate(src []byte, blocks []Block) string {
+	var buf bytes.Buffer
+	line, col := 1, 1
+	var open *Block
+	for i := 0; i <= len(src); i++ {
+		if open != nil && (line > open.EndLine || line == open.EndLine && col >= open.EndCol) {
+			buf.WriteString("</span>")
+			open = nil
+		}
+		if open == nil && len(blocks) > 0 && (line > blocks[0].StartLine || line == blocks[0].StartLine && col >= blocks[0].StartCol) {
+			open, blocks = &blocks[0], blocks[1:]
+			span(&buf, open)
+		}
+		if i == len(src) {
+			break
+		}
+		switch c := src[i]; c {
+		case '\n':
+			// Spans don't cross lines, so the lines of a statement
+			// are colored individually.
+			if open != nil {
+				buf.WriteString("</span>\n")
+				span(&buf, open)
+			} else {
+				buf.WriteByte('\n')
+			}
+			line, col = line+1, 1
+		default:
+			template.HTMLEscape(&buf, []byte{c})
+			col++
+		}
+	}
+	if open != nil {
+		buf.WriteString("</span>")
+	}
+	return buf.String()
+}
+
+// span opens the span of a block. The count of the block is shown as title.
+func span(buf *bytes.Buffer, b *Block) {
+	class := "cov0"
+	if b.Count > 0 {
+		class = "cov1"
+	}
+	fmt.Fprintf(buf, `<span class="%s" title="%d">`, class, b.Count)
+}
+
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>SPL coverage</title>
+<style>
+body { background: #000; color: #808080; font-family: Menlo, monospace; font-size: 14px; margin: 0; }
+#topbar { background: #000; border-bottom: 1px solid #333; padding: 8px; position: fixed; top: 0; width: 100%; }
+#legend span { margin: 0 8px; }
+#content { padding: 48px 8px 8px; }
+.cov0 { color: rgb(192, 0, 0); }
+.cov1 { color: rgb(44, 212, 149); }
+</style>
+</head>
+<body>
+<div id="topbar">
+<select id="files">
+{{range $i, $f := .Files}}<option value="file{{$i}}">{{$f.Name}} ({{printf "%.1f" $f.Percent}}%)</option>
+{{end}}</select>
+<span id="legend"><span>not tracked</span><span class="cov0">not covered</span><span class="cov1">covered</span></span>
+</div>
+<div id="content">
+{{range $i, $f := .Files}}<pre class="file" id="file{{$i}}"{{if $i}} style="display: none"{{end}}>{{$f.Body}}</pre>
+{{end}}</div>
+<script>
+var files = document.getElementById("files");
+files.addEventListener("change", function() {
+	var pres = document.getElementsByClassName("file");
+	for (var i = 0; i < pres.length; i++) {
+		pres[i].style.display = pres[i].id === files.value ? "block" : "none";
+	}
+});
+</script>
+</body>
+</html>
+`))
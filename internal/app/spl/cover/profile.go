@@ -0,0 +1,207 @@
+package cover
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/interp"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+)
+
+// Mode is the mode of the profiles written by a Recorder. Blocks hold the
+// number of times their statement was executed.
+const Mode = "count"
+
+// Profile is the coverage profile of a source file.
+type Profile struct {
+	FileName string
+	Mode     string
+	Blocks   []Block
+}
+
+// Block is a range of source code holding one or more statements, and the
+// number of times it was executed. Lines and columns start at 1, the end
+// column is exclusive.
+type Block struct {
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+	NumStmt   int
+	Count     int
+}
+
+// Recorder records the statements executed by a program.
+type Recorder struct {
+	m      *interp.Machine
+	hooks  interp.Hooks
+	stmts  []ast.Stmt
+	counts map[ast.Stmt]int
+}
+
+// Start starts recording the statements of the program executed by the
+// machine. Hooks installed on the machine before are still called.
+func Start(m *interp.Machine, prog *ast.Program) *Recorder {
+	r := &Recorder{m: m, hooks: m.Hooks, counts: make(map[ast.Stmt]int)}
+	ast.Inspect(prog, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.ExprStmt, *ast.AssignStmt, *ast.IfStmt, *ast.WhileStmt, *ast.ReturnStmt:
+			r.stmts = append(r.stmts, s.(ast.Stmt))
+		}
+		return true
+	})
+
+	m.Hooks.Stmt = func(s ast.Stmt) error {
+		r.counts[s]++
+		if r.hooks.Stmt != nil {
+			return r.hooks.Stmt(s)
+		}
+		return nil
+	}
+	return r
+}
+
+// Stop stops recording. It must be called after the program terminated.
+func (r *Recorder) Stop() { r.m.Hooks = r.hooks }
+
+// Profiles returns the coverage profiles of the source files of the program.
+// Every statement is a block. The condition of while statements is counted
+// for every evaluation.
+func (r *Recorder) Profiles() []*Profile {
+	var profiles []*Profile
+	byName := make(map[string]*Profile)
+	for _, s := range r.stmts {
+		start, end := stmtRange(s)
+		p, ok := byName[start.Filename]
+		if !ok {
+			p = &Profile{FileName: start.Filename, Mode: Mode}
+			byName[start.Filename] = p
+			profiles = append(profiles, p)
+		}
+		p.Blocks = append(p.Blocks, Block{
+			StartLine: start.Line,
+			StartCol:  start.Column,
+			EndLine:   end.Line,
+			EndCol:    end.Column,
+			NumStmt:   1,
+			Count:     r.counts[s],
+		})
+	}
+	return profiles
+}
+
+// stmtRange returns the range of the statement in the source code. If and
+// while statements only cover their head, as their bodies consist of
+// statements of their own.
+func stmtRange(s ast.Stmt) (token.Position, token.Position) {
+	switch s := s.(type) {
+	case *ast.IfStmt:
+		return s.If, s.Body.Pos()
+	case *ast.WhileStmt:
+		return s.While, s.Body.Pos()
+	}
+	return s.Pos(), s.End()
+}
+
+// WriteProfiles writes the profiles in the format of go test -coverprofile.
+func WriteProfiles(w io.Writer, profiles []*Profile) error {
+	bw := bufio.NewWriter(w)
+	mode := Mode
+	if len(profiles) > 0 {
+		mode = profiles[0].Mode
+	}
+	fmt.Fprintf(bw, "mode: %s\n", mode)
+	for _, p := range profiles {
+		for _, b := range p.Blocks {
+			fmt.Fprintf(bw, "%s:%d.%d,%d.%d %d %d\n", p.FileName, b.StartLine, b.StartCol, b.EndLine, b.EndCol, b.NumStmt, b.Count)
+		}
+	}
+	return bw.Flush()
+}
+
+// ParseProfiles parses profiles in the format of go test -coverprofile.
+// Blocks of the same range are merged. The profiles are sorted by file name,
+// their blocks by position.
+func ParseProfiles(r io.Reader) ([]*Profile, error) {
+	s := bufio.NewScanner(r)
+	byName := make(map[string]*Profile)
+	mode := ""
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		if n == 1 {
+			if !strings.HasPrefix(line, "mode: ") {
+				return nil, fmt.Errorf("line %d: missing mode", n)
+			}
+			mode = strings.TrimPrefix(line, "mode: ")
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		name, b, err := parseBlock(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		p, ok := byName[name]
+		if !ok {
+			p = &Profile{FileName: name, Mode: mode}
+			byName[name] = p
+		}
+		p.Blocks = append(p.Blocks, b)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if mode == "" {
+		return nil, fmt.Errorf("empty profile")
+	}
+
+	profiles := make([]*Profile, 0, len(byName))
+	for _, p := range byName {
+		sort.Slice(p.Blocks, func(i, j int) bool {
+			a, b := p.Blocks[i], p.Blocks[j]
+			return a.StartLine < b.StartLine || a.StartLine == b.StartLine && a.StartCol < b.StartCol
+		})
+		blocks := p.Blocks[:0]
+		for _, b := range p.Blocks {
+			if n := len(blocks); n > 0 && blocks[n-1].StartLine == b.StartLine && blocks[n-1].StartCol == b.StartCol &&
+				blocks[n-1].EndLine == b.EndLine && blocks[n-1].EndCol == b.EndCol {
+				blocks[n-1].Count += b.Count
+				continue
+			}
+			blocks = append(blocks, b)
+		}
+		p.Blocks = blocks
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].FileName < profiles[j].FileName })
+	return profiles, nil
+}
+
+// parseBlock parses a line like "file.spl:3.2,3.14 1 4".
+func parseBlock(line string) (string, Block, error) {
+	var b Block
+	i := strings.LastIndex(line, ":")
+	if i < 0 {
+		return "", b, fmt.Errorf("invalid block %q", line)
+	}
+	fields := strings.Fields(line[i+1:])
+	if len(fields) != 3 {
+		return "", b, fmt.Errorf("invalid block %q", line)
+	}
+	var err error
+	if _, err = fmt.Sscanf(fields[0], "%d.%d,%d.%d", &b.StartLine, &b.StartCol, &b.EndLine, &b.EndCol); err == nil {
+		if b.NumStmt, err = strconv.Atoi(fields[1]); err == nil {
+			b.Count, err = strconv.Atoi(fields[2])
+		}
+	}
+	if err != nil {
+		return "", b, fmt.Errorf("invalid block %q", line)
+	}
+	return line[:i], b, nil
+}
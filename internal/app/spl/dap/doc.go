@@ -0,0 +1,9 @@
+// Package dap implements a server for the Debug Adapter Protocol, which lets
+// editors like VS Code debug programs. The server launches a single program on
+// the execution engine of package interp and supports breakpoints, stepping,
+// call stacks, scopes and variables. Program output is sent to the debug
+// console, program input is read from it.
+//
+// The protocol is specified at
+// https://microsoft.github.io/debug-adapter-protocol/specification.
+package dap
@@ -0,0 +1,70 @@
+package dap
+
+import (
+	"io"
+	"sync"
+)
+
+// output sends the output of the program to the debug console of the client.
+type output struct {
+	s        *server
+	category string
+}
+
+// Write implements the io.Writer interface.
+func (o *output) Write(p []byte) (int, error) {
+	err := o.s.event("output", struct {
+		Category string `json:"category"`
+		Output   string `json:"output"`
+	}{o.category, string(p)})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// input is the input of the program entered in the debug console. Reads block
+// until input is available or the input is closed.
+type input struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	buf    []byte
+	closed bool
+}
+
+func newInput() *input {
+	in := &input{}
+	in.cond = sync.NewCond(&in.mu)
+	return in
+}
+
+// Read implements the io.Reader interface.
+func (in *input) Read(p []byte) (int, error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	for len(in.buf) == 0 && !in.closed {
+		in.cond.Wait()
+	}
+	if len(in.buf) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, in.buf)
+	in.buf = in.buf[n:]
+	return n, nil
+}
+
+// Write appends p to the input.
+func (in *input) Write(p []byte) {
+	in.mu.Lock()
+	in.buf = append(in.buf, p...)
+	in.mu.Unlock()
+	in.cond.Broadcast()
+}
+
+// Close closes the input. Pending and subsequent reads return io.EOF.
+func (in *input) Close() {
+	in.mu.Lock()
+	in.closed = true
+	in.mu.Unlock()
+	in.cond.Broadcast()
+}
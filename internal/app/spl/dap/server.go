@@ -0,0 +1,630 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/interp"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+// threadID is the ID of the only thread of a program.
+const threadID = 1
+
+// errTerminated is returned by the hooks of a program which is terminated by
+// the client.
+var errTerminated = errors.New("terminated")
+
+// LoadFunc parses and type checks the program at the given path.
+type LoadFunc func(path string) (*ast.Program, *types.Info, error)
+
+// mode describes when a resumed program is stopped again.
+type mode int
+
+const (
+	modeContinue mode = iota // stop on breakpoints only
+	modeStepIn               // stop on the next line, entering procedures
+	modeNext                 // stop on the next line of the same procedure
+	modeStepOut              // stop after the procedure returned
+)
+
+// container holds the variables of a scope or the elements of an array which
+// are referenced by the client.
+type container struct {
+	frame *interp.Frame
+	vars  []*types.Var
+	typ   *types.Array
+	mem   []int32
+}
+
+// location is a line of a source file.
+type location struct {
+	path string
+	line int
+}
+
+// server is the state of a debug session.
+type server struct {
+	conn conn
+	load LoadFunc
+
+	// mu guards the fields below and the writes to the client.
+	mu          sync.Mutex
+	seq         int
+	breakpoints map[location]bool
+	stopped     bool
+
+	// lineBase and columnBase are the numbers of the first line and column
+	// of the client.
+	lineBase, columnBase int
+
+	m           *interp.Machine
+	stmts       map[location]bool
+	input       *input
+	noDebug     bool
+	stopOnEntry bool
+	started     bool
+	done        chan struct{}
+
+	// The following fields are owned by the program while it runs and by
+	// the request loop while it is stopped. Ownership is passed by resume.
+	resume     chan struct{}
+	resuming   bool
+	mode       mode
+	depth      int // depth and line at the time the program was resumed
+	line       location
+	prev       location // line and depth of the previous statement
+	prevDepth  int
+	refs       []container
+	pause      int32
+	terminated int32
+}
+
+// Serve serves a debug session on the connection. It returns when the client
+// disconnects or closes the connection.
+func Serve(rw io.ReadWriter, load LoadFunc) error {
+	s := &server{
+		conn:        conn{r: bufio.NewReader(rw), w: rw},
+		load:        load,
+		breakpoints: make(map[location]bool),
+		lineBase:    1,
+		columnBase:  1,
+		input:       newInput(),
+		resume:      make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	defer s.terminate()
+
+	for {
+		req, err := s.conn.read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if req.Type != "request" {
+			continue
+		}
+
+		body, err := s.handle(req)
+		resp := &response{Type: "response", RequestSeq: req.Seq, Success: err == nil, Command: req.Command, Body: body}
+		if err != nil {
+			resp.Message = err.Error()
+		}
+		if err := s.send(resp); err != nil {
+			return err
+		}
+		// The program is resumed after the response is sent, so the
+		// client doesn't receive events of the resumed program first.
+		if s.resuming {
+			s.resuming = false
+			s.resume <- struct{}{}
+		}
+		switch req.Command {
+		case "launch":
+			// Configuration requests are accepted once the program is
+			// launched.
+			if resp.Success {
+				if err := s.event("initialized", nil); err != nil {
+					return err
+				}
+			}
+		case "disconnect":
+			return nil
+		}
+	}
+}
+
+// send sends a message to the client.
+func (s *server) send(msg interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	switch msg := msg.(type) {
+	case *response:
+		msg.Seq = s.seq
+	case *event:
+		msg.Seq = s.seq
+	}
+	return s.conn.write(msg)
+}
+
+// event sends an event to the client.
+func (s *server) event(name string, body interface{}) error {
+	return s.send(&event{Type: "event", Event: name, Body: body})
+}
+
+// handle handles a request and returns the body of the response.
+func (s *server) handle(req *request) (interface{}, error) {
+	switch req.Command {
+	case "initialize":
+		var args initializeArguments
+		if err := unmarshal(req, &args); err != nil {
+			return nil, err
+		}
+		if args.LinesStartAt1 != nil && !*args.LinesStartAt1 {
+			s.lineBase = 0
+		}
+		if args.ColumnsStartAt1 != nil && !*args.ColumnsStartAt1 {
+			s.columnBase = 0
+		}
+		return capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsEvaluateForHovers:        true,
+			SupportsTerminateRequest:         true,
+		}, nil
+	case "launch":
+		var args launchArguments
+		if err := unmarshal(req, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.launch(args)
+	case "setBreakpoints":
+		var args setBreakpointsArguments
+		if err := unmarshal(req, &args); err != nil {
+			return nil, err
+		}
+		return s.setBreakpoints(args)
+	case "setExceptionBreakpoints":
+		return nil, nil
+	case "configurationDone":
+		if s.m == nil {
+			return nil, errors.New("no program launched")
+		}
+		if !s.started {
+			s.started = true
+			go s.run()
+		}
+		return nil, nil
+	case "threads":
+		return struct {
+			Threads []thread `json:"threads"`
+		}{[]thread{{ID: threadID, Name: "main"}}}, nil
+	case "stackTrace":
+		return s.stackTrace()
+	case "scopes", "variables", "evaluate":
+		var args frameArguments
+		if err := unmarshal(req, &args); err != nil {
+			return nil, err
+		}
+		switch req.Command {
+		case "scopes":
+			return s.scopes(args)
+		case "variables":
+			return s.variables(args)
+		}
+		return s.evaluate(args)
+	case "continue":
+		return struct {
+			AllThreadsContinued bool `json:"allThreadsContinued"`
+		}{true}, s.continueAs(modeContinue)
+	case "next":
+		return nil, s.continueAs(modeNext)
+	case "stepIn":
+		return nil, s.continueAs(modeStepIn)
+	case "stepOut":
+		return nil, s.continueAs(modeStepOut)
+	case "pause":
+		atomic.StoreInt32(&s.pause, 1)
+		return nil, nil
+	case "disconnect", "terminate":
+		s.terminate()
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported command %q", req.Command)
+}
+
+// unmarshal decodes the arguments of a request.
+func unmarshal(req *request, args interface{}) error {
+	if len(req.Arguments) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(req.Arguments, args); err != nil {
+		return fmt.Errorf("invalid arguments of %s request: %w", req.Command, err)
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+// Program execution
+
+// launch loads the program. It is started by the configurationDone request.
+func (s *server) launch(args launchArguments) error {
+	if s.m != nil {
+		return errors.New("program already launched")
+	}
+	path, err := filepath.Abs(args.Program)
+	if err != nil {
+		return err
+	}
+	prog, info, err := s.load(path)
+	if err != nil {
+		return err
+	}
+	m, err := interp.New(prog, info, s.input, &output{s, "stdout"})
+	if err != nil {
+		return err
+	}
+	m.Hooks.Stmt = s.stmt
+
+	s.m = m
+	s.noDebug, s.stopOnEntry = args.NoDebug, args.StopOnEntry
+	s.stmts = make(map[location]bool)
+	// The procedures include the ones of imported modules.
+	for _, p := range info.Procs {
+		ast.Inspect(p.Decl(), func(n ast.Node) bool {
+			switch n.(type) {
+			case *ast.ExprStmt, *ast.AssignStmt, *ast.IfStmt, *ast.WhileStmt, *ast.ReturnStmt:
+				pos := n.Pos()
+				s.stmts[location{pos.Filename, pos.Line}] = true
+			}
+			return true
+		})
+	}
+	return nil
+}
+
+// run runs the program and reports its termination. A runtime error stops the
+// program until it is resumed, so its state can be inspected.
+func (s *server) run() {
+	defer close(s.done)
+
+	exitCode := 0
+	err := s.m.Run()
+	if rerr, ok := err.(*interp.RuntimeError); ok {
+		exitCode = 1
+		_, _ = (&output{s, "stderr"}).Write([]byte(rerr.Error() + "\n"))
+		if !s.noDebug {
+			err = s.stop("exception", rerr.Msg)
+		}
+	}
+	if err != errTerminated {
+		_ = s.event("exited", struct {
+			ExitCode int `json:"exitCode"`
+		}{exitCode})
+	}
+	_ = s.event("terminated", nil)
+}
+
+// stmt is the statement hook of the program. It stops the program on
+// breakpoints, pause requests and when a step is completed.
+func (s *server) stmt(stmt ast.Stmt) error {
+	if atomic.LoadInt32(&s.terminated) != 0 {
+		return errTerminated
+	}
+	if s.noDebug {
+		return nil
+	}
+	pos := stmt.Pos()
+	depth, line := len(s.m.Frames()), location{pos.Filename, pos.Line}
+	newLine := line != s.prev || depth != s.prevDepth
+	s.prev, s.prevDepth = line, depth
+
+	var reason string
+	switch s.mode {
+	case modeStepIn:
+		if depth != s.depth || line != s.line {
+			reason = "step"
+		}
+	case modeNext:
+		if depth < s.depth || depth == s.depth && line != s.line {
+			reason = "step"
+		}
+	case modeStepOut:
+		if depth < s.depth {
+			reason = "step"
+		}
+	}
+	if s.stopOnEntry {
+		s.stopOnEntry = false
+		reason = "entry"
+	}
+	if atomic.SwapInt32(&s.pause, 0) != 0 {
+		reason = "pause"
+	}
+	s.mu.Lock()
+	if newLine && s.breakpoints[line] {
+		reason = "breakpoint"
+	}
+	s.mu.Unlock()
+	if reason == "" {
+		return nil
+	}
+	return s.stop(reason, "")
+}
+
+// stop stops the program and blocks until it is resumed.
+func (s *server) stop(reason, text string) error {
+	_ = s.m.Flush()
+	// The terminated flag is set before terminate checks whether the
+	// program is stopped, so either it is seen here or terminate resumes
+	// the program.
+	s.mu.Lock()
+	if atomic.LoadInt32(&s.terminated) != 0 {
+		s.mu.Unlock()
+		return errTerminated
+	}
+	s.stopped, s.refs = true, nil
+	s.mu.Unlock()
+	_ = s.event("stopped", struct {
+		Reason            string `json:"reason"`
+		Text              string `json:"text,omitempty"`
+		ThreadID          int    `json:"threadId"`
+		AllThreadsStopped bool   `json:"allThreadsStopped"`
+	}{reason, text, threadID, true})
+
+	<-s.resume
+	if atomic.LoadInt32(&s.terminated) != 0 {
+		return errTerminated
+	}
+	return nil
+}
+
+// continueAs resumes the stopped program in the given mode once the response
+// is sent.
+func (s *server) continueAs(mode mode) error {
+	s.mu.Lock()
+	stopped := s.stopped
+	s.stopped = false
+	s.mu.Unlock()
+	if !stopped {
+		return errors.New("program is not stopped")
+	}
+
+	if frames := s.m.Frames(); len(frames) > 0 {
+		pos := frames[len(frames)-1].Pos
+		s.depth, s.line = len(frames), location{pos.Filename, pos.Line}
+	}
+	s.mode, s.resuming = mode, true
+	return nil
+}
+
+// terminate terminates the program and waits until it has stopped.
+func (s *server) terminate() {
+	if !atomic.CompareAndSwapInt32(&s.terminated, 0, 1) {
+		return
+	}
+	s.input.Close()
+	if !s.started {
+		return
+	}
+	s.mu.Lock()
+	stopped := s.stopped
+	s.stopped = false
+	s.mu.Unlock()
+	if stopped {
+		s.resume <- struct{}{}
+	}
+	<-s.done
+}
+
+// -----------------------------------------------------------------------------
+// Breakpoints
+
+// setBreakpoints replaces the breakpoints of a source file of the program,
+// which is the launched file or an imported module. Breakpoints on lines
+// without a statement are moved to the next line with one.
+func (s *server) setBreakpoints(args setBreakpointsArguments) (interface{}, error) {
+	path, err := filepath.Abs(args.Source.Path)
+	if err != nil {
+		return nil, err
+	}
+	last := 0
+	for l := range s.stmts {
+		if l.path == path && l.line > last {
+			last = l.line
+		}
+	}
+
+	var lines []location
+	res := make([]breakpoint, len(args.Breakpoints))
+	for i, b := range args.Breakpoints {
+		l := location{path, b.Line - s.lineBase + 1}
+		for ; l.line <= last && !s.stmts[l]; l.line++ {
+		}
+		if !s.stmts[l] {
+			res[i] = breakpoint{Message: "no statement at or after this line"}
+			continue
+		}
+		lines = append(lines, l)
+		res[i] = breakpoint{
+			Verified: true,
+			Source:   &source{Name: filepath.Base(path), Path: path},
+			Line:     l.line + s.lineBase - 1,
+		}
+	}
+	s.mu.Lock()
+	for l := range s.breakpoints {
+		if l.path == path {
+			delete(s.breakpoints, l)
+		}
+	}
+	for _, l := range lines {
+		s.breakpoints[l] = true
+	}
+	s.mu.Unlock()
+	return struct {
+		Breakpoints []breakpoint `json:"breakpoints"`
+	}{res}, nil
+}
+
+// -----------------------------------------------------------------------------
+// Program state
+
+// frames returns the frames of the stopped program, the innermost one first.
+func (s *server) frames() ([]*interp.Frame, error) {
+	s.mu.Lock()
+	stopped := s.stopped
+	s.mu.Unlock()
+	if !stopped {
+		return nil, errors.New("program is not stopped")
+	}
+	frames := s.m.Frames()
+	res := make([]*interp.Frame, len(frames))
+	for i, f := range frames {
+		res[len(frames)-1-i] = f
+	}
+	return res, nil
+}
+
+// frame returns the frame with the given ID, the innermost one if the ID is 0.
+func (s *server) frame(id int) (*interp.Frame, error) {
+	frames, err := s.frames()
+	if err != nil {
+		return nil, err
+	}
+	if id == 0 {
+		id = 1
+	}
+	if id < 1 || id > len(frames) {
+		return nil, fmt.Errorf("invalid frame %d", id)
+	}
+	return frames[id-1], nil
+}
+
+func (s *server) stackTrace() (interface{}, error) {
+	frames, err := s.frames()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]stackFrame, len(frames))
+	for i, f := range frames {
+		res[i] = stackFrame{
+			ID:     i + 1,
+			Name:   f.Proc.Name(),
+			Source: &source{Name: filepath.Base(f.Pos.Filename), Path: f.Pos.Filename},
+		}
+		res[i].Line, res[i].Column = s.position(f.Pos)
+	}
+	return struct {
+		StackFrames []stackFrame `json:"stackFrames"`
+		TotalFrames int          `json:"totalFrames"`
+	}{res, len(res)}, nil
+}
+
+// position maps a source position to the line and column of the client.
+func (s *server) position(pos token.Position) (int, int) {
+	return pos.Line + s.lineBase - 1, pos.Column + s.columnBase - 1
+}
+
+func (s *server) scopes(args frameArguments) (interface{}, error) {
+	f, err := s.frame(args.FrameID)
+	if err != nil {
+		return nil, err
+	}
+	params, locals := f.Proc.Params(), f.Proc.Locals()
+	return struct {
+		Scopes []scope `json:"scopes"`
+	}{[]scope{
+		{Name: "Parameters", PresentationHint: "arguments", VariablesReference: s.ref(container{frame: f, vars: params}), NamedVariables: len(params)},
+		{Name: "Locals", PresentationHint: "locals", VariablesReference: s.ref(container{frame: f, vars: locals}), NamedVariables: len(locals)},
+	}}, nil
+}
+
+func (s *server) variables(args frameArguments) (interface{}, error) {
+	if _, err := s.frames(); err != nil {
+		return nil, err
+	}
+	if args.VariablesReference < 1 || args.VariablesReference > len(s.refs) {
+		return nil, fmt.Errorf("invalid variables reference %d", args.VariablesReference)
+	}
+	c := s.refs[args.VariablesReference-1]
+
+	res := []variable{}
+	if c.typ == nil {
+		for _, v := range c.vars {
+			res = append(res, s.variable(v.Name(), v.Type(), c.frame.Value(v)))
+		}
+	} else {
+		n := int(types.Sizeof(c.typ.Elem()) / 4)
+		end := int(c.typ.Len())
+		if args.Count > 0 && args.Start+args.Count < end {
+			end = args.Start + args.Count
+		}
+		for i := args.Start; i < end; i++ {
+			res = append(res, s.variable(fmt.Sprintf("[%d]", i), c.typ.Elem(), c.mem[i*n:(i+1)*n]))
+		}
+	}
+	return struct {
+		Variables []variable `json:"variables"`
+	}{res}, nil
+}
+
+// variable describes a value to the client. Arrays are expandable.
+func (s *server) variable(name string, typ types.Type, mem []int32) variable {
+	v := variable{Name: name, Value: interp.FormatValue(typ, mem), Type: typ.String()}
+	if a, ok := typ.(*types.Array); ok {
+		v.VariablesReference = s.ref(container{typ: a, mem: mem})
+		v.IndexedVariables = int(a.Len())
+	}
+	return v
+}
+
+// ref returns a reference to the container which is valid until the program
+// is resumed.
+func (s *server) ref(c container) int {
+	s.refs = append(s.refs, c)
+	return len(s.refs)
+}
+
+// evaluate evaluates an expression in a frame of the stopped program. While
+// the program runs, expressions entered in the debug console are sent as a
+// line of input to the program.
+func (s *server) evaluate(args frameArguments) (interface{}, error) {
+	s.mu.Lock()
+	stopped := s.stopped
+	s.mu.Unlock()
+
+	type result struct {
+		Result             string `json:"result"`
+		Type               string `json:"type,omitempty"`
+		VariablesReference int    `json:"variablesReference"`
+		IndexedVariables   int    `json:"indexedVariables,omitempty"`
+	}
+	if !stopped {
+		if args.Context != "repl" {
+			return nil, errors.New("program is not stopped")
+		}
+		s.input.Write([]byte(args.Expression + "\n"))
+		return result{}, nil
+	}
+
+	f, err := s.frame(args.FrameID)
+	if err != nil {
+		return nil, err
+	}
+	typ, mem, err := f.Eval(args.Expression)
+	if err != nil {
+		return nil, err
+	}
+	v := s.variable(args.Expression, typ, mem)
+	return result{v.Value, v.Type, v.VariablesReference, v.IndexedVariables}, nil
+}
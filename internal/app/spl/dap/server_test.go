@@ -0,0 +1,278 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/ext"
+	"github.com/lukasmalkmus/spl/internal/app/spl/internal/testutil"
+	"github.com/lukasmalkmus/spl/internal/app/spl/loader"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+const src = `type A = array [2] of int;
+
+proc set(ref a: A, i: int) {
+  a[i] := i + 1;
+}
+
+proc main() {
+  var a: A;
+  var n: int;
+  readi(n);
+  set(a, 0);
+  set(a, 1);
+  printi(a[1] * n);
+  set(a, n);
+}
+`
+
+func TestServe(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "test.spl")
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(t)
+	defer c.close()
+
+	c.request("initialize", map[string]interface{}{"adapterID": "spl"})
+	c.request("launch", map[string]interface{}{"program": path})
+	c.wait("initialized")
+	body := c.request("setBreakpoints", map[string]interface{}{
+		"source":      map[string]interface{}{"path": path},
+		"breakpoints": []map[string]interface{}{{"line": 3}, {"line": 12}},
+	})
+	if got, want := jsonString(body["breakpoints"]), `[{"line":4,"source":{"name":"test.spl","path":"`+path+`"},"verified":true},{"line":12,"source":{"name":"test.spl","path":"`+path+`"},"verified":true}]`; got != want {
+		t.Errorf("got breakpoints %s, want %s", got, want)
+	}
+	c.request("configurationDone", nil)
+
+	// The program waits for input before it hits the first breakpoint.
+	c.request("evaluate", map[string]interface{}{"expression": "21", "context": "repl"})
+	c.wait("stopped")
+	body = c.request("stackTrace", map[string]interface{}{"threadId": 1})
+	if got, want := jsonString(body["stackFrames"]), `[{"column":3,"id":1,"line":4,"name":"set","source":{"name":"test.spl","path":"`+path+`"}},{"column":3,"id":2,"line":11,"name":"main","source":{"name":"test.spl","path":"`+path+`"}}]`; got != want {
+		t.Errorf("got stack frames %s, want %s", got, want)
+	}
+
+	body = c.request("scopes", map[string]interface{}{"frameId": 2})
+	scopes := body["scopes"].([]interface{})
+	if len(scopes) != 2 {
+		t.Fatalf("got %d scopes, want 2", len(scopes))
+	}
+	ref := scopes[1].(map[string]interface{})["variablesReference"]
+	body = c.request("variables", map[string]interface{}{"variablesReference": ref})
+	if got, want := jsonString(body["variables"]), `[{"indexedVariables":2,"name":"a","type":"array [2] of int","value":"{0, 0}","variablesReference":3},{"name":"n","type":"int","value":"21","variablesReference":0}]`; got != want {
+		t.Errorf("got variables %s, want %s", got, want)
+	}
+
+	c.request("next", map[string]interface{}{"threadId": 1})
+	c.wait("stopped")
+	body = c.request("variables", map[string]interface{}{"variablesReference": 3})
+	if len(body) != 0 {
+		t.Errorf("got variables %v of a reference which is no longer valid", body)
+	}
+	body = c.request("evaluate", map[string]interface{}{"expression": "a", "frameId": 1, "context": "hover"})
+	ref = body["variablesReference"]
+	body = c.request("variables", map[string]interface{}{"variablesReference": ref, "start": 1, "count": 1})
+	if got, want := jsonString(body["variables"]), `[{"name":"[1]","type":"int","value":"0","variablesReference":0}]`; got != want {
+		t.Errorf("got variables %s, want %s", got, want)
+	}
+
+	c.request("continue", map[string]interface{}{"threadId": 1})
+	if got, want := c.wait("stopped")["reason"], "breakpoint"; got != want {
+		t.Errorf("got stop reason %v, want %v", got, want)
+	}
+	c.request("setBreakpoints", map[string]interface{}{"source": map[string]interface{}{"path": path}})
+	c.request("continue", map[string]interface{}{"threadId": 1})
+	if got, want := c.wait("stopped")["reason"], "exception"; got != want {
+		t.Errorf("got stop reason %v, want %v", got, want)
+	}
+	c.request("continue", map[string]interface{}{"threadId": 1})
+	if got, want := c.wait("exited")["exitCode"], 1.0; got != want {
+		t.Errorf("got exit code %v, want %v", got, want)
+	}
+	c.wait("terminated")
+	c.request("disconnect", nil)
+
+	want := "42" + path + ":4: runtime error: index out of range\n"
+	if got := c.output.String(); got != want {
+		t.Errorf("got output %q, want %q", got, want)
+	}
+}
+
+func TestServe_Module(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	files := map[string]string{
+		"main.spl": "import \"lib.spl\" as lib;\nproc main() {\n  var i: int;\n  lib.inc(i);\n  printi(i);\n}\n",
+		"lib.spl":  "proc inc(ref i: int) {\n  i := i + 1;\n  i := i + 2;\n  i := i + 3;\n}\n",
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path, lib := filepath.Join(dir, "main.spl"), filepath.Join(dir, "lib.spl")
+	c := newClient(t)
+	defer c.close()
+
+	c.request("initialize", map[string]interface{}{"adapterID": "spl"})
+	c.request("launch", map[string]interface{}{"program": path})
+	c.wait("initialized")
+	// Line 4 of both files holds a statement, the breakpoint is only hit in
+	// the module. Setting the breakpoints of main.spl keeps the ones of the
+	// module.
+	for _, b := range []struct {
+		path string
+		line int
+	}{{lib, 4}, {path, 5}} {
+		body := c.request("setBreakpoints", map[string]interface{}{
+			"source":      map[string]interface{}{"path": b.path},
+			"breakpoints": []map[string]interface{}{{"line": b.line}},
+		})
+		if got, want := jsonString(body["breakpoints"]), fmt.Sprintf(`[{"line":%d,"source":{"name":"%s","path":"%s"},"verified":true}]`, b.line, filepath.Base(b.path), b.path); got != want {
+			t.Errorf("got breakpoints %s, want %s", got, want)
+		}
+	}
+	c.request("configurationDone", nil)
+
+	for _, want := range []string{
+		`{"column":3,"id":1,"line":4,"name":"inc","source":{"name":"lib.spl","path":"` + lib + `"}}`,
+		`{"column":3,"id":1,"line":5,"name":"main","source":{"name":"main.spl","path":"` + path + `"}}`,
+	} {
+		if got := c.wait("stopped")["reason"]; got != "breakpoint" {
+			t.Errorf("got stop reason %v, want breakpoint", got)
+		}
+		body := c.request("stackTrace", map[string]interface{}{"threadId": 1})
+		if got := jsonString(body["stackFrames"].([]interface{})[0]); got != want {
+			t.Errorf("got stack frame %s, want %s", got, want)
+		}
+		c.request("continue", map[string]interface{}{"threadId": 1})
+	}
+	if got, want := c.wait("exited")["exitCode"], 0.0; got != want {
+		t.Errorf("got exit code %v, want %v", got, want)
+	}
+	c.request("disconnect", nil)
+	if got, want := c.output.String(), "6"; got != want {
+		t.Errorf("got output %q, want %q", got, want)
+	}
+}
+
+func TestServe_LaunchError(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	c.request("initialize", nil)
+	c.send("launch", map[string]interface{}{"program": "missing.spl"})
+	msg := c.next()
+	if msg["success"] != false || !strings.Contains(msg["message"].(string), "missing.spl") {
+		t.Errorf("got response %v, want error", msg)
+	}
+	c.request("disconnect", nil)
+}
+
+// client is a client of a server running in the background.
+type client struct {
+	tb     testing.TB
+	conn   net.Conn
+	r      *bufio.Reader
+	seq    int
+	output strings.Builder
+	done   chan error
+}
+
+func newClient(tb testing.TB) *client {
+	server, conn := net.Pipe()
+	c := &client{tb: tb, conn: conn, r: bufio.NewReader(conn), done: make(chan error)}
+	go func() { c.done <- Serve(server, load) }()
+	return c
+}
+
+// close closes the connection and waits for the server to return.
+func (c *client) close() {
+	_ = c.conn.Close()
+	if err := <-c.done; err != nil {
+		c.tb.Error(err)
+	}
+}
+
+// send sends a request.
+func (c *client) send(command string, args interface{}) {
+	c.tb.Helper()
+	c.seq++
+	msg := map[string]interface{}{"seq": c.seq, "type": "request", "command": command}
+	if args != nil {
+		msg["arguments"] = args
+	}
+	if err := (&conn{w: c.conn}).write(msg); err != nil {
+		c.tb.Fatal(err)
+	}
+}
+
+// request sends a request and returns the body of the successful response.
+func (c *client) request(command string, args interface{}) map[string]interface{} {
+	c.tb.Helper()
+	c.send(command, args)
+	for {
+		msg := c.next()
+		if msg["type"] != "response" {
+			continue
+		}
+		if msg["request_seq"] != float64(c.seq) || msg["command"] != command {
+			c.tb.Fatalf("got response %v, want response to %s", msg, command)
+		}
+		if msg["success"] != true && command != "variables" {
+			c.tb.Fatalf("%s failed: %v", command, msg["message"])
+		}
+		body, _ := msg["body"].(map[string]interface{})
+		return body
+	}
+}
+
+// wait waits for an event and returns its body.
+func (c *client) wait(name string) map[string]interface{} {
+	c.tb.Helper()
+	for {
+		msg := c.next()
+		if msg["type"] == "event" && msg["event"] == name {
+			body, _ := msg["body"].(map[string]interface{})
+			return body
+		}
+	}
+}
+
+// next reads the next message. Output events are recorded.
+func (c *client) next() map[string]interface{} {
+	c.tb.Helper()
+	req, err := (&conn{r: c.r}).readRaw()
+	if err != nil {
+		c.tb.Fatal(err)
+	}
+	var msg map[string]interface{}
+	if err := json.Unmarshal(req, &msg); err != nil {
+		c.tb.Fatal(err)
+	}
+	if msg["event"] == "output" {
+		c.output.WriteString(msg["body"].(map[string]interface{})["output"].(string))
+	}
+	return msg
+}
+
+func load(path string) (*ast.Program, *types.Info, error) {
+	conf := &loader.Config{Extensions: ext.Imports}
+	return conf.Load(path)
+}
+
+func jsonString(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
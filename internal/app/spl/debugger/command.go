@@ -0,0 +1,369 @@
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/interp"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+// command is a debugger command.
+type command struct {
+	names []string
+	usage string
+	help  string
+	// run executes the command with the given argument. It reports whether
+	// the execution of the program is resumed.
+	run func(d *Debugger, arg string) (bool, error)
+}
+
+// commands are the commands understood by the debugger. A command can be
+// abbreviated by any of its names or by an unambiguous prefix.
+var commands []command
+
+func init() {
+	commands = []command{
+		{[]string{"break", "b"}, "break [file:]line | break proc", "Set a breakpoint on a line or at the beginning of a procedure.", (*Debugger).cmdBreak},
+		{[]string{"watch"}, "watch expr", "Set a watchpoint on an expression, evaluated in the selected frame.", (*Debugger).cmdWatch},
+		{[]string{"delete", "d"}, "delete [n...]", "Delete the given breakpoints or watchpoints, or all of them.", (*Debugger).cmdDelete},
+		{[]string{"run", "r"}, "run", "Start the program.", (*Debugger).cmdRun},
+		{[]string{"continue", "c"}, "continue", "Continue the program until a breakpoint or watchpoint is hit.", cmdResume(modeContinue)},
+		{[]string{"step", "s"}, "step", "Continue to the next line, entering procedure calls.", cmdResume(modeStep)},
+		{[]string{"next", "n"}, "next", "Continue to the next line of the current procedure.", cmdResume(modeNext)},
+		{[]string{"finish"}, "finish", "Continue until the selected procedure returns.", (*Debugger).cmdFinish},
+		{[]string{"print", "p"}, "print expr", "Print the value of an expression, evaluated in the selected frame.", (*Debugger).cmdPrint},
+		{[]string{"backtrace", "bt"}, "backtrace", "Print the procedure activations, the innermost one first.", (*Debugger).cmdBacktrace},
+		{[]string{"frame", "f"}, "frame [n]", "Select and print a frame.", (*Debugger).cmdFrame},
+		{[]string{"up"}, "up", "Select the frame of the caller.", cmdMove(1)},
+		{[]string{"down"}, "down", "Select the frame of the callee.", cmdMove(-1)},
+		{[]string{"list", "l"}, "list [file:]line | list proc", "List source lines around a line or procedure.", (*Debugger).cmdList},
+		{[]string{"info", "i"}, "info breakpoints | locals", "Print the breakpoints or the variables of the selected frame.", (*Debugger).cmdInfo},
+		{[]string{"help", "h"}, "help", "Print this help.", (*Debugger).cmdHelp},
+		{[]string{"quit", "q"}, "quit", "End the debugging session.", func(*Debugger, string) (bool, error) { return false, errQuit }},
+	}
+}
+
+// exec executes a command line.
+func (d *Debugger) exec(line string) (bool, error) {
+	fields := strings.SplitN(line, " ", 2)
+	name, arg := fields[0], ""
+	if len(fields) > 1 {
+		arg = strings.TrimSpace(fields[1])
+	}
+
+	var matches []command
+	for _, c := range commands {
+		for _, n := range c.names {
+			if n == name {
+				return c.run(d, arg)
+			}
+		}
+		if strings.HasPrefix(c.names[0], name) {
+			matches = append(matches, c)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return false, fmt.Errorf("Undefined command: %q. Try \"help\".", name)
+	case 1:
+		return matches[0].run(d, arg)
+	}
+	var names []string
+	for _, c := range matches {
+		names = append(names, c.names[0])
+	}
+	return false, fmt.Errorf("Ambiguous command %q: %s.", name, strings.Join(names, ", "))
+}
+
+var (
+	errNotRunning = errors.New("The program is not being run.")
+	errNoStack    = errors.New("No stack.")
+)
+
+func (d *Debugger) cmdBreak(arg string) (bool, error) {
+	if arg == "" {
+		return false, errors.New("Argument required (line or procedure).")
+	}
+	b := &breakpoint{id: d.nextID}
+
+	filename, spec, err := d.splitFile(arg)
+	if err != nil {
+		return false, err
+	}
+	if n, err := strconv.Atoi(spec); err == nil {
+		lines := d.lines(filename)
+		for ; n <= len(lines) && !d.stmts[location{filename, n}]; n++ {
+		}
+		if n < 1 || n > len(lines) {
+			return false, fmt.Errorf("No line %s in file %q.", spec, filename)
+		}
+		b.line = location{filename, n}
+		b.pos = token.Position{Filename: filename, Line: n, Column: 1}
+	} else if p := d.proc(spec); p != nil {
+		b.proc = p
+		b.pos = p.Decl().Pos()
+		if body := p.Decl().Body; body != nil && len(body.List) > 0 {
+			b.pos = body.List[0].Pos()
+		}
+	} else {
+		return false, fmt.Errorf("Procedure %q not defined.", spec)
+	}
+
+	d.nextID++
+	d.breakpoints = append(d.breakpoints, b)
+	fmt.Fprintf(d.out, "Breakpoint %d at %s:%d\n", b.id, b.pos.Filename, b.pos.Line)
+	return false, nil
+}
+
+// splitFile splits an argument of the form [file:]spec. The file is given by
+// its name or base name and defaults to the file listed last.
+func (d *Debugger) splitFile(arg string) (filename, spec string, err error) {
+	i := strings.LastIndex(arg, ":")
+	if i < 0 {
+		return d.listFile, arg, nil
+	}
+	for _, f := range d.fset.Files() {
+		if name := f.Name(); arg[:i] == name || arg[:i] == filepath.Base(name) {
+			return name, arg[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("No source file named %s.", arg[:i])
+}
+
+// proc returns the declared procedure with the given name, or nil.
+func (d *Debugger) proc(name string) *types.Proc {
+	for _, p := range d.info.Procs {
+		if p.Name() == name {
+			return p
+		}
+	}
+	return nil
+}
+
+func (d *Debugger) cmdWatch(arg string) (bool, error) {
+	if !d.running {
+		return false, errNotRunning
+	}
+	f := d.frames()[d.frame]
+	typ, mem, err := f.Eval(arg)
+	if err != nil {
+		return false, err
+	}
+	b := &breakpoint{id: d.nextID, expr: arg, frame: f, value: interp.FormatValue(typ, mem)}
+	d.nextID++
+	d.breakpoints = append(d.breakpoints, b)
+	fmt.Fprintf(d.out, "Watchpoint %d: %s\n", b.id, b.expr)
+	return false, nil
+}
+
+func (d *Debugger) cmdDelete(arg string) (bool, error) {
+	if arg == "" {
+		d.breakpoints = nil
+		return false, nil
+	}
+	for _, s := range strings.Fields(arg) {
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			return false, fmt.Errorf("Convenience variable must have integer value: %s", s)
+		}
+		found := false
+		for i, b := range d.breakpoints {
+			if b.id == id {
+				d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, fmt.Errorf("No breakpoint number %d.", id)
+		}
+	}
+	return false, nil
+}
+
+func (d *Debugger) cmdRun(string) (bool, error) {
+	if d.running {
+		return false, errors.New("The program is already running.")
+	}
+	return true, nil
+}
+
+// cmdResume returns a command which resumes the program in the given mode.
+func cmdResume(mode mode) func(d *Debugger, arg string) (bool, error) {
+	return func(d *Debugger, arg string) (bool, error) {
+		if !d.running {
+			return false, errNotRunning
+		}
+		d.resume(mode)
+		return true, nil
+	}
+}
+
+func (d *Debugger) cmdFinish(string) (bool, error) {
+	if !d.running {
+		return false, errNotRunning
+	}
+	if d.frame == len(d.m.Frames())-1 {
+		return false, errors.New(`"finish" not meaningful in the outermost frame.`)
+	}
+	fmt.Fprintf(d.out, "Run till exit from #%d  %s\n", d.frame, d.describe(d.frame))
+	d.resume(modeFinish)
+	return true, nil
+}
+
+func (d *Debugger) cmdPrint(arg string) (bool, error) {
+	if len(d.m.Frames()) == 0 {
+		return false, errNoStack
+	}
+	typ, mem, err := d.frames()[d.frame].Eval(arg)
+	if err != nil {
+		return false, err
+	}
+	fmt.Fprintf(d.out, "%s = %s\n", arg, interp.FormatValue(typ, mem))
+	return false, nil
+}
+
+func (d *Debugger) cmdBacktrace(string) (bool, error) {
+	frames := d.frames()
+	if len(frames) == 0 {
+		return false, errNoStack
+	}
+	for i, f := range frames {
+		fmt.Fprintf(d.out, "#%-2d %s at %s:%d\n", i, d.describe(i), f.Pos.Filename, f.Pos.Line)
+	}
+	return false, nil
+}
+
+func (d *Debugger) cmdFrame(arg string) (bool, error) {
+	frames := d.frames()
+	if len(frames) == 0 {
+		return false, errNoStack
+	}
+	if arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 || n >= len(frames) {
+			return false, fmt.Errorf("No frame at level %s.", arg)
+		}
+		d.frame = n
+	}
+	d.printSelected()
+	return false, nil
+}
+
+// cmdMove returns a command which selects the frame delta levels up.
+func cmdMove(delta int) func(d *Debugger, arg string) (bool, error) {
+	return func(d *Debugger, arg string) (bool, error) {
+		frames := d.frames()
+		if len(frames) == 0 {
+			return false, errNoStack
+		}
+		n := d.frame + delta
+		switch {
+		case n < 0:
+			return false, errors.New("Bottom (innermost) frame selected; you cannot go down.")
+		case n >= len(frames):
+			return false, errors.New("Initial frame selected; you cannot go up.")
+		}
+		d.frame = n
+		d.printSelected()
+		return false, nil
+	}
+}
+
+// printSelected prints the selected frame with its level.
+func (d *Debugger) printSelected() {
+	fmt.Fprintf(d.out, "#%-2d ", d.frame)
+	d.printFrame(true)
+}
+
+func (d *Debugger) cmdList(arg string) (bool, error) {
+	filename, first := d.listFile, d.listNext
+	if arg != "" {
+		var spec string
+		var err error
+		if filename, spec, err = d.splitFile(arg); err != nil {
+			return false, err
+		}
+		n, err := strconv.Atoi(spec)
+		if err != nil {
+			p := d.proc(spec)
+			if p == nil {
+				return false, fmt.Errorf("Procedure %q not defined.", spec)
+			}
+			pos := p.Decl().Pos()
+			filename, n = pos.Filename, pos.Line
+		}
+		first = n - 5
+	}
+	lines := d.lines(filename)
+	if first < 1 {
+		first = 1
+	}
+	if first > len(lines) {
+		return false, fmt.Errorf("Line number %d out of range; %q has %d lines.", first, filename, len(lines))
+	}
+	last := first + 10
+	if last > len(lines)+1 {
+		last = len(lines) + 1
+	}
+	for n := first; n < last; n++ {
+		d.printLine(filename, n)
+	}
+	d.listFile, d.listNext = filename, last
+	return false, nil
+}
+
+func (d *Debugger) cmdInfo(arg string) (bool, error) {
+	switch {
+	case arg != "" && strings.HasPrefix("breakpoints", arg):
+		if len(d.breakpoints) == 0 {
+			fmt.Fprintln(d.out, "No breakpoints or watchpoints.")
+			return false, nil
+		}
+		fmt.Fprintln(d.out, "Num     Type           What")
+		for _, b := range d.breakpoints {
+			switch {
+			case b.frame != nil:
+				fmt.Fprintf(d.out, "%-7d watchpoint     %s\n", b.id, b.expr)
+			case b.proc != nil:
+				fmt.Fprintf(d.out, "%-7d breakpoint     in %s at %s:%d\n", b.id, b.proc.Name(), b.pos.Filename, b.pos.Line)
+			default:
+				fmt.Fprintf(d.out, "%-7d breakpoint     %s:%d\n", b.id, b.pos.Filename, b.pos.Line)
+			}
+		}
+	case arg != "" && strings.HasPrefix("locals", arg):
+		frames := d.frames()
+		if len(frames) == 0 {
+			return false, errNoStack
+		}
+		f := frames[d.frame]
+		if len(f.Vars()) == 0 {
+			fmt.Fprintln(d.out, "No locals.")
+		}
+		for _, v := range f.Vars() {
+			fmt.Fprintf(d.out, "%s = %s\n", v.Name(), interp.FormatValue(v.Type(), f.Value(v)))
+		}
+	default:
+		return false, errors.New(`"info" must be followed by "breakpoints" or "locals".`)
+	}
+	return false, nil
+}
+
+func (d *Debugger) cmdHelp(string) (bool, error) {
+	var lines []string
+	for _, c := range commands {
+		lines = append(lines, fmt.Sprintf("  %-28s %s", c.usage, c.help))
+	}
+	sort.Strings(lines)
+	fmt.Fprintln(d.out, "Commands (an empty line repeats the last command):")
+	for _, l := range lines {
+		fmt.Fprintln(d.out, l)
+	}
+	return false, nil
+}
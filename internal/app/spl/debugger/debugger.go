@@ -0,0 +1,384 @@
+package debugger
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/interp"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+// Prompt is printed when the debugger reads a command.
+const Prompt = "(spl) "
+
+// errQuit is returned by a command which ends the debugging session.
+var errQuit = errors.New("quit")
+
+// mode describes when a resumed program is stopped again.
+type mode int
+
+const (
+	modeContinue mode = iota // stop on breakpoints and watchpoints only
+	modeStep                 // stop on the next line, entering procedures
+	modeNext                 // stop on the next line of the same procedure
+	modeFinish               // stop after the procedure returned
+)
+
+// location is a line of a source file.
+type location struct {
+	filename string
+	line     int
+}
+
+// breakpoint is a breakpoint on a line or procedure, or a watchpoint on an
+// expression.
+type breakpoint struct {
+	id int
+
+	// line and proc are set for breakpoints on a line respectively a
+	// procedure. pos is the position the breakpoint was set at.
+	line location
+	proc *types.Proc
+	pos  token.Position
+
+	// expr, frame and value are set for watchpoints. The expression is
+	// evaluated in the frame, value is its last formatted value.
+	expr  string
+	frame *interp.Frame
+	value string
+}
+
+// Debugger is an interactive debugger for a type checked program. Program
+// input and debugger commands are read from the same reader, program output
+// and debugger output are written to the same writer.
+type Debugger struct {
+	prog  *ast.Program
+	info  *types.Info
+	m     *interp.Machine
+	fset  *token.FileSet
+	files map[string][]string // source lines by file name
+	stmts map[location]bool
+	in    *bufio.Reader
+	out   io.Writer
+
+	breakpoints []*breakpoint
+	nextID      int
+
+	running bool
+	mode    mode
+	depth   int // depth and line at the time the program was resumed
+	line    location
+	prev    token.Position // position and depth of the previous statement
+	prevN   int
+	entered *breakpoint // procedure breakpoint hit by the last call
+	watched []string    // pending watchpoint reports
+
+	frame    int    // selected frame, 0 is the innermost one
+	last     string // last command, repeated on empty input
+	listFile string // file listed last, the default file of line numbers
+	listNext int    // first line printed by a list command without argument
+}
+
+// New returns a debugger for the type checked program. The source code of its
+// files, including imported modules, is read from the file set for listings.
+func New(prog *ast.Program, info *types.Info, fset *token.FileSet, in io.Reader, out io.Writer) (*Debugger, error) {
+	d := &Debugger{
+		prog:     prog,
+		info:     info,
+		fset:     fset,
+		files:    make(map[string][]string),
+		stmts:    make(map[location]bool),
+		in:       bufio.NewReader(in),
+		out:      out,
+		nextID:   1,
+		listFile: prog.Name,
+	}
+	m, err := interp.New(prog, info, d.in, out)
+	if err != nil {
+		return nil, err
+	}
+	m.Hooks = interp.Hooks{Stmt: d.stmt, Call: d.call, Return: d.ret}
+	d.m = m
+
+	// The procedures include the ones of imported modules.
+	for _, p := range info.Procs {
+		ast.Inspect(p.Decl(), func(n ast.Node) bool {
+			switch n.(type) {
+			case *ast.ExprStmt, *ast.AssignStmt, *ast.IfStmt, *ast.WhileStmt, *ast.ReturnStmt:
+				pos := n.Pos()
+				d.stmts[location{pos.Filename, pos.Line}] = true
+			}
+			return true
+		})
+	}
+	return d, nil
+}
+
+// Run reads and executes commands until the session is ended by the quit
+// command or the end of the input.
+func (d *Debugger) Run() error {
+	for {
+		if err := d.prompt(); err != nil {
+			if err == errQuit {
+				return nil
+			}
+			return err
+		}
+		if err := d.start(); err != nil {
+			if err == errQuit {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// prompt reads and executes commands until the program is started or resumed.
+func (d *Debugger) prompt() error {
+	for {
+		fmt.Fprint(d.out, Prompt)
+		line, err := d.in.ReadString('\n')
+		if err != nil && line == "" {
+			if err == io.EOF {
+				fmt.Fprintln(d.out)
+				return errQuit
+			}
+			return err
+		}
+		if line = strings.TrimSpace(line); line == "" {
+			line = d.last
+		}
+		d.last = line
+		if line == "" {
+			continue
+		}
+
+		resume, err := d.exec(line)
+		if err == errQuit {
+			return err
+		} else if err != nil {
+			fmt.Fprintln(d.out, err)
+		} else if resume {
+			return nil
+		}
+	}
+}
+
+// start runs the program until it terminates and reports how it terminated.
+func (d *Debugger) start() error {
+	d.running, d.mode, d.depth = true, modeContinue, 0
+	d.prev, d.prevN = token.Position{}, 0
+	d.entered, d.watched = nil, nil
+	err := d.m.Run()
+	d.running = false
+	d.deleteWatchpoints(nil)
+
+	switch err.(type) {
+	case nil:
+		fmt.Fprintln(d.out, "[Program exited normally]")
+	case *interp.RuntimeError:
+		fmt.Fprintf(d.out, "\nProgram terminated with %s.\n", err)
+		d.frame = 0
+		d.printFrame(true)
+	default:
+		return err
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+// Hooks
+
+func (d *Debugger) call(f *interp.Frame) error {
+	for _, b := range d.breakpoints {
+		if b.proc == f.Proc {
+			d.entered = b
+		}
+	}
+	return nil
+}
+
+func (d *Debugger) ret(f *interp.Frame) error {
+	d.checkWatchpoints()
+	d.deleteWatchpoints(f)
+	if d.entered != nil && d.entered.proc == f.Proc {
+		d.entered = nil
+	}
+	return nil
+}
+
+func (d *Debugger) stmt(s ast.Stmt) error {
+	depth, pos := len(d.m.Frames()), s.Pos()
+	at := location{pos.Filename, pos.Line}
+	newLine := at != location{d.prev.Filename, d.prev.Line} || depth != d.prevN
+	d.prev, d.prevN = pos, depth
+
+	// The frame is described if the program stops in another procedure
+	// activation than the one it was resumed in.
+	var stop bool
+	showFrame := depth != d.depth
+	switch d.mode {
+	case modeStep:
+		stop = depth != d.depth || at != d.line
+	case modeNext:
+		stop = depth < d.depth || depth == d.depth && at != d.line
+	case modeFinish:
+		stop = depth < d.depth
+	}
+	hit := d.entered
+	d.entered = nil
+	for _, b := range d.breakpoints {
+		if hit == nil && newLine && b.line == at {
+			hit = b
+		}
+	}
+	if hit != nil {
+		d.flush()
+		fmt.Fprintf(d.out, "\nBreakpoint %d, ", hit.id)
+		stop, showFrame = true, true
+	}
+	d.checkWatchpoints()
+	if len(d.watched) > 0 {
+		d.flush()
+		for _, w := range d.watched {
+			fmt.Fprint(d.out, w)
+		}
+		d.watched = d.watched[:0]
+		stop = true
+	}
+	if !stop {
+		return nil
+	}
+
+	d.flush()
+	d.frame = 0
+	d.printFrame(showFrame)
+	return d.prompt()
+}
+
+// resume continues the execution in the given mode.
+func (d *Debugger) resume(mode mode) {
+	frames := d.m.Frames()
+	d.mode = mode
+	d.depth = len(frames)
+	pos := frames[len(frames)-1].Pos
+	d.line = location{pos.Filename, pos.Line}
+	if mode == modeFinish {
+		d.depth -= d.frame
+	}
+}
+
+// flush writes pending program output before the debugger writes to the
+// shared output.
+func (d *Debugger) flush() { _ = d.m.Flush() }
+
+// -----------------------------------------------------------------------------
+// Watchpoints
+
+// checkWatchpoints reevaluates all watchpoints and queues a report for the
+// ones whose value changed.
+func (d *Debugger) checkWatchpoints() {
+	for _, b := range d.breakpoints {
+		if b.frame == nil {
+			continue
+		}
+		v := evalString(b.frame, b.expr)
+		if v != b.value {
+			d.watched = append(d.watched, fmt.Sprintf("\nWatchpoint %d: %s\n\nOld value = %s\nNew value = %s\n", b.id, b.expr, b.value, v))
+			b.value = v
+		}
+	}
+}
+
+// deleteWatchpoints deletes the watchpoints evaluated in the frame, or all of
+// them if the frame is nil.
+func (d *Debugger) deleteWatchpoints(f *interp.Frame) {
+	bps := d.breakpoints[:0]
+	for _, b := range d.breakpoints {
+		if b.frame != nil && (f == nil || b.frame == f) {
+			if f != nil {
+				d.watched = append(d.watched, fmt.Sprintf("\nWatchpoint %d deleted because the program has left the procedure in\nwhich its expression is valid.\n", b.id))
+			}
+			continue
+		}
+		bps = append(bps, b)
+	}
+	d.breakpoints = bps
+}
+
+// -----------------------------------------------------------------------------
+// Frames
+
+// frames returns the frames of the program, the innermost one first.
+func (d *Debugger) frames() []*interp.Frame {
+	frames := d.m.Frames()
+	res := make([]*interp.Frame, len(frames))
+	for i, f := range frames {
+		res[len(frames)-1-i] = f
+	}
+	return res
+}
+
+// printFrame prints the source line of the selected frame, preceded by a
+// description of the frame if full is set.
+func (d *Debugger) printFrame(full bool) {
+	f := d.frames()[d.frame]
+	if full {
+		fmt.Fprintf(d.out, "%s at %s:%d\n", d.describe(d.frame), f.Pos.Filename, f.Pos.Line)
+	}
+	d.printLine(f.Pos.Filename, f.Pos.Line)
+	d.listFile, d.listNext = f.Pos.Filename, f.Pos.Line-4
+}
+
+// describe formats the procedure and arguments of a frame. Value parameters
+// are printed with their value, reference parameters with the argument they
+// refer to.
+func (d *Debugger) describe(i int) string {
+	frames := d.frames()
+	f := frames[i]
+	var args []string
+	for j, p := range f.Proc.Params() {
+		if p.IsRef() {
+			args = append(args, fmt.Sprintf("%s=@%s", p.Name(), types.ExprString(f.Call.Args[j])))
+		} else {
+			args = append(args, fmt.Sprintf("%s=%s", p.Name(), interp.FormatValue(p.Type(), f.Value(p))))
+		}
+	}
+	return fmt.Sprintf("%s (%s)", f.Proc.Name(), strings.Join(args, ", "))
+}
+
+// lines returns the source lines of a file of the program, or nil if the file
+// set doesn't hold it.
+func (d *Debugger) lines(filename string) []string {
+	if lines, ok := d.files[filename]; ok {
+		return lines
+	}
+	var lines []string
+	if f := d.fset.File(filename); f != nil {
+		lines = strings.Split(strings.TrimSuffix(string(f.Source()), "\n"), "\n")
+	}
+	d.files[filename] = lines
+	return lines
+}
+
+// printLine prints the source line of the file with the given number.
+func (d *Debugger) printLine(filename string, n int) {
+	if lines := d.lines(filename); n >= 1 && n <= len(lines) {
+		fmt.Fprintf(d.out, "%d\t%s\n", n, lines[n-1])
+	}
+}
+
+// evalString evaluates an expression in a frame and formats its value or the
+// error.
+func evalString(f *interp.Frame, x string) string {
+	typ, mem, err := f.Eval(x)
+	if err != nil {
+		return "<" + err.Error() + ">"
+	}
+	return interp.FormatValue(typ, mem)
+}
@@ -0,0 +1,191 @@
+package debugger_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/debugger"
+	"github.com/lukasmalkmus/spl/internal/app/spl/ext"
+	"github.com/lukasmalkmus/spl/internal/app/spl/internal/testutil"
+	"github.com/lukasmalkmus/spl/internal/app/spl/loader"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+const src = `type A = array [3] of int;
+
+proc inc(ref i: int, n: int) {
+  i := i + n;
+}
+
+proc main() {
+  var a: A;
+  var k: int;
+  while (k < 3) {
+    inc(a[k], k + 1);
+    k := k + 1;
+  }
+  printi(a[2]);
+  a[k] := 0;
+}
+`
+
+func TestDebugger(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands string
+		output   string
+	}{
+		{
+			"breakpoint",
+			"break inc\nrun\nbacktrace\nprint i\nup\nprint a\nprint a[k + 1]\nprint b\ncontinue\nquit\n",
+			`(spl) Breakpoint 1 at test.spl:4
+(spl) 
+Breakpoint 1, inc (i=@a[k], n=1) at test.spl:4
+4	  i := i + n;
+(spl) #0  inc (i=@a[k], n=1) at test.spl:4
+#1  main () at test.spl:11
+(spl) i = 0
+(spl) #1  main () at test.spl:11
+11	    inc(a[k], k + 1);
+(spl) a = {0, 0, 0}
+(spl) a[k + 1] = 0
+(spl) no symbol "b" in current context
+(spl) 
+Breakpoint 1, inc (i=@a[k], n=2) at test.spl:4
+4	  i := i + n;
+(spl) `,
+		},
+		{
+			"step",
+			"break test.spl:10\nrun\nstep\nstep\nstep\n\nnext\nfinish\n",
+			`(spl) Breakpoint 1 at test.spl:10
+(spl) 
+Breakpoint 1, main () at test.spl:10
+10	  while (k < 3) {
+(spl) 11	    inc(a[k], k + 1);
+(spl) inc (i=@a[k], n=1) at test.spl:4
+4	  i := i + n;
+(spl) main () at test.spl:12
+12	    k := k + 1;
+(spl) 
+Breakpoint 1, main () at test.spl:10
+10	  while (k < 3) {
+(spl) 11	    inc(a[k], k + 1);
+(spl) "finish" not meaningful in the outermost frame.
+(spl) 
+`,
+		},
+		{
+			"watchpoint",
+			"break inc\nrun\ndelete\nwatch i\ncontinue\ncontinue\n",
+			`(spl) Breakpoint 1 at test.spl:4
+(spl) 
+Breakpoint 1, inc (i=@a[k], n=1) at test.spl:4
+4	  i := i + n;
+(spl) (spl) Watchpoint 2: i
+(spl) 
+Watchpoint 2: i
+
+Old value = 0
+New value = 1
+
+Watchpoint 2 deleted because the program has left the procedure in
+which its expression is valid.
+main () at test.spl:12
+12	    k := k + 1;
+(spl) 3
+Program terminated with test.spl:15: runtime error: index out of range.
+main () at test.spl:15
+15	  a[k] := 0;
+(spl) 
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testutil.TempDir(t)
+			defer cleanup()
+			filename := filepath.Join(dir, "test.spl")
+			if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			fset := token.NewFileSet()
+			prog, err := parser.ParseFile(fset, filename, nil, parser.DeclarationErrors)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := types.Check(prog)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			d, err := debugger.New(prog, info, fset, strings.NewReader(tt.commands), &out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := d.Run(); err != nil {
+				t.Fatal(err)
+			}
+			got := strings.ReplaceAll(out.String(), dir+string(filepath.Separator), "")
+			if got != tt.output {
+				t.Errorf("got output\n%s\nwant\n%s", got, tt.output)
+			}
+		})
+	}
+}
+
+func TestDebugger_Module(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	files := map[string]string{
+		"main.spl": "import \"lib.spl\" as lib;\nproc main() {\n  var i: int;\n  lib.inc(i);\n  printi(i);\n}\n",
+		"lib.spl":  "proc inc(ref i: int) {\n  i := i + 1;\n  i := i + 2;\n  i := i + 3;\n}\n",
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conf := &loader.Config{Extensions: ext.Imports}
+	prog, info, err := conf.Load(filepath.Join(dir, "main.spl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Line 4 of both files holds a statement, the breakpoint is only hit in
+	// the module.
+	var out bytes.Buffer
+	commands := "break lib.spl:4\nbreak 5\nrun\nlist\ncontinue\ncontinue\n"
+	d, err := debugger.New(prog, info, conf.Fset, strings.NewReader(commands), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := `(spl) Breakpoint 1 at lib.spl:4
+(spl) Breakpoint 2 at main.spl:5
+(spl) 
+Breakpoint 1, inc (i=@i) at lib.spl:4
+4	  i := i + 3;
+(spl) 1	proc inc(ref i: int) {
+2	  i := i + 1;
+3	  i := i + 2;
+4	  i := i + 3;
+5	}
+(spl) 
+Breakpoint 2, main () at main.spl:5
+5	  printi(i);
+(spl) 6[Program exited normally]
+(spl) 
+`
+	if got := strings.ReplaceAll(out.String(), dir+string(filepath.Separator), ""); got != want {
+		t.Errorf("got output\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,5 @@
+// Package debugger implements an interactive debugger with a gdb like command
+// line interface. It runs a program on the execution engine of package interp
+// and stops it on breakpoints, watchpoints and while stepping through the
+// source code.
+package debugger
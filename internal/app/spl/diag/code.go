@@ -0,0 +1,97 @@
+package diag
+
+// Code is the stable identifier of the rule a diagnostic belongs to. Codes
+// consist of the letter E followed by four digits. The first two digits denote
+// the kind of rule: 00 lexical, 01 syntactic, 02 semantic rules checked by the
+// resolver of the parser and the type checker and 03 the limits of the code
+// generators and the interpreter.
+type Code string
+
+// Codes of the diagnostics.
+const (
+	// Scanner
+	InvalidToken Code = "E0001"
+
+	// Parser
+	SyntaxError       Code = "E0101"
+	MissingComma      Code = "E0102"
+	Redeclared        Code = "E0103"
+	MissingType       Code = "E0104"
+	ExtensionRequired Code = "E0105"
+	NotInSpec         Code = "E0106"
+
+	// Type checker
+	UndeclaredName      Code = "E0201"
+	UsedBeforeDecl      Code = "E0202"
+	InvalidMain         Code = "E0203"
+	NotAType            Code = "E0204"
+	NotAnExpr           Code = "E0205"
+	NotACall            Code = "E0206"
+	NotAProc            Code = "E0207"
+	WrongArgCount       Code = "E0208"
+	MismatchedTypes     Code = "E0209"
+	UnassignableOperand Code = "E0210"
+	InvalidOperator     Code = "E0211"
+	InvalidLiteral      Code = "E0212"
+	NonIndexable        Code = "E0213"
+	InvalidDecl         Code = "E0214"
+	InvalidImport       Code = "E0215"
+	InvalidReturn       Code = "E0216"
+	UnknownField        Code = "E0217"
+
+	// Code generators and interpreter
+	Unsupported Code = "E0301"
+)
+
+// Rule describes the rule identified by a code.
+type Rule struct {
+	Code    Code
+	Name    string
+	Summary string
+}
+
+// rules holds the rules in the order of their codes.
+var rules = []Rule{
+	{InvalidToken, "invalid-token", "The source code contains characters which don't form a valid token."},
+
+	{SyntaxError, "syntax-error", "The source code doesn't follow the grammar of the language."},
+	{MissingComma, "missing-comma", "The elements of a list must be separated by commas."},
+	{Redeclared, "redeclared", "A name must be declared only once in a block."},
+	{MissingType, "missing-type", "A variable declaration must name the type of the variable."},
+	{ExtensionRequired, "extension-required", "The construct requires a language extension which isn't enabled."},
+	{NotInSpec, "not-in-spec", "The construct is not part of the SPL 1.2 specification."},
+
+	{UndeclaredName, "undeclared-name", "A name must be declared before it is used."},
+	{UsedBeforeDecl, "used-before-declaration", "A name must not be used before its declaration."},
+	{InvalidMain, "invalid-main", "A program must declare a parameterless procedure main."},
+	{NotAType, "not-a-type", "A type is expected."},
+	{NotAnExpr, "not-an-expression", "An expression is expected."},
+	{NotACall, "not-a-call", "An expression statement must be a procedure call."},
+	{NotAProc, "not-a-procedure", "Only procedures can be called."},
+	{WrongArgCount, "wrong-argument-count", "A procedure call must pass one argument per parameter."},
+	{MismatchedTypes, "mismatched-types", "An operand has an unexpected type."},
+	{UnassignableOperand, "unassignable-operand", "Only variables and array elements can be assigned or passed as reference parameter."},
+	{InvalidOperator, "invalid-operator", "The operator can't be applied to its operands."},
+	{InvalidLiteral, "invalid-literal", "A literal is malformed or out of range."},
+	{NonIndexable, "non-indexable", "Only arrays can be indexed."},
+	{InvalidDecl, "invalid-declaration", "A declaration is not allowed at its place."},
+	{InvalidImport, "invalid-import", "A module can't be imported."},
+	{InvalidReturn, "invalid-return", "A return statement must return a value if and only if the procedure has a result, which must be returned on every path."},
+	{UnknownField, "unknown-field", "A selector must name a field of the record its operand denotes."},
+
+	{Unsupported, "unsupported", "The target doesn't support the construct."},
+}
+
+// Rules returns the rules of all codes ordered by code.
+func Rules() []Rule { return append([]Rule(nil), rules...) }
+
+// Lookup returns the rule identified by the code. The result is the zero Rule
+// if there is no such rule.
+func Lookup(code Code) Rule {
+	for _, r := range rules {
+		if r.Code == code {
+			return r
+		}
+	}
+	return Rule{}
+}
@@ -0,0 +1,151 @@
+package diag
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+)
+
+// Severity is the severity of a diagnostic.
+type Severity int
+
+// Severities of diagnostics.
+const (
+	Error Severity = iota
+	Warning
+	Note
+)
+
+var severities = [...]string{
+	Error:   "error",
+	Warning: "warning",
+	Note:    "note",
+}
+
+func (s Severity) String() string { return severities[s] }
+
+// Diagnostic is a message about a range of the source code. The position Pos,
+// if valid, points to the beginning of the range and End, if valid, to the
+// first character immediately after it.
+type Diagnostic struct {
+	Severity Severity
+	Code     Code
+	Pos      token.Position
+	End      token.Position
+	Msg      string
+
+	// Related are other source locations which help to understand the
+	// diagnostic, e.g. the previous declaration of a redeclared name.
+	Related []Related
+
+	// Fixes are suggested changes of the source code resolving the
+	// diagnostic.
+	Fixes []Fix
+
+	// Notes explain the diagnostic, Help tells how to resolve it.
+	Notes []string
+	Help  string
+}
+
+// Related is a source location related to a diagnostic.
+type Related struct {
+	Pos token.Position
+	End token.Position
+	Msg string
+}
+
+// Fix is a suggested fix described by Msg which consists of edits of the
+// source code.
+type Fix struct {
+	Msg   string
+	Edits []Edit
+}
+
+// Edit replaces the source code between Pos and End by NewText. If both
+// positions are equal, the text is inserted.
+type Edit struct {
+	Pos     token.Position
+	End     token.Position
+	NewText string
+}
+
+// Error implements the error interface. The related locations follow the
+// message, one per line.
+func (d *Diagnostic) Error() string {
+	s := d.Msg
+	if d.Severity != Error {
+		s = d.Severity.String() + ": " + s
+	}
+	if d.Pos.Filename != "" || d.Pos.IsValid() {
+		s = d.Pos.String() + ": " + s
+	}
+	for _, r := range d.Related {
+		s += fmt.Sprintf("\n\t%s at %s", r.Msg, r.Pos)
+	}
+	return s
+}
+
+// List is a list of diagnostics. The zero value for a List is an empty List
+// ready to use.
+type List []*Diagnostic
+
+// Add adds an error with the given position and message to the list.
+func (l *List) Add(pos token.Position, msg string) {
+	*l = append(*l, &Diagnostic{Pos: pos, Msg: msg})
+}
+
+// Report adds the diagnostic to the list.
+func (l *List) Report(d *Diagnostic) { *l = append(*l, d) }
+
+func (l List) Len() int { return len(l) }
+
+func (l List) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+
+func (l List) Less(i, j int) bool {
+	e := &l[i].Pos
+	f := &l[j].Pos
+	if e.Filename != f.Filename {
+		return e.Filename < f.Filename
+	}
+	if e.Line != f.Line {
+		return e.Line < f.Line
+	}
+	if e.Column != f.Column {
+		return e.Column < f.Column
+	}
+	return l[i].Msg < l[j].Msg
+}
+
+// Sort sorts the list by position and message.
+func (l List) Sort() { sort.Stable(l) }
+
+// HasErrors reports whether the list contains diagnostics of severity Error.
+func (l List) HasErrors() bool {
+	for _, d := range l {
+		if d.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// Error implements the error interface.
+func (l List) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns an error equivalent to this list. If the list doesn't contain
+// errors, Err returns nil.
+func (l List) Err() error {
+	if !l.HasErrors() {
+		return nil
+	}
+	return l
+}
@@ -0,0 +1,67 @@
+package diag_test
+
+import (
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/diag"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+)
+
+func TestDiagnostic_Error(t *testing.T) {
+	pos := func(line, col int) token.Position {
+		return token.Position{Filename: "a.spl", Line: line, Column: col}
+	}
+	tests := []struct {
+		d    *diag.Diagnostic
+		want string
+	}{
+		{&diag.Diagnostic{Pos: pos(1, 2), Msg: "bad"}, "a.spl:1:2: bad"},
+		{&diag.Diagnostic{Severity: diag.Warning, Pos: pos(1, 2), Msg: "odd"}, "a.spl:1:2: warning: odd"},
+		{&diag.Diagnostic{Msg: "no position"}, "no position"},
+		{
+			&diag.Diagnostic{
+				Code:    diag.Redeclared,
+				Pos:     pos(3, 6),
+				Msg:     "x redeclared in this block",
+				Related: []diag.Related{{Pos: pos(1, 6), Msg: "previous declaration"}},
+			},
+			"a.spl:3:6: x redeclared in this block\n\tprevious declaration at a.spl:1:6",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.d.Error(); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestList_Err(t *testing.T) {
+	var l diag.List
+	if l.Err() != nil {
+		t.Error("empty list is an error")
+	}
+	l.Report(&diag.Diagnostic{Severity: diag.Warning, Msg: "odd"})
+	if l.Err() != nil {
+		t.Error("list of warnings is an error")
+	}
+	l.Add(token.Position{Line: 1}, "bad")
+	if err := l.Err(); err == nil || err.Error() != "warning: odd (and 1 more errors)" {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestRules(t *testing.T) {
+	seen := make(map[diag.Code]bool)
+	for _, r := range diag.Rules() {
+		if seen[r.Code] || len(r.Code) != 5 || r.Name == "" || r.Summary == "" {
+			t.Errorf("invalid rule %+v", r)
+		}
+		seen[r.Code] = true
+		if diag.Lookup(r.Code) != r {
+			t.Errorf("lookup of %s failed", r.Code)
+		}
+	}
+	if (diag.Lookup("E9999") != diag.Rule{}) {
+		t.Error("lookup of unknown code succeeded")
+	}
+}
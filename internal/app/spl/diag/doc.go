@@ -0,0 +1,5 @@
+// Package diag defines the diagnostics reported about source code by the
+// scanner, parser, type checker and code generators. A diagnostic has a
+// severity, a stable code identifying its rule, the source range it applies to,
+// related source locations and optionally suggested fixes.
+package diag
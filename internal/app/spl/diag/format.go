@@ -0,0 +1,433 @@
+package diag
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+)
+
+// Format is a machine-readable output format of diagnostics.
+type Format string
+
+// Supported formats. Text is rendered by a Printer, the others by an Encoder.
+const (
+	Text       Format = "text"
+	JSON       Format = "json"
+	SARIF      Format = "sarif"
+	Checkstyle Format = "checkstyle"
+)
+
+// Formats returns the names of the supported formats.
+func Formats() []string {
+	return []string{string(Text), string(JSON), string(SARIF), string(Checkstyle)}
+}
+
+// ParseFormat returns the format with the given name.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case Text, JSON, SARIF, Checkstyle:
+		return f, nil
+	}
+	return "", fmt.Errorf("unsupported diagnostics format %q, must be one of %s", name, strings.Join(Formats(), ", "))
+}
+
+// Encoder writes diagnostics in a machine-readable format. The rule metadata is
+// taken from the codes of the diagnostics.
+type Encoder struct {
+	// Format is the output format. It must not be Text.
+	Format Format
+
+	// Root is the directory file names are made relative to, typically the
+	// root of the repository. File names outside of Root and all file names
+	// if Root is empty are written as they are. Paths always use forward
+	// slashes.
+	Root string
+}
+
+// Encode writes the diagnostics to w. An empty list yields an empty report.
+func (e *Encoder) Encode(w io.Writer, l List) error {
+	switch e.Format {
+	case JSON:
+		return e.encodeJSON(w, l)
+	case SARIF:
+		return e.encodeSARIF(w, l)
+	case Checkstyle:
+		return e.encodeCheckstyle(w, l)
+	}
+	return fmt.Errorf("diag: can't encode diagnostics as %q", e.Format)
+}
+
+// path returns the file name relative to the root.
+func (e *Encoder) path(filename string) string {
+	name, _ := e.rel(filename)
+	return name
+}
+
+// rel returns the file name relative to the root and whether it is inside of
+// the root. File names outside of the root are returned unaltered.
+func (e *Encoder) rel(filename string) (string, bool) {
+	if filename == "" || e.Root == "" {
+		return filepath.ToSlash(filename), false
+	}
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return filepath.ToSlash(filename), false
+	}
+	rel, err := filepath.Rel(e.Root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(filename), false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// jsonDiagnostic is the JSON representation of a Diagnostic.
+type jsonDiagnostic struct {
+	Severity string        `json:"severity"`
+	Code     Code          `json:"code,omitempty"`
+	Rule     string        `json:"rule,omitempty"`
+	Location jsonLocation  `json:"location"`
+	Message  string        `json:"message"`
+	Related  []jsonRelated `json:"related,omitempty"`
+	Notes    []string      `json:"notes,omitempty"`
+	Help     string        `json:"help,omitempty"`
+	Fixes    []jsonFix     `json:"fixes,omitempty"`
+}
+
+type jsonLocation struct {
+	File      string `json:"file,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	Column    int    `json:"column,omitempty"`
+	EndLine   int    `json:"endLine,omitempty"`
+	EndColumn int    `json:"endColumn,omitempty"`
+}
+
+type jsonRelated struct {
+	Location jsonLocation `json:"location"`
+	Message  string       `json:"message"`
+}
+
+type jsonFix struct {
+	Message string     `json:"message"`
+	Edits   []jsonEdit `json:"edits,omitempty"`
+}
+
+type jsonEdit struct {
+	Location jsonLocation `json:"location"`
+	NewText  string       `json:"newText"`
+}
+
+func (e *Encoder) location(pos, end token.Position) jsonLocation {
+	loc := jsonLocation{File: e.path(pos.Filename), Line: pos.Line, Column: pos.Column}
+	if end.IsValid() {
+		loc.EndLine, loc.EndColumn = end.Line, end.Column
+	}
+	return loc
+}
+
+// encodeJSON writes the diagnostics as JSON array.
+func (e *Encoder) encodeJSON(w io.Writer, l List) error {
+	res := make([]jsonDiagnostic, 0, len(l))
+	for _, d := range l {
+		jd := jsonDiagnostic{
+			Severity: d.Severity.String(),
+			Code:     d.Code,
+			Rule:     Lookup(d.Code).Name,
+			Location: e.location(d.Pos, d.End),
+			Message:  d.Msg,
+			Notes:    d.Notes,
+			Help:     d.Help,
+		}
+		for _, r := range d.Related {
+			jd.Related = append(jd.Related, jsonRelated{e.location(r.Pos, r.End), r.Msg})
+		}
+		for _, f := range d.Fixes {
+			jf := jsonFix{Message: f.Msg}
+			for _, ed := range f.Edits {
+				jf.Edits = append(jf.Edits, jsonEdit{e.location(ed.Pos, ed.End), ed.NewText})
+			}
+			jd.Fixes = append(jd.Fixes, jf)
+		}
+		res = append(res, jd)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(res)
+}
+
+// SARIF 2.1.0 log, reduced to the properties written by the Encoder.
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+
+	sarifRun struct {
+		Tool               sarifTool                   `json:"tool"`
+		OriginalURIBaseIDs map[string]sarifArtifactLoc `json:"originalUriBaseIds,omitempty"`
+		Results            []sarifResult               `json:"results"`
+	}
+
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+
+	sarifRule struct {
+		ID               string       `json:"id"`
+		Name             string       `json:"name"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+
+	sarifResult struct {
+		RuleID           string          `json:"ruleId,omitempty"`
+		RuleIndex        *int            `json:"ruleIndex,omitempty"`
+		Level            string          `json:"level"`
+		Message          sarifMessage    `json:"message"`
+		Locations        []sarifLocation `json:"locations,omitempty"`
+		RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+		Fixes            []sarifFix      `json:"fixes,omitempty"`
+	}
+
+	sarifLocation struct {
+		ID               *int                  `json:"id,omitempty"`
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+		Message          *sarifMessage         `json:"message,omitempty"`
+	}
+
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLoc `json:"artifactLocation"`
+		Region           *sarifRegion     `json:"region,omitempty"`
+	}
+
+	sarifArtifactLoc struct {
+		URI       string `json:"uri"`
+		URIBaseID string `json:"uriBaseId,omitempty"`
+	}
+
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn,omitempty"`
+		EndLine     int `json:"endLine,omitempty"`
+		EndColumn   int `json:"endColumn,omitempty"`
+	}
+
+	sarifFix struct {
+		Description     sarifMessage          `json:"description"`
+		ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+	}
+
+	sarifArtifactChange struct {
+		ArtifactLocation sarifArtifactLoc   `json:"artifactLocation"`
+		Replacements     []sarifReplacement `json:"replacements"`
+	}
+
+	sarifReplacement struct {
+		DeletedRegion   sarifRegion   `json:"deletedRegion"`
+		InsertedContent *sarifMessage `json:"insertedContent,omitempty"`
+	}
+)
+
+// sarifLevels maps severities to SARIF levels.
+var sarifLevels = [...]string{
+	Error:   "error",
+	Warning: "warning",
+	Note:    "note",
+}
+
+// srcRoot is the URI base id of file names relative to the root.
+const srcRoot = "SRCROOT"
+
+func (e *Encoder) artifact(filename string) sarifArtifactLoc {
+	name, ok := e.rel(filename)
+	loc := sarifArtifactLoc{URI: name}
+	if ok {
+		loc.URIBaseID = srcRoot
+	}
+	return loc
+}
+
+func region(pos, end token.Position) *sarifRegion {
+	if !pos.IsValid() {
+		return nil
+	}
+	r := &sarifRegion{StartLine: pos.Line, StartColumn: pos.Column}
+	if end.IsValid() {
+		r.EndLine, r.EndColumn = end.Line, end.Column
+	}
+	return r
+}
+
+// encodeSARIF writes the diagnostics as SARIF 2.1.0 log with a single run. All
+// rules are listed in the metadata of the tool.
+func (e *Encoder) encodeSARIF(w io.Writer, l List) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "spl",
+			InformationURI: "https://github.com/lukasmalkmus/spl",
+		}},
+		Results: []sarifResult{},
+	}
+	index := make(map[Code]int)
+	for i, r := range rules {
+		index[r.Code] = i
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               string(r.Code),
+			Name:             r.Name,
+			ShortDescription: sarifMessage{r.Summary},
+		})
+	}
+	if e.Root != "" {
+		uri := filepath.ToSlash(e.Root)
+		if !strings.HasPrefix(uri, "/") {
+			uri = "/" + uri
+		}
+		if !strings.HasSuffix(uri, "/") {
+			uri += "/"
+		}
+		run.OriginalURIBaseIDs = map[string]sarifArtifactLoc{srcRoot: {URI: "file://" + uri}}
+	}
+
+	for _, d := range l {
+		res := sarifResult{
+			RuleID:  string(d.Code),
+			Level:   sarifLevels[d.Severity],
+			Message: sarifMessage{d.Msg},
+		}
+		if i, ok := index[d.Code]; ok {
+			i := i
+			res.RuleIndex = &i
+		}
+		if d.Help != "" {
+			res.Message.Text += "\nhelp: " + d.Help
+		}
+		for _, n := range d.Notes {
+			res.Message.Text += "\nnote: " + n
+		}
+		if d.Pos.Filename != "" {
+			res.Locations = []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: e.artifact(d.Pos.Filename),
+				Region:           region(d.Pos, d.End),
+			}}}
+		}
+		for i, r := range d.Related {
+			id := i + 1
+			res.RelatedLocations = append(res.RelatedLocations, sarifLocation{
+				ID: &id,
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: e.artifact(r.Pos.Filename),
+					Region:           region(r.Pos, r.End),
+				},
+				Message: &sarifMessage{r.Msg},
+			})
+		}
+		for _, f := range d.Fixes {
+			fix := sarifFix{Description: sarifMessage{f.Msg}, ArtifactChanges: []sarifArtifactChange{}}
+			for _, ed := range f.Edits {
+				reg := region(ed.Pos, ed.End)
+				if reg == nil {
+					continue
+				}
+				rep := sarifReplacement{DeletedRegion: *reg}
+				if ed.NewText != "" {
+					rep.InsertedContent = &sarifMessage{ed.NewText}
+				}
+				fix.ArtifactChanges = append(fix.ArtifactChanges, sarifArtifactChange{
+					ArtifactLocation: e.artifact(ed.Pos.Filename),
+					Replacements:     []sarifReplacement{rep},
+				})
+			}
+			res.Fixes = append(res.Fixes, fix)
+		}
+		run.Results = append(run.Results, res)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// Checkstyle XML report.
+type (
+	checkstyleReport struct {
+		XMLName xml.Name         `xml:"checkstyle"`
+		Version string           `xml:"version,attr"`
+		Files   []checkstyleFile `xml:"file"`
+	}
+
+	checkstyleFile struct {
+		Name   string            `xml:"name,attr"`
+		Errors []checkstyleError `xml:"error"`
+	}
+
+	checkstyleError struct {
+		Line     int    `xml:"line,attr"`
+		Column   int    `xml:"column,attr,omitempty"`
+		Severity string `xml:"severity,attr"`
+		Message  string `xml:"message,attr"`
+		Source   string `xml:"source,attr,omitempty"`
+	}
+)
+
+// checkstyleSeverities maps severities to Checkstyle severities.
+var checkstyleSeverities = [...]string{
+	Error:   "error",
+	Warning: "warning",
+	Note:    "info",
+}
+
+// encodeCheckstyle writes the diagnostics as Checkstyle XML report. The
+// diagnostics are grouped by file in the order of their first appearance. The
+// source of an error is the rule name qualified by "spl.".
+func (e *Encoder) encodeCheckstyle(w io.Writer, l List) error {
+	report := checkstyleReport{Version: "4.3"}
+	files := make(map[string]int)
+	for _, d := range l {
+		name := e.path(d.Pos.Filename)
+		i, ok := files[name]
+		if !ok {
+			i = len(report.Files)
+			files[name] = i
+			report.Files = append(report.Files, checkstyleFile{Name: name})
+		}
+		ce := checkstyleError{
+			Line:     d.Pos.Line,
+			Column:   d.Pos.Column,
+			Severity: checkstyleSeverities[d.Severity],
+			Message:  d.Msg,
+		}
+		if r := Lookup(d.Code); r.Name != "" {
+			ce.Source = "spl." + r.Name
+		}
+		report.Files[i].Errors = append(report.Files[i].Errors, ce)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
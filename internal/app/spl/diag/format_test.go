@@ -0,0 +1,198 @@
+package diag_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/diag"
+	"github.com/lukasmalkmus/spl/internal/app/spl/internal/testutil"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+)
+
+// testList returns diagnostics of a file in the directory root.
+func testList(root string) diag.List {
+	pos := func(line, col int) token.Position {
+		return token.Position{Filename: filepath.Join(root, "src", "a.spl"), Line: line, Column: col}
+	}
+	return diag.List{
+		{
+			Code:    diag.Redeclared,
+			Pos:     pos(3, 6),
+			End:     pos(3, 7),
+			Msg:     "x redeclared in this block",
+			Related: []diag.Related{{Pos: pos(2, 6), End: pos(2, 7), Msg: "previous declaration"}},
+		},
+		{
+			Code:  diag.SyntaxError,
+			Pos:   pos(3, 12),
+			End:   pos(3, 13),
+			Msg:   "expected ';', found ','",
+			Fixes: []diag.Fix{{Msg: "replace ',' with ';'", Edits: []diag.Edit{{Pos: pos(3, 12), End: pos(3, 13), NewText: ";"}}}},
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, name := range diag.Formats() {
+		if f, err := diag.ParseFormat(name); err != nil || string(f) != name {
+			t.Errorf("ParseFormat(%q) = %q, %v", name, f, err)
+		}
+	}
+	if _, err := diag.ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat(\"xml\") succeeded")
+	}
+}
+
+func TestEncoder_Encode_JSON(t *testing.T) {
+	root, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	var b strings.Builder
+	if err := (&diag.Encoder{Format: diag.JSON, Root: root}).Encode(&b, testList(root)); err != nil {
+		t.Fatal(err)
+	}
+	var got []struct {
+		Severity string
+		Code     string
+		Rule     string
+		Location struct {
+			File                             string
+			Line, Column, EndLine, EndColumn int
+		}
+		Related []struct{ Message string }
+		Fixes   []struct {
+			Edits []struct{ NewText string }
+		}
+	}
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d diagnostics, want 2", len(got))
+	}
+	d := got[0]
+	if d.Severity != "error" || d.Code != "E0103" || d.Rule != "redeclared" {
+		t.Errorf("got %s %s %s, want error E0103 redeclared", d.Severity, d.Code, d.Rule)
+	}
+	if l := d.Location; l.File != "src/a.spl" || l.Line != 3 || l.Column != 6 || l.EndLine != 3 || l.EndColumn != 7 {
+		t.Errorf("got location %+v", l)
+	}
+	if len(d.Related) != 1 || d.Related[0].Message != "previous declaration" {
+		t.Errorf("got related %+v", d.Related)
+	}
+	if f := got[1].Fixes; len(f) != 1 || len(f[0].Edits) != 1 || f[0].Edits[0].NewText != ";" {
+		t.Errorf("got fixes %+v", f)
+	}
+}
+
+func TestEncoder_Encode_SARIF(t *testing.T) {
+	root, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	var b strings.Builder
+	if err := (&diag.Encoder{Format: diag.SARIF, Root: root}).Encode(&b, testList(root)); err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Version string
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string
+					Rules []struct{ ID, Name string }
+				}
+			}
+			Results []struct {
+				RuleID    string
+				RuleIndex int
+				Level     string
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI, URIBaseID string }
+						Region           struct{ StartLine, StartColumn, EndLine, EndColumn int }
+					}
+				}
+				RelatedLocations []struct{ Message struct{ Text string } }
+				Fixes            []struct {
+					ArtifactChanges []struct {
+						Replacements []struct {
+							InsertedContent struct{ Text string }
+						}
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != "2.1.0" || len(got.Runs) != 1 {
+		t.Fatalf("got version %s with %d runs", got.Version, len(got.Runs))
+	}
+	run := got.Runs[0]
+	if run.Tool.Driver.Name != "spl" || len(run.Tool.Driver.Rules) != len(diag.Rules()) {
+		t.Errorf("got driver %s with %d rules", run.Tool.Driver.Name, len(run.Tool.Driver.Rules))
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("got %d results, want 2", len(run.Results))
+	}
+	res := run.Results[0]
+	if res.RuleID != "E0103" || res.Level != "error" || run.Tool.Driver.Rules[res.RuleIndex].ID != "E0103" {
+		t.Errorf("got rule %s (index %d), level %s", res.RuleID, res.RuleIndex, res.Level)
+	}
+	loc := res.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "src/a.spl" || loc.ArtifactLocation.URIBaseID != "SRCROOT" {
+		t.Errorf("got artifact %+v", loc.ArtifactLocation)
+	}
+	if r := loc.Region; r.StartLine != 3 || r.StartColumn != 6 || r.EndLine != 3 || r.EndColumn != 7 {
+		t.Errorf("got region %+v", r)
+	}
+	if rel := res.RelatedLocations; len(rel) != 1 || rel[0].Message.Text != "previous declaration" {
+		t.Errorf("got related locations %+v", rel)
+	}
+	if f := run.Results[1].Fixes; len(f) != 1 || f[0].ArtifactChanges[0].Replacements[0].InsertedContent.Text != ";" {
+		t.Errorf("got fixes %+v", f)
+	}
+}
+
+func TestEncoder_Encode_Checkstyle(t *testing.T) {
+	root, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	var b strings.Builder
+	if err := (&diag.Encoder{Format: diag.Checkstyle, Root: root}).Encode(&b, testList(root)); err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="4.3">
+  <file name="src/a.spl">
+    <error line="3" column="6" severity="error" message="x redeclared in this block" source="spl.redeclared"></error>
+    <error line="3" column="12" severity="error" message="expected &#39;;&#39;, found &#39;,&#39;" source="spl.syntax-error"></error>
+  </file>
+</checkstyle>
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEncoder_Encode_Empty(t *testing.T) {
+	tests := []struct {
+		format diag.Format
+		want   string
+	}{
+		{diag.JSON, "[]\n"},
+		{diag.Checkstyle, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<checkstyle version=\"4.3\"></checkstyle>\n"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := (&diag.Encoder{Format: tt.format}).Encode(&b, nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.format, got, tt.want)
+		}
+	}
+	if err := (&diag.Encoder{Format: diag.Text}).Encode(&strings.Builder{}, nil); err == nil {
+		t.Error("encoding as text succeeded")
+	}
+}
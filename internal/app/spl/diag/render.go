@@ -0,0 +1,329 @@
+package diag
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+)
+
+// ANSI escape sequences used by the Printer.
+const (
+	reset = "\x1b[0m"
+	bold  = "\x1b[1m"
+	red   = "\x1b[1;31m"
+	yel   = "\x1b[1;33m"
+	green = "\x1b[1;32m"
+	blue  = "\x1b[1;34m"
+)
+
+// maxLines is the maximum number of source lines shown for a range. Longer
+// ranges are shortened in the middle.
+const maxLines = 4
+
+// Printer renders diagnostics together with the source code they refer to: The
+// source lines are shown with the range of the diagnostic underlined by carets
+// and the related locations underlined by dashes and labeled. Notes, help and
+// suggested fixes follow.
+type Printer struct {
+	// Color enables colored output using ANSI escape sequences.
+	Color bool
+
+	// ReadFile returns the content of a source file. If it is nil, the file
+	// is read from the file system. Diagnostics of files which can't be
+	// read are rendered without source code.
+	ReadFile func(filename string) ([]byte, error)
+
+	files map[string][]string
+}
+
+// Fprint renders err to w. A List is rendered one diagnostic after another, a
+// *Diagnostic on its own and any other error as plain message.
+func (p *Printer) Fprint(w io.Writer, err error) error {
+	switch err := err.(type) {
+	case List:
+		for i, d := range err {
+			if i > 0 {
+				if _, werr := io.WriteString(w, "\n"); werr != nil {
+					return werr
+				}
+			}
+			if werr := p.print(w, d); werr != nil {
+				return werr
+			}
+		}
+		return nil
+	case *Diagnostic:
+		return p.print(w, err)
+	case nil:
+		return nil
+	}
+	_, werr := fmt.Fprintln(w, err)
+	return werr
+}
+
+// annotation is an underlined range of a source file.
+type annotation struct {
+	pos, end token.Position
+	primary  bool
+	label    string
+}
+
+func (p *Printer) print(w io.Writer, d *Diagnostic) error {
+	var b strings.Builder
+	sev := d.Severity.String()
+	if d.Code != "" {
+		sev += "[" + string(d.Code) + "]"
+	}
+	b.WriteString(p.style(severityColor(d.Severity), sev) + p.style(bold, ": "+d.Msg) + "\n")
+
+	// Annotations are grouped by file, the file of the diagnostic first.
+	files := []string{d.Pos.Filename}
+	groups := map[string][]annotation{
+		d.Pos.Filename: {{pos: d.Pos, end: d.End, primary: true}},
+	}
+	for _, r := range d.Related {
+		if _, ok := groups[r.Pos.Filename]; !ok {
+			files = append(files, r.Pos.Filename)
+		}
+		groups[r.Pos.Filename] = append(groups[r.Pos.Filename], annotation{pos: r.Pos, end: r.End, label: r.Msg})
+	}
+	width := 1
+	for _, anns := range groups {
+		for _, a := range anns {
+			for _, line := range []int{a.pos.Line, a.end.Line} {
+				if n := len(strconv.Itoa(line)); n > width {
+					width = n
+				}
+			}
+		}
+	}
+	gutter := strings.Repeat(" ", width)
+	for i, filename := range files {
+		arrow := "-->"
+		if i > 0 {
+			arrow = ":::"
+		}
+		anns := groups[filename]
+		b.WriteString(gutter + p.style(blue, arrow) + " " + anns[0].pos.String() + "\n")
+		p.snippet(&b, filename, anns, width)
+	}
+
+	var trailer []string
+	for _, note := range d.Notes {
+		trailer = append(trailer, p.style(bold, "note")+": "+note)
+	}
+	if d.Help != "" {
+		trailer = append(trailer, p.style(bold, "help")+": "+d.Help)
+	}
+	for _, fix := range d.Fixes {
+		trailer = append(trailer, p.style(bold, "help")+": "+fix.Msg)
+	}
+	for _, t := range trailer {
+		b.WriteString(gutter + " " + p.style(blue, "=") + " " + t + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// snippet renders the source lines of the annotations. Lines which can't be
+// read are omitted.
+func (p *Printer) snippet(b *strings.Builder, filename string, anns []annotation, width int) {
+	src := p.lines(filename)
+	if src == nil {
+		return
+	}
+	gutter := strings.Repeat(" ", width)
+	bar := p.style(blue, "|")
+
+	// marks collects the underlines of each line.
+	type mark struct {
+		from, to int // display columns
+		primary  bool
+		label    string
+	}
+	marks := make(map[int][]mark)
+	var lines []int
+	addLine := func(line int) {
+		if _, ok := marks[line]; !ok && line >= 1 && line <= len(src) {
+			marks[line] = nil
+			lines = append(lines, line)
+		}
+	}
+	for _, a := range anns {
+		if !a.pos.IsValid() || a.pos.Line > len(src) {
+			continue
+		}
+		first, last := a.pos.Line, a.end.Line
+		ranged := a.end.IsValid() && last <= len(src) &&
+			(last > first || last == first && a.end.Column > a.pos.Column)
+		if !ranged {
+			last = first
+		}
+		for line := first; line <= last; line++ {
+			if last-first >= maxLines && line > first+1 && line < last {
+				continue
+			}
+			addLine(line)
+			text := src[line-1]
+			from, to := a.pos.Column, a.pos.Column+1
+			if ranged {
+				if line > first {
+					from = len([]rune(text)) - len([]rune(strings.TrimLeft(text, " \t"))) + 1
+				}
+				to = len([]rune(text)) + 1
+				if line == last {
+					to = a.end.Column
+				}
+			}
+			m := mark{from: column(text, from), to: column(text, to), primary: a.primary}
+			if m.to <= m.from {
+				m.to = m.from + 1
+			}
+			if line == last {
+				m.label = a.label
+			}
+			marks[line] = append(marks[line], m)
+		}
+	}
+	sort.Ints(lines)
+
+	b.WriteString(gutter + " " + bar + "\n")
+	for i, line := range lines {
+		if i > 0 && line > lines[i-1]+1 {
+			b.WriteString(p.style(blue, "...") + "\n")
+		}
+		num := strconv.Itoa(line)
+		b.WriteString(p.style(blue, strings.Repeat(" ", width-len(num))+num+" |") + " " + expand(src[line-1]) + "\n")
+		ms := marks[line]
+		sort.Slice(ms, func(i, j int) bool { return ms[i].from < ms[j].from })
+		var u strings.Builder
+		col := 0
+		// pending holds the marks whose labels are printed on the lines
+		// below the underlines, connected to their mark by a bar.
+		var pending []mark
+		for i, m := range ms {
+			if m.from < col {
+				m.from = col
+			}
+			if m.to <= m.from {
+				continue
+			}
+			u.WriteString(strings.Repeat(" ", m.from-col))
+			ch, color := markStyle(m.primary)
+			u.WriteString(p.style(color, strings.Repeat(ch, m.to-m.from)))
+			col = m.to
+			switch {
+			case m.label == "":
+			case i == len(ms)-1:
+				u.WriteString(" " + p.style(color, m.label))
+			default:
+				pending = append(pending, m)
+			}
+		}
+		b.WriteString(gutter + " " + bar + " " + u.String() + "\n")
+		if len(pending) == 0 {
+			continue
+		}
+
+		// connect prints a bar under each of the marks and, if label is
+		// set, the label of the last one instead of its bar.
+		connect := func(ms []mark, label bool) {
+			var u strings.Builder
+			col := 0
+			for i, m := range ms {
+				u.WriteString(strings.Repeat(" ", m.from-col))
+				s := "|"
+				if label && i == len(ms)-1 {
+					s = m.label
+				}
+				_, color := markStyle(m.primary)
+				u.WriteString(p.style(color, s))
+				col = m.from + 1
+			}
+			b.WriteString(gutter + " " + bar + " " + u.String() + "\n")
+		}
+		connect(pending, false)
+		for i := len(pending); i > 0; i-- {
+			connect(pending[:i], true)
+		}
+	}
+}
+
+// markStyle returns the character and the color underlining a primary or a
+// related range.
+func markStyle(primary bool) (ch, color string) {
+	if primary {
+		return "^", red
+	}
+	return "-", blue
+}
+
+// lines returns the lines of a source file or nil if it can't be read.
+func (p *Printer) lines(filename string) []string {
+	if filename == "" {
+		return nil
+	}
+	if src, ok := p.files[filename]; ok {
+		return src
+	}
+	read := p.ReadFile
+	if read == nil {
+		read = ioutil.ReadFile
+	}
+	var src []string
+	if b, err := read(filename); err == nil {
+		src = strings.Split(strings.ReplaceAll(string(b), "\r\n", "\n"), "\n")
+	}
+	if p.files == nil {
+		p.files = make(map[string][]string)
+	}
+	p.files[filename] = src
+	return src
+}
+
+func (p *Printer) style(color, s string) string {
+	if !p.Color {
+		return s
+	}
+	return color + s + reset
+}
+
+func severityColor(s Severity) string {
+	switch s {
+	case Warning:
+		return yel
+	case Note:
+		return green
+	}
+	return red
+}
+
+// tabWidth is the width tabs are expanded to.
+const tabWidth = 4
+
+// expand replaces the tabs of a source line by spaces.
+func expand(line string) string {
+	return strings.ReplaceAll(line, "\t", strings.Repeat(" ", tabWidth))
+}
+
+// column returns the display column, counted from 0, of the 1-based rune
+// column col of the line with expanded tabs.
+func column(line string, col int) int {
+	n := 0
+	for i, r := range []rune(line) {
+		if i >= col-1 {
+			return n
+		}
+		if r == '\t' {
+			n += tabWidth
+		} else {
+			n++
+		}
+	}
+	return n + col - 1 - len([]rune(line))
+}
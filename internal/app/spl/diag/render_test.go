@@ -0,0 +1,153 @@
+package diag_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/diag"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+)
+
+func TestPrinter_Fprint(t *testing.T) {
+	src := "proc main() {\n  var x: int;\n\tvar x: int,\n}\nproc p(a: int, b: int, a: int) {}\n"
+	pos := func(line, col int) token.Position {
+		return token.Position{Filename: "a.spl", Line: line, Column: col}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "related",
+			err: diag.List{{
+				Code:    diag.Redeclared,
+				Pos:     pos(3, 6),
+				End:     pos(3, 7),
+				Msg:     "x redeclared in this block",
+				Related: []diag.Related{{Pos: pos(2, 7), End: pos(2, 8), Msg: "first declared here"}},
+			}},
+			want: `error[E0103]: x redeclared in this block
+ --> a.spl:3:6
+  |
+2 |   var x: int;
+  |       - first declared here
+3 |     var x: int,
+  |         ^
+`,
+		},
+		{
+			name: "same line",
+			err: &diag.Diagnostic{
+				Code:    diag.Redeclared,
+				Pos:     pos(5, 24),
+				End:     pos(5, 25),
+				Msg:     "a redeclared in this block",
+				Related: []diag.Related{{Pos: pos(5, 8), End: pos(5, 9), Msg: "previous declaration"}},
+			},
+			want: `error[E0103]: a redeclared in this block
+ --> a.spl:5:24
+  |
+5 | proc p(a: int, b: int, a: int) {}
+  |        -               ^
+  |        |
+  |        previous declaration
+`,
+		},
+		{
+			name: "labels on one line",
+			err: &diag.Diagnostic{
+				Code: diag.Redeclared,
+				Pos:  pos(5, 16),
+				End:  pos(5, 17),
+				Msg:  "b redeclared in this block",
+				Related: []diag.Related{
+					{Pos: pos(5, 8), End: pos(5, 9), Msg: "first"},
+					{Pos: pos(5, 24), End: pos(5, 25), Msg: "last"},
+					{Pos: pos(5, 11), End: pos(5, 14), Msg: "second"},
+				},
+			},
+			want: `error[E0103]: b redeclared in this block
+ --> a.spl:5:16
+  |
+5 | proc p(a: int, b: int, a: int) {}
+  |        -  ---  ^       - last
+  |        |  |
+  |        |  second
+  |        first
+`,
+		},
+		{
+			name: "help",
+			err: &diag.Diagnostic{
+				Code:  diag.SyntaxError,
+				Pos:   pos(3, 12),
+				End:   pos(3, 13),
+				Msg:   "expected ';', found ','",
+				Notes: []string{"declarations end with a semicolon"},
+				Fixes: []diag.Fix{{Msg: "replace ',' with ';'"}},
+			},
+			want: `error[E0101]: expected ';', found ','
+ --> a.spl:3:12
+  |
+3 |     var x: int,
+  |               ^
+  = note: declarations end with a semicolon
+  = help: replace ',' with ';'
+`,
+		},
+		{
+			name: "multi-column",
+			err: &diag.Diagnostic{
+				Code: diag.UndeclaredName,
+				Pos:  pos(1, 6),
+				End:  pos(1, 10),
+				Msg:  "undeclared name: main",
+			},
+			want: `error[E0201]: undeclared name: main
+ --> a.spl:1:6
+  |
+1 | proc main() {
+  |      ^^^^
+`,
+		},
+		{
+			name: "plain error",
+			err:  errors.New("boom"),
+			want: "boom\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &diag.Printer{ReadFile: func(filename string) ([]byte, error) {
+				if filename != "a.spl" {
+					return nil, os.ErrNotExist
+				}
+				return []byte(src), nil
+			}}
+			var b strings.Builder
+			if err := p.Fprint(&b, tt.err); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrinter_Fprint_Color(t *testing.T) {
+	p := &diag.Printer{Color: true, ReadFile: func(string) ([]byte, error) {
+		return nil, os.ErrNotExist
+	}}
+	var b strings.Builder
+	err := &diag.Diagnostic{Code: diag.SyntaxError, Pos: token.Position{Filename: "a.spl", Line: 1, Column: 1}, Msg: "bad"}
+	if err := p.Fprint(&b, err); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); !strings.Contains(got, "\x1b[") || !strings.Contains(got, "a.spl:1:1") {
+		t.Errorf("got %q, want colored output", got)
+	}
+}
@@ -0,0 +1,4 @@
+// Package elf implements a writer for statically linked ELF64 executables. The
+// executables consist of loadable segments only and don't need a dynamic
+// linker or any external tools to run.
+package elf
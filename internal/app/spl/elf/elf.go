@@ -0,0 +1,101 @@
+package elf
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// PageSize is the alignment of loadable segments in the file and in memory.
+const PageSize = 0x1000
+
+// headerSize is the combined size of the ELF header and the program header
+// table which precede the first segment in the file.
+func headerSize(segments int) uint64 {
+	return uint64(binary.Size(elf.Header64{}) + segments*binary.Size(elf.Prog64{}))
+}
+
+// Segment is a loadable segment of an executable.
+type Segment struct {
+	// Addr is the virtual address the segment is loaded to. It must be
+	// aligned to PageSize.
+	Addr uint64
+	// Flags are the memory access permissions of the segment.
+	Flags elf.ProgFlag
+	// Data is the content of the segment.
+	Data []byte
+	// MemSize is the size of the segment in memory. If it is larger than the
+	// size of Data, the remaining memory is zero initialized.
+	MemSize uint64
+}
+
+// File describes a statically linked x86-64 Linux executable.
+type File struct {
+	// Entry is the virtual address of the first instruction executed.
+	Entry uint64
+	// Segments are the loadable segments of the executable.
+	Segments []*Segment
+}
+
+// WriteTo writes the executable to w. Each segment is placed at a page aligned
+// file offset.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	if len(f.Segments) == 0 {
+		return 0, errors.New("elf: no segments")
+	}
+
+	hdr := elf.Header64{
+		Type:      uint16(elf.ET_EXEC),
+		Machine:   uint16(elf.EM_X86_64),
+		Version:   uint32(elf.EV_CURRENT),
+		Entry:     f.Entry,
+		Phoff:     uint64(binary.Size(elf.Header64{})),
+		Ehsize:    uint16(binary.Size(elf.Header64{})),
+		Phentsize: uint16(binary.Size(elf.Prog64{})),
+		Phnum:     uint16(len(f.Segments)),
+	}
+	copy(hdr.Ident[:], elf.ELFMAG)
+	hdr.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	hdr.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	hdr.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	hdr.Ident[elf.EI_OSABI] = byte(elf.ELFOSABI_NONE)
+
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, hdr)
+
+	off := alignUp(headerSize(len(f.Segments)), PageSize)
+	offsets := make([]uint64, len(f.Segments))
+	for i, s := range f.Segments {
+		if s.Addr%PageSize != 0 {
+			return 0, fmt.Errorf("elf: segment %d address %#x is not page aligned", i, s.Addr)
+		}
+		memsz := s.MemSize
+		if memsz < uint64(len(s.Data)) {
+			memsz = uint64(len(s.Data))
+		}
+		offsets[i] = off
+		_ = binary.Write(&buf, binary.LittleEndian, elf.Prog64{
+			Type:   uint32(elf.PT_LOAD),
+			Flags:  uint32(s.Flags),
+			Off:    off,
+			Vaddr:  s.Addr,
+			Paddr:  s.Addr,
+			Filesz: uint64(len(s.Data)),
+			Memsz:  memsz,
+			Align:  PageSize,
+		})
+		off = alignUp(off+uint64(len(s.Data)), PageSize)
+	}
+
+	for i, s := range f.Segments {
+		_, _ = buf.Write(make([]byte, offsets[i]-uint64(buf.Len())))
+		_, _ = buf.Write(s.Data)
+	}
+	return buf.WriteTo(w)
+}
+
+// alignUp rounds x up to a multiple of a.
+func alignUp(x, a uint64) uint64 { return (x + a - 1) &^ (a - 1) }
@@ -0,0 +1,60 @@
+package elf_test
+
+import (
+	"bytes"
+	"debug/elf"
+	"testing"
+
+	splelf "github.com/lukasmalkmus/spl/internal/app/spl/elf"
+)
+
+func TestFile_WriteTo(t *testing.T) {
+	text := []byte{0xB8, 0x3C, 0x00, 0x00, 0x00, 0x31, 0xFF, 0x0F, 0x05} // exit(0)
+	f := &splelf.File{
+		Entry: 0x401000,
+		Segments: []*splelf.Segment{
+			{Addr: 0x401000, Flags: elf.PF_R | elf.PF_X, Data: text},
+			{Addr: 0x402000, Flags: elf.PF_R | elf.PF_W, MemSize: 0x100},
+		},
+	}
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	ef, err := elf.NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal("failed to read written executable:", err)
+	}
+	if ef.Class != elf.ELFCLASS64 || ef.Machine != elf.EM_X86_64 || ef.Type != elf.ET_EXEC {
+		t.Errorf("unexpected header: %v %v %v", ef.Class, ef.Machine, ef.Type)
+	}
+	if ef.Entry != 0x401000 {
+		t.Errorf("got entry %#x, want %#x", ef.Entry, 0x401000)
+	}
+	if len(ef.Progs) != 2 {
+		t.Fatalf("got %d program headers, want 2", len(ef.Progs))
+	}
+
+	data := make([]byte, len(text))
+	if _, err := ef.Progs[0].ReadAt(data, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, text) {
+		t.Errorf("got text % x, want % x", data, text)
+	}
+	if p := ef.Progs[0]; p.Off%splelf.PageSize != p.Vaddr%splelf.PageSize {
+		t.Errorf("segment offset %#x and address %#x are not congruent", p.Off, p.Vaddr)
+	}
+	if p := ef.Progs[1]; p.Filesz != 0 || p.Memsz != 0x100 || p.Flags != elf.PF_R|elf.PF_W {
+		t.Errorf("unexpected data segment: %+v", p.ProgHeader)
+	}
+}
+
+func TestFile_WriteToUnaligned(t *testing.T) {
+	f := &splelf.File{Segments: []*splelf.Segment{{Addr: 0x401001}}}
+	if _, err := f.WriteTo(&bytes.Buffer{}); err == nil {
+		t.Error("expected error for unaligned segment")
+	}
+}
@@ -0,0 +1,116 @@
+// Package ext defines the extensions of the language beyond the SPL 1.2
+// specification. Extensions are disabled by default and must be enabled
+// explicitly, so programs written for the specification are processed
+// unaltered.
+package ext
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Set is a set of language extensions. The zero value is the language of the
+// specification.
+type Set uint
+
+// Language extensions.
+const (
+	// Imports enables the import of modules from other source files and
+	// the access of their procedures and types by qualified identifiers:
+	//
+	//	import "sort.spl" as s;
+	//
+	//	proc main() {
+	//	  var a: s.vector;
+	//	  s.sort(a);
+	//	}
+	Imports Set = 1 << iota
+
+	// Functions enables procedures with a result type, the return
+	// statement and calls of such procedures inside expressions:
+	//
+	//	proc square(x: int): int {
+	//	  return x * x;
+	//	}
+	Functions
+
+	// Bool enables the predeclared type bool, the constants true and false
+	// and the logical operators and, or and not, which evaluate their
+	// right operand only if needed:
+	//
+	//	var inside: bool;
+	//	inside := 0 <= i and i < n;
+	Bool
+
+	// Records enables record types, whose fields are selected by name.
+	// Like arrays, records are passed as reference parameters:
+	//
+	//	type point = record { x: int; y: int; };
+	//
+	//	proc move(ref p: point, dx: int) {
+	//	  p.x := p.x + dx;
+	//	}
+	Records
+
+	// Strings enables string literals, which are printed by the library
+	// procedure prints or initialize character buffers, arrays of int
+	// holding one character per element:
+	//
+	//	var name: array [16] of int := "world";
+	//	prints("hello\n");
+	Strings
+)
+
+// names contains the names of the extensions.
+var names = []struct {
+	ext  Set
+	name string
+}{
+	{Imports, "imports"},
+	{Functions, "functions"},
+	{Bool, "bool"},
+	{Records, "records"},
+	{Strings, "strings"},
+}
+
+// Has reports whether all extensions of x are in the set.
+func (s Set) Has(x Set) bool { return s&x == x }
+
+// String returns the comma separated names of the extensions in the set.
+func (s Set) String() string {
+	var list []string
+	for _, n := range names {
+		if s.Has(n.ext) {
+			list = append(list, n.name)
+		}
+	}
+	return strings.Join(list, ",")
+}
+
+// Parse returns the set of the extensions with the given names. Each name may
+// also be a comma separated list of names.
+func Parse(list ...string) (Set, error) {
+	var s Set
+	for _, l := range list {
+		for _, name := range strings.Split(l, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			x, ok := lookup(name)
+			if !ok {
+				return 0, fmt.Errorf("unknown language extension %q", name)
+			}
+			s |= x
+		}
+	}
+	return s, nil
+}
+
+func lookup(name string) (Set, bool) {
+	for _, n := range names {
+		if n.name == name {
+			return n.ext, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,78 @@
+package ext_test
+
+import (
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ext"
+)
+
+func TestParse(t *testing.T) {
+	s, err := ext.Parse("imports", " ,imports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Has(ext.Imports) || s.String() != "imports" {
+		t.Errorf("got extensions %q, want imports", s)
+	}
+	if s, err := ext.Parse("functions,imports"); err != nil || s != ext.Imports|ext.Functions || s.String() != "imports,functions" {
+		t.Errorf("got extensions %q, %v, want imports,functions", s, err)
+	}
+	if s, err := ext.Parse("bool", "functions"); err != nil || s != ext.Functions|ext.Bool || s.String() != "functions,bool" {
+		t.Errorf("got extensions %q, %v, want functions,bool", s, err)
+	}
+	if s, err := ext.Parse("records,bool"); err != nil || s != ext.Bool|ext.Records || s.String() != "bool,records" {
+		t.Errorf("got extensions %q, %v, want bool,records", s, err)
+	}
+	if s, err := ext.Parse("strings"); err != nil || s != ext.Strings || s.String() != "strings" {
+		t.Errorf("got extensions %q, %v, want strings", s, err)
+	}
+	if s, err := ext.Parse(); err != nil || s != 0 {
+		t.Errorf("got extensions %q, %v, want none", s, err)
+	}
+	if _, err := ext.Parse("goto"); err == nil || err.Error() != `unknown language extension "goto"` {
+		t.Errorf("got error %v, want unknown extension", err)
+	}
+}
+
+func TestParseLang(t *testing.T) {
+	tests := []struct {
+		name string
+		want ext.Lang
+		err  string
+	}{
+		{"", ext.Default, ""},
+		{"spl1.2", ext.SPL12, ""},
+		{"spl1.3", 0, `unknown language version "spl1.3"`},
+	}
+	for _, tt := range tests {
+		got, err := ext.ParseLang(tt.name)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("ParseLang(%q): got error %v, want %s", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want || got.String() != tt.name {
+			t.Errorf("ParseLang(%q): got %v, %v", tt.name, got, err)
+		}
+	}
+}
+
+func TestLang_Check(t *testing.T) {
+	tests := []struct {
+		lang ext.Lang
+		exts ext.Set
+		err  string
+	}{
+		{ext.Default, 0, ""},
+		{ext.Default, ext.Bool | ext.Strings, ""},
+		{ext.SPL12, 0, ""},
+		{ext.SPL12, ext.Bool | ext.Strings, "language extensions bool,strings can't be enabled with language version spl1.2"},
+	}
+	for _, tt := range tests {
+		err := tt.lang.Check(tt.exts)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || err.Error() != tt.err) {
+			t.Errorf("%v.Check(%v): got error %v, want %q", tt.lang, tt.exts, err, tt.err)
+		}
+	}
+}
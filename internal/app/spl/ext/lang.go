@@ -0,0 +1,54 @@
+package ext
+
+import "fmt"
+
+// Lang is a version of the language.
+type Lang int
+
+// Language versions.
+const (
+	// Default is the language accepted by default: SPL 1.2 with a few
+	// relaxations. Declarations may follow statements and appear in nested
+	// blocks, types may be parenthesized, array lengths may be constant
+	// expressions and # and * are unary operators as well.
+	Default Lang = iota
+
+	// SPL12 is exactly the language of the SPL 1.2 specification. The
+	// relaxations of Default are reported as errors.
+	SPL12
+)
+
+// langs contains the names of the language versions.
+var langs = []string{
+	Default: "",
+	SPL12:   "spl1.2",
+}
+
+// String returns the name of the language version. The name of Default is
+// empty.
+func (l Lang) String() string {
+	if 0 <= l && int(l) < len(langs) {
+		return langs[l]
+	}
+	return fmt.Sprintf("Lang(%d)", int(l))
+}
+
+// Check reports an error if the extensions can't be enabled together with the
+// language version. SPL 1.2 has no extensions.
+func (l Lang) Check(exts Set) error {
+	if l == SPL12 && exts != 0 {
+		return fmt.Errorf("language extensions %s can't be enabled with language version %s", exts, l)
+	}
+	return nil
+}
+
+// ParseLang returns the language version with the given name. The empty name
+// denotes the Default language.
+func ParseLang(name string) (Lang, error) {
+	for l, n := range langs {
+		if n == name {
+			return Lang(l), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown language version %q", name)
+}
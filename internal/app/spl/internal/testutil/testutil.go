@@ -0,0 +1,19 @@
+// Package testutil provides helpers shared by the tests of the spl packages.
+package testutil
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// TempDir creates a new temporary directory and returns it together with a
+// function removing it, which is deferred by the test.
+func TempDir(tb testing.TB) (string, func()) {
+	tb.Helper()
+	dir, err := ioutil.TempDir("", "spl")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return dir, func() { _ = os.RemoveAll(dir) }
+}
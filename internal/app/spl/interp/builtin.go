@@ -0,0 +1,125 @@
+package interp
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// builtins contains the implementations of the library procedures supported
+// by the machine. Arguments are passed as storage like the arguments of
+// declared procedures.
+var builtins map[string]func(m *Machine, args [][]int32)
+
+func init() {
+	builtins = map[string]func(m *Machine, args [][]int32){
+		"printi": printi,
+		"printc": printc,
+		"prints": prints,
+		"readi":  readi,
+		"readc":  readc,
+		"exit":   exit,
+		"time":   now,
+
+		"assertEq": assertEq,
+		"fail":     fail,
+	}
+}
+
+// printi(i: int) writes the decimal representation of i.
+func printi(m *Machine, args [][]int32) {
+	_, _ = m.out.WriteString(strconv.Itoa(int(args[0][0])))
+}
+
+// printc(i: int) writes the character with the ASCII code i.
+func printc(m *Machine, args [][]int32) { _ = m.out.WriteByte(byte(args[0][0])) }
+
+// prints(s: string) writes the characters of s, which are passed one per
+// element of the storage.
+func prints(m *Machine, args [][]int32) {
+	for _, c := range args[0] {
+		_ = m.out.WriteByte(byte(c))
+	}
+}
+
+// readi(ref i: int) reads a line and stores the integer at its beginning in i.
+// Leading blanks and a sign are accepted, everything after the digits is
+// ignored. If there are no digits, 0 is stored.
+func readi(m *Machine, args [][]int32) {
+	_ = m.out.Flush()
+	const (
+		blanks = iota
+		digits
+		rest
+	)
+	var v int32
+	neg, state := false, blanks
+	for {
+		c, err := m.in.ReadByte()
+		if err != nil || c == '\n' {
+			break
+		}
+		switch {
+		case state == rest:
+		case '0' <= c && c <= '9':
+			v = v*10 + int32(c-'0')
+			state = digits
+		case state == digits:
+			state = rest
+		case c == ' ' || c == '\t':
+		case c == '-' || c == '+':
+			neg, state = c == '-', digits
+		default:
+			state = rest
+		}
+	}
+	if neg {
+		v = -v
+	}
+	args[0][0] = v
+}
+
+// readc(ref i: int) reads a single character and stores its ASCII code in i.
+// At the end of the input -1 is stored.
+func readc(m *Machine, args [][]int32) {
+	_ = m.out.Flush()
+	c, err := m.in.ReadByte()
+	if err == io.EOF {
+		args[0][0] = -1
+		return
+	}
+	args[0][0] = int32(c)
+}
+
+// exit() terminates the program.
+func exit(m *Machine, args [][]int32) { panic(abort{errExit}) }
+
+// time(ref i: int) stores the number of seconds since the start of the program
+// in i.
+func now(m *Machine, args [][]int32) { args[0][0] = int32(time.Since(m.start) / time.Second) }
+
+// assertEq(got, want: int) reports a failure of the test if got is not equal
+// to want. The test continues.
+func assertEq(m *Machine, args [][]int32) {
+	if got, want := args[0][0], args[1][0]; got != want {
+		m.fail(fmt.Sprintf("got %d, want %d", got, want))
+	}
+}
+
+// fail() reports a failure of the test and stops it.
+func fail(m *Machine, args [][]int32) {
+	m.fail("fail called")
+	panic(abort{errFailNow})
+}
+
+// fail reports a failure at the statement currently executed.
+func (m *Machine) fail(msg string) {
+	pos := m.frames[len(m.frames)-1].Pos
+	if m.Hooks.Fail == nil {
+		panic(abort{fmt.Errorf("%s:%d: %s", pos.Filename, pos.Line, msg)})
+	}
+	if err := m.Hooks.Fail(pos, msg); err != nil {
+		panic(abort{err})
+	}
+}
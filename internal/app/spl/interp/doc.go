@@ -0,0 +1,5 @@
+// Package interp implements an execution engine which interprets a type
+// checked program. It exposes hook points per statement and procedure
+// activation which are used to build tools like debuggers and profilers on top
+// of it.
+package interp
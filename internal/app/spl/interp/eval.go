@@ -0,0 +1,141 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/constant"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+// FormatValue formats the value of the given type held by the storage. Arrays
+// and records are printed in braces, record fields are prefixed with their
+// names.
+func FormatValue(typ types.Type, mem []int32) string {
+	if types.IsBoolean(typ) {
+		return strconv.FormatBool(mem[0] != 0)
+	}
+	switch t := typ.(type) {
+	case *types.Array:
+		n := int(types.Sizeof(t.Elem()) / 4)
+		elems := make([]string, t.Len())
+		for i := range elems {
+			elems[i] = FormatValue(t.Elem(), mem[i*n:(i+1)*n])
+		}
+		return "{" + strings.Join(elems, ", ") + "}"
+	case *types.Record:
+		fields := make([]string, len(t.Fields()))
+		for i, f := range t.Fields() {
+			off := int(t.Offset(f) / 4)
+			fields[i] = f.Name() + ": " + FormatValue(f.Type(), mem[off:off+int(types.Sizeof(f.Type())/4)])
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+	return strconv.Itoa(int(mem[0]))
+}
+
+// Eval evaluates an expression in the frame and returns the type and storage
+// of its value. Variables are the parameters and local variables of the
+// frame, reference parameters evaluate to what they refer to. Array elements
+// are bounds checked and arithmetic wraps around. The storage of variables and
+// array elements is shared with the program.
+func (f *Frame) Eval(x string) (types.Type, []int32, error) {
+	e, err := parser.ParseExpr(x)
+	if err != nil {
+		return nil, nil, err
+	}
+	v, err := f.eval(e)
+	return v.typ, v.mem, err
+}
+
+// value is the value of an evaluated expression.
+type value struct {
+	typ types.Type
+	mem []int32
+}
+
+func (f *Frame) eval(e ast.Expr) (value, error) {
+	switch e := e.(type) {
+	case *ast.IntLit:
+		i, err := constant.Int(e.Value)
+		if err != nil {
+			return value{}, fmt.Errorf("invalid number %s: %s", e.Value, err)
+		}
+		return integer(i), nil
+	case *ast.Ident:
+		for _, v := range f.Vars() {
+			if v.Name() == e.Name {
+				return value{v.Type(), f.vars[v]}, nil
+			}
+		}
+		return value{}, fmt.Errorf("no symbol %q in current context", e.Name)
+	case *ast.ParenExpr:
+		return f.eval(e.X)
+	case *ast.IndexExpr:
+		x, err := f.eval(e.X)
+		if err != nil {
+			return value{}, err
+		}
+		a, ok := x.typ.(*types.Array)
+		if !ok {
+			return value{}, fmt.Errorf("cannot index %s, it is not an array", types.ExprString(e.X))
+		}
+		i, err := f.evalInt(e.Index)
+		if err != nil {
+			return value{}, err
+		}
+		if i < 0 || int64(i) >= a.Len() {
+			return value{}, fmt.Errorf("index %d out of range [0, %d)", i, a.Len())
+		}
+		n := int(types.Sizeof(a.Elem()) / 4)
+		return value{a.Elem(), x.mem[int(i)*n : int(i+1)*n]}, nil
+	case *ast.UnaryExpr:
+		x, err := f.evalInt(e.X)
+		if err != nil {
+			return value{}, err
+		}
+		return integer(-x), nil
+	case *ast.BinaryExpr:
+		x, err := f.evalInt(e.X)
+		if err != nil {
+			return value{}, err
+		}
+		y, err := f.evalInt(e.Y)
+		if err != nil {
+			return value{}, err
+		}
+		switch e.Op {
+		case token.ADD:
+			return integer(x + y), nil
+		case token.SUB:
+			return integer(x - y), nil
+		case token.MUL:
+			return integer(x * y), nil
+		case token.QUO:
+			if y == 0 {
+				return value{}, errors.New("division by zero")
+			}
+			return integer(x / y), nil
+		}
+		return value{}, fmt.Errorf("operator %s is not supported", e.Op)
+	}
+	return value{}, fmt.Errorf("cannot evaluate %s", types.ExprString(e))
+}
+
+func (f *Frame) evalInt(e ast.Expr) (int32, error) {
+	v, err := f.eval(e)
+	if err != nil {
+		return 0, err
+	}
+	if !types.IsInteger(v.typ) {
+		return 0, fmt.Errorf("%s is not an integer", types.ExprString(e))
+	}
+	return v.mem[0], nil
+}
+
+func integer(i int32) value { return value{types.Typ[types.Int], []int32{i}} }
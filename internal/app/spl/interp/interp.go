@@ -0,0 +1,445 @@
+package interp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/diag"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+// MaxDepth is the maximum number of procedure activations.
+const MaxDepth = 1 << 16
+
+// Hooks are called by a Machine while it executes a program. Nil hooks are
+// ignored. A hook which returns an error stops the execution and Run returns
+// the error.
+type Hooks struct {
+	// Stmt is called before a statement is executed. Block statements are
+	// not reported. For while statements it is called before each
+	// evaluation of the condition.
+	Stmt func(s ast.Stmt) error
+
+	// Call is called after a procedure has been entered, before its first
+	// statement is executed.
+	Call func(f *Frame) error
+
+	// Return is called before a procedure returns.
+	Return func(f *Frame) error
+
+	// Fail is called when an assertion of a test fails. pos is the position
+	// of the statement which called the assertion. If it is nil, the
+	// failure stops the execution and is returned by Run.
+	Fail func(pos token.Position, msg string) error
+}
+
+// RuntimeError is an error which occurred during the execution of a program.
+type RuntimeError struct {
+	Pos token.Position
+	Msg string
+}
+
+// Error implements the error interface.
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("%s:%d: runtime error: %s", e.Pos.Filename, e.Pos.Line, e.Msg)
+}
+
+// Frame is the activation record of a procedure.
+type Frame struct {
+	// Proc is the activated procedure.
+	Proc *types.Proc
+
+	// Call is the call which activated the procedure. It is nil for the
+	// main procedure.
+	Call *ast.CallExpr
+
+	// Pos is the position of the statement currently executed.
+	Pos token.Position
+
+	vars   map[*types.Var][]int32
+	result int32
+}
+
+// Value returns the storage of a parameter or local variable of the
+// procedure. The storage of a reference parameter is the storage of the
+// variable or array element it refers to. Integers occupy one element, arrays
+// are laid out contiguously.
+func (f *Frame) Value(v *types.Var) []int32 { return f.vars[v] }
+
+// Vars returns the parameters and local variables of the procedure.
+func (f *Frame) Vars() []*types.Var {
+	return append(append([]*types.Var(nil), f.Proc.Params()...), f.Proc.Locals()...)
+}
+
+// Machine executes a type checked program.
+type Machine struct {
+	// Hooks are called during the execution.
+	Hooks Hooks
+
+	info   *types.Info
+	main   *types.Proc
+	in     *bufio.Reader
+	out    *bufio.Writer
+	start  time.Time
+	frames []*Frame
+}
+
+// New returns a machine which executes the type checked program. It reads
+// input from stdin and writes output to stdout. Procedures of the graphics
+// library are not supported and reported as error.
+func New(prog *ast.Program, info *types.Info, stdin io.Reader, stdout io.Writer) (*Machine, error) {
+	m := &Machine{
+		info: info,
+		in:   bufio.NewReader(stdin),
+		out:  bufio.NewWriter(stdout),
+	}
+	var errs parser.ErrorList
+	for _, proc := range info.Procs {
+		if proc.LinkName() == "main" {
+			m.main = proc
+		}
+		ast.Inspect(proc.Decl().Body, func(n ast.Node) bool {
+			if x, ok := n.(*ast.CallExpr); ok {
+				p := info.Callee(x)
+				if _, ok := builtins[p.Name()]; p.Builtin() && !ok {
+					errs.Report(&diag.Diagnostic{
+						Code: diag.Unsupported,
+						Pos:  x.Pos(),
+						End:  x.End(),
+						Msg:  fmt.Sprintf("procedure %s is not supported by the interpreter", p.Name()),
+					})
+				}
+			}
+			return true
+		})
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Frames returns the activation records of the procedures which are currently
+// executed, the innermost one last. After a runtime error occurred, the frames
+// at the time of the error are retained.
+func (m *Machine) Frames() []*Frame { return m.frames }
+
+// Flush writes any buffered output of the program.
+func (m *Machine) Flush() error { return m.out.Flush() }
+
+// Run executes the program. Output is flushed when the program terminates.
+func (m *Machine) Run() error { return m.RunProc(m.main) }
+
+// RunProc executes the parameterless procedure proc instead of the main
+// procedure, e.g. a test. Output is flushed when the procedure returns.
+func (m *Machine) RunProc(proc *types.Proc) (err error) {
+	m.start = time.Now()
+	m.frames = m.frames[:0]
+	defer func() {
+		if r := recover(); r != nil {
+			a, ok := r.(abort)
+			if !ok {
+				panic(r)
+			}
+			if a.err == errExit || a.err == errFailNow {
+				m.frames = m.frames[:0]
+			} else {
+				err = a.err
+			}
+		}
+		if ferr := m.out.Flush(); err == nil {
+			err = ferr
+		}
+	}()
+	m.call(proc, nil, nil)
+	return nil
+}
+
+// abort is used to unwind the execution when the program terminates early.
+type abort struct{ err error }
+
+// errExit is the error used to terminate the program by the exit procedure.
+var errExit = fmt.Errorf("exit")
+
+// errFailNow is the error used to stop a test by the fail procedure.
+var errFailNow = fmt.Errorf("fail")
+
+func (m *Machine) errorf(pos token.Position, format string, args ...interface{}) {
+	panic(abort{&RuntimeError{Pos: pos, Msg: fmt.Sprintf(format, args...)}})
+}
+
+// -----------------------------------------------------------------------------
+// Procedures
+
+// call activates a procedure and returns its result, which is zero for
+// procedures without result. Value parameters are copied, reference
+// parameters share the storage of their arguments. Local variables are zero
+// initialized unless they have an initial value. On a runtime error the frames
+// are not popped.
+func (m *Machine) call(proc *types.Proc, x *ast.CallExpr, args [][]int32) int32 {
+	if proc.Builtin() {
+		builtins[proc.Name()](m, args)
+		return 0
+	}
+	if len(m.frames) == MaxDepth {
+		m.errorf(x.Pos(), "stack overflow")
+	}
+
+	f := &Frame{Proc: proc, Call: x, Pos: proc.Decl().Body.Pos(), vars: make(map[*types.Var][]int32)}
+	for i, v := range proc.Params() {
+		if v.IsRef() {
+			f.vars[v] = args[i]
+		} else {
+			f.vars[v] = []int32{args[i][0]}
+		}
+	}
+	size := 0
+	for _, v := range proc.Locals() {
+		size += int(types.Sizeof(v.Type()) / 4)
+	}
+	mem := make([]int32, size)
+	for _, v := range proc.Locals() {
+		n := int(types.Sizeof(v.Type()) / 4)
+		f.vars[v], mem = mem[:n:n], mem[n:]
+		if init := v.Init(); init != nil {
+			copy(f.vars[v], chars(m.info.Strings[init]))
+		}
+	}
+
+	m.frames = append(m.frames, f)
+	m.hook(m.Hooks.Call, f)
+	m.stmt(proc.Decl().Body)
+	m.hook(m.Hooks.Return, f)
+	m.frames = m.frames[:len(m.frames)-1]
+	return f.result
+}
+
+func (m *Machine) hook(h func(*Frame) error, f *Frame) {
+	if h != nil {
+		if err := h(f); err != nil {
+			panic(abort{err})
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Statements
+
+// stmt executes a statement and reports whether it executed a return
+// statement.
+func (m *Machine) stmt(s ast.Stmt) bool {
+	switch s := s.(type) {
+	case *ast.BlockStmt:
+		for _, s := range s.List {
+			if m.stmt(s) {
+				return true
+			}
+		}
+	case *ast.ExprStmt:
+		m.before(s)
+		m.callExpr(unparen(s.X).(*ast.CallExpr))
+	case *ast.AssignStmt:
+		m.before(s)
+		// The left hand side is evaluated before the right hand side.
+		a := m.addr(s.Left)
+		a[0] = m.expr(s.Right)
+	case *ast.IfStmt:
+		m.before(s)
+		if m.cond(s.Cond) {
+			return m.stmt(s.Body)
+		} else if s.Else != nil {
+			return m.stmt(s.Else)
+		}
+	case *ast.WhileStmt:
+		for {
+			m.before(s)
+			if !m.cond(s.Cond) {
+				break
+			}
+			if m.stmt(s.Body) {
+				return true
+			}
+		}
+	case *ast.ReturnStmt:
+		m.before(s)
+		if s.Result != nil {
+			m.frames[len(m.frames)-1].result = m.expr(s.Result)
+		}
+		return true
+	}
+	return false
+}
+
+// before records the position of the statement and calls the statement hook.
+func (m *Machine) before(s ast.Stmt) {
+	m.frames[len(m.frames)-1].Pos = s.Pos()
+	if m.Hooks.Stmt != nil {
+		if err := m.Hooks.Stmt(s); err != nil {
+			panic(abort{err})
+		}
+	}
+}
+
+// callExpr evaluates the arguments from left to right, calls the procedure
+// and returns its result. Reference arguments are passed as storage, value
+// arguments as storage holding a copy of the value.
+func (m *Machine) callExpr(x *ast.CallExpr) int32 {
+	proc := m.info.Callee(x)
+	params := proc.Params()
+	args := make([][]int32, len(x.Args))
+	for i, arg := range x.Args {
+		switch {
+		case params[i].IsRef():
+			args[i] = m.addr(arg)
+		case params[i].Type() == types.Typ[types.String]:
+			args[i] = chars(m.info.Strings[arg])
+		default:
+			args[i] = []int32{m.expr(arg)}
+		}
+	}
+	return m.call(proc, x, args)
+}
+
+// -----------------------------------------------------------------------------
+// Expressions
+
+// cond evaluates a boolean expression. The right operand of a logical operator
+// is evaluated only if it determines the result.
+func (m *Machine) cond(e ast.Expr) bool {
+	if v, ok := m.info.Values[e]; ok {
+		return v != 0
+	}
+	var b *ast.BinaryExpr
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return m.cond(e.X)
+	case *ast.UnaryExpr:
+		return !m.cond(e.X)
+	case *ast.BinaryExpr:
+		b = e
+	default:
+		return m.expr(e) != 0
+	}
+	switch b.Op {
+	case token.LAND:
+		return m.cond(b.X) && m.cond(b.Y)
+	case token.LOR:
+		return m.cond(b.X) || m.cond(b.Y)
+	}
+	x, y := m.expr(b.X), m.expr(b.Y)
+	switch b.Op {
+	case token.EQL:
+		return x == y
+	case token.NOT:
+		return x != y
+	case token.LSS:
+		return x < y
+	case token.LEQ:
+		return x <= y
+	case token.GTR:
+		return x > y
+	case token.GEQ:
+		return x >= y
+	}
+	panic(fmt.Sprintf("interp: unexpected operator %s", b.Op))
+}
+
+// expr evaluates an integer or boolean expression. Arithmetic wraps around,
+// booleans evaluate to 0 or 1.
+func (m *Machine) expr(e ast.Expr) int32 {
+	if v, ok := m.info.Values[e]; ok {
+		return v
+	}
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return m.expr(e.X)
+	case *ast.UnaryExpr:
+		if e.Op == token.LNOT {
+			return boolean(!m.cond(e.X))
+		}
+		return -m.expr(e.X)
+	case *ast.BinaryExpr:
+		if types.IsBoolean(m.info.Types[e]) {
+			return boolean(m.cond(e))
+		}
+		x, y := m.expr(e.X), m.expr(e.Y)
+		switch e.Op {
+		case token.ADD:
+			return x + y
+		case token.SUB:
+			return x - y
+		case token.MUL:
+			return x * y
+		case token.QUO:
+			if y == 0 {
+				m.errorf(e.OpPos, "integer divide by zero")
+			}
+			return x / y
+		}
+	case *ast.Ident, *ast.IndexExpr, *ast.SelectorExpr:
+		return m.addr(e)[0]
+	case *ast.CallExpr:
+		return m.callExpr(e)
+	}
+	panic(fmt.Sprintf("interp: unexpected expression %T", e))
+}
+
+// chars returns the characters of s as stored in memory, one per element.
+func chars(s string) []int32 {
+	c := make([]int32, len(s))
+	for i := range s {
+		c[i] = int32(s[i])
+	}
+	return c
+}
+
+// boolean returns the value of b as stored in memory.
+func boolean(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// addr returns the storage of a variable, array element or record field. Array
+// indices are bounds checked.
+func (m *Machine) addr(e ast.Expr) []int32 {
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return m.addr(e.X)
+	case *ast.Ident:
+		return m.frames[len(m.frames)-1].vars[m.info.Uses[e].(*types.Var)]
+	case *ast.IndexExpr:
+		a := m.info.Types[e.X].(*types.Array)
+		x := m.addr(e.X)
+		i := m.expr(e.Index)
+		if i < 0 || int64(i) >= a.Len() {
+			m.errorf(e.Lbrack, "index out of range")
+		}
+		n := int(types.Sizeof(a.Elem()) / 4)
+		return x[int(i)*n : int(i+1)*n : int(i+1)*n]
+	case *ast.SelectorExpr:
+		r := m.info.Types[e.X].(*types.Record)
+		f := m.info.Uses[e.Sel].(*types.Var)
+		off := int(r.Offset(f) / 4)
+		end := off + int(types.Sizeof(f.Type())/4)
+		return m.addr(e.X)[off:end:end]
+	}
+	panic(fmt.Sprintf("interp: unexpected operand %T", e))
+}
+
+// unparen returns e with any enclosing parentheses stripped.
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		p, ok := e.(*ast.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.X
+	}
+}
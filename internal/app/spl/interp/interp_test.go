@@ -0,0 +1,306 @@
+package interp_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/ext"
+	"github.com/lukasmalkmus/spl/internal/app/spl/internal/testutil"
+	"github.com/lukasmalkmus/spl/internal/app/spl/interp"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+// exts are the language extensions the test programs may use.
+const exts = ext.Functions | ext.Bool | ext.Records | ext.Strings
+
+func TestMachine_FullValidProgram(t *testing.T) {
+	var out bytes.Buffer
+	m := newMachine(t, parseFile(t, "../testdata/valid.spl"), "", &out)
+	if err := m.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out.String(), "\n\n"); n != 92 {
+		t.Errorf("got %d solutions, want 92", n)
+	}
+	if want := " 0 . . . . . . .\n . . . . 0 . . .\n"; !strings.HasPrefix(out.String(), want) {
+		t.Errorf("got output starting with %q, want %q", out.String()[:len(want)], want)
+	}
+}
+
+func TestMachine(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		input  string
+		output string
+		err    string
+	}{
+		{
+			"print",
+			"proc main() { printi(-42); printc(' '); printi(-2147483647 - 1); printc('\\n'); }",
+			"",
+			"-42 -2147483648\n",
+			"",
+		},
+		{
+			"arithmetic",
+			"proc main() { printi(7 / 2); printc(' '); printi(-7 / 2); printc(' '); printi(3 * -5 + 1); }",
+			"",
+			"3 -3 -14",
+			"",
+		},
+		{
+			"wraparound",
+			"proc main() { var i: int; i := -2147483647 - 1; printi(i / -1); printc(' '); printi(i - 1); }",
+			"",
+			"-2147483648 2147483647",
+			"",
+		},
+		{
+			"read",
+			"proc main() { var i: int; readi(i); printi(i * 2); readc(i); printi(i); readc(i); printi(i); }",
+			"  -17\nZ",
+			"-3490-1",
+			"",
+		},
+		{
+			"ref",
+			"type A = array [2] of int;\nproc inc(ref i: int) { i := i + 1; }\nproc main() { var a: A; inc(a[1]); inc(a[1]); printi(a[0]); printi(a[1]); }",
+			"",
+			"02",
+			"",
+		},
+		{
+			"functions",
+			"proc fib(n: int): int { if (n < 2) return n; return fib(n - 1) + fib(n - 2); }\nproc hello() { printc('h'); return; printc('x'); }\nproc main() { printi(fib(10) * 2); hello(); }",
+			"",
+			"110h",
+			"",
+		},
+		{
+			"bool",
+			"proc inRange(i: int, n: int): bool { return 0 <= i and i < n; }\nproc flip(ref b: bool) { b := not b; }\nproc main() { var a: array [3] of bool; var b: bool; var i: int; a[1] := true; flip(b); while (inRange(i, 3)) { if (a[i] or i = 2 and b) printi(i); i := i + 1; } b := i < 0 and 1 / 0 = 0; if (not b or 1 / 0 = 0) printc('x'); }",
+			"",
+			"12x",
+			"",
+		},
+		{
+			"records",
+			"type point = record { x: int; y: int; };\ntype line = record { from: point; to: point; };\nproc move(ref p: point, d: int) { p.x := p.x + d; p.y := (p).y - d; }\nproc main() { var l: line; var ps: array [2] of point; l.to.x := 5; move(l.to, 2); ps[1].y := l.to.x; move(ps[1], 1); printi(l.from.x); printi(l.to.x); printi(l.to.y); printi(ps[1].x); printi(ps[1].y); }",
+			"",
+			"07-216",
+			"",
+		},
+		{
+			"strings",
+			"proc main() { var b: array [4] of int := \"ok\"; prints(\"a\\tb\\n\"); printc(b[0]); printc(b[1]); printi(b[2]); prints((\"\\\"\\\\\")); }",
+			"",
+			"a\tb\nok0\"\\",
+			"",
+		},
+		{
+			"exit",
+			"proc main() { printi(1); exit(); printi(2); }",
+			"",
+			"1",
+			"",
+		},
+		{
+			"index out of range",
+			"type A = array [3] of int;\nproc main() {\n  var a: A;\n  printi(1);\n  a[3] := 1;\n}",
+			"",
+			"1",
+			"test.spl:5: runtime error: index out of range",
+		},
+		{
+			"divide by zero",
+			"proc main() {\n  var i: int;\n  printi(1 / i);\n}",
+			"",
+			"",
+			"test.spl:3: runtime error: integer divide by zero",
+		},
+		{
+			"stack overflow",
+			"proc f() { f(); }\nproc main() { f(); }",
+			"",
+			"",
+			"test.spl:1: runtime error: stack overflow",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testutil.TempDir(t)
+			defer cleanup()
+			filename := filepath.Join(dir, "test.spl")
+			var out bytes.Buffer
+			m := newMachine(t, parse(t, filename, tt.src), tt.input, &out)
+			var got string
+			if err := m.Run(); err != nil {
+				got = strings.TrimPrefix(err.Error(), filepath.Dir(filename)+string(filepath.Separator))
+			}
+			if got != tt.err {
+				t.Errorf("got error %q, want %q", got, tt.err)
+			}
+			if got := out.String(); got != tt.output {
+				t.Errorf("got output %q, want %q", got, tt.output)
+			}
+		})
+	}
+}
+
+func TestMachine_Hooks(t *testing.T) {
+	src := "proc f(ref i: int) {\n  i := 1;\n}\nproc main() {\n  var i: int;\n  while (i < 1) {\n    f(i);\n  }\n}"
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	m := newMachine(t, parse(t, filepath.Join(dir, "test.spl"), src), "", &bytes.Buffer{})
+
+	var events []string
+	m.Hooks = interp.Hooks{
+		Stmt: func(s ast.Stmt) error {
+			events = append(events, fmt.Sprintf("stmt %s%d", strings.Repeat("  ", len(m.Frames())-1), s.Pos().Line))
+			return nil
+		},
+		Call: func(f *interp.Frame) error {
+			events = append(events, "call "+f.Proc.Name())
+			return nil
+		},
+		Return: func(f *interp.Frame) error {
+			if params := f.Proc.Params(); len(params) > 0 {
+				events = append(events, fmt.Sprintf("return %s %d", f.Proc.Name(), f.Value(params[0])[0]))
+			} else {
+				events = append(events, "return "+f.Proc.Name())
+			}
+			return nil
+		},
+	}
+	if err := m.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"call main",
+		"stmt 6",
+		"stmt 7",
+		"call f",
+		"stmt   2",
+		"return f 1",
+		"stmt 6",
+		"return main",
+	}
+	if got := strings.Join(events, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("got events\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestMachine_HookError(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	m := newMachine(t, parse(t, filepath.Join(dir, "test.spl"), "proc main() { printi(1); printi(2); }"), "", &bytes.Buffer{})
+	stop := errors.New("stop")
+	m.Hooks.Stmt = func(s ast.Stmt) error {
+		if s.Pos().Column > 20 {
+			return stop
+		}
+		return nil
+	}
+	if err := m.Run(); err != stop {
+		t.Errorf("got error %v, want %v", err, stop)
+	}
+	if n := len(m.Frames()); n != 1 {
+		t.Errorf("got %d frames, want 1", n)
+	}
+}
+
+func TestMachine_RunProc(t *testing.T) {
+	src := `proc testEq() {
+  assertEq(1 + 1, 2);
+  assertEq(2 * 2, 5);
+  assertEq(3, 3);
+  fail();
+  printi(1);
+}`
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	prog := parse(t, filepath.Join(dir, "test_test.spl"), src)
+	info, err := (&types.Config{Test: true}).Check(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	m, err := interp.New(prog, info, nil, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failures []string
+	m.Hooks.Fail = func(pos token.Position, msg string) error {
+		failures = append(failures, fmt.Sprintf("%d: %s", pos.Line, msg))
+		return nil
+	}
+	if err := m.RunProc(info.Procs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(failures, "\n"), "3: got 4, want 5\n5: fail called"; got != want {
+		t.Errorf("got failures\n%s\nwant\n%s", got, want)
+	}
+	if out.Len() != 0 {
+		t.Errorf("got output %q after fail, want none", out.String())
+	}
+
+	m.Hooks.Fail = nil
+	if err := m.RunProc(info.Procs[0]); err == nil || !strings.HasSuffix(err.Error(), "test_test.spl:3: got 4, want 5") {
+		t.Errorf("got error %v, want failure", err)
+	}
+}
+
+func TestNew_UnsupportedProcedure(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	prog := parse(t, filepath.Join(dir, "test.spl"), "proc main() { clearAll(0); }")
+	info, err := types.Check(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = interp.New(prog, info, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "procedure clearAll is not supported by the interpreter") {
+		t.Errorf("got error %v, want unsupported procedure", err)
+	}
+}
+
+// parse writes the source code to the file and parses it.
+func parse(tb testing.TB, filename, src string) *ast.Program {
+	tb.Helper()
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		tb.Fatal(err)
+	}
+	return parseFile(tb, filename)
+}
+
+func parseFile(tb testing.TB, filename string) *ast.Program {
+	tb.Helper()
+	prog, err := parser.ParseFiles(token.NewFileSet(), []string{filename}, exts, parser.DeclarationErrors|parser.Testing)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return prog
+}
+
+func newMachine(tb testing.TB, prog *ast.Program, input string, out io.Writer) *interp.Machine {
+	tb.Helper()
+	info, err := types.Check(prog)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	m, err := interp.New(prog, info, strings.NewReader(input), out)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return m
+}
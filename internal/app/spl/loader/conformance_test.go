@@ -0,0 +1,126 @@
+package loader_test
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/diag"
+	"github.com/lukasmalkmus/spl/internal/app/spl/ext"
+	"github.com/lukasmalkmus/spl/internal/app/spl/loader"
+)
+
+// The expected diagnostics of the conformance corpus are given by a trailing
+// ERROR comment on the line they are reported at. It lists the codes of the
+// diagnostics, each followed by a quoted regular expression matching the
+// message:
+//
+//	i := +1; // ERROR E0106 "unary operator \+ is not allowed" default:E0211 "invalid unary operator \+"
+//
+// A code prefixed by the name of a language version and a colon is only
+// reported in that version. Diagnostics with the code E0106 are only reported
+// in SPL 1.2.
+var (
+	errorTag  = regexp.MustCompile(`// ERROR (.*)$`)
+	errorCode = regexp.MustCompile(`(?:(\S+):)?(E\d{4}) "((?:[^"\\]|\\.)*)"`)
+)
+
+type expectedError struct {
+	line    int
+	code    diag.Code
+	msg     *regexp.Regexp
+	matched bool
+}
+
+// TestConformance loads the programs of the conformance corpus, which are
+// taken from the examples and the rules of the SPL 1.2 specification. Programs
+// without ERROR tags must be valid. In the default language, constructs beyond
+// the specification are accepted.
+func TestConformance(t *testing.T) {
+	files, err := filepath.Glob("../testdata/conformance/*.spl")
+	if err != nil || len(files) == 0 {
+		t.Fatal("no conformance programs:", err)
+	}
+	for _, file := range files {
+		for _, lang := range []ext.Lang{ext.SPL12, ext.Default} {
+			name := strings.TrimSuffix(filepath.Base(file), ".spl") + "/" + langName(lang)
+			t.Run(name, func(t *testing.T) {
+				want := expectedErrors(t, file, lang)
+				conf := &loader.Config{Lang: lang}
+				_, _, err := conf.Load(file)
+				var list diag.List
+				if err != nil {
+					var ok bool
+					if list, ok = err.(diag.List); !ok {
+						t.Fatal(err)
+					}
+				}
+			L:
+				for _, d := range list {
+					for _, e := range want {
+						if !e.matched && e.line == d.Pos.Line && e.code == d.Code && e.msg.MatchString(d.Msg) {
+							e.matched = true
+							continue L
+						}
+					}
+					t.Errorf("unexpected %s at line %d: %s", d.Code, d.Pos.Line, d.Msg)
+				}
+				for _, e := range want {
+					if !e.matched {
+						t.Errorf("missing %s at line %d: %s", e.code, e.line, e.msg)
+					}
+				}
+			})
+		}
+	}
+}
+
+// langName returns the name of the language version used by ERROR tags.
+func langName(lang ext.Lang) string {
+	if lang == ext.Default {
+		return "default"
+	}
+	return lang.String()
+}
+
+// expectedErrors returns the errors tagged in the file which are reported in
+// the language version lang.
+func expectedErrors(tb testing.TB, filename string, lang ext.Lang) []*expectedError {
+	tb.Helper()
+	f, err := os.Open(filename)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+
+	var errs []*expectedError
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		m := errorTag.FindStringSubmatch(s.Text())
+		if m == nil {
+			continue
+		}
+		codes := errorCode.FindAllStringSubmatch(m[1], -1)
+		if len(codes) == 0 {
+			tb.Fatalf("%s:%d: invalid ERROR tag", filename, line)
+		}
+		for _, c := range codes {
+			code := diag.Code(c[2])
+			if c[1] != "" && c[1] != langName(lang) || code == diag.NotInSpec && lang != ext.SPL12 {
+				continue
+			}
+			msg, err := regexp.Compile(c[3])
+			if err != nil {
+				tb.Fatalf("%s:%d: invalid ERROR tag: %v", filename, line, err)
+			}
+			errs = append(errs, &expectedError{line: line, code: code, msg: msg})
+		}
+	}
+	if err := s.Err(); err != nil {
+		tb.Fatal(err)
+	}
+	return errs
+}
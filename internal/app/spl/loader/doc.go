@@ -0,0 +1,3 @@
+// Package loader loads programs: It parses the source files of a program and
+// the modules they import and type checks the result.
+package loader
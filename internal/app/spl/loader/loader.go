@@ -0,0 +1,107 @@
+package loader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/ext"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+// Config configures the loading of programs.
+type Config struct {
+	// Extensions are the language extensions enabled while parsing.
+	Extensions ext.Set
+
+	// Lang is the language version of the source files. Constructs beyond
+	// the SPL 1.2 specification are errors if it is ext.SPL12.
+	Lang ext.Lang
+
+	// Path lists the directories searched for imported modules. Modules
+	// are searched relative to the directory of the importing source file
+	// first.
+	Path []string
+
+	// Test enables the checking of tests, see types.Config.
+	Test bool
+
+	// Fset is the file set the source files are added to. If nil, a new
+	// file set is used.
+	Fset *token.FileSet
+}
+
+// Load parses and type checks the source files as a single program. Errors in
+// the source code are returned as parser.ErrorList.
+func (conf *Config) Load(filenames ...string) (*ast.Program, *types.Info, error) {
+	if conf.Fset == nil {
+		conf.Fset = token.NewFileSet()
+	}
+	prog, err := parser.ParseFiles(conf.Fset, filenames, conf.Extensions, conf.mode())
+	if err != nil {
+		return nil, nil, err
+	}
+	i := &importer{conf: conf, modules: make(map[string]*module)}
+	tconf := &types.Config{Test: conf.Test, Importer: i.importModule}
+	info, err := tconf.Check(prog)
+	if err != nil {
+		return nil, nil, err
+	}
+	return prog, info, nil
+}
+
+// mode returns the parser mode for the language version.
+func (conf *Config) mode() parser.Mode {
+	mode := parser.DeclarationErrors
+	if conf.Lang == ext.SPL12 {
+		mode |= parser.Strict
+	}
+	if conf.Test {
+		mode |= parser.Testing
+	}
+	return mode
+}
+
+// importer parses imported modules. Each module is parsed once.
+type importer struct {
+	conf    *Config
+	modules map[string]*module
+}
+
+type module struct {
+	prog *ast.Program
+	err  error
+}
+
+func (i *importer) importModule(path, dir string) (*ast.Program, error) {
+	filename, err := i.find(path, dir)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := i.modules[filename]; ok {
+		return m.prog, m.err
+	}
+	prog, err := parser.ParseFiles(i.conf.Fset, []string{filename}, i.conf.Extensions, i.conf.mode())
+	i.modules[filename] = &module{prog, err}
+	return prog, err
+}
+
+// find returns the name of the module file imported by path from a source file
+// in dir.
+func (i *importer) find(path, dir string) (string, error) {
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+	dirs := append([]string{dir}, i.conf.Path...)
+	for _, dir := range dirs {
+		filename := filepath.Join(dir, path)
+		if fi, err := os.Stat(filename); err == nil && !fi.IsDir() {
+			return filename, nil
+		}
+	}
+	return "", fmt.Errorf("module not found in any of:\n\t%s", strings.Join(dirs, "\n\t"))
+}
@@ -0,0 +1,61 @@
+package loader_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ext"
+	"github.com/lukasmalkmus/spl/internal/app/spl/internal/testutil"
+	"github.com/lukasmalkmus/spl/internal/app/spl/loader"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+)
+
+func TestConfig_Load(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	write := func(name, src string) string {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+	write("lib/sort.spl", "type vector = array [3] of int;\nproc sort(ref v: vector) {}\n")
+	write("lib/broken.spl", "proc f() {}\nproc f() {}\n")
+	main := write("src/main.spl", `import "sort.spl" as s;
+import "../lib/broken.spl" as a;
+import "../lib/broken.spl" as b;
+
+proc main() {
+  var v: s.vector;
+  s.sort(v);
+}
+`)
+
+	conf := &loader.Config{Extensions: ext.Imports, Path: []string{filepath.Join(dir, "lib")}}
+	_, _, err := conf.Load(main)
+	list, ok := err.(parser.ErrorList)
+	if !ok || len(list) != 1 || list[0].Pos.Filename != filepath.Join(dir, "lib/broken.spl") {
+		t.Fatalf("got error %v, want one error in broken.spl", err)
+	}
+
+	write("lib/broken.spl", "proc f() {}\n")
+	prog, info, err := conf.Load(main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prog.Name != main || len(info.Procs) != 3 {
+		t.Errorf("got program %s with %d procedures", prog.Name, len(info.Procs))
+	}
+
+	conf.Path = nil
+	if _, _, err := conf.Load(main); err == nil || !strings.Contains(err.Error(), "could not import sort.spl") {
+		t.Errorf("got error %v, want module not found", err)
+	}
+}
@@ -0,0 +1,184 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/ext"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+)
+
+// A Mode value is a set of flags (or 0). They control the amount of source
+// code parsed and other optional parser functionality.
+type Mode uint
+
+// Parser modes.
+const (
+	ParseComments     Mode = 1 << iota // parse comments and add them to the AST
+	Trace                              // print a trace of parsed productions
+	DeclarationErrors                  // report declaration errors
+	AllErrors                          // report all errors (not just the first 10)
+	Strict                             // report constructs beyond the SPL 1.2 specification
+	Testing                            // predeclare the library procedures of tests
+)
+
+// maxErrors is the number of errors after which parsing stops unless the
+// AllErrors mode is set.
+const maxErrors = 10
+
+// readSource returns the source code given by src. If src is nil, the file is
+// read.
+func readSource(filename string, src interface{}) ([]byte, error) {
+	if src != nil {
+		switch s := src.(type) {
+		case string:
+			return []byte(s), nil
+		case []byte:
+			return s, nil
+		case *bytes.Buffer:
+			// is io.Reader, but src is already available in []byte form
+			if s != nil {
+				return s.Bytes(), nil
+			}
+		case io.Reader:
+			return ioutil.ReadAll(s)
+		}
+		return nil, errors.New("invalid source")
+	}
+	return ioutil.ReadFile(filename)
+}
+
+// ParseFile parses the source code of a single SPL source file and returns the
+// program it forms. The source code is added to the file set fset and
+// positions carry the filename.
+//
+// If src != nil, ParseFile parses the source from src and the filename is only
+// used when recording position information. The type of the argument for the
+// src parameter must be string, []byte, or io.Reader. If src == nil, ParseFile
+// parses the file specified by filename.
+//
+// The mode parameter controls the amount of source text parsed and other
+// optional parser functionality.
+//
+// If the source couldn't be read, the returned program is nil and the error
+// indicates the specific failure. If the source was read but syntax errors
+// were found, the result is a partial AST and the error is an ErrorList.
+func ParseFile(fset *token.FileSet, filename string, src interface{}, mode Mode) (*ast.Program, error) {
+	if fset == nil {
+		panic("parser.ParseFile: no token.FileSet provided (fset == nil)")
+	}
+	text, err := readSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	prog, errs := parseFiles(fset, []string{filename}, [][]byte{text}, 0, mode)
+	return prog, errs.Err()
+}
+
+// ParseFiles parses the source files into a single program which is named
+// after the first file. The declarations of all files share one package scope:
+// Identifiers are resolved across files and declarations of the same name in
+// different files are reported as redeclaration. The declarations of the
+// program are in the order of the files. The language extensions exts are
+// enabled while parsing.
+//
+// The result is like the one of ParseFile. The error is an ErrorList if any
+// file contains syntax errors.
+func ParseFiles(fset *token.FileSet, filenames []string, exts ext.Set, mode Mode) (*ast.Program, error) {
+	if fset == nil {
+		panic("parser.ParseFiles: no token.FileSet provided (fset == nil)")
+	}
+	if len(filenames) == 0 {
+		return nil, errors.New("no source files")
+	}
+	srcs := make([][]byte, len(filenames))
+	for i, filename := range filenames {
+		src, err := readSource(filename, nil)
+		if err != nil {
+			return nil, err
+		}
+		srcs[i] = src
+	}
+	prog, errs := parseFiles(fset, filenames, srcs, exts, mode)
+	return prog, errs.Err()
+}
+
+// ParseExpr is a convenience function for obtaining the AST of an expression
+// x. The position information recorded in the AST is undefined.
+func ParseExpr(x string) (expr ast.Expr, err error) {
+	var p parser
+	p.init(token.NewFileSet(), "", []byte(x), 0, 0)
+	defer func() {
+		p.recover()
+		if p.errors.Len() > 0 {
+			p.errors.Sort()
+			expr, err = nil, p.errors.Err()
+		}
+	}()
+
+	p.next()
+	expr = p.parseRHS()
+
+	// If a semicolon was inserted, consume it. Report an error if there's more
+	// tokens.
+	if p.tok == token.SEMICOLON {
+		p.next()
+	}
+	p.expect(token.EOF)
+	return expr, nil
+}
+
+// ParseStatement is a convenience function for obtaining the AST of a single
+// statement src. The position information recorded in the AST is undefined.
+func ParseStatement(src string) (stmt ast.Stmt, err error) {
+	var p parser
+	p.init(token.NewFileSet(), "", []byte(src), 0, 0)
+	defer func() {
+		p.recover()
+		err = p.errors.Err()
+	}()
+	p.next()
+	stmt = p.parseStmt()
+	return stmt, nil
+}
+
+// parseFiles parses the source code of the files into a single program and
+// returns it together with the sorted errors.
+func parseFiles(fset *token.FileSet, filenames []string, srcs [][]byte, exts ext.Set, mode Mode) (*ast.Program, ErrorList) {
+	var (
+		p        parser
+		decls    []ast.Decl
+		comments []*ast.Comment
+		errs     ErrorList
+		ok       bool
+	)
+	for i, filename := range filenames {
+		p = parser{}
+		p.init(fset, filename, srcs[i], exts, mode)
+		// The error limit applies to all files together.
+		p.errors = errs
+		var d []ast.Decl
+		d, ok = p.parseFile()
+		decls = append(decls, d...)
+		comments = append(comments, p.comments...)
+		errs = p.errors
+		if !ok {
+			break
+		}
+	}
+	var unresolved []*ast.Ident
+	if ok {
+		unresolved = resolve(&p, decls)
+		errs = p.errors
+	}
+	errs.Sort()
+	return &ast.Program{
+		Name:       filenames[0],
+		Decls:      decls,
+		Unresolved: unresolved,
+		Comments:   comments,
+	}, errs
+}
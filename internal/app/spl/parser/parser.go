@@ -1,36 +1,41 @@
 package parser
 
 import (
-	"errors"
+	"bytes"
 	"fmt"
 	"io"
 	"os"
-	"strings"
 
 	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/diag"
+	"github.com/lukasmalkmus/spl/internal/app/spl/ext"
 	"github.com/lukasmalkmus/spl/internal/app/spl/scanner"
 	"github.com/lukasmalkmus/spl/internal/app/spl/token"
 )
 
-// Parser implements a parser for the simple programing language (SPL). The
+// parser implements a parser for the simple programing language (SPL). The
 // parser initializes a scanner itself which is used for the lexical analysis of
 // the source code.
-type Parser struct {
+type parser struct {
 	scanner *scanner.Scanner
 	errors  ErrorList
+	exts    ext.Set
+
+	// Tracing/debugging
+	mode   Mode // parsing mode
+	trace  bool // == (mode&Trace != 0)
+	indent int  // indentation used for tracing output
+
+	// Comments
+	comments []*ast.Comment
 
 	// Current token
 	tok token.Token
 	lit string
 	pos token.Position
 
-	// Buffered token
-	buf struct {
-		tok token.Token
-		lit string
-		pos token.Position
-		n   int
-	}
+	// End of the previous token
+	prevEnd token.Position
 
 	// Error recovery
 	syncCnt int
@@ -38,114 +43,100 @@ type Parser struct {
 
 	// Non-syntactic parser control
 	exprLev int
-	inRHS   bool
-
-	// Ordinary identifier scopes
-	pkgScope   *ast.Scope
-	topScope   *ast.Scope
-	unresolved []*ast.Ident
+	declOK  bool // declarations are allowed by SPL 1.2 at this point
 }
 
-// New returns a new Parser which is initialized with the provided reader as its
-// source.
-func New(r io.Reader) *Parser {
-	// Init Parser with EOF token. This ensures functions must read the first
-	// token themselves.
-	return &Parser{
-		scanner: scanner.New(r),
-
-		tok: token.EOF,
-	}
+// init prepares the parser to parse the source code of the file filename,
+// which is added to the file set. The language extensions exts are enabled.
+// The first token must be read explicitly.
+func (p *parser) init(fset *token.FileSet, filename string, src []byte, exts ext.Set, mode Mode) {
+	fset.AddFile(filename, src)
+	p.scanner = scanner.NewFile(filename, bytes.NewReader(src))
+	p.scanner.SetErrorHandler(func(pos token.Position, msg string) {
+		p.report(&diag.Diagnostic{Code: diag.InvalidToken, Pos: pos, Msg: msg})
+	})
+	p.exts = exts
+	p.mode = mode
+	p.trace = mode&Trace != 0
+	p.tok = token.EOF
+	p.pos = token.Position{Filename: filename}
 }
 
-// NewFileParser returns a new instance of Parser, but will exclusively take an
-// *os.File as argument instead of the more general io.Reader interface.
-// Therefore it will enhance token positions with the filename.
-func NewFileParser(f *os.File) *Parser {
-	// Init Parser with EOF token. This ensures the first token must be read
-	// explicitly.
-	return &Parser{
-		scanner: scanner.NewFileScanner(f),
+// ----------------------------------------------------------------------------
+// Parsing support
 
-		tok: token.EOF,
-		pos: token.Position{Filename: f.Name()},
+func (p *parser) printTrace(a ...interface{}) {
+	const dots = ". . . . . . . . . . . . . . . . . . . . . . . . . . . . . . . . "
+	const n = len(dots)
+	fmt.Fprintf(traceOutput, "%5d:%3d: ", p.pos.Line, p.pos.Column)
+	i := 2 * p.indent
+	for i > n {
+		fmt.Fprint(traceOutput, dots)
+		i -= n
 	}
+	// i <= n
+	fmt.Fprint(traceOutput, dots[0:i])
+	fmt.Fprintln(traceOutput, a...)
 }
 
-// ParseStatement parses a single SPL statement.
-func ParseStatement(src string) (ast.Stmt, error) {
-	p := New(strings.NewReader(src))
-	p.next()
-	return p.parseStmt(), p.errors
+func trace(p *parser, msg string) *parser {
+	p.printTrace(msg, "(")
+	p.indent++
+	return p
 }
 
-// Feed will provide the parser with a new scanner source, which effectively
-// adds a new source of tokens. This preserves the previous parsing context
-// when parsing new data.
-func (p *Parser) Feed(r io.Reader) { p.scanner = scanner.New(r) }
+// Usage pattern: defer un(trace(p, "..."))
+func un(p *parser) {
+	p.indent--
+	p.printTrace(")")
+}
 
-// Parse parses the source the Parser is initialized with into an AST program.
-func (p *Parser) Parse() (*ast.Program, error) {
-	// If scanning the first token fails, this is probably not a spl source
-	// file.
-	if p.next(); p.errors.Len() != 0 {
-		return nil, errors.New("not a spl source file")
-	}
+// traceOutput is where the Trace mode writes to.
+var traceOutput io.Writer = os.Stdout
 
-	p.openScope()
-	p.pkgScope = p.topScope
-	var decls []ast.Decl
-	for p.tok != token.EOF {
-		decls = append(decls, p.parseDecl(declStart))
-	}
-	p.closeScope()
+// A bailout panic is raised to indicate early termination.
+type bailout struct{}
 
-	// Resolve global identifiers.
-	i := 0
-	for _, ident := range p.unresolved {
-		ident.Obj = p.pkgScope.Lookup(ident.Name)
-		if ident.Obj == nil {
-			p.unresolved[i] = ident
-			i++
+// recover recovers from a bailout panic. Other panics are passed on.
+func (p *parser) recover() {
+	if e := recover(); e != nil {
+		if _, ok := e.(bailout); !ok {
+			panic(e)
 		}
 	}
-
-	return &ast.Program{
-		Name:       p.pos.Filename,
-		Decls:      decls,
-		Unresolved: p.unresolved[0:i],
-	}, p.errors
 }
 
-// ParseExpr parses an expression.
-func ParseExpr(x string) (ast.Expr, error) {
-	p := New(strings.NewReader(x))
-
-	p.openScope()
-	p.pkgScope = p.topScope
-	expr := p.parseRHS()
-	p.closeScope()
+// parseFile parses the declarations of a source file. The result is false if
+// parsing stopped because of too many errors.
+func (p *parser) parseFile() (decls []ast.Decl, ok bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			if _, isBailout := e.(bailout); !isBailout {
+				panic(e)
+			}
+			ok = false
+		}
+	}()
 
-	// If a semicolon was inserted, consume it. Report an error if there's more
-	// tokens.
-	if p.tok == token.SEMICOLON {
-		p.next()
+	p.next()
+	if p.trace {
+		defer un(trace(p, "File"))
 	}
-	p.expect(token.EOF)
 
-	if p.errors.Len() > 0 {
-		p.errors.Sort()
-		return nil, p.errors.Err()
+	for p.tok != token.EOF {
+		decls = append(decls, p.parseDecl(declStart))
 	}
-	return expr, nil
+	return decls, true
 }
 
 // -----------------------------------------------------------------------------
 // Declarations
 
 // parseDecl parses a declaration AST object.
-func (p *Parser) parseDecl(sync map[token.Token]bool) ast.Decl {
+func (p *parser) parseDecl(sync map[token.Token]bool) ast.Decl {
 	switch p.tok {
+	case token.IMPORT:
+		return p.parseImportDecl()
 	case token.VAR:
 		return p.parseVarDecl()
 	case token.TYPE:
@@ -154,61 +145,122 @@ func (p *Parser) parseDecl(sync map[token.Token]bool) ast.Decl {
 		return p.parseProcDecl()
 	}
 	pos := p.pos
-	p.advance(sync)
+	if p.tok == token.IDENT && token.Lookup(p.lit) == token.IMPORT {
+		p.extRequired(ext.Imports, pos, p.tokEnd(), "import declarations")
+		p.advance(sync)
+		return &ast.BadDecl{From: pos, To: p.pos}
+	}
 	p.errorExpected(pos, "declaration")
+	p.advance(sync)
 	return &ast.BadDecl{From: pos, To: p.pos}
 }
 
+// parseImportDecl parses an import declaration AST object.
+func (p *parser) parseImportDecl() *ast.ImportDecl {
+	if p.trace {
+		defer un(trace(p, "ImportDecl"))
+	}
+
+	decl := &ast.ImportDecl{Import: p.expect(token.IMPORT)}
+	if p.tok == token.STRING {
+		decl.Path = &ast.StringLit{ValuePos: p.pos, Value: p.lit}
+		p.next()
+	} else {
+		decl.Path = &ast.StringLit{ValuePos: p.pos}
+		p.errorExpected(p.pos, "import path")
+	}
+	decl.As = p.expect(token.AS)
+	decl.Name = p.parseIdent()
+	p.expectSemi()
+	return decl
+}
+
 // parseVarDecl parses a variable declaration AST object.
-func (p *Parser) parseVarDecl() *ast.VarDecl {
+func (p *parser) parseVarDecl() *ast.VarDecl {
+	if p.trace {
+		defer un(trace(p, "VarDecl"))
+	}
+
 	_ = p.expect(token.VAR)
 	ident := p.parseIdent()
 	_ = p.expect(token.COLON)
-	typ := p.tryType()
+	typ := p.tryIdentOrType()
+	var (
+		assign token.Position
+		value  ast.Expr
+	)
+	if p.tok == token.ASSIGN {
+		if !p.exts.Has(ext.Strings) {
+			p.extRequired(ext.Strings, p.pos, p.tokEnd(), "initial values")
+		}
+		assign = p.pos
+		p.next()
+		value = p.parseRHS()
+	}
 	p.expectSemi()
 	if typ == nil {
-		p.error(ident.NamePos, "missing variable type")
+		p.report(&diag.Diagnostic{
+			Code: diag.MissingType,
+			Pos:  ident.Pos(),
+			End:  ident.End(),
+			Msg:  "missing variable type",
+		})
 	}
 
-	decl := &ast.VarDecl{Name: ident, Type: typ}
-	p.declare(decl, p.topScope, ast.Var, ident)
-	return decl
+	return &ast.VarDecl{Name: ident, Type: typ, Assign: assign, Value: value}
 }
 
 // parseTypeDecl parses a type declaration AST object.
-func (p *Parser) parseTypeDecl() *ast.TypeDecl {
+func (p *parser) parseTypeDecl() *ast.TypeDecl {
+	if p.trace {
+		defer un(trace(p, "TypeDecl"))
+	}
+
 	_ = p.expect(token.TYPE)
 	ident := p.parseIdent()
 	decl := &ast.TypeDecl{Name: ident}
-	p.declare(decl, p.topScope, ast.Typ, ident)
 	decl.Assign = p.expect(token.EQL)
 	decl.Type = p.parseType()
 	p.expectSemi()
 	return decl
 }
 
-func (p *Parser) parseProcDecl() *ast.ProcDecl {
+func (p *parser) parseProcDecl() *ast.ProcDecl {
+	if p.trace {
+		defer un(trace(p, "ProcDecl"))
+	}
+
 	pos := p.expect(token.PROC)
-	scope := ast.NewScope(p.topScope)
 	ident := p.parseIdent()
-	params := p.parseParameters(scope)
-	body := p.parseBody(scope)
+	params := p.parseParameters()
+	var result ast.Expr
+	if p.tok == token.COLON {
+		if !p.exts.Has(ext.Functions) {
+			p.extRequired(ext.Functions, p.pos, p.tokEnd(), "result types")
+		}
+		p.next()
+		result = p.parseType()
+	}
+	body := p.parseBody()
 
-	decl := &ast.ProcDecl{
+	return &ast.ProcDecl{
 		Name:   ident,
 		Proc:   pos,
 		Params: params,
+		Result: result,
 		Body:   body,
 	}
-	p.declare(decl, p.pkgScope, ast.Pro, ident)
-	return decl
 }
 
 // -----------------------------------------------------------------------------
 // Identifiers
 
 // parseIdent parses an identifier AST object.
-func (p *Parser) parseIdent() *ast.Ident {
+func (p *parser) parseIdent() *ast.Ident {
+	if p.trace {
+		defer un(trace(p, "Ident"))
+	}
+
 	pos := p.pos
 	var name string
 	if p.tok == token.IDENT {
@@ -223,28 +275,23 @@ func (p *Parser) parseIdent() *ast.Ident {
 // ----------------------------------------------------------------------------
 // Common productions
 
-func (p *Parser) parseLHS() ast.Expr {
-	old := p.inRHS
-	p.inRHS = false
-	x := p.checkExpr(p.parseExpr(true))
-	p.resolve(x)
-	p.inRHS = old
-	return x
+func (p *parser) parseLHS() ast.Expr {
+	return p.checkExpr(p.parseExpr())
 }
 
-func (p *Parser) parseRHS() ast.Expr {
-	old := p.inRHS
-	p.inRHS = true
-	x := p.checkExpr(p.parseExpr(false))
-	p.inRHS = old
-	return x
+func (p *parser) parseRHS() ast.Expr {
+	return p.checkExpr(p.parseExpr())
 }
 
 // -----------------------------------------------------------------------------
 // Types
 
-func (p *Parser) parseType() ast.Expr {
-	typ := p.tryType()
+func (p *parser) parseType() ast.Expr {
+	if p.trace {
+		defer un(trace(p, "Type"))
+	}
+
+	typ := p.tryIdentOrType()
 	if typ == nil {
 		pos := p.pos
 		p.errorExpected(pos, "type")
@@ -254,35 +301,32 @@ func (p *Parser) parseType() ast.Expr {
 	return typ
 }
 
-func (p *Parser) tryType() ast.Expr {
-	typ := p.tryIdentOrType()
-	if typ != nil {
-		p.resolve(typ)
+func (p *parser) parseParameters() *ast.FieldList {
+	if p.trace {
+		defer un(trace(p, "Parameters"))
 	}
-	return typ
-}
 
-func (p *Parser) parseParameters(scope *ast.Scope) *ast.FieldList {
 	var params []*ast.Field
 	lparen := p.expect(token.LPAREN)
 	if p.tok != token.RPAREN {
-		params = p.parseParameterList(scope)
+		params = p.parseParameterList()
 	}
 	rparen := p.expect(token.RPAREN)
 	return &ast.FieldList{Opening: lparen, List: params, Closing: rparen}
 }
 
-func (p *Parser) parseParameterList(scope *ast.Scope) []*ast.Field {
+func (p *parser) parseParameterList() []*ast.Field {
+	if p.trace {
+		defer un(trace(p, "ParameterList"))
+	}
+
 	params := make([]*ast.Field, 0)
 	for p.tok != token.RPAREN && p.tok != token.EOF {
 		ref := p.optional(token.REF)
 		ident := p.parseIdent()
 		_ = p.expect(token.COLON)
 		typ := p.parseVarType()
-		field := &ast.Field{Ref: ref, Name: ident, Type: typ}
-		params = append(params, field)
-		p.declare(field, scope, ast.Var, ident)
-		p.resolve(typ)
+		params = append(params, &ast.Field{Ref: ref, Name: ident, Type: typ})
 		if !p.atComma("parameter list", token.RPAREN) {
 			break
 		}
@@ -291,8 +335,11 @@ func (p *Parser) parseParameterList(scope *ast.Scope) []*ast.Field {
 	return params
 }
 
-// If the result is an identifier, it is not resolved.
-func (p *Parser) parseVarType() ast.Expr {
+func (p *parser) parseVarType() ast.Expr {
+	if p.trace {
+		defer un(trace(p, "VarType"))
+	}
+
 	typ := p.tryIdentOrType()
 	if typ == nil {
 		pos := p.pos
@@ -303,61 +350,115 @@ func (p *Parser) parseVarType() ast.Expr {
 	return typ
 }
 
-// If the result is an identifier, it is not resolved.
-func (p *Parser) tryIdentOrType() ast.Expr {
+func (p *parser) tryIdentOrType() ast.Expr {
 	switch p.tok {
 	case token.IDENT:
-		return p.parseIdent()
+		ident := p.parseIdent()
+		if p.tok == token.PERIOD && p.exts.Has(ext.Imports) {
+			return p.parseSelector(ident)
+		}
+		if p.tok == token.LBRACE && token.Lookup(ident.Name) == token.RECORD {
+			// The record keyword was scanned as identifier because the
+			// extension is disabled.
+			p.extRequired(ext.Records, ident.Pos(), ident.End(), "record types")
+			return p.parseRecordType(ident.Pos())
+		}
+		return ident
 	case token.ARRAY:
 		return p.parseArrayType()
+	case token.RECORD:
+		record := p.pos
+		p.next()
+		return p.parseRecordType(record)
 	case token.LPAREN:
 		lparen := p.pos
 		p.next()
 		typ := p.parseType()
 		rparen := p.expect(token.RPAREN)
-		return &ast.ParenExpr{Lparen: lparen, X: typ, Rparen: rparen}
+		x := &ast.ParenExpr{Lparen: lparen, X: typ, Rparen: rparen}
+		p.notInSpec(x.Pos(), x.End(), "parenthesized types are not allowed")
+		return x
 	}
 	return nil
 }
 
-func (p *Parser) parseArrayType() ast.Expr {
+func (p *parser) parseArrayType() ast.Expr {
+	if p.trace {
+		defer un(trace(p, "ArrayType"))
+	}
+
 	array := p.expect(token.ARRAY)
 	_ = p.expect(token.LBRACK)
 	p.exprLev++
 	len := p.parseRHS()
 	p.exprLev--
+	if _, ok := len.(*ast.IntLit); !ok {
+		p.notInSpec(len.Pos(), len.End(), "array length must be an integer literal")
+	}
 	_ = p.expect(token.RBRACK)
 	of := p.expect(token.OF)
 	elt := p.parseType()
 	return &ast.ArrayType{Array: array, Len: len, Of: of, Elt: elt}
 }
 
+// parseRecordType parses a record type whose keyword at the given position
+// has been consumed. Each field declaration is terminated by a semicolon,
+// which may be omitted before the closing brace.
+func (p *parser) parseRecordType(record token.Position) ast.Expr {
+	if p.trace {
+		defer un(trace(p, "RecordType"))
+	}
+
+	lbrace := p.expect(token.LBRACE)
+	var fields []*ast.Field
+	for p.tok == token.IDENT {
+		ident := p.parseIdent()
+		_ = p.expect(token.COLON)
+		typ := p.parseVarType()
+		fields = append(fields, &ast.Field{Name: ident, Type: typ})
+		p.expectSemi()
+	}
+	rbrace := p.expect(token.RBRACE)
+	return &ast.RecordType{Record: record, Fields: &ast.FieldList{Opening: lbrace, List: fields, Closing: rbrace}}
+}
+
 // -----------------------------------------------------------------------------
 // Blocks
 
-func (p *Parser) parseStmtList() []ast.Stmt {
+func (p *parser) parseStmtList() []ast.Stmt {
+	if p.trace {
+		defer un(trace(p, "StatementList"))
+	}
+
+	// A procedure declaration ends the statement list. It most likely follows
+	// a procedure whose closing brace is missing.
 	list := make([]ast.Stmt, 0)
-	for p.tok != token.RBRACE && p.tok != token.EOF {
+	for p.tok != token.RBRACE && p.tok != token.PROC && p.tok != token.EOF {
 		list = append(list, p.parseStmt())
 	}
 	return list
 }
 
-func (p *Parser) parseBody(scope *ast.Scope) *ast.BlockStmt {
+func (p *parser) parseBody() *ast.BlockStmt {
+	if p.trace {
+		defer un(trace(p, "Body"))
+	}
+
 	lbrace := p.expect(token.LBRACE)
-	p.topScope = scope
+	p.declOK = true
 	list := p.parseStmtList()
-	p.closeScope()
-	rbrace := p.expect(token.RBRACE)
+	rbrace := p.expectRbrace()
 	return &ast.BlockStmt{Lbrace: lbrace, List: list, Rbrace: rbrace}
 }
 
-func (p *Parser) parseBlockStmt() *ast.BlockStmt {
+func (p *parser) parseBlockStmt() *ast.BlockStmt {
+	if p.trace {
+		defer un(trace(p, "BlockStmt"))
+	}
+
 	lbrace := p.expect(token.LBRACE)
-	p.openScope()
 	list := p.parseStmtList()
-	p.closeScope()
-	rbrace := p.expect(token.RBRACE)
+	rbrace := p.expectRbrace()
 	return &ast.BlockStmt{Lbrace: lbrace, List: list, Rbrace: rbrace}
 }
 
@@ -365,14 +466,16 @@ func (p *Parser) parseBlockStmt() *ast.BlockStmt {
 // Expressions
 
 // checkExpr checks that x is an expression (and not a type).
-func (p *Parser) checkExpr(x ast.Expr) ast.Expr {
+func (p *parser) checkExpr(x ast.Expr) ast.Expr {
 	switch unparen(x).(type) {
 	case *ast.BadExpr:
 	case *ast.Ident:
 	case *ast.IntLit:
+	case *ast.StringLit:
 	case *ast.UnaryExpr:
 	case *ast.BinaryExpr:
 	case *ast.IndexExpr:
+	case *ast.SelectorExpr:
 	case *ast.CallExpr:
 	default:
 		p.errorExpected(x.Pos(), "expression")
@@ -381,81 +484,102 @@ func (p *Parser) checkExpr(x ast.Expr) ast.Expr {
 	return x
 }
 
-// If lhs is set and the result is an identifier, it is not resolved. The result
-// may be a type and callers must check the result (using checkExpr or
-// checkExprOrType).
-func (p *Parser) parseExpr(lhs bool) ast.Expr {
-	return p.parseBinaryExpr(lhs, token.LowestPrec+1)
+// The result may be a type and callers must check the result (using
+// checkExpr).
+func (p *parser) parseExpr() ast.Expr {
+	if p.trace {
+		defer un(trace(p, "Expression"))
+	}
+
+	return p.parseBinaryExpr(token.LowestPrec + 1)
 }
 
-// If lhs is set and the result is an identifier, it is not resolved.
-func (p *Parser) parseBinaryExpr(lhs bool, prec1 int) ast.Expr {
-	x := p.parseUnaryExpr(lhs)
+func (p *parser) parseBinaryExpr(prec1 int) ast.Expr {
+	if p.trace {
+		defer un(trace(p, "BinaryExpr"))
+	}
+
+	x := p.parseUnaryExpr()
 	for {
 		op, oprec := p.tokPrec()
 		if oprec < prec1 {
 			return x
 		}
 		pos := p.expect(op)
-		if lhs {
-			p.resolve(x)
-			lhs = false
-		}
-		y := p.parseBinaryExpr(false, oprec+1)
+		y := p.parseBinaryExpr(oprec + 1)
 		x = &ast.BinaryExpr{X: p.checkExpr(x), OpPos: pos, Op: op, Y: p.checkExpr(y)}
 	}
 }
 
-// If lhs is set and the result is an identifier, it is not resolved.
-func (p *Parser) parseUnaryExpr(lhs bool) ast.Expr {
-	if p.tok == token.ADD || p.tok == token.SUB || p.tok == token.NOT || p.tok == token.MUL {
+func (p *parser) parseUnaryExpr() ast.Expr {
+	if p.trace {
+		defer un(trace(p, "UnaryExpr"))
+	}
+
+	switch p.tok {
+	case token.LNOT:
+		pos, op := p.pos, p.tok
+		p.next()
+		x := p.parseUnaryExpr()
+		return &ast.UnaryExpr{OpPos: pos, Op: op, X: p.checkExpr(x)}
+	case token.ADD, token.SUB, token.NOT, token.MUL:
 		pos, op := p.pos, p.tok
+		if op != token.SUB {
+			p.notInSpec(pos, p.tokEnd(), fmt.Sprintf("unary operator %s is not allowed", op))
+		}
 		p.next()
-		x := p.parseUnaryExpr(false)
+		x := p.parseUnaryExpr()
 		return &ast.UnaryExpr{OpPos: pos, Op: op, X: p.checkExpr(x)}
 	}
-	return p.parsePrimaryExpr(lhs)
+	return p.parsePrimaryExpr()
 }
 
-// If lhs is set and the result is an identifier, it is not resolved.
-func (p *Parser) parsePrimaryExpr(lhs bool) ast.Expr {
-	x := p.parseOperand(lhs)
+func (p *parser) parsePrimaryExpr() ast.Expr {
+	if p.trace {
+		defer un(trace(p, "PrimaryExpr"))
+	}
+
+	x := p.parseOperand()
 L:
 	for {
 		switch p.tok {
 		case token.LBRACK:
-			if lhs {
-				p.resolve(x)
-			}
 			x = p.parseIndex(p.checkExpr(x))
 		case token.LPAREN:
-			if lhs {
-				p.resolve(x)
-			}
 			x = p.parseCall(p.checkExpr(x))
+		case token.PERIOD:
+			if !p.exts.Has(ext.Imports) && !p.exts.Has(ext.Records) {
+				break L
+			}
+			x = p.parseSelector(x)
 		default:
 			break L
 		}
-		lhs = false
 	}
 	return x
 }
 
 // parseOperand may return an expression or a raw type (incl. array types).
-// Callers must verify the result. If lhs is set and the result is an
-// identifier, it is not resolved.
-func (p *Parser) parseOperand(lhs bool) ast.Expr {
+// Callers must verify the result.
+func (p *parser) parseOperand() ast.Expr {
+	if p.trace {
+		defer un(trace(p, "Operand"))
+	}
+
 	switch p.tok {
 	case token.IDENT:
-		x := p.parseIdent()
-		if !lhs {
-			p.resolve(x)
-		}
-		return x
+		return p.parseIdent()
 	case token.INT:
 		x := &ast.IntLit{ValuePos: p.pos, Value: p.lit}
 		p.next()
 		return x
+	case token.STRING:
+		if !p.exts.Has(ext.Strings) {
+			p.extRequired(ext.Strings, p.pos, p.tokEnd(), "string literals")
+		}
+		x := &ast.StringLit{ValuePos: p.pos, Value: p.lit}
+		p.next()
+		return x
 	case token.LPAREN:
 		lparen := p.pos
 		p.next()
@@ -472,11 +596,25 @@ func (p *Parser) parseOperand(lhs bool) ast.Expr {
 
 	pos := p.pos
 	p.errorExpected(pos, "operand")
-	p.advance(stmtStart)
+	p.advance(exprEnd)
 	return &ast.BadExpr{From: pos, To: p.pos}
 }
 
-func (p *Parser) parseIndex(x ast.Expr) ast.Expr {
+// parseSelector parses the selector following x.
+func (p *parser) parseSelector(x ast.Expr) ast.Expr {
+	if p.trace {
+		defer un(trace(p, "Selector"))
+	}
+
+	_ = p.expect(token.PERIOD)
+	return &ast.SelectorExpr{X: x, Sel: p.parseIdent()}
+}
+
+func (p *parser) parseIndex(x ast.Expr) ast.Expr {
+	if p.trace {
+		defer un(trace(p, "IndexExpr"))
+	}
+
 	lbrack := p.expect(token.LBRACK)
 	p.exprLev++
 	index := p.parseRHS()
@@ -485,7 +623,11 @@ func (p *Parser) parseIndex(x ast.Expr) ast.Expr {
 	return &ast.IndexExpr{X: x, Lbrack: lbrack, Index: index, Rbrack: rbrack}
 }
 
-func (p *Parser) parseCall(pro ast.Expr) *ast.CallExpr {
+func (p *parser) parseCall(pro ast.Expr) *ast.CallExpr {
+	if p.trace {
+		defer un(trace(p, "CallExpr"))
+	}
+
 	lparen := p.expect(token.LPAREN)
 	p.exprLev++
 	var list []ast.Expr
@@ -501,12 +643,8 @@ func (p *Parser) parseCall(pro ast.Expr) *ast.CallExpr {
 	return &ast.CallExpr{Pro: pro, Lparen: lparen, Args: list, Rparen: rparen}
 }
 
-func (p *Parser) tokPrec() (token.Token, int) {
-	tok := p.tok
-	if p.inRHS && tok == token.EQL {
-		tok = token.EQL
-	}
-	return tok, tok.Precedence()
+func (p *parser) tokPrec() (token.Token, int) {
+	return p.tok, p.tok.Precedence()
 }
 
 // If x is of the form (T), unparen returns unparen(T), otherwise it returns x.
@@ -521,30 +659,54 @@ func unparen(x ast.Expr) ast.Expr {
 // Statements
 
 // parseStmt parses lexical tokens into a Statement AST object.
-func (p *Parser) parseStmt() (stmt ast.Stmt) {
+func (p *parser) parseStmt() (stmt ast.Stmt) {
+	if p.trace {
+		defer un(trace(p, "Statement"))
+	}
+
+	// SPL 1.2 allows variable declarations only in front of the statements of
+	// a procedure body and type declarations only outside of procedures.
+	declOK := p.declOK
+	p.declOK = false
+
 	switch p.tok {
 	case token.VAR, token.TYPE:
+		if p.tok == token.TYPE {
+			p.notInSpec(p.pos, p.tokEnd(), "type declarations must not appear inside procedures")
+		} else if !declOK {
+			p.notInSpec(p.pos, p.tokEnd(), "variable declarations must precede the statements of a procedure body")
+		}
 		stmt = &ast.DeclStmt{Decl: p.parseDecl(stmtStart)}
+		p.declOK = declOK
 	case token.IDENT, token.INT, token.LPAREN,
-		token.LBRACK, token.ADD, token.SUB, token.MUL, token.NOT:
+		token.LBRACK, token.ADD, token.SUB, token.MUL, token.NOT, token.LNOT:
 		stmt = p.parseSimpleStmt()
 		p.expectSemi()
+	case token.SEMICOLON:
+		stmt = &ast.EmptyStmt{Semicolon: p.pos}
+		p.next()
 	case token.LBRACE:
 		stmt = p.parseBlockStmt()
 	case token.WHILE:
 		stmt = p.parseWhileStmt()
 	case token.IF:
 		stmt = p.parseIfStmt()
+	case token.RETURN:
+		stmt = p.parseReturnStmt()
 	default:
 		pos := p.pos
 		p.errorExpected(pos, "statement")
-		p.advance(stmtStart)
+		p.syncStmt()
 		stmt = &ast.BadStmt{From: pos, To: p.pos}
 	}
 	return stmt
 }
 
-func (p *Parser) parseSimpleStmt() ast.Stmt {
+func (p *parser) parseSimpleStmt() ast.Stmt {
+	if p.trace {
+		defer un(trace(p, "SimpleStmt"))
+	}
+
 	x := p.parseLHS()
 	if p.tok == token.ASSIGN {
 		pos, tok := p.pos, p.tok
@@ -555,12 +717,16 @@ func (p *Parser) parseSimpleStmt() ast.Stmt {
 	return &ast.ExprStmt{X: x}
 }
 
-func (p *Parser) parseWhileStmt() ast.Stmt {
+func (p *parser) parseWhileStmt() ast.Stmt {
+	if p.trace {
+		defer un(trace(p, "WhileStmt"))
+	}
+
 	pos := p.expect(token.WHILE)
 	_ = p.expect(token.LPAREN)
 	prevLev := p.exprLev
 	p.exprLev = -1
-	x := p.checkExpr(p.parseExpr(false))
+	x := p.checkExpr(p.parseExpr())
 	p.exprLev = prevLev
 	_ = p.expect(token.RPAREN)
 	body := p.parseStmt()
@@ -572,12 +738,16 @@ func (p *Parser) parseWhileStmt() ast.Stmt {
 	}
 }
 
-func (p *Parser) parseIfStmt() ast.Stmt {
+func (p *parser) parseIfStmt() ast.Stmt {
+	if p.trace {
+		defer un(trace(p, "IfStmt"))
+	}
+
 	pos := p.expect(token.IF)
 	_ = p.expect(token.LPAREN)
 	prevLev := p.exprLev
 	p.exprLev = -1
-	x := p.checkExpr(p.parseExpr(false))
+	x := p.checkExpr(p.parseExpr())
 	p.exprLev = prevLev
 	_ = p.expect(token.RPAREN)
 	body := p.parseStmt()
@@ -596,67 +766,24 @@ func (p *Parser) parseIfStmt() ast.Stmt {
 	}
 }
 
-// -----------------------------------------------------------------------------
-// Scoping support
-
-func (p *Parser) openScope() {
-	p.topScope = ast.NewScope(p.topScope)
-}
-
-func (p *Parser) closeScope() {
-	p.topScope = p.topScope.Outer
-}
-
-func (p *Parser) declare(decl interface{}, scope *ast.Scope, kind ast.ObjKind, idents ...*ast.Ident) {
-	for _, ident := range idents {
-		obj := ast.NewObj(kind, ident.Name)
-		obj.Decl = decl
-		ident.Obj = obj
-		if ident.Name != "_" {
-			if alt := scope.Insert(obj); alt != nil {
-				prevDecl := ""
-				if pos := alt.Pos(); pos.IsValid() {
-					prevDecl = fmt.Sprintf("\n\tprevious declaration at %s", pos)
-				}
-				p.error(ident.Pos(), fmt.Sprintf("%s redeclared in this block%s", ident.Name, prevDecl))
-			}
-		}
-	}
-}
-
-// The unresolved object is a sentinel to mark identifiers that have been added
-// to the list of unresolved identifiers. The sentinel is only used for
-// verifying internal consistency.
-var unresolved = new(ast.Object)
-
-func (p *Parser) tryResolve(x ast.Expr, collectUnresolved bool) {
-	// Nothing to do if x is not an identifier.
-	ident, ok := x.(*ast.Ident)
-	if !ok {
-		return
+func (p *parser) parseReturnStmt() *ast.ReturnStmt {
+	if p.trace {
+		defer un(trace(p, "ReturnStmt"))
 	}
 
-	for s := p.topScope; s != nil; s = s.Outer {
-		if obj := s.Lookup(ident.Name); obj != nil {
-			ident.Obj = obj
-			return
-		}
+	pos := p.expect(token.RETURN)
+	var x ast.Expr
+	if p.tok != token.SEMICOLON && p.tok != token.RBRACE {
+		x = p.parseRHS()
 	}
-
-	if collectUnresolved {
-		ident.Obj = unresolved
-		p.unresolved = append(p.unresolved, ident)
-	}
-}
-
-func (p *Parser) resolve(x ast.Expr) {
-	p.tryResolve(x, true)
+	p.expectSemi()
+	return &ast.ReturnStmt{Return: pos, Result: x}
 }
 
 // -----------------------------------------------------------------------------
 // Parsing support
 
-func (p *Parser) expect(tok token.Token) token.Position {
+func (p *parser) expect(tok token.Token) token.Position {
 	pos := p.pos
 	if p.tok != tok {
 		p.errorExpected(pos, "'"+tok.String()+"'")
@@ -665,7 +792,7 @@ func (p *Parser) expect(tok token.Token) token.Position {
 	return pos
 }
 
-func (p *Parser) optional(tok token.Token) token.Position {
+func (p *parser) optional(tok token.Token) token.Position {
 	pos := p.pos
 	if p.tok != tok {
 		return token.NoPos
@@ -676,30 +803,51 @@ func (p *Parser) optional(tok token.Token) token.Position {
 
 // expectClosing is like expect but provides a better error message for the
 // common case of a missing comma before a newline.
-func (p *Parser) expectClosing(tok token.Token, context string) token.Position {
+func (p *parser) expectClosing(tok token.Token, context string) token.Position {
 	if p.tok != tok && p.tok == token.SEMICOLON && p.lit == "\n" {
-		p.error(p.pos, "missing ',' before newline in "+context)
+		p.errorMissing(diag.MissingComma, "missing ',' before newline in "+context, ",")
 		p.next()
 	}
 	return p.expect(tok)
 }
 
-func (p *Parser) expectSemi() {
-	if p.tok != token.RPAREN && p.tok != token.RBRACE {
-		switch p.tok {
-		case token.COMMA:
-			p.errorExpected(p.pos, "';'")
-			fallthrough
-		case token.SEMICOLON:
-			p.next()
-		default:
-			p.errorExpected(p.pos, "';'")
-			p.advance(stmtStart)
-		}
+// expectRbrace is like expect for the closing brace of a block, but it doesn't
+// consume the start of a following procedure declaration.
+func (p *parser) expectRbrace() token.Position {
+	if p.tok == token.PROC {
+		pos := p.pos
+		p.errorExpected(pos, "'}'")
+		return pos
 	}
+	return p.expect(token.RBRACE)
 }
 
-func (p *Parser) atComma(context string, follow token.Token) bool {
+func (p *parser) expectSemi() {
+	switch p.tok {
+	case token.RPAREN, token.RBRACE:
+		// The semicolon may be omitted before a closing ')' or '}'.
+		p.notInSpec(p.prevEnd, p.prevEnd, fmt.Sprintf("';' before '%s' must not be omitted", p.tok))
+	case token.COMMA:
+		p.report(&diag.Diagnostic{
+			Code: diag.SyntaxError,
+			Pos:  p.pos,
+			End:  p.tokEnd(),
+			Msg:  p.expected(p.pos, "';'"),
+			Fixes: []diag.Fix{{
+				Msg:   "replace ',' with ';'",
+				Edits: []diag.Edit{{Pos: p.pos, End: p.tokEnd(), NewText: ";"}},
+			}},
+		})
+		fallthrough
+	case token.SEMICOLON:
+		p.next()
+	default:
+		p.errorMissing(diag.SyntaxError, p.expected(p.pos, "';'"), ";")
+		p.syncStmt()
+	}
+}
+
+func (p *parser) atComma(context string, follow token.Token) bool {
 	if p.tok == token.COMMA {
 		return true
 	}
@@ -708,84 +856,206 @@ func (p *Parser) atComma(context string, follow token.Token) bool {
 		if p.tok == token.SEMICOLON && p.lit == "\n" {
 			msg += " before newline"
 		}
-		p.error(p.pos, msg+" in "+context)
+		p.errorMissing(diag.MissingComma, msg+" in "+context, ",")
 		return true
 	}
 	return false
 }
 
 // next scans the next non-comment token.
-func (p *Parser) next() {
-	// TODO: Collect comments.
+func (p *parser) next() {
+	p.prevEnd = p.tokEnd()
+
 	p.scan()
 	for p.tok == token.COMMENT {
+		if p.mode&ParseComments != 0 {
+			p.comments = append(p.comments, &ast.Comment{Slash: p.pos, Text: p.lit})
+		}
 		p.scan()
 	}
-}
 
-// scan returns the next token from the underlying scanner. If a token has been
-// unscanned read that one instead.
-func (p *Parser) scan() {
-	// If we have a token on the buffer return it.
-	if p.buf.n != 0 {
-		p.buf.n = 0
-		p.tok, p.lit, p.pos = p.buf.tok, p.buf.lit, p.buf.pos
-		return
+	if p.trace {
+		s := p.tok.String()
+		switch {
+		case p.tok.IsLiteral():
+			p.printTrace(s, p.lit)
+		case p.tok.IsOperator(), p.tok.IsKeyword():
+			p.printTrace("\"" + s + "\"")
+		default:
+			p.printTrace(s)
+		}
 	}
+}
 
-	// Otherwise read the next token from the scanner and save it to the buffer
-	// in case we unscan later.
+// scan reads the next token from the underlying scanner. Keywords of disabled
+// language extensions are identifiers.
+func (p *parser) scan() {
 	p.tok, p.lit, p.pos = p.scanner.Scan()
-	p.buf.tok, p.buf.lit, p.buf.pos = p.tok, p.lit, p.pos
+	if x, ok := extKeywords[p.tok]; ok && !p.exts.Has(x) {
+		p.tok = token.IDENT
+	}
 }
 
+// The token sets the parser synchronizes to after a syntax error.
 var (
+	// stmtStart holds the tokens starting a statement or ending the
+	// previous statement or the enclosing statement list.
 	stmtStart = map[token.Token]bool{
-		token.ARRAY: true,
-		token.ELSE:  true,
-		token.IF:    true,
-		token.OF:    true,
-		token.PROC:  true,
-		token.REF:   true,
-		token.TYPE:  true,
-		token.VAR:   true,
-		token.WHILE: true,
+		token.IF:        true,
+		token.PROC:      true,
+		token.RBRACE:    true,
+		token.RETURN:    true,
+		token.SEMICOLON: true,
+		token.TYPE:      true,
+		token.VAR:       true,
+		token.WHILE:     true,
 	}
 
 	declStart = map[token.Token]bool{
-		token.TYPE: true,
-		token.VAR:  true,
+		token.IMPORT: true,
+		token.PROC:   true,
+		token.TYPE:   true,
+		token.VAR:    true,
 	}
 
 	exprEnd = map[token.Token]bool{
-		token.RPAREN: true,
+		token.COMMA:     true,
+		token.COLON:     true,
+		token.SEMICOLON: true,
+		token.RPAREN:    true,
+		token.RBRACK:    true,
+		token.RBRACE:    true,
+	}
+
+	// extKeywords maps the keywords of language extensions to the extension
+	// introducing them. They are scanned as identifiers if the extension is
+	// disabled.
+	extKeywords = map[token.Token]ext.Set{
+		token.AS:     ext.Imports,
+		token.IMPORT: ext.Imports,
+		token.RETURN: ext.Functions,
+		token.LAND:   ext.Bool,
+		token.LOR:    ext.Bool,
+		token.LNOT:   ext.Bool,
+		token.RECORD: ext.Records,
 	}
 )
 
 // advance consumes tokens until the current token is in the provided set, or
 // token.EOF.
-func (p *Parser) advance(to map[token.Token]bool) {
+func (p *parser) advance(to map[token.Token]bool) {
 	for ; p.tok != token.EOF; p.next() {
 		if to[p.tok] {
+			// Return only if the parser made some progress since the last
+			// sync or if it has not reached 10 advance calls without progress.
+			// Otherwise consume at least one token to avoid an endless parser
+			// loop: Several productions may call advance at the same token
+			// without consuming it.
 			if p.pos == p.syncPos && p.syncCnt < 10 {
 				p.syncCnt++
-			} else if p.pos.Line > p.syncPos.Line && p.pos.Column > p.syncPos.Column {
+				return
+			}
+			if p.pos.Char > p.syncPos.Char {
 				p.syncPos = p.pos
 				p.syncCnt = 0
+				return
 			}
 		}
 	}
 }
 
-// unscan pushes the previously read token back onto the buffer.
-// func (p *Parser) unscan() { p.buf.n = 1 }
+// syncStmt advances to the start of the next statement. A semicolon ending the
+// current statement is consumed.
+func (p *parser) syncStmt() {
+	p.advance(stmtStart)
+	if p.tok == token.SEMICOLON {
+		p.next()
+	}
+}
 
 // -----------------------------------------------------------------------------
 // Errors
 
-func (p *Parser) error(pos token.Position, msg string) { p.errors.Add(pos, msg) }
+// report records the diagnostic d. Unless the AllErrors mode is set, only the
+// first error of a line is recorded and parsing stops once maxErrors errors
+// have been reported.
+func (p *parser) report(d *diag.Diagnostic) {
+	if p.mode&AllErrors == 0 {
+		if n := p.errors.Len(); n > 0 {
+			last := p.errors[n-1].Pos
+			if last.Filename == d.Pos.Filename && last.Line == d.Pos.Line {
+				return
+			}
+		}
+	}
+	p.errors.Report(d)
+	if p.mode&AllErrors == 0 && p.errors.Len() >= maxErrors {
+		panic(bailout{})
+	}
+}
+
+// extRequired reports a construct spanning pos to end which requires the
+// disabled language extension x.
+func (p *parser) extRequired(x ext.Set, pos, end token.Position, what string) {
+	p.report(&diag.Diagnostic{
+		Code: diag.ExtensionRequired,
+		Pos:  pos,
+		End:  end,
+		Msg:  fmt.Sprintf("%s require the %s language extension", what, x),
+		Help: "enable the extension with --ext=" + x.String(),
+	})
+}
+
+// notInSpec reports a construct beyond the SPL 1.2 specification spanning pos
+// to end if the Strict mode is set.
+func (p *parser) notInSpec(pos, end token.Position, msg string) {
+	if p.mode&Strict == 0 {
+		return
+	}
+	p.report(&diag.Diagnostic{
+		Code: diag.NotInSpec,
+		Pos:  pos,
+		End:  end,
+		Msg:  msg + " in SPL 1.2",
+		Help: "the construct is accepted without --lang=spl1.2",
+	})
+}
+
+// error reports an error at pos. If pos is the position of the current token,
+// the error spans the token.
+func (p *parser) error(code diag.Code, pos token.Position, msg string) {
+	end := pos
+	if pos == p.pos {
+		end = p.tokEnd()
+	}
+	p.report(&diag.Diagnostic{Code: code, Pos: pos, End: end, Msg: msg})
+}
+
+func (p *parser) errorExpected(pos token.Position, msg string) {
+	if pos == p.pos && p.tok == token.ILLEGAL {
+		// The scanner already reported the illegal character.
+		return
+	}
+	p.error(diag.SyntaxError, pos, p.expected(pos, msg))
+}
 
-func (p *Parser) errorExpected(pos token.Position, msg string) {
+// errorMissing reports an error at the current token about text missing after
+// the previous token. The insertion of text is suggested as fix.
+func (p *parser) errorMissing(code diag.Code, msg, text string) {
+	p.report(&diag.Diagnostic{
+		Code: code,
+		Pos:  p.pos,
+		End:  p.tokEnd(),
+		Msg:  msg,
+		Fixes: []diag.Fix{{
+			Msg:   "insert '" + text + "'",
+			Edits: []diag.Edit{{Pos: p.prevEnd, End: p.prevEnd, NewText: text}},
+		}},
+	})
+}
+
+// expected returns the message of an error about what is expected at pos.
+func (p *parser) expected(pos token.Position, msg string) string {
 	msg = "expected " + msg
 	if pos == p.pos {
 		switch {
@@ -797,5 +1067,13 @@ func (p *Parser) errorExpected(pos token.Position, msg string) {
 			msg += ", found '" + p.tok.String() + "'"
 		}
 	}
-	p.error(pos, msg)
+	return msg
+}
+
+// tokEnd returns the position immediately after the current token.
+func (p *parser) tokEnd() token.Position {
+	pos := p.pos
+	pos.Column += len(p.lit)
+	pos.Char += len(p.lit)
+	return pos
 }
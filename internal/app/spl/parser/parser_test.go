@@ -1,7 +1,11 @@
 package parser
 
 import (
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -9,19 +13,16 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 
 	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/diag"
+	"github.com/lukasmalkmus/spl/internal/app/spl/ext"
+	"github.com/lukasmalkmus/spl/internal/app/spl/internal/testutil"
 	"github.com/lukasmalkmus/spl/internal/app/spl/token"
 )
 
 func TestParser_ParseFullValidProgram(t *testing.T) {
-	f, err := os.Open("../testdata/valid.spl")
+	prog, err := ParseFile(token.NewFileSet(), "../testdata/valid.spl", nil, DeclarationErrors)
 	if err != nil {
-		t.Fatal("failed to open testdata:", err)
-	}
-	p := NewFileParser(f)
-
-	prog, _ := p.Parse()
-	if p.errors.Len() > 0 {
-		t.Errorf("expected no errors got %d: %s", p.errors.Len(), p.errors.Error())
+		t.Errorf("expected no errors got: %s", err)
 	}
 	if len(prog.Decls) == 0 {
 		t.Errorf("didn't parse any top level declarations")
@@ -31,6 +32,287 @@ func TestParser_ParseFullValidProgram(t *testing.T) {
 	}
 }
 
+func TestParseFiles(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	write := func(name, src string) string {
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+	lib := write("lib.spl", "type A = array [2] of int;\n\nproc f(ref a: A) {}\n")
+	main := write("main.spl", "proc main() {\n  var a: A;\n  f(a);\n}\n")
+	dup := write("dup.spl", "proc main() {}\ntype A = int;\n")
+
+	prog, err := ParseFiles(token.NewFileSet(), []string{lib, main}, 0, DeclarationErrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prog.Name != lib || len(prog.Decls) != 3 || len(prog.Unresolved) != 0 {
+		t.Errorf("got program %s with %d declarations and unresolved %v", prog.Name, len(prog.Decls), prog.Unresolved)
+	}
+	typ := prog.Decls[0].(*ast.TypeDecl).Type.(*ast.ArrayType)
+	if obj := typ.Elt.(*ast.Ident).Obj; obj != ast.UniverseScope(0, false).Lookup("int") {
+		t.Errorf("got object %v for int, want predeclared type", obj)
+	}
+	call := prog.Decls[2].(*ast.ProcDecl).Body.List[1].(*ast.ExprStmt).X.(*ast.CallExpr)
+	if obj := call.Pro.(*ast.Ident).Obj; obj == nil || obj.Pos().Filename != lib {
+		t.Errorf("got object %v for f, want declaration in %s", obj, lib)
+	}
+
+	_, err = ParseFiles(token.NewFileSet(), []string{lib, main, dup}, 0, DeclarationErrors)
+	want := dup + ":1:6: main redeclared in this block\n\tprevious declaration at " + main + ":1:6\n" +
+		dup + ":2:6: A redeclared in this block\n\tprevious declaration at " + lib + ":1:6"
+	if list, ok := err.(ErrorList); !ok || len(list) != 2 || list[0].Error()+"\n"+list[1].Error() != want {
+		t.Errorf("got error %v, want\n%s", err, want)
+	}
+}
+
+func TestParseFiles_Imports(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	filename := filepath.Join(dir, "main.spl")
+	src := "import \"lib/sort.spl\" as s;\n\nproc main() {\n  var a: s.vector;\n  s.sort(a);\n}\n"
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	prog, err := ParseFiles(token.NewFileSet(), []string{filename}, ext.Imports, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imp := prog.Decls[0].(*ast.ImportDecl)
+	if imp.Path.Value != `"lib/sort.spl"` || imp.Name.Name != "s" || imp.Name.Obj == nil || imp.Name.Obj.Kind != ast.Mod {
+		t.Errorf("got import of %s as %s (%v)", imp.Path.Value, imp.Name.Name, imp.Name.Obj)
+	}
+	body := prog.Decls[1].(*ast.ProcDecl).Body
+	typ := body.List[0].(*ast.DeclStmt).Decl.(*ast.VarDecl).Type.(*ast.SelectorExpr)
+	call := body.List[1].(*ast.ExprStmt).X.(*ast.CallExpr).Pro.(*ast.SelectorExpr)
+	for _, sel := range []*ast.SelectorExpr{typ, call} {
+		if x := sel.X.(*ast.Ident); x.Obj != imp.Name.Obj || sel.Sel.Obj != nil {
+			t.Errorf("selector %s.%s resolved to %v and %v", x.Name, sel.Sel.Name, x.Obj, sel.Sel.Obj)
+		}
+	}
+
+	_, err = ParseFiles(token.NewFileSet(), []string{filename}, 0, 0)
+	want := filename + ":1:1: import declarations require the imports language extension"
+	if list, ok := err.(ErrorList); !ok || list[0].Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
+
+func TestParseFiles_Functions(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	filename := filepath.Join(dir, "main.spl")
+	src := "proc sq(x: int): int {\n  return x * x;\n}\n\nproc main() {\n  printi(sq(2));\n  return;\n}\n"
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	prog, err := ParseFiles(token.NewFileSet(), []string{filename}, ext.Functions, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sq := prog.Decls[0].(*ast.ProcDecl)
+	if res, ok := sq.Result.(*ast.Ident); !ok || res.Name != "int" || res.Obj == nil || res.Obj.Kind != ast.Typ {
+		t.Errorf("got result type %#v", sq.Result)
+	}
+	if ret := sq.Body.List[0].(*ast.ReturnStmt); ret.Result == nil || ret.End().Column != 15 {
+		t.Errorf("got return statement %#v", ret)
+	}
+	body := prog.Decls[1].(*ast.ProcDecl).Body
+	call := body.List[0].(*ast.ExprStmt).X.(*ast.CallExpr).Args[0].(*ast.CallExpr)
+	if pro := call.Pro.(*ast.Ident); pro.Obj != sq.Name.Obj {
+		t.Errorf("call of %s resolved to %v", pro.Name, pro.Obj)
+	}
+	if ret := body.List[1].(*ast.ReturnStmt); ret.Result != nil {
+		t.Errorf("got return statement with result %#v", ret.Result)
+	}
+
+	_, err = ParseFiles(token.NewFileSet(), []string{filename}, 0, 0)
+	want := filename + ":1:16: result types require the functions language extension"
+	if list, ok := err.(ErrorList); !ok || list[0].Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
+
+func TestParseFiles_Bool(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	filename := filepath.Join(dir, "main.spl")
+	src := "proc main() {\n  var b: bool;\n  b := not b or 1 < 2 and true;\n}\n"
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	prog, err := ParseFiles(token.NewFileSet(), []string{filename}, ext.Bool, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := prog.Decls[0].(*ast.ProcDecl).Body
+	or := body.List[1].(*ast.AssignStmt).Right.(*ast.BinaryExpr)
+	not := or.X.(*ast.UnaryExpr)
+	and := or.Y.(*ast.BinaryExpr)
+	if or.Op != token.LOR || not.Op != token.LNOT || and.Op != token.LAND || and.X.(*ast.BinaryExpr).Op != token.LSS {
+		t.Errorf("got expression %s %s (%s %s ...)", or.Op, not.Op, and.Op, and.X)
+	}
+	if obj := and.Y.(*ast.Ident).Obj; obj == nil || obj.Kind != ast.Con || obj != ast.UniverseScope(ext.Bool, false).Lookup("true") {
+		t.Errorf("true resolved to %v", obj)
+	}
+
+	// Without the extension the keywords are identifiers.
+	src = "proc main() {\n  var and: int;\n  and := 1;\n}\n"
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseFiles(token.NewFileSet(), []string{filename}, 0, DeclarationErrors); err != nil {
+		t.Errorf("got error %v, want none", err)
+	}
+	src = "proc main() {\n  var b: bool;\n}\n"
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ParseFiles(token.NewFileSet(), []string{filename}, 0, DeclarationErrors)
+	want := filename + ":2:10: undeclared name: bool"
+	if list, ok := err.(ErrorList); !ok || list[0].Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
+
+func TestParseFiles_Records(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	filename := filepath.Join(dir, "main.spl")
+	src := "type point = record { x: int; y: array [2] of int; };\n\nproc main() {\n  var ps: array [2] of point;\n  ps[1].y[0] := ps[0].x;\n}\n"
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	prog, err := ParseFiles(token.NewFileSet(), []string{filename}, ext.Records, DeclarationErrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := prog.Decls[0].(*ast.TypeDecl).Type.(*ast.RecordType)
+	if fields := rec.Fields.List; len(fields) != 2 || fields[0].Name.Name != "x" || fields[1].Name.Name != "y" || rec.End().Column != 53 {
+		t.Errorf("got record type %#v", rec)
+	}
+	if typ := rec.Fields.List[1].Type.(*ast.ArrayType).Elt.(*ast.Ident); typ.Obj == nil || typ.Obj.Kind != ast.Typ {
+		t.Errorf("field type %s resolved to %v", typ.Name, typ.Obj)
+	}
+	assign := prog.Decls[1].(*ast.ProcDecl).Body.List[1].(*ast.AssignStmt)
+	lhs := assign.Left.(*ast.IndexExpr).X.(*ast.SelectorExpr)
+	if lhs.Sel.Name != "y" || lhs.Sel.Obj != nil || lhs.X.(*ast.IndexExpr).X.(*ast.Ident).Name != "ps" {
+		t.Errorf("got left hand side %#v", assign.Left)
+	}
+	if rhs := assign.Right.(*ast.SelectorExpr); rhs.Sel.Name != "x" {
+		t.Errorf("got right hand side %#v", assign.Right)
+	}
+
+	_, err = ParseFiles(token.NewFileSet(), []string{filename}, 0, 0)
+	want := filename + ":1:14: record types require the records language extension"
+	if list, ok := err.(ErrorList); !ok || list[0].Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
+
+func TestParseFiles_Strings(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	filename := filepath.Join(dir, "main.spl")
+	src := "proc main() {\n  var s: array [4] of int := \"hi\";\n  prints(\"a\\n\");\n}\n"
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	prog, err := ParseFiles(token.NewFileSet(), []string{filename}, ext.Bool|ext.Strings, DeclarationErrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := prog.Decls[0].(*ast.ProcDecl).Body
+	decl := body.List[0].(*ast.DeclStmt).Decl.(*ast.VarDecl)
+	if lit, ok := decl.Value.(*ast.StringLit); !ok || lit.Value != `"hi"` || decl.Assign.Column != 27 || decl.End().Column != 34 {
+		t.Errorf("got variable declaration %#v", decl)
+	}
+	call := body.List[1].(*ast.ExprStmt).X.(*ast.CallExpr)
+	if lit := call.Args[0].(*ast.StringLit); lit.Value != `"a\n"` {
+		t.Errorf("got argument %s", lit.Value)
+	}
+	if obj := call.Pro.(*ast.Ident).Obj; obj == nil || obj != ast.UniverseScope(ext.Strings, false).Lookup("prints") {
+		t.Errorf("prints resolved to %v", obj)
+	}
+
+	_, err = ParseFiles(token.NewFileSet(), []string{filename}, 0, AllErrors)
+	list, ok := err.(ErrorList)
+	if !ok || len(list) < 3 {
+		t.Fatalf("got error %v, want 3 errors", err)
+	}
+	for i, want := range []string{
+		filename + ":2:27: initial values require the strings language extension",
+		filename + ":2:30: string literals require the strings language extension",
+		filename + ":3:10: string literals require the strings language extension",
+	} {
+		if got := list[i].Error(); got != want {
+			t.Errorf("got error %s, want %s", got, want)
+		}
+	}
+}
+
+func TestParser_Diagnostics(t *testing.T) {
+	src := "proc main() {\n  var x: int;\n  var x: int,\n  x := 1;\n}\n"
+	_, err := ParseFile(token.NewFileSet(), "", src, DeclarationErrors|AllErrors)
+	list, ok := err.(ErrorList)
+	if !ok || len(list) != 2 {
+		t.Fatalf("got error %v, want 2 diagnostics", err)
+	}
+	list.Sort()
+
+	redecl := list[0]
+	if redecl.Code != diag.Redeclared || redecl.Pos.Line != 3 || redecl.End.Column != 8 ||
+		len(redecl.Related) != 1 || redecl.Related[0].Pos.Line != 2 {
+		t.Errorf("got redeclaration %+v", redecl)
+	}
+
+	semi := list[1]
+	want := diag.Edit{Pos: semi.Pos, End: semi.End, NewText: ";"}
+	if semi.Code != diag.SyntaxError || semi.Pos.Column != 13 || semi.End.Column != 14 ||
+		len(semi.Fixes) != 1 || semi.Fixes[0].Edits[0] != want {
+		t.Errorf("got missing semicolon %+v with fixes %+v", semi, semi.Fixes)
+	}
+}
+
+func TestParser_LexicalErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"x := 'a;", "1:6: unterminated character literal"},
+		{`x := '\q';`, `1:7: invalid escape sequence \q`},
+		{`x := "a\qb";`, `1:8: invalid escape sequence \q`},
+		{"x := 2147483648;", "1:6: integer literal 2147483648 exceeds 2^31-1"},
+		{"x := ä;", "1:6: illegal character U+00E4 'ä'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := ParseStatement(tt.src)
+			list, ok := err.(ErrorList)
+			if !ok || len(list) == 0 {
+				t.Fatalf("got error %v, want lexical error", err)
+			}
+			if d := list[0]; d.Code != diag.InvalidToken || d.Error() != tt.want {
+				t.Errorf("got %s %q, want %s %q", d.Code, d.Error(), diag.InvalidToken, tt.want)
+			}
+			for _, d := range list {
+				if strings.Contains(d.Msg, "ILLEGAL") {
+					t.Errorf("got %q, want no error about illegal token", d.Msg)
+				}
+			}
+		})
+	}
+}
+
 func TestParser_ParseStatement(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -89,6 +371,27 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"empty",
+			"if (i > 0) ; else i - 1;",
+			&ast.IfStmt{
+				If: pos(1),
+				Cond: &ast.BinaryExpr{
+					OpPos: pos(7),
+					Op:    token.GTR,
+					X:     &ast.Ident{NamePos: pos(5), Name: "i"},
+					Y:     &ast.IntLit{ValuePos: pos(9), Value: "0"},
+				},
+				Body: &ast.EmptyStmt{Semicolon: pos(12)},
+				Else: &ast.ExprStmt{X: &ast.BinaryExpr{
+					OpPos: pos(21),
+					Op:    token.SUB,
+					X:     &ast.Ident{NamePos: pos(19), Name: "i"},
+					Y:     &ast.IntLit{ValuePos: pos(23), Value: "1"},
+				}},
+			},
+			false,
+		},
 	}
 	for _, tt := range tests {
 		_ = t.Run(tt.name, func(t *testing.T) {
@@ -101,6 +404,53 @@ func TestParser_ParseStatement(t *testing.T) {
 	}
 }
 
+func TestParser_ParseExpr(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    ast.Expr
+		wantErr bool
+	}{
+		{
+			"identifier",
+			"i",
+			&ast.Ident{NamePos: pos(1), Name: "i"},
+			false,
+		},
+		{
+			"index",
+			"a[i + 1]",
+			&ast.IndexExpr{
+				X:      &ast.Ident{NamePos: pos(1), Name: "a"},
+				Lbrack: pos(2),
+				Index: &ast.BinaryExpr{
+					OpPos: pos(5),
+					Op:    token.ADD,
+					X:     &ast.Ident{NamePos: pos(3), Name: "i"},
+					Y:     &ast.IntLit{ValuePos: pos(7), Value: "1"},
+				},
+				Rbrack: pos(8),
+			},
+			false,
+		},
+		{
+			"trailing tokens",
+			"i j",
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		_ = t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExpr(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseExpr() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			equals(t, got, tt.want)
+		})
+	}
+}
+
 func TestParser_parseDecl(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -249,8 +599,8 @@ func TestParser_parseDecl(t *testing.T) {
 	}
 	for _, tt := range tests {
 		_ = t.Run(tt.name, func(t *testing.T) {
-			p := New(strings.NewReader(tt.text))
-			initParser(p)
+			var p parser
+			initParser(&p, tt.text)
 			got, err := p.parseDecl(declStart), p.errors
 			if (err != nil) != tt.wantErr {
 				t.Errorf("parseDecl() error = %v, wantErr %v", err, tt.wantErr)
@@ -264,9 +614,8 @@ func pos(column int) token.Position {
 	return token.Position{Filename: "", Line: 1, Column: column}
 }
 
-func initParser(p *Parser) {
-	p.openScope()
-	p.pkgScope = p.topScope
+func initParser(p *parser, src string) {
+	p.init(token.NewFileSet(), "", []byte(src), 0, DeclarationErrors)
 	p.next()
 }
 
@@ -281,3 +630,263 @@ func equals(tb testing.TB, got, want interface{}) {
 		tb.Errorf("\033[31m\n\n\tgot: %#+v\n\n\twant: %#+v\n\n\t%s\033[39m\n\n", got, want, diff)
 	}
 }
+
+func TestParseFile_Modes(t *testing.T) {
+	// Every but the first line holds a redeclaration.
+	src := strings.Repeat("proc main() {}\n", 13)
+
+	_, err := ParseFile(token.NewFileSet(), "test.spl", src, DeclarationErrors)
+	if list, ok := err.(ErrorList); !ok || len(list) != maxErrors {
+		t.Errorf("got %d errors without AllErrors, want %d", len(list), maxErrors)
+	}
+	_, err = ParseFile(token.NewFileSet(), "test.spl", src, DeclarationErrors|AllErrors)
+	if list, ok := err.(ErrorList); !ok || len(list) != 12 {
+		t.Errorf("got %d errors with AllErrors, want 12", len(list))
+	}
+
+	src = "// Package comment.\nproc main() {\n  var x: int;\n  var x: int; // redeclared\n}\n"
+	prog, err := ParseFile(token.NewFileSet(), "test.spl", strings.NewReader(src), 0)
+	if err != nil {
+		t.Fatalf("got error %v without DeclarationErrors", err)
+	}
+	if len(prog.Comments) != 0 {
+		t.Errorf("got %d comments without ParseComments", len(prog.Comments))
+	}
+	prog, err = ParseFile(token.NewFileSet(), "test.spl", src, ParseComments|DeclarationErrors)
+	if list, ok := err.(ErrorList); !ok || len(list) != 1 || list[0].Code != diag.Redeclared {
+		t.Errorf("got error %v, want redeclaration", err)
+	}
+	want := []*ast.Comment{
+		{Slash: token.Position{Filename: "test.spl", Line: 1, Column: 1}, Text: "// Package comment."},
+		{Slash: token.Position{Filename: "test.spl", Line: 4, Column: 15}, Text: "// redeclared"},
+	}
+	equals(t, prog.Comments, want)
+}
+
+func TestParseFile_FileSet(t *testing.T) {
+	fset := token.NewFileSet()
+	if _, err := ParseFile(fset, "../testdata/valid.spl", nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	f := fset.File("../testdata/valid.spl")
+	if f == nil {
+		t.Fatal("source file not added to the file set")
+	}
+	src, err := ioutil.ReadFile("../testdata/valid.spl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(f.Source()) != string(src) {
+		t.Error("got different source in the file set")
+	}
+
+	if _, err := ParseFile(fset, "missing.spl", nil, 0); !os.IsNotExist(err) {
+		t.Errorf("got error %v, want file not found", err)
+	}
+	if _, err := ParseFile(fset, "test.spl", 42, 0); err == nil {
+		t.Error("expected error for invalid source")
+	}
+}
+
+func TestParseFile_Trace(t *testing.T) {
+	var buf strings.Builder
+	defer func(w io.Writer) { traceOutput = w }(traceOutput)
+	traceOutput = &buf
+
+	if _, err := ParseFile(token.NewFileSet(), "", "proc main() {}", Trace); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"    1:  1: \"proc\"",
+		"    1:  1: File (",
+		"    1:  1: . ProcDecl (",
+		"    1:  6: . . IDENT main",
+		"    1:  6: . . Ident (",
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) < len(want) {
+		t.Fatalf("got trace\n%s", buf.String())
+	}
+	for i, line := range want {
+		if lines[i] != line {
+			t.Errorf("got trace line %q, want %q", lines[i], line)
+		}
+	}
+	if got := lines[len(lines)-2]; got != "    1: 14: )" {
+		t.Errorf("got last trace line %q", got)
+	}
+}
+
+func TestParseFile_Recovery(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		errs  []string
+		decls string
+	}{
+		{
+			"bad operand",
+			"proc f() { x := ; }\nproc main() { y := 1; }\n",
+			[]string{"1:17: expected operand, found ';'"},
+			"ProcDecl(AssignStmt) ProcDecl(AssignStmt)",
+		},
+		{
+			"bad statement",
+			"proc main() {\n  ) x;\n  y := 1;\n}\n",
+			[]string{"2:3: expected statement, found ')'"},
+			"ProcDecl(BadStmt AssignStmt)",
+		},
+		{
+			"missing closing brace",
+			"proc f() {\n  x := 1;\nproc main() {}\n",
+			[]string{"3:1: expected '}', found 'proc'"},
+			"ProcDecl(AssignStmt) ProcDecl()",
+		},
+		{
+			"bad declaration",
+			"x := 1;\nvar y: int;\nproc main() {}\n",
+			[]string{"1:1: expected declaration, found x"},
+			"BadDecl VarDecl ProcDecl()",
+		},
+		{
+			"one error per line",
+			"proc main() { x := ; y := ); }\nproc f() { a := ; }\n",
+			[]string{"1:20: expected operand, found ';'", "2:17: expected operand, found ';'"},
+			"ProcDecl(AssignStmt AssignStmt BadStmt) ProcDecl(AssignStmt)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := ParseFile(token.NewFileSet(), "", tt.src, 0)
+			list, _ := err.(ErrorList)
+			var errs []string
+			for _, e := range list {
+				errs = append(errs, e.Error())
+			}
+			equals(t, errs, tt.errs)
+
+			if prog == nil {
+				t.Fatal("no program returned")
+			}
+			var decls []string
+			for _, decl := range prog.Decls {
+				switch decl := decl.(type) {
+				case *ast.ProcDecl:
+					var stmts []string
+					for _, stmt := range decl.Body.List {
+						stmts = append(stmts, strings.TrimPrefix(fmt.Sprintf("%T", stmt), "*ast."))
+					}
+					decls = append(decls, "ProcDecl("+strings.Join(stmts, " ")+")")
+				default:
+					decls = append(decls, strings.TrimPrefix(fmt.Sprintf("%T", decl), "*ast."))
+				}
+			}
+			if got := strings.Join(decls, " "); got != tt.decls {
+				t.Errorf("got declarations %s, want %s", got, tt.decls)
+			}
+		})
+	}
+}
+
+func TestParseFile_Strict(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"proc main() { var i: int; i := -1; }", ""},
+		{"proc main() { var i: int; i := +1; }", "1:32: unary operator + is not allowed in SPL 1.2"},
+		{"proc main() { var i: int; i := #i; }", "1:32: unary operator # is not allowed in SPL 1.2"},
+		{"proc main() { var i: int; i := *i; }", "1:32: unary operator * is not allowed in SPL 1.2"},
+		{"type T = (int); proc main() {}", "1:10: parenthesized types are not allowed in SPL 1.2"},
+		{"type A = array [1 + 1] of int; proc main() {}", "1:17: array length must be an integer literal in SPL 1.2"},
+		{"proc main() { var i: int; ; var j: int; }", "1:29: variable declarations must precede the statements of a procedure body in SPL 1.2"},
+		{"proc main() { { var i: int; } }", "1:17: variable declarations must precede the statements of a procedure body in SPL 1.2"},
+		{"proc main() { type T = int; }", "1:15: type declarations must not appear inside procedures in SPL 1.2"},
+		{"proc main() { printi(1) }", "1:24: ';' before '}' must not be omitted in SPL 1.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			if _, err := ParseFile(token.NewFileSet(), "", tt.src, 0); err != nil {
+				t.Fatalf("got error %v without Strict", err)
+			}
+			_, err := ParseFile(token.NewFileSet(), "", tt.src, Strict)
+			list, _ := err.(ErrorList)
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("got error %v, want none", err)
+			case tt.want != "" && (len(list) != 1 || list[0].Code != diag.NotInSpec || list[0].Error() != tt.want):
+				t.Errorf("got error %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFile_Resolve(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		errs []string
+	}{
+		{
+			"predeclared",
+			"proc main() { var i: int; readi(i); printi(i); }",
+			nil,
+		},
+		{
+			"forward procedure",
+			"proc main() { f(); }\nproc f() {}",
+			nil,
+		},
+		{
+			"forward type",
+			"type A = array [2] of B;\ntype B = int;",
+			[]string{"1:23: B used before its declaration\n\tdeclaration at 2:6"},
+		},
+		{
+			"recursive type",
+			"type A = array [2] of A;",
+			[]string{"1:23: A used before its declaration\n\tdeclaration at 1:6"},
+		},
+		{
+			"undeclared",
+			"proc main() {\n  x := y;\n  q();\n}",
+			[]string{"2:3: undeclared name: x", "3:3: undeclared name: q"},
+		},
+		{
+			"type as variable",
+			"type T = int;\nproc main() { var i: int;\n  i := T;\n}",
+			[]string{"3:8: type T is not an expression"},
+		},
+		{
+			"procedure as type",
+			"proc main() {\n  var i: printi;\n}",
+			[]string{"2:10: printi is not a type"},
+		},
+		{
+			"variable as procedure",
+			"proc main() { var i: int;\n  i();\n}",
+			[]string{"2:3: cannot call non-procedure i"},
+		},
+		{
+			"local scopes",
+			"proc f(i: int) { var i: int; }\nproc main() { { var j: int; } j := 1; }",
+			[]string{"1:22: i redeclared in this block\n\tprevious declaration at 1:8", "2:31: undeclared name: j"},
+		},
+		{
+			"missing names",
+			"var : int;\nvar : int;\nproc main() {}\nproc (i: int) { i := 1; }",
+			[]string{"1:5: expected 'IDENT', found ':'", "2:5: expected 'IDENT', found ':'", "4:6: expected 'IDENT', found '('"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFile(token.NewFileSet(), "", tt.src, DeclarationErrors)
+			list, _ := err.(ErrorList)
+			var errs []string
+			for _, e := range list {
+				errs = append(errs, e.Error())
+			}
+			equals(t, errs, tt.errs)
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/diag"
+)
+
+// resolve resolves the identifiers of the declarations of a program and
+// returns the ones which denote no declared or predeclared object. The
+// declarations of all source files share the package scope, which is nested in
+// the universe scope of the enabled extensions, which holds the library
+// procedures of tests in Testing mode. Errors are reported to p if the
+// DeclarationErrors mode is set.
+//
+// Procedures may be used before their declaration, types may not: Type
+// declarations and procedure signatures are resolved in source order,
+// procedure bodies afterwards.
+func resolve(p *parser, decls []ast.Decl) []*ast.Ident {
+	r := &resolver{
+		p:        p,
+		declErr:  p.mode&DeclarationErrors != 0,
+		topScope: ast.NewScope(ast.UniverseScope(p.exts, p.mode&Testing != 0)),
+		pending:  make(map[*ast.Object]bool),
+	}
+	r.decls(decls)
+	return r.unresolved
+}
+
+// decls resolves the declarations. It stops if too many errors are reported.
+func (r *resolver) decls(decls []ast.Decl) {
+	defer r.p.recover()
+
+	for _, decl := range decls {
+		switch d := decl.(type) {
+		case *ast.ImportDecl:
+			r.declare(d, ast.Mod, d.Name)
+		case *ast.VarDecl:
+			r.declare(d, ast.Var, d.Name)
+		case *ast.TypeDecl:
+			r.declare(d, ast.Typ, d.Name)
+			r.pending[d.Name.Obj] = true
+		case *ast.ProcDecl:
+			r.declare(d, ast.Pro, d.Name)
+		}
+	}
+
+	var (
+		procs  []*ast.ProcDecl
+		scopes []*ast.Scope
+	)
+	for _, decl := range decls {
+		switch d := decl.(type) {
+		case *ast.VarDecl:
+			r.resolveType(d.Type)
+			if d.Value != nil {
+				r.expr(d.Value, valueCtx)
+			}
+		case *ast.TypeDecl:
+			r.resolveType(d.Type)
+			delete(r.pending, d.Name.Obj)
+		case *ast.ProcDecl:
+			r.openScope()
+			r.params(d.Params)
+			if d.Result != nil {
+				r.resolveType(d.Result)
+			}
+			procs = append(procs, d)
+			scopes = append(scopes, r.topScope)
+			r.closeScope()
+		}
+	}
+	// The parameters and the local declarations of a procedure share a
+	// scope.
+	for i, d := range procs {
+		r.topScope = scopes[i]
+		r.stmtList(d.Body.List)
+		r.closeScope()
+	}
+}
+
+// resolver resolves the identifiers of a program. The resolved identifiers
+// refer to the object they denote by their Obj field.
+type resolver struct {
+	p       *parser
+	declErr bool // report declaration errors
+
+	topScope   *ast.Scope
+	unresolved []*ast.Ident
+
+	// pending holds the types of the package scope whose declaration
+	// hasn't been resolved yet.
+	pending map[*ast.Object]bool
+}
+
+// context describes how an identifier is used.
+type context int
+
+const (
+	valueCtx context = iota
+	typeCtx
+	callCtx
+	qualCtx // operand of a selector
+)
+
+func (r *resolver) openScope() {
+	r.topScope = ast.NewScope(r.topScope)
+}
+
+func (r *resolver) closeScope() {
+	r.topScope = r.topScope.Outer
+}
+
+// declare declares the identifiers in the innermost scope.
+func (r *resolver) declare(decl interface{}, kind ast.ObjKind, idents ...*ast.Ident) {
+	for _, ident := range idents {
+		if ident.Name == "" {
+			// The parser already reported the missing identifier.
+			continue
+		}
+		obj := ast.NewObj(kind, ident.Name)
+		obj.Decl = decl
+		ident.Obj = obj
+		if alt := r.topScope.Insert(obj); alt != nil && r.declErr {
+			r.p.report(&diag.Diagnostic{
+				Code:    diag.Redeclared,
+				Pos:     ident.Pos(),
+				End:     ident.End(),
+				Msg:     fmt.Sprintf("%s redeclared in this block", ident.Name),
+				Related: related(alt, "previous declaration"),
+			})
+		}
+	}
+}
+
+// ident resolves the identifier used in the context ctx.
+func (r *resolver) ident(ident *ast.Ident, ctx context) {
+	if ident.Name == "" {
+		return
+	}
+	var obj *ast.Object
+	for s := r.topScope; s != nil && obj == nil; s = s.Outer {
+		obj = s.Lookup(ident.Name)
+	}
+	if obj == nil {
+		r.unresolved = append(r.unresolved, ident)
+		r.errorf(ident, diag.UndeclaredName, "undeclared name: %s", ident.Name)
+		return
+	}
+	ident.Obj = obj
+
+	switch ctx {
+	case typeCtx:
+		if obj.Kind != ast.Typ {
+			r.errorf(ident, diag.NotAType, "%s is not a type", ident.Name)
+		} else if r.pending[obj] && r.declErr {
+			r.p.report(&diag.Diagnostic{
+				Code:    diag.UsedBeforeDecl,
+				Pos:     ident.Pos(),
+				End:     ident.End(),
+				Msg:     fmt.Sprintf("%s used before its declaration", ident.Name),
+				Related: related(obj, "declaration"),
+			})
+		}
+	case valueCtx:
+		switch obj.Kind {
+		case ast.Typ:
+			r.errorf(ident, diag.NotAnExpr, "type %s is not an expression", ident.Name)
+		case ast.Pro:
+			r.errorf(ident, diag.NotAnExpr, "procedure %s is not an expression", ident.Name)
+		case ast.Mod:
+			r.errorf(ident, diag.NotAnExpr, "module %s is not an expression", ident.Name)
+		}
+	case callCtx:
+		if obj.Kind != ast.Pro {
+			r.errorf(ident, diag.NotAProc, "cannot call non-procedure %s", ident.Name)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Declarations and statements
+
+func (r *resolver) params(list *ast.FieldList) {
+	if list == nil {
+		return
+	}
+	for _, f := range list.List {
+		r.resolveType(f.Type)
+		r.declare(f, ast.Var, f.Name)
+	}
+}
+
+func (r *resolver) stmtList(list []ast.Stmt) {
+	for _, s := range list {
+		r.stmt(s)
+	}
+}
+
+func (r *resolver) stmt(s ast.Stmt) {
+	switch s := s.(type) {
+	case *ast.DeclStmt:
+		switch d := s.Decl.(type) {
+		case *ast.VarDecl:
+			r.resolveType(d.Type)
+			if d.Value != nil {
+				r.expr(d.Value, valueCtx)
+			}
+			r.declare(d, ast.Var, d.Name)
+		case *ast.TypeDecl:
+			r.resolveType(d.Type)
+			r.declare(d, ast.Typ, d.Name)
+		}
+	case *ast.BlockStmt:
+		r.openScope()
+		r.stmtList(s.List)
+		r.closeScope()
+	case *ast.ExprStmt:
+		r.expr(s.X, valueCtx)
+	case *ast.AssignStmt:
+		r.expr(s.Left, valueCtx)
+		r.expr(s.Right, valueCtx)
+	case *ast.WhileStmt:
+		r.expr(s.Cond, valueCtx)
+		r.stmt(s.Body)
+	case *ast.IfStmt:
+		r.expr(s.Cond, valueCtx)
+		r.stmt(s.Body)
+		if s.Else != nil {
+			r.stmt(s.Else)
+		}
+	case *ast.ReturnStmt:
+		if s.Result != nil {
+			r.expr(s.Result, valueCtx)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Expressions and types
+
+func (r *resolver) resolveType(x ast.Expr) {
+	r.expr(x, typeCtx)
+}
+
+// expr resolves the identifiers of the expression x used in the context ctx.
+// Selectors and the field names of records are not resolved, they are looked
+// up by the type checker.
+func (r *resolver) expr(x ast.Expr, ctx context) {
+	switch x := x.(type) {
+	case *ast.Ident:
+		r.ident(x, ctx)
+	case *ast.ParenExpr:
+		r.expr(x.X, ctx)
+	case *ast.UnaryExpr:
+		r.expr(x.X, valueCtx)
+	case *ast.BinaryExpr:
+		r.expr(x.X, valueCtx)
+		r.expr(x.Y, valueCtx)
+	case *ast.IndexExpr:
+		r.expr(x.X, valueCtx)
+		r.expr(x.Index, valueCtx)
+	case *ast.SelectorExpr:
+		r.expr(x.X, qualCtx)
+	case *ast.CallExpr:
+		r.expr(x.Pro, callCtx)
+		for _, arg := range x.Args {
+			r.expr(arg, valueCtx)
+		}
+	case *ast.ArrayType:
+		if x.Len != nil {
+			r.expr(x.Len, valueCtx)
+		}
+		r.expr(x.Elt, typeCtx)
+	case *ast.RecordType:
+		for _, f := range x.Fields.List {
+			r.resolveType(f.Type)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Error reporting
+
+// errorf reports an error spanning the identifier if the DeclarationErrors mode
+// is set.
+func (r *resolver) errorf(ident *ast.Ident, code diag.Code, format string, args ...interface{}) {
+	if !r.declErr {
+		return
+	}
+	r.p.report(&diag.Diagnostic{
+		Code: code,
+		Pos:  ident.Pos(),
+		End:  ident.End(),
+		Msg:  fmt.Sprintf(format, args...),
+	})
+}
+
+// related returns the location of the declaration of obj labeled msg. It is
+// empty for predeclared objects.
+func related(obj *ast.Object, msg string) []diag.Related {
+	pos := obj.Pos()
+	if !pos.IsValid() {
+		return nil
+	}
+	end := pos
+	end.Column += len(obj.Name)
+	end.Char += len(obj.Name)
+	return []diag.Related{{Pos: pos, End: end, Msg: msg}}
+}
@@ -0,0 +1,5 @@
+// Package profile records where a program spends its time and writes the
+// recording as profile in the format of pprof (https://github.com/google/pprof),
+// so the existing tools like go tool pprof can analyze it. The call stacks of
+// the profile consist of procedures and source lines.
+package profile
@@ -0,0 +1,61 @@
+package profile
+
+// buffer encodes protocol buffer messages. Only the wire types used by the
+// profile message are supported.
+type buffer struct {
+	b []byte
+}
+
+// varint appends the field with the value as varint.
+func (b *buffer) varint(field int, v uint64) {
+	b.uvarint(uint64(field)<<3 | 0)
+	b.uvarint(v)
+}
+
+// int appends the field with the integer value. Zero values are omitted.
+func (b *buffer) int(field int, v int64) {
+	if v != 0 {
+		b.varint(field, uint64(v))
+	}
+}
+
+// bool appends the field with the boolean value. False values are omitted.
+func (b *buffer) bool(field int, v bool) {
+	if v {
+		b.varint(field, 1)
+	}
+}
+
+// bytes appends the field with the length delimited value.
+func (b *buffer) bytes(field int, v []byte) {
+	b.uvarint(uint64(field)<<3 | 2)
+	b.uvarint(uint64(len(v)))
+	b.b = append(b.b, v...)
+}
+
+// string appends the field with the string value.
+func (b *buffer) string(field int, v string) { b.bytes(field, []byte(v)) }
+
+// message appends the field with an embedded message encoded by enc.
+func (b *buffer) message(field int, enc func(b *buffer)) {
+	var m buffer
+	enc(&m)
+	b.bytes(field, m.b)
+}
+
+// packed appends the repeated field with the values in packed encoding.
+func (b *buffer) packed(field int, vs []uint64) {
+	var m buffer
+	for _, v := range vs {
+		m.uvarint(v)
+	}
+	b.bytes(field, m.b)
+}
+
+func (b *buffer) uvarint(v uint64) {
+	for v >= 0x80 {
+		b.b = append(b.b, byte(v)|0x80)
+		v >>= 7
+	}
+	b.b = append(b.b, byte(v))
+}
@@ -0,0 +1,296 @@
+package profile
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/interp"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+// Period is the sampling period of the time spent on source lines.
+const Period = 10 * time.Millisecond
+
+// Profiler records the execution of a program. For every call stack and source
+// line it counts the executed statements and samples the time spent.
+type Profiler struct {
+	m        *interp.Machine
+	hooks    interp.Hooks
+	start    time.Time
+	duration time.Duration
+	ticker   *time.Ticker
+	done     chan struct{}
+	ticks    int64
+
+	root *node
+	cur  *node // activation of the procedure executed
+	last *node // activation and line of the statement executed
+	line int
+}
+
+// node is a procedure activation in the call tree of a program.
+type node struct {
+	parent   *node
+	proc     *types.Proc
+	line     int // line of the call in the parent
+	children map[callSite]*node
+	counts   map[int]*count
+}
+
+type callSite struct {
+	proc *types.Proc
+	line int
+}
+
+// count holds the number of executed statements and time samples of a line.
+type count struct {
+	stmts, samples int64
+}
+
+// Start starts profiling the program executed by the machine. Hooks installed
+// on the machine before are still called.
+func Start(m *interp.Machine) *Profiler {
+	p := &Profiler{
+		m:      m,
+		hooks:  m.Hooks,
+		start:  time.Now(),
+		ticker: time.NewTicker(Period),
+		done:   make(chan struct{}),
+		root:   &node{},
+	}
+	p.cur = p.root
+	m.Hooks = interp.Hooks{Stmt: p.stmt, Call: p.call, Return: p.ret}
+
+	go func() {
+		for {
+			select {
+			case <-p.ticker.C:
+				atomic.AddInt64(&p.ticks, 1)
+			case <-p.done:
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// Stop stops profiling. It must be called after the program terminated.
+func (p *Profiler) Stop() {
+	p.ticker.Stop()
+	close(p.done)
+	p.sample()
+	p.duration = time.Since(p.start)
+	p.m.Hooks = p.hooks
+}
+
+// sample attributes the time elapsed since the last sample to the statement
+// executed.
+func (p *Profiler) sample() {
+	if n := atomic.SwapInt64(&p.ticks, 0); n > 0 && p.last != nil {
+		p.last.count(p.line).samples += n
+	}
+}
+
+func (p *Profiler) stmt(s ast.Stmt) error {
+	p.sample()
+	p.last, p.line = p.cur, s.Pos().Line
+	p.cur.count(p.line).stmts++
+	if p.hooks.Stmt != nil {
+		return p.hooks.Stmt(s)
+	}
+	return nil
+}
+
+func (p *Profiler) call(f *interp.Frame) error {
+	site := callSite{proc: f.Proc}
+	if frames := p.m.Frames(); len(frames) > 1 {
+		site.line = frames[len(frames)-2].Pos.Line
+	}
+	n, ok := p.cur.children[site]
+	if !ok {
+		n = &node{parent: p.cur, proc: site.proc, line: site.line}
+		if p.cur.children == nil {
+			p.cur.children = make(map[callSite]*node)
+		}
+		p.cur.children[site] = n
+	}
+	p.cur = n
+	if p.hooks.Call != nil {
+		return p.hooks.Call(f)
+	}
+	return nil
+}
+
+func (p *Profiler) ret(f *interp.Frame) error {
+	p.cur = p.cur.parent
+	if p.hooks.Return != nil {
+		return p.hooks.Return(f)
+	}
+	return nil
+}
+
+func (n *node) count(line int) *count {
+	if n.counts == nil {
+		n.counts = make(map[int]*count)
+	}
+	c, ok := n.counts[line]
+	if !ok {
+		c = &count{}
+		n.counts[line] = c
+	}
+	return c
+}
+
+// -----------------------------------------------------------------------------
+// Encoding
+
+// Write writes the profile as gzip compressed protocol buffer as specified by
+// https://github.com/google/pprof/blob/master/proto/profile.proto. The
+// samples have the values samples/count, cpu/nanoseconds and
+// statements/count.
+func (p *Profiler) Write(w io.Writer) error {
+	e := &encoder{
+		strings:   map[string]int64{"": 0},
+		table:     []string{""},
+		locations: make(map[callSite]uint64),
+		functions: make(map[*types.Proc]uint64),
+	}
+	var b buffer
+
+	valueType := func(typ, unit string) func(*buffer) {
+		return func(b *buffer) {
+			b.int(1, e.string(typ))
+			b.int(2, e.string(unit))
+		}
+	}
+	b.message(1, valueType("samples", "count"))
+	b.message(1, valueType("cpu", "nanoseconds"))
+	b.message(1, valueType("statements", "count"))
+
+	p.root.walk(func(n *node, line int, c *count) {
+		stack := []uint64{e.location(n.proc, line)}
+		for ; n.parent.proc != nil; n = n.parent {
+			stack = append(stack, e.location(n.parent.proc, n.line))
+		}
+		b.message(2, func(b *buffer) {
+			b.packed(1, stack)
+			b.packed(2, []uint64{uint64(c.samples), uint64(c.samples * int64(Period)), uint64(c.stmts)})
+		})
+	})
+
+	filename := ""
+	if len(e.procs) > 0 {
+		filename = e.procs[0].Decl().Pos().Filename
+	}
+	b.message(3, func(b *buffer) {
+		b.int(1, 1)
+		b.int(5, e.string(filename))
+		b.bool(7, true)
+		b.bool(8, true)
+		b.bool(9, true)
+	})
+	for i, site := range e.sites {
+		b.message(4, func(b *buffer) {
+			b.int(1, int64(i+1))
+			b.int(2, 1)
+			b.message(4, func(b *buffer) {
+				b.int(1, int64(e.functions[site.proc]))
+				b.int(2, int64(site.line))
+			})
+		})
+	}
+	for i, proc := range e.procs {
+		pos := proc.Decl().Pos()
+		b.message(5, func(b *buffer) {
+			b.int(1, int64(i+1))
+			b.int(2, e.string(proc.Name()))
+			b.int(3, e.string(proc.Name()))
+			b.int(4, e.string(pos.Filename))
+			b.int(5, int64(pos.Line))
+		})
+	}
+
+	b.int(9, p.start.UnixNano())
+	b.int(10, int64(p.duration))
+	b.message(11, valueType("cpu", "nanoseconds"))
+	b.int(12, int64(Period))
+	b.int(14, e.string("cpu"))
+
+	// The string table is written last, when all strings are known.
+	for _, s := range e.table {
+		b.string(6, s)
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.b); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// walk calls fn for the lines of the activations in the call tree, in the
+// order of their source lines.
+func (n *node) walk(fn func(n *node, line int, c *count)) {
+	lines := make([]int, 0, len(n.counts))
+	for line := range n.counts {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	for _, line := range lines {
+		fn(n, line, n.counts[line])
+	}
+
+	children := make([]*node, 0, len(n.children))
+	for _, c := range n.children {
+		children = append(children, c)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		if children[i].line != children[j].line {
+			return children[i].line < children[j].line
+		}
+		return children[i].proc.Name() < children[j].proc.Name()
+	})
+	for _, c := range children {
+		c.walk(fn)
+	}
+}
+
+// encoder assigns the IDs of the locations and functions and the indices of
+// the string table.
+type encoder struct {
+	strings   map[string]int64
+	table     []string
+	locations map[callSite]uint64
+	sites     []callSite
+	functions map[*types.Proc]uint64
+	procs     []*types.Proc
+}
+
+func (e *encoder) string(s string) int64 {
+	i, ok := e.strings[s]
+	if !ok {
+		i = int64(len(e.table))
+		e.strings[s] = i
+		e.table = append(e.table, s)
+	}
+	return i
+}
+
+func (e *encoder) location(proc *types.Proc, line int) uint64 {
+	site := callSite{proc, line}
+	id, ok := e.locations[site]
+	if !ok {
+		if _, ok := e.functions[proc]; !ok {
+			e.procs = append(e.procs, proc)
+			e.functions[proc] = uint64(len(e.procs))
+		}
+		e.sites = append(e.sites, site)
+		id = uint64(len(e.sites))
+		e.locations[site] = id
+	}
+	return id
+}
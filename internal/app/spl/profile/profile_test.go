@@ -0,0 +1,88 @@
+package profile_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/internal/testutil"
+	"github.com/lukasmalkmus/spl/internal/app/spl/interp"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+	"github.com/lukasmalkmus/spl/internal/app/spl/profile"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+const src = `proc f(n: int) {
+  var i: int;
+  while (i < n) {
+    i := i + 1;
+  }
+}
+
+proc main() {
+  f(2);
+  f(3);
+}
+`
+
+func TestProfiler(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	filename := filepath.Join(dir, "test.spl")
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	prog, err := parser.ParseFile(token.NewFileSet(), filename, nil, parser.DeclarationErrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := types.Check(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := interp.New(prog, info, strings.NewReader(""), &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := profile.Start(m)
+	if err := m.Run(); err != nil {
+		t.Fatal(err)
+	}
+	p.Stop()
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	profname := filepath.Join(dir, "prof.pb.gz")
+	if err := ioutil.WriteFile(profname, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command(goTool, "tool", "pprof", "-top", "-lines", "-sample_index=statements", profname).CombinedOutput()
+	if err != nil {
+		t.Fatalf("pprof failed: %v\n%s", err, out)
+	}
+	got := strings.ReplaceAll(string(out), dir+string(filepath.Separator), "")
+	for _, want := range []string{
+		"Type: statements",
+		"Total samples = 14",
+		"7 50.00% 50.00%          7 50.00%  f test.spl:3",
+		"5 35.71% 85.71%          5 35.71%  f test.spl:4",
+		"1  7.14% 92.86%          8 57.14%  main test.spl:10",
+		"1  7.14%   100%          6 42.86%  main test.spl:9",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("got pprof output\n%s\nwant it to contain %q", got, want)
+		}
+	}
+}
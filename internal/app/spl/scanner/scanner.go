@@ -3,20 +3,34 @@ package scanner
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"io"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/lukasmalkmus/spl/internal/app/spl/token"
 )
 
 var eof = rune(0)
 
+// maxInt is the largest value of an integer literal.
+const maxInt = 1<<31 - 1
+
+// An ErrorHandler may be provided to Scanner.SetErrorHandler. If a lexical
+// error is encountered and a handler was installed, the handler is called with
+// the position of the error and a message describing it.
+type ErrorHandler func(pos token.Position, msg string)
+
 // Scanner represents a lexical scanner which tokenizes source code.
 type Scanner struct {
 	r                *bufio.Reader
 	pos              token.Position
 	resetColumnCount bool
+	err              ErrorHandler
+
+	// ErrorCount is the number of lexical errors encountered.
+	ErrorCount int
 }
 
 // New returns a new Scanner instance which reads from the given reader.
@@ -30,13 +44,28 @@ func New(r io.Reader) *Scanner {
 // NewFileScanner returns a new Scanner instance, but will exclusively take an
 // *os.File as argument instead of the more general io.Reader interface.
 // Therefore it will enhance token positions with the filename.
-func NewFileScanner(f *os.File) *Scanner {
+func NewFileScanner(f *os.File) *Scanner { return NewFile(f.Name(), f) }
+
+// NewFile returns a new Scanner instance which reads the source file with the
+// given name from r. Token positions carry the filename.
+func NewFile(filename string, r io.Reader) *Scanner {
 	return &Scanner{
-		r:   bufio.NewReader(f),
-		pos: token.Position{Filename: f.Name(), Line: 1, Column: 0},
+		r:   bufio.NewReader(r),
+		pos: token.Position{Filename: filename, Line: 1, Column: 0},
 	}
 }
 
+// SetErrorHandler installs the handler called for lexical errors.
+func (s *Scanner) SetErrorHandler(h ErrorHandler) { s.err = h }
+
+// error reports a lexical error at pos.
+func (s *Scanner) error(pos token.Position, msg string) {
+	if s.err != nil {
+		s.err(pos, msg)
+	}
+	s.ErrorCount++
+}
+
 // Scan scans the next token and returns the token itself, its literal and its
 // position in the source code. The source end is indicated by token.EOF.
 func (s *Scanner) Scan() (token.Token, string, token.Position) {
@@ -45,8 +74,8 @@ func (s *Scanner) Scan() (token.Token, string, token.Position) {
 
 	// If we see a letter consume as an ident or reserved word.
 	// If we see a digit consume as an integer.
-	// If we see a "'" consume as an integer as well, but us a specialized
-	// scanning method.
+	// If we see a "'" consume as a character literal, which is an integer as
+	// well.
 	if isLetter(ch) {
 		s.unread()
 		return s.scanIdent()
@@ -55,7 +84,10 @@ func (s *Scanner) Scan() (token.Token, string, token.Position) {
 		return s.scanInteger()
 	} else if ch == '\'' {
 		s.unread()
-		return s.scanSpecialInteger()
+		return s.scanChar()
+	} else if ch == '"' {
+		s.unread()
+		return s.scanString()
 	}
 
 	// Otherwise tokenize the individual characters. No match results in an
@@ -72,7 +104,7 @@ func (s *Scanner) Scan() (token.Token, string, token.Position) {
 		return token.MUL, string(ch), pos
 	case '/':
 		if pch := s.peek(); pch == '/' {
-			return s.scanComment()
+			return s.scanComment(pos)
 		}
 		return token.QUO, string(ch), pos
 	case '=':
@@ -111,19 +143,23 @@ func (s *Scanner) Scan() (token.Token, string, token.Position) {
 		return token.RBRACE, string(ch), pos
 	case ',':
 		return token.COMMA, string(ch), pos
+	case '.':
+		return token.PERIOD, string(ch), pos
 	case ';':
 		return token.SEMICOLON, string(ch), pos
 	}
+	s.error(pos, fmt.Sprintf("illegal character %#U", ch))
 	return token.ILLEGAL, string(ch), pos
 }
 
-// scanComment consumes the current rune and all contiguous comment runes.
-func (s *Scanner) scanComment() (token.Token, string, token.Position) {
+// scanComment consumes the current rune and all contiguous comment runes. The
+// position pos is the one of the first slash, which has already been consumed.
+func (s *Scanner) scanComment(pos token.Position) (token.Token, string, token.Position) {
 	// Create a buffer for the comments text. It is initially populated with a
 	// slash which is the first slash of the comment token.
 	var buf bytes.Buffer
 	_ = buf.WriteByte('/')
-	ch, pos := s.read()
+	ch, _ := s.read()
 	_, _ = buf.WriteRune(ch)
 
 	// Read every subsequent character into the buffer. Newline or EOF will
@@ -141,7 +177,8 @@ func (s *Scanner) scanComment() (token.Token, string, token.Position) {
 	return token.COMMENT, buf.String(), pos
 }
 
-// scanIdent consumes the current rune and all contiguous ident runes.
+// scanIdent consumes the current rune and all contiguous ident runes. The
+// underscore counts as letter.
 func (s *Scanner) scanIdent() (token.Token, string, token.Position) {
 	var buf bytes.Buffer
 	ch, pos := s.read()
@@ -152,29 +189,27 @@ func (s *Scanner) scanIdent() (token.Token, string, token.Position) {
 	for {
 		if ch, _ := s.read(); ch == eof {
 			break
-		} else if !isLetter(ch) && !isDigit(ch) && ch != '_' {
+		} else if !isLetter(ch) && !isDigit(ch) {
 			s.unread()
 			break
 		} else {
 			_, _ = buf.WriteRune(ch)
 		}
 	}
-
-	// Make sure the last character is not an underscore, which is illegal.
-	if ch := buf.Bytes()[buf.Len()-1]; ch == '_' {
-		return token.ILLEGAL, buf.String(), pos
-	}
 	return token.Lookup(buf.String()), buf.String(), pos
 }
 
-// scanInteger consumes the current rune and all contiguous integer runes.
+// scanInteger consumes the current rune and all contiguous integer runes. An
+// integer is either decimal or hexadecimal with the prefix "0x". Its value must
+// not exceed 2^31-1.
 func (s *Scanner) scanInteger() (token.Token, string, token.Position) {
 	var buf bytes.Buffer
 	ch, pos := s.read()
 	_, _ = buf.WriteRune(ch)
 
-	// Read every subsequent character into the buffer. Non-integer characters
-	// and EOF will cause the loop to exit.
+	// Read every subsequent character into the buffer. Letters are read as
+	// well, so malformed literals like 123x are reported as a whole. Other
+	// characters and EOF will cause the loop to exit.
 	for {
 		if ch, _ := s.read(); ch == eof {
 			break
@@ -186,63 +221,107 @@ func (s *Scanner) scanInteger() (token.Token, string, token.Position) {
 		}
 	}
 
-	// Uppercase 'X' not allowed in hexadecimal representation.
-	if bytes.ContainsRune(buf.Bytes(), 'X') {
-		return token.ILLEGAL, buf.String(), pos
-	} else if _, err := strconv.ParseInt(buf.String(), 0, 32); err != nil {
-		return token.ILLEGAL, buf.String(), pos
+	lit := buf.String()
+	digits, base, kind := lit, 10, "decimal"
+	if len(lit) > 1 && lit[0] == '0' && (lit[1] == 'x' || lit[1] == 'X') {
+		if lit[1] == 'X' {
+			s.error(pos, `hexadecimal literal must start with "0x", not "0X"`)
+			return token.INT, lit, pos
+		}
+		digits, base, kind = lit[2:], 16, "hexadecimal"
+		if digits == "" {
+			s.error(pos, "hexadecimal literal has no digits")
+			return token.INT, lit, pos
+		}
 	}
-	return token.INT, buf.String(), pos
+	for i, ch := range digits {
+		if !isDigit(ch) && (base != 16 || !isHexLetter(ch)) {
+			at := pos
+			at.Column += len(lit) - len(digits) + i
+			at.Char += len(lit) - len(digits) + i
+			s.error(at, fmt.Sprintf("invalid character %q in %s literal", ch, kind))
+			return token.INT, lit, pos
+		}
+	}
+	if v, err := strconv.ParseUint(digits, base, 64); err != nil || v > maxInt {
+		s.error(pos, fmt.Sprintf("integer literal %s exceeds 2^31-1", lit))
+	}
+	return token.INT, lit, pos
 }
 
-// scanSpecialInteger consumes the current rune and all contiguous special
-// integer runes.
-func (s *Scanner) scanSpecialInteger() (token.Token, string, token.Position) {
+// scanChar consumes a character literal, which is a printable ASCII character
+// or the escape sequence \n enclosed by single quotation marks.
+func (s *Scanner) scanChar() (token.Token, string, token.Position) {
 	var buf bytes.Buffer
 	ch, pos := s.read()
 	_, _ = buf.WriteRune(ch)
 
-	// Read every subsequent character into the buffer. Non-integer characters,
-	// multiple whitespaces and EOF will cause the loop to exit.
-	var (
-		charCount uint8 = 1 // We already saw one.
-		sqmCount  uint8 = 1 // We already saw one.
-		wsCount   uint8
-	)
-	for {
-		if ch, _ := s.read(); ch == eof {
-			break
-		} else if (sqmCount == 2 && charCount >= 3) || sqmCount > 2 || wsCount > 2 {
+	ch, chPos := s.read()
+	switch {
+	case ch == eof || isNewline(ch):
+		s.unread()
+		s.error(pos, "unterminated character literal")
+		return token.INT, buf.String(), pos
+	case ch == '\\':
+		_, _ = buf.WriteRune(ch)
+		esc, _ := s.read()
+		if esc == eof || isNewline(esc) {
 			s.unread()
-			break
-		} else {
-			charCount++
-			if ch == '\'' {
-				sqmCount++
-			} else if ch == ' ' {
-				wsCount++
-			}
-			_, _ = buf.WriteRune(ch)
+			s.error(pos, "unterminated character literal")
+			return token.INT, buf.String(), pos
+		}
+		_, _ = buf.WriteRune(esc)
+		if esc != 'n' {
+			s.error(chPos, fmt.Sprintf("invalid escape sequence \\%c", esc))
+		}
+	default:
+		_, _ = buf.WriteRune(ch)
+		if ch < ' ' || ch > '~' {
+			s.error(chPos, fmt.Sprintf("illegal character %#U in character literal", ch))
 		}
 	}
-	b := buf.Bytes()
 
-	// The first character is a tick so the last one must be one, too.
-	if l := len(b); l < 3 || b[l-1] != '\'' {
-		return token.ILLEGAL, buf.String(), pos
+	if ch, _ := s.read(); ch != '\'' {
+		s.unread()
+		s.error(pos, "unterminated character literal")
+		return token.INT, buf.String(), pos
 	}
+	_, _ = buf.WriteRune('\'')
+	return token.INT, buf.String(), pos
+}
 
-	// If the length of the input is three, the character encapsuled by the
-	// single quotation marks must be a printable ASCII character.
-	// If the length of the input is four, the characters encapsuled by the
-	// single quotation marks must form an ASCII escape sequence by the first
-	// character being a backslash and the second character being a letter.
-	if len(b) == 3 && b[1] > 31 && b[1] < 127 {
-		return token.INT, buf.String(), pos
-	} else if len(b) == 4 && b[1] == '\\' && isLetter(rune(b[2])) {
-		return token.INT, buf.String(), pos
+// scanString consumes the current rune and all runes up to and including the
+// closing double quotation mark. The literal consists of printable ASCII
+// characters and the escape sequences \n, \t, \" and \\. It must not span
+// multiple lines.
+func (s *Scanner) scanString() (token.Token, string, token.Position) {
+	var buf bytes.Buffer
+	ch, pos := s.read()
+	_, _ = buf.WriteRune(ch)
+
+	var escPos token.Position
+	for escaped := false; ; {
+		ch, chPos := s.read()
+		if ch == eof || isNewline(ch) {
+			s.unread()
+			s.error(pos, "unterminated string literal")
+			return token.STRING, buf.String(), pos
+		}
+		_, _ = buf.WriteRune(ch)
+		switch {
+		case escaped:
+			escaped = false
+			if !strings.ContainsRune(`nt"\`, ch) {
+				s.error(escPos, fmt.Sprintf("invalid escape sequence \\%c", ch))
+			}
+		case ch == '\\':
+			escaped, escPos = true, chPos
+		case ch == '"':
+			return token.STRING, buf.String(), pos
+		case ch < ' ' || ch > '~':
+			s.error(chPos, fmt.Sprintf("illegal character %#U in string literal", ch))
+		}
 	}
-	return token.ILLEGAL, buf.String(), pos
 }
 
 // skipWhitespace consumes the current rune and all contiguous newline and
@@ -298,8 +377,14 @@ func isWhitespace(ch rune) bool { return ch == ' ' || ch == '\t' }
 // isNewline returns true if the rune is a newline.
 func isNewline(ch rune) bool { return ch == '\n' || ch == '\r' }
 
-// isLetter returns true if the rune is a letter.
-func isLetter(ch rune) bool { return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') }
+// isLetter returns true if the rune is a letter. The underscore counts as
+// letter.
+func isLetter(ch rune) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '_'
+}
+
+// isHexLetter returns true if the rune is a hexadecimal digit greater than 9.
+func isHexLetter(ch rune) bool { return (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F') }
 
 // isDigit returns true if the rune is a digit.
 func isDigit(ch rune) bool { return (ch >= '0' && ch <= '9') }
@@ -21,18 +21,16 @@ func TestScanner_Scan(t *testing.T) {
 	}{
 		// Special tokens
 		{"!", token.ILLEGAL, "!", 1},
-		{"_", token.ILLEGAL, "_", 1},
-		{"_x", token.ILLEGAL, "_", 1},      // Underscore can't prefix identifier
-		{"foo_", token.ILLEGAL, "foo_", 1}, // Underscore can't suffix identifier
-		{"_123", token.ILLEGAL, "_", 1},    // Underscore can't prefix integer
-		{"1foo", token.ILLEGAL, "1foo", 1}, // Integer can't suffix identifier
-		{".", token.ILLEGAL, ".", 1},
-		{".123", token.ILLEGAL, ".", 1},      // Dot can't prefix integer.
-		{"123x", token.ILLEGAL, "123x", 1},   // Illegal integer
-		{"0XAB", token.ILLEGAL, "0XAB", 1},   // Illegal integer
-		{"0xx08", token.ILLEGAL, "0xx08", 1}, // Illegal integer
-		{"'", token.ILLEGAL, "'", 1},         // Illegal integer (ASCII syntax)
-		{"''", token.ILLEGAL, "''", 1},       // Illegal integer (ASCII syntax)
+		{"ä", token.ILLEGAL, "ä", 1},
+		{"1foo", token.INT, "1foo", 1},      // Integer can't prefix identifier
+		{".123", token.PERIOD, ".", 1},      // Dot can't prefix integer.
+		{"123x", token.INT, "123x", 1},      // Illegal integer
+		{"0XAB", token.INT, "0XAB", 1},      // Illegal integer
+		{"0xx08", token.INT, "0xx08", 1},    // Illegal integer
+		{"'", token.INT, "'", 1},            // Unterminated character literal
+		{`"abc`, token.STRING, `"abc`, 1},   // Unterminated string
+		{"\"a\nb\"", token.STRING, `"a`, 1}, // String spans multiple lines
+		{"''", token.INT, "''", 1},          // Unterminated character literal
 		{" x", token.IDENT, "x", 1},
 		{"\nx", token.IDENT, "x", 2},
 		{"", token.EOF, "", 1},
@@ -48,10 +46,18 @@ func TestScanner_Scan(t *testing.T) {
 
 		// Literals
 		{"x", token.IDENT, "x", 1},
+		{`"lib/sort.spl"`, token.STRING, `"lib/sort.spl"`, 1},
+		{`"a\"b\\" x`, token.STRING, `"a\"b\\"`, 1},
 		{"foo ", token.IDENT, "foo", 1},
 		{"foo_bar", token.IDENT, "foo_bar", 1},
 		{"foo1", token.IDENT, "foo1", 1},
 		{"foo_1", token.IDENT, "foo_1", 1},
+		{"_", token.IDENT, "_", 1},       // Underscore is a letter
+		{"_x", token.IDENT, "_x", 1},     // Underscore can prefix identifier
+		{"foo_", token.IDENT, "foo_", 1}, // Underscore can suffix identifier
+		{"_123", token.IDENT, "_123", 1}, // Identifier, not integer
+		{"2147483647", token.INT, "2147483647", 1},
+		{"0x7fffffff", token.INT, "0x7fffffff", 1},
 		{"8", token.INT, "8", 1},
 		{"64", token.INT, "64", 1},
 		{"128", token.INT, "128", 1},
@@ -90,13 +96,16 @@ func TestScanner_Scan(t *testing.T) {
 		{"}", token.RBRACE, "}", 1},
 
 		{",", token.COMMA, ",", 1},
+		{".", token.PERIOD, ".", 1},
 		{":", token.COLON, ":", 1},
 		{";", token.SEMICOLON, ";", 1},
 
 		// Keywords
 		{"array", token.ARRAY, "array", 1},
+		{"as", token.AS, "as", 1},
 		{"else", token.ELSE, "else", 1},
 		{"if", token.IF, "if", 1},
+		{"import", token.IMPORT, "import", 1},
 		{"of", token.OF, "of", 1},
 		{"proc", token.PROC, "proc", 1},
 		{"ref", token.REF, "ref", 1},
@@ -116,6 +125,59 @@ func TestScanner_Scan(t *testing.T) {
 	}
 }
 
+func TestScanner_Scan_Errors(t *testing.T) {
+	tests := []struct {
+		str string
+		col int
+		err string
+	}{
+		{"ä", 1, "illegal character U+00E4 'ä'"},
+		{"x := 1 ! 2", 8, "illegal character U+0021 '!'"},
+		{"'", 1, "unterminated character literal"},
+		{"'a", 1, "unterminated character literal"},
+		{"'\n", 1, "unterminated character literal"},
+		{`'\q'`, 2, `invalid escape sequence \q`},
+		{`'\t'`, 2, `invalid escape sequence \t`},
+		{"'\t'", 2, "illegal character U+0009 in character literal"},
+		{"2147483648", 1, "integer literal 2147483648 exceeds 2^31-1"},
+		{"99999999999999999999", 1, "integer literal 99999999999999999999 exceeds 2^31-1"},
+		{"0x80000000", 1, "integer literal 0x80000000 exceeds 2^31-1"},
+		{"0XAB", 1, `hexadecimal literal must start with "0x", not "0X"`},
+		{"0x", 1, "hexadecimal literal has no digits"},
+		{"0xx08", 3, "invalid character 'x' in hexadecimal literal"},
+		{"123x", 4, "invalid character 'x' in decimal literal"},
+		{"0xAg", 4, "invalid character 'g' in hexadecimal literal"},
+		{`"abc`, 1, "unterminated string literal"},
+		{`"a\qb"`, 3, `invalid escape sequence \q`},
+		{"\"a\tb\"", 3, "illegal character U+0009 in string literal"},
+		{`"\\\"`, 1, "unterminated string literal"},
+
+		// The check for an uppercase X applies to the prefix only.
+		{"0xAB X", 0, ""},
+		{`'\n'`, 0, ""},
+		{`"\n\t\"\\"`, 0, ""},
+		{"_x_", 0, ""},
+	}
+	for _, tt := range tests {
+		_ = t.Run(tt.str, func(t *testing.T) {
+			s := scanner.New(strings.NewReader(tt.str))
+			var errs []string
+			s.SetErrorHandler(func(pos token.Position, msg string) {
+				errs = append(errs, msg)
+				equals(t, pos.Column, tt.col)
+			})
+			for tok, _, _ := s.Scan(); tok != token.EOF; tok, _, _ = s.Scan() {
+			}
+			var want []string
+			if tt.err != "" {
+				want = []string{tt.err}
+			}
+			equals(t, errs, want)
+			equals(t, s.ErrorCount, len(want))
+		})
+	}
+}
+
 func TestScanner_ScanFullValidProgram(t *testing.T) {
 	f, err := os.Open("../testdata/valid.spl")
 	if err != nil {
@@ -0,0 +1,5 @@
+// Package tester runs the tests of source files named *_test.spl. Each
+// parameterless procedure whose name starts with "test" is run as a test. The
+// output follows the one of go test and is optionally written as stream of
+// events in the format of go tool test2json.
+package tester
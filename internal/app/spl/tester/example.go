@@ -0,0 +1,157 @@
+package tester
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/scanner"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+)
+
+// errNoExample is returned for source files which are no examples.
+var errNoExample = errors.New("no example")
+
+// example is a program whose expected output is given by the comments at the
+// end of its source file. The comments following a line "// Output:" hold the
+// expected output, the ones following a line "// Input:" the input of the
+// program:
+//
+//	// Input:
+//	// 21
+//	// Output:
+//	// 42
+type example struct {
+	Input  string
+	Output string
+}
+
+// readExample reads the example from the source file. If the file doesn't end
+// with an output comment, nil is returned.
+func readExample(filename string) (*example, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Collect the comments following the last token which is no comment.
+	var comments []string
+	s := scanner.NewFileScanner(f)
+	for {
+		tok, lit, _ := s.Scan()
+		if tok == token.EOF {
+			break
+		} else if tok == token.COMMENT {
+			comments = append(comments, strings.TrimPrefix(strings.TrimPrefix(lit, "//"), " "))
+		} else {
+			comments = comments[:0]
+		}
+	}
+
+	var (
+		ex        example
+		block     *string
+		hasOutput bool
+	)
+	for _, c := range comments {
+		switch strings.TrimSpace(c) {
+		case "Input:":
+			block = &ex.Input
+		case "Output:":
+			block, hasOutput = &ex.Output, true
+		default:
+			if block != nil {
+				*block += c + "\n"
+			}
+		}
+	}
+	if !hasOutput {
+		return nil, nil
+	}
+	return &ex, nil
+}
+
+// diff compares the expected output with the actual one and returns the lines
+// which differ, prefixed with "-" if they are missing and "+" if they are
+// unexpected. Trailing blanks of lines and blank lines at the end are ignored.
+// If the outputs are equal, nil is returned.
+func diff(want, got string) []string {
+	a, b := lines(want), lines(got)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and
+	// b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	if lcs[0][0] == len(a) && len(a) == len(b) {
+		return nil
+	}
+
+	var d []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			d = append(d, "  "+a[i])
+			i, j = i+1, j+1
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			d = append(d, "- "+a[i])
+			i++
+		default:
+			d = append(d, "+ "+b[j])
+			j++
+		}
+	}
+	return context(d, 2)
+}
+
+// context drops the common lines of the diff which are more than n lines away
+// from a difference. Dropped lines are replaced by "...".
+func context(d []string, n int) []string {
+	keep := make([]bool, len(d))
+	for i, l := range d {
+		if l[0] == ' ' {
+			continue
+		}
+		for k := i - n; k <= i+n; k++ {
+			if k >= 0 && k < len(d) {
+				keep[k] = true
+			}
+		}
+	}
+	var res []string
+	for i, l := range d {
+		if keep[i] {
+			res = append(res, l)
+		} else if i == 0 || keep[i-1] {
+			res = append(res, "...")
+		}
+	}
+	return res
+}
+
+// lines splits the text into lines without trailing blanks and drops the blank
+// lines at its end.
+func lines(s string) []string {
+	l := strings.Split(s, "\n")
+	for i := range l {
+		l[i] = strings.TrimRight(l[i], " \t\r")
+	}
+	for len(l) > 0 && l[len(l)-1] == "" {
+		l = l[:len(l)-1]
+	}
+	return l
+}
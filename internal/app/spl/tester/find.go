@@ -0,0 +1,90 @@
+package tester
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Suffix is the suffix of the names of test files.
+const Suffix = "_test.spl"
+
+// Find returns the source files matched by the patterns. A pattern is a file,
+// a directory whose source files are matched or a directory followed by "/..."
+// which matches the source files in the directory and all its subdirectories.
+// Without patterns, the source files of the current directory are matched.
+// Test files are named *_test.spl, other source files may be examples.
+func Find(patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+	seen := make(map[string]bool)
+	var files []string
+	add := func(name string) {
+		if name = filepath.Clean(name); !seen[name] {
+			seen[name] = true
+			files = append(files, name)
+		}
+	}
+	for _, pattern := range patterns {
+		if dir := strings.TrimSuffix(pattern, "..."); dir != pattern {
+			if dir = filepath.Clean(dir); dir == "" {
+				dir = "."
+			}
+			err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if !fi.IsDir() && filepath.Ext(path) == ".spl" {
+					add(path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		fi, err := os.Stat(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			add(pattern)
+			continue
+		}
+		names, err := filepath.Glob(filepath.Join(pattern, "*.spl"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			add(name)
+		}
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no source files")
+	}
+	return files, nil
+}
+
+// sources returns the source files of the directory which are no test files.
+func sources(dir string) ([]string, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.spl"))
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, name := range names {
+		if !strings.HasSuffix(name, Suffix) {
+			files = append(files, name)
+		}
+	}
+	return files, nil
+}
+
+// isTest reports whether name is the name of a test procedure.
+func isTest(name string) bool { return strings.HasPrefix(name, "test") }
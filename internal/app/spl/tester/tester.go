@@ -0,0 +1,299 @@
+package tester
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/diag"
+	"github.com/lukasmalkmus/spl/internal/app/spl/interp"
+	"github.com/lukasmalkmus/spl/internal/app/spl/loader"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+)
+
+// Event is a test event in the format of go tool test2json. Each test file is
+// reported as package.
+type Event struct {
+	Time    *time.Time `json:",omitempty"`
+	Action  string
+	Package string  `json:",omitempty"`
+	Test    string  `json:",omitempty"`
+	Elapsed float64 `json:",omitempty"`
+	Output  string  `json:",omitempty"`
+}
+
+// Options configure the test runs.
+type Options struct {
+	// Run selects the tests whose name matches the regular expression. If
+	// it is nil, all tests are run.
+	Run *regexp.Regexp
+
+	// Verbose reports tests which are run and pass, not only failed tests.
+	Verbose bool
+
+	// JSON writes the output as stream of events, one JSON object per line.
+	// It implies Verbose.
+	JSON bool
+
+	// Config configures the loading of the source files. Its Test field is
+	// ignored, it is set for test files.
+	Config loader.Config
+
+	// Diagnostics, if set, is called with the errors of each file which
+	// doesn't type check, in addition to writing them to the output.
+	Diagnostics func(parser.ErrorList)
+
+	// Printer renders the errors written to the output. If it is nil, they
+	// are rendered without colors.
+	Printer *diag.Printer
+}
+
+// Run runs the tests of the files and writes the results to w. It reports
+// whether all tests passed. Files named *_test.spl hold tests and are checked
+// together with the other source files of their directory, like the files of a
+// Go package. Other files are run on their own as examples if they end with an
+// output comment and skipped otherwise. Files which don't type check fail as a
+// whole.
+func Run(w io.Writer, files []string, opts Options) (bool, error) {
+	r := &runner{w: w, opts: opts, enc: json.NewEncoder(w)}
+	r.opts.Verbose = r.opts.Verbose || r.opts.JSON
+	ok := true
+	for _, name := range files {
+		if !r.runFile(name) {
+			ok = false
+		}
+		if r.err != nil {
+			return false, r.err
+		}
+	}
+	return ok, nil
+}
+
+// runner runs tests and reports their results.
+type runner struct {
+	w    io.Writer
+	opts Options
+	enc  *json.Encoder
+	err  error
+
+	// pkg and test are the names of the test file and the test currently
+	// run.
+	pkg  string
+	test string
+}
+
+// runFile runs the tests or the example of the file and reports whether they
+// passed.
+func (r *runner) runFile(filename string) bool {
+	start := time.Now()
+	r.pkg, r.test = filename, ""
+	r.event(Event{Action: "start"})
+
+	var ok, ran bool
+	var err error
+	if strings.HasSuffix(filename, Suffix) {
+		ok, ran, err = r.runTests(filename)
+	} else {
+		ok, ran, err = r.runExample(filename)
+	}
+	r.test = ""
+	if err == errNoExample {
+		r.output(fmt.Sprintf("?   \t%s\t[no test files]\n", filename))
+		r.event(Event{Action: "skip", Elapsed: elapsed(start)})
+		return true
+	} else if err != nil {
+		if list, ok := err.(parser.ErrorList); ok {
+			p := r.opts.Printer
+			if p == nil {
+				p = &diag.Printer{}
+			}
+			var b strings.Builder
+			_ = p.Fprint(&b, list)
+			r.output(b.String())
+			if r.opts.Diagnostics != nil {
+				r.opts.Diagnostics(list)
+			}
+		} else {
+			r.output(err.Error() + "\n")
+		}
+		r.output(fmt.Sprintf("FAIL\t%s [build failed]\n", filename))
+		r.event(Event{Action: "fail", Elapsed: elapsed(start)})
+		return false
+	}
+
+	d := time.Since(start)
+	if !ok {
+		r.output("FAIL\n")
+		r.output(fmt.Sprintf("FAIL\t%s\t%.3fs\n", filename, d.Seconds()))
+		r.event(Event{Action: "fail", Elapsed: elapsed(start)})
+		return false
+	}
+	if r.opts.Verbose {
+		r.output("PASS\n")
+	}
+	suffix := ""
+	if !ran {
+		suffix = " [no tests to run]"
+	}
+	r.output(fmt.Sprintf("ok  \t%s\t%.3fs%s\n", filename, d.Seconds(), suffix))
+	r.event(Event{Action: "pass", Elapsed: elapsed(start)})
+	return true
+}
+
+// runTests runs the test procedures of the test file, which is loaded together
+// with the source files of its directory which are no test files. It reports
+// whether the tests passed and whether any test was run.
+func (r *runner) runTests(filename string) (ok, ran bool, err error) {
+	files, err := sources(filepath.Dir(filename))
+	if err != nil {
+		return false, false, err
+	}
+	conf := r.opts.Config
+	conf.Test = true
+	prog, info, err := conf.Load(append([]string{filename}, files...)...)
+	if err != nil {
+		return false, false, err
+	}
+	m, err := interp.New(prog, info, strings.NewReader(""), r)
+	if err != nil {
+		return false, false, err
+	}
+
+	ok = true
+	for _, proc := range info.Procs {
+		if len(proc.Params()) != 0 || !isTest(proc.Name()) || !r.match(proc.Name()) {
+			continue
+		}
+		if proc.Module() != "" || proc.Decl().Pos().Filename != filename {
+			continue
+		}
+		ran = true
+		ok = r.run(proc.Name(), func() []string {
+			var failures []string
+			m.Hooks.Fail = func(pos token.Position, msg string) error {
+				failures = append(failures, fmt.Sprintf("%s:%d: %s", filepath.Base(pos.Filename), pos.Line, msg))
+				return nil
+			}
+			if err := m.RunProc(proc); err != nil {
+				failures = append(failures, failure(err))
+			}
+			return failures
+		}) && ok
+	}
+	return ok, ran, nil
+}
+
+// runExample runs the main procedure of the example program and compares its
+// output with the expected one. The test is named after the main procedure. If
+// the file is no example, errNoExample is returned.
+func (r *runner) runExample(filename string) (ok, ran bool, err error) {
+	ex, err := readExample(filename)
+	if err != nil {
+		return false, false, err
+	} else if ex == nil {
+		return false, false, errNoExample
+	}
+	if !r.match("main") {
+		return true, false, nil
+	}
+	conf := r.opts.Config
+	conf.Test = false
+	prog, info, err := conf.Load(filename)
+	if err != nil {
+		return false, false, err
+	}
+	var out strings.Builder
+	m, err := interp.New(prog, info, strings.NewReader(ex.Input), &out)
+	if err != nil {
+		return false, false, err
+	}
+
+	ok = r.run("main", func() []string {
+		var failures []string
+		if err := m.Run(); err != nil {
+			failures = append(failures, failure(err))
+		}
+		if d := diff(ex.Output, out.String()); d != nil {
+			failures = append(failures, "output differs (-want +got):")
+			failures = append(failures, d...)
+		}
+		return failures
+	})
+	return ok, true, nil
+}
+
+// match reports whether the test with the given name is selected.
+func (r *runner) match(name string) bool { return r.opts.Run == nil || r.opts.Run.MatchString(name) }
+
+// run runs a test and reports whether it passed. f runs the test and returns
+// its failures.
+func (r *runner) run(name string, f func() []string) bool {
+	start := time.Now()
+	r.test = name
+	r.event(Event{Action: "run"})
+	if r.opts.Verbose {
+		r.output(fmt.Sprintf("=== RUN   %s\n", name))
+	}
+
+	failures := f()
+	d := time.Since(start)
+	if len(failures) > 0 {
+		r.output(fmt.Sprintf("--- FAIL: %s (%.2fs)\n", name, d.Seconds()))
+		for _, f := range failures {
+			r.output("    " + f + "\n")
+		}
+		r.event(Event{Action: "fail", Elapsed: elapsed(start)})
+		return false
+	}
+	if r.opts.Verbose {
+		r.output(fmt.Sprintf("--- PASS: %s (%.2fs)\n", name, d.Seconds()))
+	}
+	r.event(Event{Action: "pass", Elapsed: elapsed(start)})
+	return true
+}
+
+// failure formats an error which stopped a test. The file names of runtime
+// errors are shortened like the ones of failures.
+func failure(err error) string {
+	if e, ok := err.(*interp.RuntimeError); ok {
+		return fmt.Sprintf("%s:%d: runtime error: %s", filepath.Base(e.Pos.Filename), e.Pos.Line, e.Msg)
+	}
+	return err.Error()
+}
+
+// Write implements io.Writer. It reports the output of the test program.
+func (r *runner) Write(p []byte) (int, error) {
+	r.output(string(p))
+	return len(p), r.err
+}
+
+// output reports output of the current package or test.
+func (r *runner) output(s string) {
+	if r.opts.JSON {
+		r.event(Event{Action: "output", Output: s})
+	} else if r.err == nil {
+		_, r.err = io.WriteString(r.w, s)
+	}
+}
+
+// event reports an event of the current package or test. Events are only
+// written as JSON, otherwise only the output is written.
+func (r *runner) event(e Event) {
+	if !r.opts.JSON || r.err != nil {
+		return
+	}
+	now := time.Now()
+	e.Time, e.Package, e.Test = &now, r.pkg, r.test
+	r.err = r.enc.Encode(e)
+}
+
+// elapsed returns the seconds since start, rounded like the durations in the
+// output.
+func elapsed(start time.Time) float64 {
+	return float64(time.Since(start).Round(time.Millisecond)) / float64(time.Second)
+}
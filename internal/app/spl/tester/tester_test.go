@@ -0,0 +1,280 @@
+package tester_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/internal/testutil"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+	"github.com/lukasmalkmus/spl/internal/app/spl/tester"
+)
+
+const src = `proc double(i: int, ref r: int) {
+  r := 2 * i;
+}
+
+proc testDouble() {
+  var r: int;
+  double(2, r);
+  assertEq(r, 4);
+  double(-3, r);
+  assertEq(r, -7);
+}
+
+proc testOutput() {
+  printi(42);
+  printc('\n');
+}
+
+proc testIndex() {
+  var a: array [2] of int;
+  a[2] := 1;
+}
+
+proc testWithParam(i: int) {}
+`
+
+// durations matches the durations in the output.
+var durations = regexp.MustCompile(`[0-9]+\.[0-9]+s`)
+
+func TestRun(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	filename := writeFile(t, dir, "double_test.spl", src)
+
+	tests := []struct {
+		name   string
+		opts   tester.Options
+		ok     bool
+		output string
+	}{
+		{
+			"failures",
+			tester.Options{},
+			false,
+			"--- FAIL: testDouble (0.00s)\n" +
+				"    double_test.spl:10: got -6, want -7\n" +
+				"42\n" +
+				"--- FAIL: testIndex (0.00s)\n" +
+				"    double_test.spl:20: runtime error: index out of range\n" +
+				"FAIL\n" +
+				"FAIL\t" + filename + "\t0.00s\n",
+		},
+		{
+			"verbose",
+			tester.Options{Run: regexp.MustCompile("Out"), Verbose: true},
+			true,
+			"=== RUN   testOutput\n" +
+				"42\n" +
+				"--- PASS: testOutput (0.00s)\n" +
+				"PASS\n" +
+				"ok  \t" + filename + "\t0.00s\n",
+		},
+		{
+			"no tests",
+			tester.Options{Run: regexp.MustCompile("none")},
+			true,
+			"ok  \t" + filename + "\t0.00s [no tests to run]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			ok, err := tester.Run(&buf, []string{filename}, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.ok {
+				t.Errorf("got ok %t, want %t", ok, tt.ok)
+			}
+			if got := durations.ReplaceAllString(buf.String(), "0.00s"); got != tt.output {
+				t.Errorf("got output\n%s\nwant\n%s", got, tt.output)
+			}
+		})
+	}
+}
+
+func TestRun_JSON(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	filename := writeFile(t, dir, "double_test.spl", src)
+
+	var buf bytes.Buffer
+	opts := tester.Options{Run: regexp.MustCompile("Double|Output"), JSON: true}
+	if ok, err := tester.Run(&buf, []string{filename}, opts); ok || err != nil {
+		t.Fatalf("got %t, %v, want failure", ok, err)
+	}
+
+	var got []string
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var e tester.Event
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Time == nil || e.Package != filename {
+			t.Errorf("got event %+v without time or package", e)
+		}
+		got = append(got, strings.TrimRight(e.Action+" "+e.Test+" "+durations.ReplaceAllString(e.Output, "0.00s"), " "))
+	}
+	want := []string{
+		"start",
+		"run testDouble",
+		"output testDouble === RUN   testDouble\n",
+		"output testDouble --- FAIL: testDouble (0.00s)\n",
+		"output testDouble     double_test.spl:10: got -6, want -7\n",
+		"fail testDouble",
+		"run testOutput",
+		"output testOutput === RUN   testOutput\n",
+		"output testOutput 42\n",
+		"output testOutput --- PASS: testOutput (0.00s)\n",
+		"pass testOutput",
+		"output  FAIL\n",
+		"output  FAIL\t" + filename + "\t0.00s\n",
+		"fail",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got events\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestRun_BuildFailed(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	filename := writeFile(t, dir, "bad_test.spl", "proc testBad() { x := 1; }")
+
+	var buf bytes.Buffer
+	var diags parser.ErrorList
+	opts := tester.Options{Diagnostics: func(l parser.ErrorList) { diags = append(diags, l...) }}
+	if ok, err := tester.Run(&buf, []string{filename}, opts); ok || err != nil {
+		t.Fatalf("got %t, %v, want failure", ok, err)
+	}
+	want := "error[E0201]: undeclared name: x\n" +
+		" --> " + filename + ":1:18\n" +
+		"  |\n" +
+		"1 | proc testBad() { x := 1; }\n" +
+		"  |                  ^\n" +
+		"FAIL\t" + filename + " [build failed]\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got output\n%s\nwant\n%s", got, want)
+	}
+	if len(diags) != 1 || diags[0].Msg != "undeclared name: x" {
+		t.Errorf("got diagnostics %v, want undeclared name", diags)
+	}
+}
+
+func TestRun_Package(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	writeFile(t, dir, "double.spl", "proc double(i: int, ref r: int) { r := 2 * i; }\nproc testSource() { fail(); }")
+	writeFile(t, dir, "other_test.spl", "proc testOther() { fail(); }")
+	filename := writeFile(t, dir, "double_test.spl", "proc testDouble() { var r: int; double(2, r); assertEq(r, 4); }")
+
+	// Only the tests of the test file are run, the source files of the
+	// directory are loaded with it.
+	var buf bytes.Buffer
+	if ok, err := tester.Run(&buf, []string{filename}, tester.Options{Verbose: true}); !ok || err != nil {
+		t.Fatalf("got %t, %v, want success\n%s", ok, err, buf.String())
+	}
+	want := "=== RUN   testDouble\n" +
+		"--- PASS: testDouble (0.00s)\n" +
+		"PASS\n" +
+		"ok  \t" + filename + "\t0.00s\n"
+	if got := durations.ReplaceAllString(buf.String(), "0.00s"); got != want {
+		t.Errorf("got output\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRun_Example(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	src := `proc main() {
+  var i: int;
+  readi(i);
+  printi(2 * i);
+  printc('\n');
+  printi(i);
+  printc('\n');
+}
+
+// Input:
+// 21
+// Output:
+// 42
+// %s
+`
+	pass := writeFile(t, dir, "pass.spl", fmt.Sprintf(src, "21"))
+	fail := writeFile(t, dir, "fail.spl", fmt.Sprintf(src, "12"))
+	plain := writeFile(t, dir, "plain.spl", "proc main() {}")
+
+	var buf bytes.Buffer
+	if ok, err := tester.Run(&buf, []string{fail, pass, plain}, tester.Options{Verbose: true}); ok || err != nil {
+		t.Fatalf("got %t, %v, want failure", ok, err)
+	}
+	want := "=== RUN   main\n" +
+		"--- FAIL: main (0.00s)\n" +
+		"    output differs (-want +got):\n" +
+		"      42\n" +
+		"    - 12\n" +
+		"    + 21\n" +
+		"FAIL\n" +
+		"FAIL\t" + fail + "\t0.00s\n" +
+		"=== RUN   main\n" +
+		"--- PASS: main (0.00s)\n" +
+		"PASS\n" +
+		"ok  \t" + pass + "\t0.00s\n" +
+		"?   \t" + plain + "\t[no test files]\n"
+	if got := durations.ReplaceAllString(buf.String(), "0.00s"); got != want {
+		t.Errorf("got output\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	a := writeFile(t, dir, "a_test.spl", "")
+	m := writeFile(t, dir, "main.spl", "")
+	writeFile(t, dir, "main.txt", "")
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	b := writeFile(t, filepath.Join(dir, "sub"), "b_test.spl", "")
+
+	tests := []struct {
+		patterns []string
+		want     []string
+	}{
+		{[]string{dir}, []string{a, m}},
+		{[]string{dir + "/..."}, []string{a, m, b}},
+		{[]string{b, dir + "/..."}, []string{b, a, m}},
+	}
+	for _, tt := range tests {
+		got, err := tester.Find(tt.patterns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("Find(%q) = %q, want %q", tt.patterns, got, tt.want)
+		}
+	}
+
+	if _, err := tester.Find([]string{filepath.Join(dir, "missing")}); err == nil {
+		t.Error("got no error for a missing directory")
+	}
+}
+
+func writeFile(tb testing.TB, dir, name, src string) string {
+	tb.Helper()
+	filename := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		tb.Fatal(err)
+	}
+	return filename
+}
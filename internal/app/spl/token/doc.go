@@ -1,4 +1,4 @@
 // Package token defines constants representing the lexical tokens of the simple
 // programming language and basic operations on tokens (printing, predicates).
-// It also implements token positions.
+// It also implements token positions and sets of source files.
 package token
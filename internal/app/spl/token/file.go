@@ -0,0 +1,63 @@
+package token
+
+import "sync"
+
+// File is a source file added to a FileSet.
+type File struct {
+	name string
+	src  []byte
+}
+
+// Name returns the file name of file f as registered with AddFile.
+func (f *File) Name() string { return f.name }
+
+// Size returns the size of file f in bytes.
+func (f *File) Size() int { return len(f.src) }
+
+// Source returns the content of file f. It must not be modified.
+func (f *File) Source() []byte { return f.src }
+
+// FileSet is the set of source files parsed together. Positions refer to
+// their files by name, so the content of a file is looked up by File. A FileSet
+// may be used concurrently.
+type FileSet struct {
+	mu    sync.RWMutex
+	files []*File
+	index map[string]*File
+}
+
+// NewFileSet creates a new file set.
+func NewFileSet() *FileSet { return &FileSet{index: make(map[string]*File)} }
+
+// AddFile adds a file with the given name and content to the set and returns
+// it. A file added before under the same name is replaced.
+func (s *FileSet) AddFile(filename string, src []byte) *File {
+	f := &File{name: filename, src: src}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.index[filename]; ok {
+		for i := range s.files {
+			if s.files[i] == old {
+				s.files[i] = f
+			}
+		}
+	} else {
+		s.files = append(s.files, f)
+	}
+	s.index[filename] = f
+	return f
+}
+
+// File returns the file with the given name or nil if there is no such file.
+func (s *FileSet) File(filename string) *File {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index[filename]
+}
+
+// Files returns the files of the set in the order they were added.
+func (s *FileSet) Files() []*File {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*File(nil), s.files...)
+}
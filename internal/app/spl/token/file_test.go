@@ -0,0 +1,29 @@
+package token_test
+
+import (
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+)
+
+func TestFileSet(t *testing.T) {
+	fset := token.NewFileSet()
+	a := fset.AddFile("a.spl", []byte("proc main() {}"))
+	fset.AddFile("b.spl", nil)
+	if a.Name() != "a.spl" || a.Size() != 14 || string(a.Source()) != "proc main() {}" {
+		t.Errorf("got file %s of size %d", a.Name(), a.Size())
+	}
+	if fset.File("a.spl") != a || fset.File("c.spl") != nil {
+		t.Error("lookup by name failed")
+	}
+
+	// Adding a file under the same name replaces it in place.
+	a2 := fset.AddFile("a.spl", []byte("proc main() { }"))
+	files := fset.Files()
+	if len(files) != 2 || files[0] != a2 || files[1].Name() != "b.spl" {
+		t.Errorf("got files %v", files)
+	}
+	if fset.File("a.spl") != a2 {
+		t.Error("file not replaced")
+	}
+}
@@ -15,8 +15,9 @@ const (
 	// Identifiers and basic type literals (these tokens stand for classes of
 	// literals)
 	literalBeg
-	IDENT // x, y, abc, foo_bar, fooBar, FooBar, main
-	INT   // 12345, 0x12aBcD, 'a', '\n'
+	IDENT  // x, y, abc, foo_bar, fooBar, FooBar, main
+	INT    // 12345, 0x12aBcD, 'a', '\n'
+	STRING // "abc"
 	literalEnd
 
 	// Operators and delimiters
@@ -43,21 +44,29 @@ const (
 	RBRACE // }
 
 	COMMA     // ,
+	PERIOD    // .
 	COLON     // :
 	SEMICOLON // ;
 	operatorEnd
 
 	// Keywords
 	keywordBeg
-	ARRAY // array
-	ELSE  // else
-	IF    // if
-	OF    // of
-	PROC  // proc
-	REF   // ref
-	TYPE  // type
-	VAR   // var
-	WHILE // while
+	LAND   // and
+	ARRAY  // array
+	AS     // as
+	ELSE   // else
+	IF     // if
+	IMPORT // import
+	LNOT   // not
+	OF     // of
+	LOR    // or
+	PROC   // proc
+	RECORD // record
+	REF    // ref
+	RETURN // return
+	TYPE   // type
+	VAR    // var
+	WHILE  // while
 	keywordEnd
 )
 
@@ -66,8 +75,9 @@ var tokens = [...]string{
 	EOF:     "EOF",
 	COMMENT: "COMMENT",
 
-	IDENT: "IDENT",
-	INT:   "INT",
+	IDENT:  "IDENT",
+	INT:    "INT",
+	STRING: "STRING",
 
 	ADD: "+",
 	SUB: "-",
@@ -91,18 +101,26 @@ var tokens = [...]string{
 	RBRACE: "}",
 
 	COMMA:     ",",
+	PERIOD:    ".",
 	COLON:     ":",
 	SEMICOLON: ";",
 
-	ARRAY: "array",
-	ELSE:  "else",
-	IF:    "if",
-	OF:    "of",
-	PROC:  "proc",
-	REF:   "ref",
-	TYPE:  "type",
-	VAR:   "var",
-	WHILE: "while",
+	LAND:   "and",
+	ARRAY:  "array",
+	AS:     "as",
+	ELSE:   "else",
+	IF:     "if",
+	IMPORT: "import",
+	LNOT:   "not",
+	OF:     "of",
+	LOR:    "or",
+	PROC:   "proc",
+	RECORD: "record",
+	REF:    "ref",
+	RETURN: "return",
+	TYPE:   "type",
+	VAR:    "var",
+	WHILE:  "while",
 }
 
 var keywords map[string]Token
@@ -142,20 +160,24 @@ func (t Token) IsKeyword() bool { return keywordBeg < t && t < keywordEnd }
 // precedence for selector, indexing, and other operator and delimiter tokens.
 const (
 	LowestPrec  = 0
-	UnaryPrec   = 4
-	HighestPrec = 5
+	UnaryPrec   = 6
+	HighestPrec = 7
 )
 
 // Precedence returns the operator precedence of the token. If t is not a binary
 // operator, the result is LowestPrecedence.
 func (t Token) Precedence() int {
 	switch t {
-	case EQL, LSS, LEQ, GTR, GEQ:
+	case LOR:
 		return 1
-	case ADD, SUB:
+	case LAND:
 		return 2
-	case MUL, QUO:
+	case EQL, NOT, LSS, LEQ, GTR, GEQ:
 		return 3
+	case ADD, SUB:
+		return 4
+	case MUL, QUO:
+		return 5
 	}
 	return LowestPrec
 }
@@ -54,11 +54,15 @@ func TestToken(t *testing.T) {
 		{"", ";", token.SEMICOLON, false, false, true, false, false},
 
 		// Keywords
+		{"and", "and", token.LAND, false, false, false, true, false},
 		{"array", "array", token.ARRAY, false, false, false, true, false},
 		{"else", "else", token.ELSE, false, false, false, true, false},
 		{"if", "if", token.IF, false, false, false, true, false},
+		{"not", "not", token.LNOT, false, false, false, true, false},
 		{"of", "of", token.OF, false, false, false, true, false},
+		{"or", "or", token.LOR, false, false, false, true, false},
 		{"proc", "proc", token.PROC, false, false, false, true, false},
+		{"record", "record", token.RECORD, false, false, false, true, false},
 		{"ref", "ref", token.REF, false, false, false, true, false},
 		{"type", "type", token.TYPE, false, false, false, true, false},
 		{"var", "var", token.VAR, false, false, false, true, false},
@@ -86,6 +90,24 @@ func TestToken(t *testing.T) {
 	}
 }
 
+func TestToken_Precedence(t *testing.T) {
+	// Each line binds tighter than the one before.
+	levels := [][]token.Token{
+		{token.LOR},
+		{token.LAND},
+		{token.EQL, token.NOT, token.LSS, token.LEQ, token.GTR, token.GEQ},
+		{token.ADD, token.SUB},
+		{token.MUL, token.QUO},
+	}
+	for i, level := range levels {
+		for _, tok := range level {
+			equals(t, tok.Precedence(), token.LowestPrec+1+i)
+		}
+	}
+	equals(t, token.UnaryPrec, token.LowestPrec+1+len(levels))
+	equals(t, token.LNOT.Precedence(), token.LowestPrec)
+}
+
 // TestLookup makes sure that Lookup returns either the right keyword or IDENT
 // for non keywords, like directives or identifiers.
 func TestLookup(t *testing.T) {
@@ -0,0 +1,1034 @@
+package types
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/constant"
+	"github.com/lukasmalkmus/spl/internal/app/spl/diag"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+)
+
+// Info holds the type information of a type checked program.
+type Info struct {
+	// Types maps expressions and type expressions to their types.
+	Types map[ast.Expr]Type
+
+	// Values maps constant integer expressions to their values. Integer
+	// literals are decoded, arithmetic on constants is folded and wraps
+	// around like at run time. Divisions by zero are not folded.
+	Values map[ast.Expr]int32
+
+	// Strings maps string literals, which may be parenthesized, to their
+	// decoded values.
+	Strings map[ast.Expr]string
+
+	// Defs maps identifiers to the objects they define.
+	Defs map[*ast.Ident]Object
+
+	// Uses maps identifiers to the objects they denote. The selector of a
+	// record field denotes the field.
+	Uses map[*ast.Ident]Object
+
+	// Procs holds the procedures declared by the program in source order.
+	Procs []*Proc
+}
+
+// TypeOf returns the type of expression e or nil if it is not recorded.
+func (info *Info) TypeOf(e ast.Expr) Type { return info.Types[e] }
+
+// Callee returns the procedure called by the call expression or nil if it is
+// not recorded.
+func (info *Info) Callee(x *ast.CallExpr) *Proc {
+	var id *ast.Ident
+	switch f := unparen(x.Pro).(type) {
+	case *ast.Ident:
+		id = f
+	case *ast.SelectorExpr:
+		id = f.Sel
+	}
+	proc, _ := info.Uses[id].(*Proc)
+	return proc
+}
+
+// ObjectOf returns the object denoted by the identifier or nil if it is not
+// recorded.
+func (info *Info) ObjectOf(id *ast.Ident) Object {
+	if obj := info.Defs[id]; obj != nil {
+		return obj
+	}
+	return info.Uses[id]
+}
+
+// Config configures the type checker.
+type Config struct {
+	// Test enables the checking of tests. The library procedures
+	// assertEq(got, want: int) and fail() are declared additionally and
+	// the program doesn't need a main procedure.
+	Test bool
+
+	// Importer returns the parsed module imported by the given path from a
+	// source file in the directory dir. The name of the returned program
+	// must identify the module, so the module is checked once and import
+	// cycles are detected. If Importer is nil, imports are reported as
+	// errors.
+	Importer func(path, dir string) (*ast.Program, error)
+}
+
+// Check type checks the program and returns the collected type information.
+// The returned error is a parser.ErrorList if the program is not valid.
+func Check(prog *ast.Program) (*Info, error) {
+	var conf Config
+	return conf.Check(prog)
+}
+
+// Check type checks the program using the configuration and returns the
+// collected type information. The returned error is a parser.ErrorList if the
+// program is not valid.
+func (conf *Config) Check(prog *ast.Program) (*Info, error) {
+	c := &checker{
+		conf: conf,
+		info: &Info{
+			Types:   make(map[ast.Expr]Type),
+			Values:  make(map[ast.Expr]int32),
+			Strings: make(map[ast.Expr]string),
+			Defs:    make(map[*ast.Ident]Object),
+			Uses:    make(map[*ast.Ident]Object),
+		},
+		objs:      make(map[*ast.Object]Object),
+		modules:   make(map[string]map[string]Object),
+		reported:  make(map[*parser.Error]bool),
+		overflows: make(map[ast.Expr]bool),
+		divisions: make(map[ast.Expr]ast.Expr),
+	}
+	c.program(prog)
+	c.link()
+	c.errors.Sort()
+	return c.info, c.errors.Err()
+}
+
+// checker maintains the state of the type checker.
+type checker struct {
+	conf   *Config
+	info   *Info
+	errors parser.ErrorList
+
+	// objs maps the objects created by the parser during identifier
+	// resolution to the objects created by the checker.
+	objs map[*ast.Object]Object
+
+	// proc is the procedure currently checked.
+	proc *Proc
+
+	// modules maps the names of the checked modules to their procedures
+	// and types. importing holds the names of the programs which are
+	// currently checked, the root program first. reported holds the errors
+	// of modules which failed to import.
+	modules   map[string]map[string]Object
+	importing []string
+	reported  map[*parser.Error]bool
+
+	// overflows holds the constant expressions whose exact value isn't
+	// representable, so their value has wrapped around.
+	overflows map[ast.Expr]bool
+
+	// divisions maps the expressions which aren't constant only because of
+	// a division by zero of constants to that division.
+	divisions map[ast.Expr]ast.Expr
+}
+
+// program checks all declarations of the program. Type declarations and
+// procedure signatures are checked in source order, so types must be declared
+// before they are used. Procedure bodies are checked afterwards which permits
+// calls to procedures declared later on.
+func (c *checker) program(prog *ast.Program) {
+	c.importing = []string{filepath.Clean(prog.Name)}
+	c.decls(prog)
+	for _, proc := range c.info.Procs {
+		c.proc = proc
+		body := proc.decl.Body
+		c.stmtList(body.List)
+		if proc.Result() != nil && !terminates(body) {
+			c.errorf(span{body.Rbrace, body.End()}, diag.InvalidReturn, "missing return at the end of procedure %s", proc.name)
+		}
+	}
+	c.proc = nil
+
+	for _, proc := range c.info.Procs {
+		if proc.name != "main" || proc.module != "" {
+			continue
+		}
+		if len(proc.Params()) != 0 {
+			c.errorf(proc.decl.Params, diag.InvalidMain, "procedure main must not have parameters")
+		}
+		if proc.Result() != nil {
+			c.errorf(proc.decl.Result, diag.InvalidMain, "procedure main must not have a result")
+		}
+		return
+	}
+	if !c.conf.Test {
+		c.errorf(span{prog.Pos(), prog.Pos()}, diag.InvalidMain, "procedure main is undeclared")
+	}
+}
+
+// decls checks the top level declarations of a program or module except for
+// the procedure bodies.
+func (c *checker) decls(prog *ast.Program) {
+	for _, decl := range prog.Decls {
+		switch d := decl.(type) {
+		case *ast.ImportDecl:
+			c.importDecl(d)
+		case *ast.TypeDecl:
+			c.typeDecl(d)
+		case *ast.ProcDecl:
+			c.procDecl(d)
+		case *ast.VarDecl:
+			c.errorf(d, diag.InvalidDecl, "variable %s declared outside of a procedure", d.Name.Name)
+		}
+	}
+}
+
+// link assigns unique link names to the procedures of imported modules. They
+// are named after the module and must not clash with any other procedure.
+func (c *checker) link() {
+	used := make(map[string]bool)
+	for _, proc := range c.info.Procs {
+		if proc.module == "" {
+			used[proc.name] = true
+		}
+	}
+	for _, proc := range c.info.Procs {
+		if proc.module == "" {
+			continue
+		}
+		name := proc.link
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s_%d", proc.link, i)
+		}
+		proc.link = name
+		used[name] = true
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Modules
+
+func (c *checker) importDecl(d *ast.ImportDecl) {
+	mod := &Module{object: object{name: d.Name.Name, pos: d.Name.Pos(), typ: Typ[Invalid]}}
+	c.declare(d.Name, mod)
+	path, err := strconv.Unquote(d.Path.Value)
+	if err != nil || path == "" {
+		c.errorf(d.Path, diag.InvalidImport, "invalid import path %s", d.Path.Value)
+		return
+	}
+	if c.conf.Importer == nil {
+		c.errorf(d.Path, diag.InvalidImport, "could not import %s: imports are not supported", path)
+		return
+	}
+	prog, err := c.conf.Importer(path, filepath.Dir(d.Pos().Filename))
+	if list, ok := err.(parser.ErrorList); ok {
+		// The errors of a module imported multiple times are reported
+		// once.
+		for _, e := range list {
+			if !c.reported[e] {
+				c.reported[e] = true
+				c.errors = append(c.errors, e)
+			}
+		}
+		return
+	} else if err != nil {
+		c.errorf(d.Path, diag.InvalidImport, "could not import %s: %v", path, err)
+		return
+	}
+	mod.path = prog.Name
+	mod.scope = c.module(d, prog)
+}
+
+// module checks the declarations of an imported module and returns the
+// procedures and types it declares. Modules are checked once, further imports
+// share the result. The module is checked in its own scope: Its identifiers
+// are resolved by the parser within the source file of the module.
+func (c *checker) module(d *ast.ImportDecl, prog *ast.Program) map[string]Object {
+	name := filepath.Clean(prog.Name)
+	for i, imp := range c.importing {
+		if imp == name {
+			cycle := append(append([]string(nil), c.importing[i:]...), name)
+			c.errorf(d.Path, diag.InvalidImport, "import cycle not allowed: %s", strings.Join(cycle, " -> "))
+			return nil
+		}
+	}
+	if scope, ok := c.modules[name]; ok {
+		return scope
+	}
+
+	c.importing = append(c.importing, name)
+	c.decls(prog)
+	c.importing = c.importing[:len(c.importing)-1]
+
+	base := moduleName(name)
+	scope := make(map[string]Object)
+	for _, decl := range prog.Decls {
+		switch d := decl.(type) {
+		case *ast.TypeDecl:
+			scope[d.Name.Name] = c.info.Defs[d.Name]
+		case *ast.ProcDecl:
+			proc, ok := c.info.Defs[d.Name].(*Proc)
+			if !ok {
+				continue
+			}
+			if proc.name == "main" {
+				c.errorf(proc.decl.Name, diag.InvalidMain, "module %s must not declare procedure main", name)
+			}
+			proc.module, proc.link = name, base+"_"+proc.name
+			scope[proc.name] = proc
+		}
+	}
+	c.modules[name] = scope
+	return scope
+}
+
+// moduleName derives an identifier from the file name of a module.
+func moduleName(filename string) string {
+	base := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	name := []byte(base)
+	for i, b := range name {
+		if !('a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' && i > 0) {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
+
+// selector returns the procedure or type denoted by a qualified identifier.
+// An error is reported and nil is returned if the object can't be found.
+func (c *checker) selector(e *ast.SelectorExpr) Object {
+	id, ok := e.X.(*ast.Ident)
+	if !ok {
+		c.errorf(e.X, diag.InvalidImport, "%s is not a module", ExprString(e.X))
+		return nil
+	}
+	var mod *Module
+	switch obj := c.lookup(id).(type) {
+	case nil:
+		return nil
+	case *Module:
+		mod = obj
+	default:
+		c.errorf(id, diag.InvalidImport, "%s is not a module", id.Name)
+		return nil
+	}
+	if mod.scope == nil {
+		// The import failed and has been reported.
+		return nil
+	}
+	obj := mod.scope[e.Sel.Name]
+	if obj == nil {
+		c.errorf(e.Sel, diag.UndeclaredName, "undeclared name: %s.%s", id.Name, e.Sel.Name)
+		return nil
+	}
+	c.info.Uses[e.Sel] = obj
+	return obj
+}
+
+// -----------------------------------------------------------------------------
+// Declarations
+
+func (c *checker) typeDecl(d *ast.TypeDecl) {
+	obj := &TypeName{object{name: d.Name.Name, pos: d.Name.Pos()}}
+	obj.typ = c.typ(d.Type)
+	c.declare(d.Name, obj)
+}
+
+func (c *checker) varDecl(d *ast.VarDecl) {
+	v := &Var{object: object{name: d.Name.Name, pos: d.Name.Pos()}}
+	v.typ = c.typ(d.Type)
+	if d.Value != nil {
+		c.init(v, d.Value)
+	}
+	c.declare(d.Name, v)
+	c.proc.locals = append(c.proc.locals, v)
+}
+
+// init checks the initial value of a variable. Only character buffers, arrays
+// of int, are initialized by string literals, which must fit into them.
+func (c *checker) init(v *Var, e ast.Expr) {
+	t := c.expr(e)
+	if t == Typ[Invalid] || v.typ == Typ[Invalid] {
+		return
+	}
+	a, ok := v.typ.(*Array)
+	if !ok || !IsInteger(a.elem) {
+		c.errorf(e, diag.MismatchedTypes, "cannot initialize %s of type %s, only arrays of int are initialized by strings", v.name, v.typ)
+		return
+	}
+	if t != Typ[String] {
+		c.errorf(e, diag.MismatchedTypes, "cannot initialize %s with %s of type %s, only string literals are allowed", v.name, ExprString(e), t)
+		return
+	}
+	if n := len(c.info.Strings[e]); int64(n) > a.len {
+		c.errorf(e, diag.MismatchedTypes, "string %s of length %d does not fit into %s of type %s", ExprString(e), n, v.name, v.typ)
+		return
+	}
+	v.init = e
+}
+
+func (c *checker) procDecl(d *ast.ProcDecl) {
+	params := make([]*Var, 0, len(d.Params.List))
+	for _, f := range d.Params.List {
+		v := &Var{object: object{name: f.Name.Name, pos: f.Name.Pos()}, param: true}
+		v.typ = c.typ(f.Type)
+		v.ref = f.Ref.IsValid()
+		if !v.ref && v.typ != Typ[Invalid] && !IsBasic(v.typ) {
+			c.errorf(f, diag.InvalidDecl, "parameter %s of type %s must be a reference parameter", v.name, v.typ)
+		}
+		c.declare(f.Name, v)
+		params = append(params, v)
+	}
+	sig := &Signature{params: params}
+	if d.Result != nil {
+		sig.result = c.typ(d.Result)
+		if sig.result != Typ[Invalid] && !IsBasic(sig.result) {
+			c.errorf(d.Result, diag.InvalidDecl, "procedure %s cannot return values of type %s", d.Name.Name, sig.result)
+		}
+	}
+	proc := &Proc{object: object{name: d.Name.Name, pos: d.Name.Pos(), typ: sig}, decl: d}
+	c.declare(d.Name, proc)
+	c.info.Procs = append(c.info.Procs, proc)
+}
+
+// declare records the object defined by the identifier.
+func (c *checker) declare(id *ast.Ident, obj Object) {
+	c.info.Defs[id] = obj
+	if id.Obj != nil {
+		c.objs[id.Obj] = obj
+	}
+}
+
+// lookup returns the object the identifier denotes. Identifiers which have not
+// been resolved by the parser or which denote an object of the universe scope
+// refer to predeclared objects. An error is reported and nil is returned if the
+// object can't be found.
+func (c *checker) lookup(id *ast.Ident) Object {
+	var obj Object
+	if id.Obj != nil && id.Obj.Decl != nil {
+		if obj = c.objs[id.Obj]; obj == nil {
+			c.report(&diag.Diagnostic{
+				Code:    diag.UsedBeforeDecl,
+				Pos:     id.Pos(),
+				End:     id.End(),
+				Msg:     fmt.Sprintf("%s used before its declaration", id.Name),
+				Related: related(id.Obj.Pos(), id.Name, "declaration"),
+			})
+			return nil
+		}
+	} else if obj = c.lookupPredeclared(id.Name); obj == nil {
+		c.errorf(id, diag.UndeclaredName, "undeclared name: %s", id.Name)
+		return nil
+	}
+	c.info.Uses[id] = obj
+	return obj
+}
+
+// lookupPredeclared returns the predeclared object with the given name or nil.
+func (c *checker) lookupPredeclared(name string) Object {
+	if testOnly[name] && !c.conf.Test {
+		return nil
+	}
+	return Lookup(name)
+}
+
+// -----------------------------------------------------------------------------
+// Types
+
+// typ checks the type expression and returns the type it denotes.
+func (c *checker) typ(e ast.Expr) Type {
+	t := c.typInternal(e)
+	c.info.Types[e] = t
+	return t
+}
+
+func (c *checker) typInternal(e ast.Expr) Type {
+	switch e := e.(type) {
+	case *ast.BadExpr:
+	case *ast.Ident:
+		switch obj := c.lookup(e).(type) {
+		case nil:
+		case *TypeName:
+			return obj.typ
+		default:
+			c.errorf(e, diag.NotAType, "%s is not a type", e.Name)
+		}
+	case *ast.SelectorExpr:
+		switch obj := c.selector(e).(type) {
+		case nil:
+		case *TypeName:
+			return obj.typ
+		default:
+			c.errorf(e, diag.NotAType, "%s is not a type", ExprString(e))
+		}
+	case *ast.ParenExpr:
+		return c.typ(e.X)
+	case *ast.ArrayType:
+		n, ok := c.arrayLen(e.Len)
+		elem := c.typ(e.Elt)
+		if ok && elem != Typ[Invalid] {
+			a := NewArray(elem, n)
+			if Sizeof(a) > constant.MaxInt {
+				c.errorf(e, diag.InvalidLiteral, "array type %s is larger than 2^31-1 bytes", ExprString(e))
+				break
+			}
+			return a
+		}
+	case *ast.RecordType:
+		return c.record(e)
+	default:
+		c.errorf(e, diag.NotAType, "%s is not a type", ExprString(e))
+	}
+	return Typ[Invalid]
+}
+
+// record checks the record type expression and returns the record it denotes.
+func (c *checker) record(e *ast.RecordType) Type {
+	var fields []*Var
+	seen := make(map[string]*Var)
+	valid := true
+	for _, f := range e.Fields.List {
+		t := c.typ(f.Type)
+		valid = valid && t != Typ[Invalid]
+		field := &Var{object: object{name: f.Name.Name, pos: f.Name.Pos(), typ: t}, field: true}
+		c.info.Defs[f.Name] = field
+		if alt := seen[f.Name.Name]; alt != nil {
+			c.report(&diag.Diagnostic{
+				Code:    diag.Redeclared,
+				Pos:     f.Name.Pos(),
+				End:     f.Name.End(),
+				Msg:     fmt.Sprintf("field %s redeclared", f.Name.Name),
+				Related: related(alt.pos, alt.name, "previous declaration"),
+			})
+			valid = false
+			continue
+		}
+		seen[f.Name.Name] = field
+		fields = append(fields, field)
+	}
+	if !valid {
+		return Typ[Invalid]
+	}
+	r := NewRecord(fields)
+	if Sizeof(r) > constant.MaxInt {
+		c.errorf(e, diag.InvalidLiteral, "record type %s is larger than 2^31-1 bytes", ExprString(e))
+		return Typ[Invalid]
+	}
+	return r
+}
+
+// arrayLen evaluates the length of an array type, which must be a positive
+// constant expression.
+func (c *checker) arrayLen(e ast.Expr) (int64, bool) {
+	if t := c.expr(e); t == Typ[Invalid] {
+		return 0, false
+	}
+	v, ok := c.info.Values[e]
+	switch {
+	case c.divisions[e] != nil:
+		c.errorf(c.divisions[e], diag.InvalidLiteral, "%s", constant.ErrDivByZero)
+	case !ok:
+		c.errorf(e, diag.InvalidLiteral, "array length %s must be a constant", ExprString(e))
+	case c.overflows[e]:
+		c.errorf(e, diag.InvalidLiteral, "array length %s overflows int", ExprString(e))
+	case v <= 0:
+		c.errorf(e, diag.InvalidLiteral, "array length must be positive, found %d", v)
+	default:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+// fold records the value of a constant expression computed by op from the
+// values of its operands, if they are all constant. Divisions by zero are left
+// to run time, but recorded for the contexts requiring a constant.
+func (c *checker) fold(e ast.Expr, op func(v []int32) (int32, error), operands ...ast.Expr) {
+	vals := make([]int32, len(operands))
+	overflow := false
+	for i, x := range operands {
+		v, ok := c.info.Values[x]
+		if !ok {
+			if d := c.divisions[x]; d != nil {
+				c.divisions[e] = d
+			}
+			return
+		}
+		vals[i] = v
+		overflow = overflow || c.overflows[x]
+	}
+	v, err := op(vals)
+	switch err {
+	case nil:
+	case constant.ErrOverflow:
+		overflow = true
+	case constant.ErrDivByZero:
+		c.divisions[e] = e
+		return
+	default:
+		return
+	}
+	c.info.Values[e] = v
+	if overflow {
+		c.overflows[e] = true
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Statements
+
+func (c *checker) stmtList(list []ast.Stmt) {
+	for _, s := range list {
+		c.stmt(s)
+	}
+}
+
+func (c *checker) stmt(s ast.Stmt) {
+	switch s := s.(type) {
+	case *ast.BadStmt, *ast.EmptyStmt:
+	case *ast.DeclStmt:
+		switch d := s.Decl.(type) {
+		case *ast.VarDecl:
+			c.varDecl(d)
+		case *ast.TypeDecl:
+			c.typeDecl(d)
+		}
+	case *ast.BlockStmt:
+		c.stmtList(s.List)
+	case *ast.ExprStmt:
+		if call, ok := unparen(s.X).(*ast.CallExpr); ok {
+			c.call(call)
+			return
+		}
+		if t := c.expr(s.X); t != Typ[Invalid] {
+			c.errorf(s, diag.NotACall, "%s is not a procedure call", ExprString(s.X))
+		}
+	case *ast.AssignStmt:
+		lhs := c.expr(s.Left)
+		rhs := c.expr(s.Right)
+		if lhs == Typ[Invalid] || rhs == Typ[Invalid] {
+			return
+		}
+		if !addressable(c.info, s.Left) {
+			c.errorf(s.Left, diag.UnassignableOperand, "cannot assign to %s", ExprString(s.Left))
+		} else if !IsBasic(lhs) {
+			c.errorf(s.Left, diag.MismatchedTypes, "cannot assign to %s of type %s", ExprString(s.Left), lhs)
+		} else if rhs != lhs {
+			c.errorf(s.Right, diag.MismatchedTypes, "cannot assign %s of type %s to %s of type %s",
+				ExprString(s.Right), rhs, ExprString(s.Left), lhs)
+		}
+	case *ast.IfStmt:
+		c.cond(s.Cond, "if")
+		c.stmt(s.Body)
+		if s.Else != nil {
+			c.stmt(s.Else)
+		}
+	case *ast.WhileStmt:
+		c.cond(s.Cond, "while")
+		c.stmt(s.Body)
+	case *ast.ReturnStmt:
+		c.returnStmt(s)
+	default:
+		c.errorf(s, diag.SyntaxError, "invalid statement")
+	}
+}
+
+// returnStmt checks that a value is returned if and only if the procedure has
+// a result and that it is of the result type.
+func (c *checker) returnStmt(s *ast.ReturnStmt) {
+	result := c.proc.Result()
+	switch {
+	case s.Result == nil && result != nil:
+		c.errorf(s, diag.InvalidReturn, "not enough return values: procedure %s returns %s", c.proc.name, result)
+	case s.Result != nil && result == nil:
+		c.expr(s.Result)
+		c.errorf(s.Result, diag.InvalidReturn, "too many return values: procedure %s has no result", c.proc.name)
+	case s.Result != nil:
+		t := c.expr(s.Result)
+		if t != Typ[Invalid] && result != Typ[Invalid] && t != result {
+			c.mismatch(s.Result, t, result, "in return statement")
+		}
+	}
+}
+
+// terminates reports whether the statement ends in a return statement on every
+// path: It is a return statement, a block whose last non-empty statement
+// terminates or an if statement with an else branch whose branches both
+// terminate.
+func terminates(s ast.Stmt) bool {
+	switch s := s.(type) {
+	case *ast.ReturnStmt:
+		return true
+	case *ast.BlockStmt:
+		for i := len(s.List) - 1; i >= 0; i-- {
+			if _, ok := s.List[i].(*ast.EmptyStmt); !ok {
+				return terminates(s.List[i])
+			}
+		}
+	case *ast.IfStmt:
+		return s.Else != nil && terminates(s.Body) && terminates(s.Else)
+	}
+	return false
+}
+
+func (c *checker) cond(e ast.Expr, context string) {
+	if t := c.expr(e); t != Typ[Invalid] && !IsBoolean(t) {
+		c.errorf(e, diag.MismatchedTypes, "non-boolean condition %s in %s statement", ExprString(e), context)
+	}
+}
+
+// call checks a procedure call and its arguments. It returns the called
+// procedure or nil, if it is not valid.
+func (c *checker) call(x *ast.CallExpr) *Proc {
+	for _, arg := range x.Args {
+		c.expr(arg)
+	}
+	var obj Object
+	switch f := unparen(x.Pro).(type) {
+	case *ast.Ident:
+		obj = c.lookup(f)
+	case *ast.SelectorExpr:
+		obj = c.selector(f)
+	default:
+		c.errorf(x.Pro, diag.NotAProc, "cannot call non-procedure %s", ExprString(x.Pro))
+		return nil
+	}
+	if obj == nil {
+		return nil
+	}
+	proc, ok := obj.(*Proc)
+	if !ok {
+		c.errorf(x.Pro, diag.NotAProc, "cannot call non-procedure %s", ExprString(x.Pro))
+		return nil
+	}
+
+	params := proc.Params()
+	if len(x.Args) < len(params) {
+		c.errorf(span{x.Rparen, x.Rparen}, diag.WrongArgCount, "not enough arguments in call to %s", proc.name)
+		return nil
+	} else if len(x.Args) > len(params) {
+		c.errorf(span{x.Args[len(params)].Pos(), x.Args[len(x.Args)-1].End()}, diag.WrongArgCount, "too many arguments in call to %s", proc.name)
+		return nil
+	}
+	for i, arg := range x.Args {
+		t := c.info.Types[arg]
+		if t == Typ[Invalid] {
+			continue
+		}
+		if p := params[i]; p.ref && !addressable(c.info, arg) {
+			c.errorf(arg, diag.UnassignableOperand, "cannot pass %s as reference parameter %s to %s", ExprString(arg), p.name, proc.name)
+		} else if t != p.typ {
+			c.mismatch(arg, t, p.typ, "in argument to "+proc.name)
+		}
+	}
+	return proc
+}
+
+// -----------------------------------------------------------------------------
+// Expressions
+
+// expr checks the expression and returns its type.
+func (c *checker) expr(e ast.Expr) Type {
+	t := c.exprInternal(e)
+	c.info.Types[e] = t
+	return t
+}
+
+func (c *checker) exprInternal(e ast.Expr) Type {
+	switch e := e.(type) {
+	case *ast.BadExpr:
+	case *ast.Ident:
+		switch obj := c.lookup(e).(type) {
+		case nil:
+		case *Var:
+			return obj.typ
+		case *Const:
+			c.info.Values[e] = obj.val
+			return obj.typ
+		case *TypeName:
+			c.errorf(e, diag.NotAnExpr, "type %s is not an expression", e.Name)
+		case *Proc:
+			c.errorf(e, diag.NotAnExpr, "procedure %s is not an expression", e.Name)
+		case *Module:
+			c.errorf(e, diag.NotAnExpr, "module %s is not an expression", e.Name)
+		}
+	case *ast.SelectorExpr:
+		if !qualified(e) {
+			return c.field(e)
+		}
+		switch c.selector(e).(type) {
+		case nil:
+		case *TypeName:
+			c.errorf(e, diag.NotAnExpr, "type %s is not an expression", ExprString(e))
+		case *Proc:
+			c.errorf(e, diag.NotAnExpr, "procedure %s is not an expression", ExprString(e))
+		}
+	case *ast.IntLit:
+		v, err := constant.Int(e.Value)
+		if err != nil {
+			c.errorf(e, diag.InvalidLiteral, "invalid integer literal: %s", err)
+			break
+		}
+		c.info.Values[e] = v
+		return Typ[Int]
+	case *ast.StringLit:
+		s, err := constant.String(e.Value)
+		if err != nil {
+			c.errorf(e, diag.InvalidLiteral, "invalid string literal: %s", err)
+			break
+		}
+		c.info.Strings[e] = s
+		return Typ[String]
+	case *ast.ParenExpr:
+		t := c.expr(e.X)
+		c.fold(e, func(v []int32) (int32, error) { return v[0], nil }, e.X)
+		if s, ok := c.info.Strings[e.X]; ok {
+			c.info.Strings[e] = s
+		}
+		return t
+	case *ast.UnaryExpr:
+		x := c.expr(e.X)
+		if e.Op == token.LNOT {
+			if x != Typ[Invalid] && !IsBoolean(x) {
+				c.errorf(e.X, diag.MismatchedTypes, "operand %s of %s must be of type bool, found %s", ExprString(e.X), e.Op, x)
+			} else if x != Typ[Invalid] {
+				c.fold(e, func(v []int32) (int32, error) { return 1 - v[0], nil }, e.X)
+				return Typ[Bool]
+			}
+		} else if e.Op != token.SUB {
+			c.errorf(tokenSpan(e.OpPos, e.Op), diag.InvalidOperator, "invalid unary operator %s", e.Op)
+		} else if x != Typ[Invalid] && !IsInteger(x) {
+			c.errorf(e.X, diag.MismatchedTypes, "operand %s of %s must be of type int, found %s", ExprString(e.X), e.Op, x)
+		} else if x != Typ[Invalid] {
+			c.fold(e, func(v []int32) (int32, error) { return constant.UnaryOp(e.Op, v[0]) }, e.X)
+			return Typ[Int]
+		}
+	case *ast.BinaryExpr:
+		x := c.expr(e.X)
+		y := c.expr(e.Y)
+		if x == Typ[Invalid] || y == Typ[Invalid] {
+			break
+		}
+		if e.Op == token.LAND || e.Op == token.LOR {
+			return c.logical(e, x, y)
+		}
+		for _, op := range []struct {
+			e ast.Expr
+			t Type
+		}{{e.X, x}, {e.Y, y}} {
+			if !IsInteger(op.t) {
+				c.errorf(op.e, diag.MismatchedTypes, "operand %s of %s must be of type int, found %s", ExprString(op.e), e.Op, op.t)
+				return Typ[Invalid]
+			}
+		}
+		switch e.Op {
+		case token.ADD, token.SUB, token.MUL, token.QUO:
+			c.fold(e, func(v []int32) (int32, error) { return constant.BinaryOp(v[0], e.Op, v[1]) }, e.X, e.Y)
+			return Typ[Int]
+		case token.EQL, token.NOT, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			return Typ[Bool]
+		}
+		c.errorf(tokenSpan(e.OpPos, e.Op), diag.InvalidOperator, "invalid binary operator %s", e.Op)
+	case *ast.IndexExpr:
+		x := c.expr(e.X)
+		i := c.expr(e.Index)
+		if x == Typ[Invalid] || i == Typ[Invalid] {
+			break
+		}
+		a, ok := x.(*Array)
+		if !ok {
+			c.errorf(e.X, diag.NonIndexable, "cannot index %s of type %s", ExprString(e.X), x)
+			break
+		}
+		if !IsInteger(i) {
+			c.errorf(e.Index, diag.MismatchedTypes, "index %s must be of type int, found %s", ExprString(e.Index), i)
+			break
+		}
+		return a.elem
+	case *ast.CallExpr:
+		proc := c.call(e)
+		if proc == nil {
+			break
+		}
+		if t := proc.Result(); t != nil {
+			return t
+		}
+		c.errorf(e, diag.NotAnExpr, "procedure call %s used as value", ExprString(e))
+	default:
+		c.errorf(e, diag.NotAnExpr, "%s is not an expression", ExprString(e))
+	}
+	return Typ[Invalid]
+}
+
+// logical checks the operands of the logical operator of e, which are of type x
+// and y, and returns the type of the expression.
+func (c *checker) logical(e *ast.BinaryExpr, x, y Type) Type {
+	for _, op := range []struct {
+		e ast.Expr
+		t Type
+	}{{e.X, x}, {e.Y, y}} {
+		if !IsBoolean(op.t) {
+			c.errorf(op.e, diag.MismatchedTypes, "operand %s of %s must be of type bool, found %s", ExprString(op.e), e.Op, op.t)
+			return Typ[Invalid]
+		}
+	}
+	c.fold(e, func(v []int32) (int32, error) {
+		if e.Op == token.LAND {
+			return v[0] & v[1], nil
+		}
+		return v[0] | v[1], nil
+	}, e.X, e.Y)
+	return Typ[Bool]
+}
+
+// field checks the selection of a record field and returns the type of the
+// field.
+func (c *checker) field(e *ast.SelectorExpr) Type {
+	x := c.expr(e.X)
+	if x == Typ[Invalid] {
+		return x
+	}
+	var f *Var
+	if r, ok := x.(*Record); ok {
+		f = r.Field(e.Sel.Name)
+	}
+	if f == nil {
+		c.errorf(e.Sel, diag.UnknownField, "%s undefined (type %s has no field %s)", ExprString(e), x, e.Sel.Name)
+		return Typ[Invalid]
+	}
+	c.info.Uses[e.Sel] = f
+	return f.typ
+}
+
+// -----------------------------------------------------------------------------
+// Helpers
+
+// qualified reports whether e is a qualified identifier, i.e. selects a
+// procedure or type of an imported module rather than a record field.
+func qualified(e *ast.SelectorExpr) bool {
+	id, ok := e.X.(*ast.Ident)
+	return ok && id.Obj != nil && id.Obj.Kind == ast.Mod
+}
+
+// addressable reports whether e denotes a variable or an element or field of
+// a variable.
+func addressable(info *Info, e ast.Expr) bool {
+	switch e := unparen(e).(type) {
+	case *ast.Ident:
+		_, ok := info.Uses[e].(*Var)
+		return ok
+	case *ast.IndexExpr:
+		return addressable(info, e.X)
+	case *ast.SelectorExpr:
+		if f, ok := info.Uses[e.Sel].(*Var); ok && f.IsField() {
+			return addressable(info, e.X)
+		}
+	}
+	return false
+}
+
+// unparen returns e with any enclosing parentheses stripped.
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		p, ok := e.(*ast.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.X
+	}
+}
+
+// ExprString returns the (possibly shortened) string representation of an
+// expression for use in error messages.
+func ExprString(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.IntLit:
+		return e.Value
+	case *ast.StringLit:
+		return e.Value
+	case *ast.ParenExpr:
+		return "(" + ExprString(e.X) + ")"
+	case *ast.UnaryExpr:
+		if e.Op.IsKeyword() {
+			return e.Op.String() + " " + ExprString(e.X)
+		}
+		return e.Op.String() + ExprString(e.X)
+	case *ast.BinaryExpr:
+		return ExprString(e.X) + " " + e.Op.String() + " " + ExprString(e.Y)
+	case *ast.IndexExpr:
+		return ExprString(e.X) + "[" + ExprString(e.Index) + "]"
+	case *ast.SelectorExpr:
+		return ExprString(e.X) + "." + e.Sel.Name
+	case *ast.CallExpr:
+		args := make([]string, 0, len(e.Args))
+		for _, arg := range e.Args {
+			args = append(args, ExprString(arg))
+		}
+		return ExprString(e.Pro) + "(" + strings.Join(args, ", ") + ")"
+	case *ast.ArrayType:
+		return "array [" + ExprString(e.Len) + "] of " + ExprString(e.Elt)
+	case *ast.RecordType:
+		return "record {...}"
+	}
+	return "BadExpr"
+}
+
+func (c *checker) errorf(at ast.Node, code diag.Code, format string, args ...interface{}) {
+	c.report(&diag.Diagnostic{Code: code, Pos: at.Pos(), End: at.End(), Msg: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) report(d *diag.Diagnostic) { c.errors.Report(d) }
+
+// mismatch reports that the expression x of type t can't be used as type want
+// in the context. Every array and record type expression denotes a new type,
+// so distinct types may read alike.
+func (c *checker) mismatch(x ast.Expr, t, want Type, context string) {
+	d := &diag.Diagnostic{
+		Code: diag.MismatchedTypes,
+		Pos:  x.Pos(),
+		End:  x.End(),
+		Msg:  fmt.Sprintf("cannot use %s of type %s as type %s %s", ExprString(x), t, want, context),
+	}
+	if t.String() == want.String() {
+		d.Msg += ": distinct types with the same structure"
+		d.Notes = []string{"every array and record type expression denotes a new type, even if it is written alike"}
+		d.Help = "declare the type once and use its name in both places"
+	}
+	c.report(d)
+}
+
+// span is a range of the source code which is not a node.
+type span struct{ pos, end token.Position }
+
+func (s span) Pos() token.Position { return s.pos }
+func (s span) End() token.Position { return s.end }
+
+// tokenSpan returns the range of the token at pos.
+func tokenSpan(pos token.Position, tok token.Token) span {
+	end := pos
+	end.Column += len(tok.String())
+	end.Char += len(tok.String())
+	return span{pos, end}
+}
+
+// related returns the related location of the declaration of name at pos, if
+// the position is valid.
+func related(pos token.Position, name, msg string) []diag.Related {
+	if !pos.IsValid() {
+		return nil
+	}
+	end := pos
+	end.Column += len(name)
+	end.Char += len(name)
+	return []diag.Related{{Pos: pos, End: end, Msg: msg}}
+}
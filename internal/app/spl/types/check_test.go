@@ -0,0 +1,672 @@
+package types_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/diag"
+	"github.com/lukasmalkmus/spl/internal/app/spl/ext"
+	"github.com/lukasmalkmus/spl/internal/app/spl/internal/testutil"
+	"github.com/lukasmalkmus/spl/internal/app/spl/parser"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+	"github.com/lukasmalkmus/spl/internal/app/spl/types"
+)
+
+func TestCheck_FullValidProgram(t *testing.T) {
+	prog, err := parser.ParseFile(token.NewFileSet(), "../testdata/valid.spl", nil, parser.DeclarationErrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := types.Check(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Procs) != 3 {
+		t.Errorf("got %d procedures, want 3", len(info.Procs))
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{
+			"no main",
+			"proc foo() {}",
+			"procedure main is undeclared",
+		},
+		{
+			"main with parameters",
+			"proc main(i: int) {}",
+			"procedure main must not have parameters",
+		},
+		{
+			"global variable",
+			"var i: int; proc main() {}",
+			"variable i declared outside of a procedure",
+		},
+		{
+			"undeclared name",
+			"proc main() { i := 1; }",
+			"undeclared name: i",
+		},
+		{
+			"array parameter by value",
+			"type A = array [2] of int; proc f(a: A) {} proc main() {}",
+			"parameter a of type array [2] of int must be a reference parameter",
+		},
+		{
+			"assign array",
+			"type A = array [2] of int; proc main() { var a: A; var b: A; a := b; }",
+			"cannot assign to a of type array [2] of int",
+		},
+		{
+			"non-boolean condition",
+			"proc main() { if (1) printi(1); }",
+			"non-boolean condition 1 in if statement",
+		},
+		{
+			"not enough arguments",
+			"proc main() { printi(); }",
+			"not enough arguments in call to printi",
+		},
+		{
+			"too many arguments",
+			"proc main() { printi(1, 2); }",
+			"too many arguments in call to printi",
+		},
+		{
+			"non-addressable reference argument",
+			"proc main() { readi(1); }",
+			"cannot pass 1 as reference parameter i to readi",
+		},
+		{
+			"index non-array",
+			"proc main() { var i: int; i[0] := 1; }",
+			"cannot index i of type int",
+		},
+		{
+			"distinct array types",
+			"type A = array [2] of int; type B = array [2] of int; proc f(ref a: A) {} proc main() { var b: B; f(b); }",
+			"cannot use b of type array [2] of int as type array [2] of int in argument to f: distinct types with the same structure",
+		},
+		{
+			"type as expression",
+			"type T = int; proc main() { printi(T); }",
+			"type T is not an expression",
+		},
+		{
+			"zero array length",
+			"type A = array [0] of int; proc main() {}",
+			"array length must be positive, found 0",
+		},
+		{
+			"negative array length",
+			"type A = array [2 - 3] of int; proc main() {}",
+			"array length must be positive, found -1",
+		},
+		{
+			"variable array length",
+			"proc main() { var n: int; var a: array [n] of int; }",
+			"array length n must be a constant",
+		},
+		{
+			"array length divided by zero",
+			"type A = array [1 / 0] of int; proc main() {}",
+			"1:17: division by zero",
+		},
+		{
+			"array length with nested division by zero",
+			"type A = array [2 * (4 / (1 - 1)) + 1] of int; proc main() {}",
+			"1:22: division by zero",
+		},
+		{
+			"overflowing array length",
+			"type A = array [65536 * 65536 + 3] of int; proc main() {}",
+			"array length 65536 * 65536 + 3 overflows int",
+		},
+		{
+			"array too large",
+			"type A = array [65536] of array [65536] of int; proc main() {}",
+			"array type array [65536] of array [65536] of int is larger than 2^31-1 bytes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The checker reports the name resolution errors itself unless
+			// the parser does.
+			prog, err := parser.ParseFile(token.NewFileSet(), "", tt.src, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = types.Check(prog)
+			if err == nil {
+				t.Fatalf("expected error %q", tt.err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %q, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCheck_Functions(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{
+			"missing return",
+			"proc f(n: int): int { if (n < 0) return 0; } proc main() {}",
+			"missing return at the end of procedure f",
+		},
+		{
+			"missing return in while",
+			"proc f(): int { while (1 = 1) return 1; } proc main() {}",
+			"missing return at the end of procedure f",
+		},
+		{
+			"not enough return values",
+			"proc f(): int { return; } proc main() {}",
+			"not enough return values: procedure f returns int",
+		},
+		{
+			"too many return values",
+			"proc main() { return 1; }",
+			"too many return values: procedure main has no result",
+		},
+		{
+			"mismatched result",
+			"type A = array [2] of int; proc f(ref a: A): int { return a; } proc main() {}",
+			"cannot use a of type array [2] of int as type int in return statement",
+		},
+		{
+			"array result",
+			"type A = array [2] of int; proc f(): A { var a: A; } proc main() {}",
+			"procedure f cannot return values of type array [2] of int",
+		},
+		{
+			"main with result",
+			"proc main(): int { return 0; }",
+			"procedure main must not have a result",
+		},
+		{
+			"procedure call as value",
+			"proc f() {} proc main() { printi(f()); }",
+			"procedure call f() used as value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testutil.TempDir(t)
+			defer cleanup()
+			filename := filepath.Join(dir, "test.spl")
+			if err := ioutil.WriteFile(filename, []byte(tt.src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			prog, err := parser.ParseFiles(token.NewFileSet(), []string{filename}, ext.Functions, parser.DeclarationErrors)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = types.Check(prog)
+			if err == nil {
+				t.Fatalf("expected error %q", tt.err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %q, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCheck_Bool(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{
+			"integer operand of and",
+			"proc main() { var b: bool; b := b and 1; }",
+			"operand 1 of and must be of type bool, found int",
+		},
+		{
+			"boolean operand of +",
+			"proc main() { var i: int; i := true + 1; }",
+			"operand true of + must be of type int, found bool",
+		},
+		{
+			"integer operand of not",
+			"proc main() { var b: bool; b := not 1; }",
+			"operand 1 of not must be of type bool, found int",
+		},
+		{
+			"assign integer to boolean",
+			"proc main() { var b: bool; b := 1; }",
+			"cannot assign 1 of type int to b of type bool",
+		},
+		{
+			"assign to constant",
+			"proc main() { true := false; }",
+			"cannot assign to true",
+		},
+		{
+			"boolean argument",
+			"proc main() { printi(1 < 2); }",
+			"cannot use 1 < 2 of type bool as type int in argument to printi",
+		},
+		{
+			"non-boolean condition",
+			"proc main() { if (1 + 1) printi(1); }",
+			"non-boolean condition 1 + 1 in if statement",
+		},
+		{
+			"bool is not an expression",
+			"proc main() { var b: bool; b := bool; }",
+			"type bool is not an expression",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testutil.TempDir(t)
+			defer cleanup()
+			filename := filepath.Join(dir, "test.spl")
+			if err := ioutil.WriteFile(filename, []byte(tt.src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			prog, err := parser.ParseFiles(token.NewFileSet(), []string{filename}, ext.Bool, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = types.Check(prog)
+			if err == nil {
+				t.Fatalf("expected error %q", tt.err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %q, want %q", err, tt.err)
+			}
+		})
+	}
+
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	filename := filepath.Join(dir, "const.spl")
+	src := "proc main() { var b: array [2] of bool; b[0] := not (true and false) or false; }"
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	prog, err := parser.ParseFiles(token.NewFileSet(), []string{filename}, ext.Bool, parser.DeclarationErrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := types.Check(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rhs := prog.Decls[0].(*ast.ProcDecl).Body.List[1].(*ast.AssignStmt).Right
+	if v, ok := info.Values[rhs]; !ok || v != 1 || info.TypeOf(rhs) != types.Typ[types.Bool] {
+		t.Errorf("got %v (%t) of type %s, want constant true", v, ok, info.TypeOf(rhs))
+	}
+}
+
+func TestCheck_Records(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{
+			"duplicate field",
+			"type P = record { x: int; x: int; }; proc main() {}",
+			"field x redeclared",
+		},
+		{
+			"unknown field",
+			"type P = record { x: int; }; proc main() { var p: P; p.y := 1; }",
+			"p.y undefined (type record { x: int; } has no field y)",
+		},
+		{
+			"selector of integer",
+			"proc main() { var i: int; printi(i.x); }",
+			"i.x undefined (type int has no field x)",
+		},
+		{
+			"assign record",
+			"type P = record { x: int; }; proc main() { var p: P; var q: P; p := q; }",
+			"cannot assign to p of type record { x: int; }",
+		},
+		{
+			"record value parameter",
+			"type P = record { x: int; }; proc f(p: P) {} proc main() {}",
+			"parameter p of type record { x: int; } must be a reference parameter",
+		},
+		{
+			"field of call",
+			"type P = record { x: int; }; proc f(ref p: P) { readi(p.x); readi((p).x); } proc main() { var p: P; f(p); readi(p); }",
+			"cannot use p of type record { x: int; } as type int in argument to readi",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testutil.TempDir(t)
+			defer cleanup()
+			filename := filepath.Join(dir, "test.spl")
+			if err := ioutil.WriteFile(filename, []byte(tt.src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			prog, err := parser.ParseFiles(token.NewFileSet(), []string{filename}, ext.Records, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = types.Check(prog)
+			if err == nil {
+				t.Fatalf("expected error %q", tt.err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %q, want %q", err, tt.err)
+			}
+		})
+	}
+
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	filename := filepath.Join(dir, "layout.spl")
+	src := "type P = record { x: int; a: array [3] of int; y: int; }; proc main() { var ps: array [2] of P; ps[1].y := 1; }"
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	prog, err := parser.ParseFiles(token.NewFileSet(), []string{filename}, ext.Records, parser.DeclarationErrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := types.Check(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := info.Types[prog.Decls[0].(*ast.TypeDecl).Type].(*types.Record)
+	if size := types.Sizeof(r); size != 20 {
+		t.Errorf("got size %d, want 20", size)
+	}
+	lhs := prog.Decls[1].(*ast.ProcDecl).Body.List[1].(*ast.AssignStmt).Left.(*ast.SelectorExpr)
+	f, ok := info.Uses[lhs.Sel].(*types.Var)
+	if !ok || !f.IsField() || f != r.Field("y") || r.Offset(f) != 16 {
+		t.Errorf("got field %v, want y at offset 16", info.Uses[lhs.Sel])
+	}
+}
+
+func TestCheck_Strings(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{
+			"initialize int",
+			`proc main() { var i: int := "a"; }`,
+			"cannot initialize i of type int, only arrays of int are initialized by strings",
+		},
+		{
+			"initialize with integer",
+			`proc main() { var a: array [4] of int := 1; }`,
+			"cannot initialize a with 1 of type int, only string literals are allowed",
+		},
+		{
+			"string too long",
+			`proc main() { var a: array [2] of int := "abc"; }`,
+			`string "abc" of length 3 does not fit into a of type array [2] of int`,
+		},
+		{
+			"assign string",
+			`proc main() { var i: int; i := "a"; }`,
+			"cannot assign \"a\" of type string to i of type int",
+		},
+		{
+			"string argument",
+			`proc main() { printi("a"); }`,
+			"cannot use \"a\" of type string as type int in argument to printi",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testutil.TempDir(t)
+			defer cleanup()
+			filename := filepath.Join(dir, "test.spl")
+			if err := ioutil.WriteFile(filename, []byte(tt.src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			prog, err := parser.ParseFiles(token.NewFileSet(), []string{filename}, ext.Strings, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = types.Check(prog)
+			if err == nil {
+				t.Fatalf("expected error %q", tt.err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %q, want %q", err, tt.err)
+			}
+		})
+	}
+
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	filename := filepath.Join(dir, "main.spl")
+	src := `proc main() { var a: array [4] of int := "hi"; prints(("a\tb")); }`
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	prog, err := parser.ParseFiles(token.NewFileSet(), []string{filename}, ext.Strings, parser.DeclarationErrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := types.Check(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := prog.Decls[0].(*ast.ProcDecl).Body.List
+	decl := body[0].(*ast.DeclStmt).Decl.(*ast.VarDecl)
+	if v := info.Defs[decl.Name].(*types.Var); v.Init() != decl.Value || info.Strings[decl.Value] != "hi" {
+		t.Errorf("got initial value %v", v.Init())
+	}
+	arg := body[1].(*ast.ExprStmt).X.(*ast.CallExpr).Args[0]
+	if s, ok := info.Strings[arg]; !ok || s != "a\tb" || info.Types[arg] != types.Typ[types.String] {
+		t.Errorf("got string %q of type %v", s, info.Types[arg])
+	}
+}
+
+func TestCheck_Values(t *testing.T) {
+	src := `type A = array [2 * (3 + 1)] of int;
+proc main() {
+  var a: A;
+  printi('a');
+  printi(0x10 - '\n');
+  printi(2147483647 + 1);
+  printi(-(7 / 2));
+  printi(1 / 0);
+}`
+	prog, err := parser.ParseFile(token.NewFileSet(), "", src, parser.DeclarationErrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := types.Check(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := info.Types[prog.Decls[0].(*ast.TypeDecl).Type].(*types.Array).Len(); n != 8 {
+		t.Errorf("got array length %d, want 8", n)
+	}
+	body := prog.Decls[1].(*ast.ProcDecl).Body.List
+	tests := []struct {
+		want   int32
+		folded bool
+	}{
+		{'a', true},
+		{6, true},
+		{-2147483648, true},
+		{-3, true},
+		{0, false},
+	}
+	for i, tt := range tests {
+		arg := body[i+1].(*ast.ExprStmt).X.(*ast.CallExpr).Args[0]
+		v, ok := info.Values[arg]
+		if ok != tt.folded || v != tt.want {
+			t.Errorf("%s: got %d (%t), want %d (%t)", types.ExprString(arg), v, ok, tt.want, tt.folded)
+		}
+	}
+}
+
+func TestConfig_Check(t *testing.T) {
+	src := "proc testFoo() { assertEq(1, 1); } proc main() {}"
+	prog, err := parser.ParseFile(token.NewFileSet(), "", src, parser.DeclarationErrors|parser.Testing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&types.Config{Test: true}).Check(prog); err != nil {
+		t.Error(err)
+	}
+	if _, err := types.Check(prog); err == nil || !strings.Contains(err.Error(), "undeclared name: assertEq") {
+		t.Errorf("got error %v, want undeclared assertEq", err)
+	}
+	// Outside of tests, the parser doesn't predeclare the procedures.
+	if _, err := parser.ParseFile(token.NewFileSet(), "", src, parser.DeclarationErrors); err == nil || !strings.Contains(err.Error(), "undeclared name: assertEq") {
+		t.Errorf("got error %v, want undeclared assertEq", err)
+	}
+}
+
+func TestConfig_Check_Imports(t *testing.T) {
+	modules := map[string]string{
+		"main.spl":  `import "sort.spl" as s; proc main() { var a: s.vector; s.sort(a); }`,
+		"sort.spl":  `type vector = array [3] of int; proc sort(ref v: vector) { swap(v[0], v[1]); } proc swap(ref a: int, ref b: int) {}`,
+		"cycle.spl": `import "a.spl" as a; proc main() { a.f(); }`,
+		"a.spl":     `import "b.spl" as b; proc f() { b.g(); }`,
+		"b.spl":     `import "a.spl" as a; proc g() { a.f(); }`,
+		"bad.spl":   `import "sort.spl" as s; proc main() { s.nope(); s.vector(); }`,
+		"swap.spl":  `import "sort.spl" as s; proc swap() {} proc main() { var a: s.vector; s.sort(a); swap(); }`,
+	}
+	dir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+	for name, src := range modules {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fset := token.NewFileSet()
+	parse := func(filename string) (*ast.Program, error) {
+		prog, err := parser.ParseFiles(fset, []string{filepath.Join(dir, filename)}, ext.Imports, parser.DeclarationErrors)
+		if prog != nil {
+			prog.Name = filename
+		}
+		return prog, err
+	}
+	conf := &types.Config{Importer: func(path, dir string) (*ast.Program, error) { return parse(path) }}
+
+	tests := []struct {
+		name string
+		err  string
+	}{
+		{"main.spl", ""},
+		{"cycle.spl", "import cycle not allowed: a.spl -> b.spl -> a.spl"},
+		{"bad.spl", "undeclared name: s.nope (and 1 more errors)"},
+		{"swap.spl", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := parse(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := conf.Check(prog)
+			if tt.err != "" {
+				if err == nil || !strings.HasSuffix(err.Error(), tt.err) {
+					t.Errorf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := make(map[string]bool)
+			for _, proc := range info.Procs {
+				if names[proc.LinkName()] {
+					t.Errorf("link name %s of procedure %s is not unique", proc.LinkName(), proc.Name())
+				}
+				names[proc.LinkName()] = true
+			}
+		})
+	}
+}
+
+func TestCheck_Diagnostics(t *testing.T) {
+	src := "proc main() {\n  printi(x);\n  printi(1, 2);\n}\n"
+	prog, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = types.Check(prog)
+	list, ok := err.(diag.List)
+	if !ok || len(list) != 2 {
+		t.Fatalf("got error %v, want 2 diagnostics", err)
+	}
+	tests := []struct {
+		code       diag.Code
+		line       int
+		start, end int
+	}{
+		{diag.UndeclaredName, 2, 10, 11},
+		{diag.WrongArgCount, 3, 13, 14},
+	}
+	for i, tt := range tests {
+		d := list[i]
+		if d.Code != tt.code || d.Pos.Line != tt.line || d.Pos.Column != tt.start || d.End.Column != tt.end {
+			t.Errorf("got %s %s at %d:%d-%d, want %s at %d:%d-%d", d.Code, d.Msg,
+				d.Pos.Line, d.Pos.Column, d.End.Column, tt.code, tt.line, tt.start, tt.end)
+		}
+	}
+}
+
+func TestUniverse(t *testing.T) {
+	for _, p := range ast.Universe {
+		var kind ast.ObjKind
+		switch types.Lookup(p.Name).(type) {
+		case *types.TypeName:
+			kind = ast.Typ
+		case *types.Const:
+			kind = ast.Con
+		case *types.Proc:
+			kind = ast.Pro
+		}
+		if kind != p.Kind {
+			t.Errorf("%s is a %s in the universe, want %s", p.Name, kind, p.Kind)
+		}
+	}
+	if got := types.Lookup("prints").(*types.Proc).Params()[0].Type(); got != types.Typ[types.String] {
+		t.Errorf("got parameter of type %s for prints, want string", got)
+	}
+
+	tests := []struct {
+		exts ext.Set
+		test bool
+		want string
+	}{
+		{0, false, "clearAll drawCircle drawLine exit int printc printi readc readi setPixel time"},
+		{ext.Bool | ext.Strings, false, "bool clearAll drawCircle drawLine exit false int printc printi prints readc readi setPixel time true"},
+		{0, true, "assertEq clearAll drawCircle drawLine exit fail int printc printi readc readi setPixel time"},
+	}
+	for _, tt := range tests {
+		var names []string
+		for name := range ast.UniverseScope(tt.exts, tt.test).Objects {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if got := strings.Join(names, " "); got != tt.want {
+			t.Errorf("UniverseScope(%v, %t) holds %s, want %s", tt.exts, tt.test, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+// Package types implements the type checker of the simple programming language
+// (SPL). It verifies the semantic rules of the language specification on a
+// parsed program and records the type of every expression and the object every
+// identifier denotes. The collected information is used by the code generators.
+package types
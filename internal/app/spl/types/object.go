@@ -0,0 +1,127 @@
+package types
+
+import (
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+	"github.com/lukasmalkmus/spl/internal/app/spl/token"
+)
+
+// An Object describes a named language entity such as a type, variable or
+// procedure.
+type Object interface {
+	// Name returns the objects name.
+	Name() string
+
+	// Type returns the objects type.
+	Type() Type
+
+	// Pos returns the declaration position of the objects identifier. It is
+	// invalid for predeclared objects.
+	Pos() token.Position
+}
+
+// object implements the common parts of an Object.
+type object struct {
+	name string
+	pos  token.Position
+	typ  Type
+}
+
+// Name implements the Object interface.
+func (obj *object) Name() string { return obj.name }
+
+// Type implements the Object interface.
+func (obj *object) Type() Type { return obj.typ }
+
+// Pos implements the Object interface.
+func (obj *object) Pos() token.Position { return obj.pos }
+
+// TypeName represents a declared or predeclared type name.
+type TypeName struct {
+	object
+}
+
+// Const represents a predeclared constant.
+type Const struct {
+	object
+	val int32
+}
+
+// Val returns the value of the constant. The boolean constants true and false
+// have the values 1 and 0.
+func (c *Const) Val() int32 { return c.val }
+
+// Var represents a local variable, a procedure parameter or a record field.
+type Var struct {
+	object
+	param bool
+	ref   bool
+	field bool
+	init  ast.Expr
+}
+
+// IsParam reports whether v is a procedure parameter.
+func (v *Var) IsParam() bool { return v.param }
+
+// IsRef reports whether v is a reference parameter.
+func (v *Var) IsRef() bool { return v.ref }
+
+// IsField reports whether v is a record field.
+func (v *Var) IsField() bool { return v.field }
+
+// Init returns the initial value of a local variable or nil, if the variable
+// is zero initialized.
+func (v *Var) Init() ast.Expr { return v.init }
+
+// Proc represents a declared or predeclared (library) procedure.
+type Proc struct {
+	object
+	decl   *ast.ProcDecl
+	locals []*Var
+	module string
+	link   string
+}
+
+// Decl returns the procedures declaration. It is nil for library procedures.
+func (p *Proc) Decl() *ast.ProcDecl { return p.decl }
+
+// Builtin reports whether p is a library procedure provided by the runtime.
+func (p *Proc) Builtin() bool { return p.decl == nil }
+
+// Module returns the file name of the module declaring the procedure. It is
+// empty for procedures of the program itself and library procedures.
+func (p *Proc) Module() string { return p.module }
+
+// LinkName returns the name of the procedure which is unique among all
+// procedures of the program including the ones of imported modules. Code
+// generators use it to name the procedure.
+func (p *Proc) LinkName() string {
+	if p.link != "" {
+		return p.link
+	}
+	return p.name
+}
+
+// Params returns the parameters of the procedure.
+func (p *Proc) Params() []*Var { return p.typ.(*Signature).params }
+
+// Result returns the result type of the procedure or nil, if it doesn't return
+// a value.
+func (p *Proc) Result() Type { return p.typ.(*Signature).result }
+
+// Locals returns the local variables of the procedure in declaration order.
+func (p *Proc) Locals() []*Var { return p.locals }
+
+// Module represents an imported module. Its name is the name the module is
+// imported as.
+type Module struct {
+	object
+	path  string
+	scope map[string]Object
+}
+
+// Path returns the file name of the module.
+func (m *Module) Path() string { return m.path }
+
+// Lookup returns the procedure or type with the given name declared by the
+// module or nil, if there is no such object.
+func (m *Module) Lookup(name string) Object { return m.scope[name] }
@@ -0,0 +1,172 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Type represents a type of the simple programming language. Two types are
+// identical if and only if they are the same Type value, because every type
+// expression constructs a new type.
+type Type interface {
+	// String returns a string representation of the type.
+	String() string
+}
+
+// BasicKind describes the kind of a basic type.
+type BasicKind int
+
+// List of basic kinds.
+const (
+	Invalid BasicKind = iota // Type is invalid
+	Int                      // Predeclared integer type
+	Bool                     // Logical values, the result of comparisons
+	String                   // Type of string literals, which has no name
+)
+
+// Basic represents a basic type.
+type Basic struct {
+	kind BasicKind
+	name string
+}
+
+// Typ contains the predeclared *Basic types indexed by their corresponding
+// BasicKind.
+var Typ = [...]*Basic{
+	Invalid: {Invalid, "invalid type"},
+	Int:     {Int, "int"},
+	Bool:    {Bool, "bool"},
+	String:  {String, "string"},
+}
+
+// Kind returns the kind of basic type b.
+func (b *Basic) Kind() BasicKind { return b.kind }
+
+// String implements the Type interface.
+func (b *Basic) String() string { return b.name }
+
+// Array represents an array type.
+type Array struct {
+	len  int64
+	elem Type
+}
+
+// NewArray returns a new array type for the given element type and length.
+func NewArray(elem Type, len int64) *Array { return &Array{len: len, elem: elem} }
+
+// Len returns the length of array a.
+func (a *Array) Len() int64 { return a.len }
+
+// Elem returns the element type of array a.
+func (a *Array) Elem() Type { return a.elem }
+
+// String implements the Type interface.
+func (a *Array) String() string { return fmt.Sprintf("array [%d] of %s", a.len, a.elem) }
+
+// Record represents a record type. Its fields are laid out contiguously in
+// declaration order without padding.
+type Record struct {
+	fields []*Var
+}
+
+// NewRecord returns a new record type with the given fields.
+func NewRecord(fields []*Var) *Record { return &Record{fields: fields} }
+
+// Fields returns the fields of record r in declaration order.
+func (r *Record) Fields() []*Var { return r.fields }
+
+// Field returns the field of record r with the given name or nil, if there is
+// no such field.
+func (r *Record) Field(name string) *Var {
+	for _, f := range r.fields {
+		if f.name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Offset returns the offset of field f from the start of record r in bytes.
+func (r *Record) Offset(f *Var) int64 {
+	var off int64
+	for _, g := range r.fields {
+		if g == f {
+			return off
+		}
+		off += Sizeof(g.typ)
+	}
+	panic("types: " + f.name + " is not a field of " + r.String())
+}
+
+// String implements the Type interface.
+func (r *Record) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("record {")
+	for _, f := range r.fields {
+		_, _ = fmt.Fprintf(&buf, " %s: %s;", f.name, f.typ)
+	}
+	_, _ = buf.WriteString(" }")
+	return buf.String()
+}
+
+// Signature represents a procedure type.
+type Signature struct {
+	params []*Var
+	result Type
+}
+
+// Params returns the parameters of signature s.
+func (s *Signature) Params() []*Var { return s.params }
+
+// Result returns the result type of signature s or nil, if the procedure
+// doesn't return a value.
+func (s *Signature) Result() Type { return s.result }
+
+// String implements the Type interface.
+func (s *Signature) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("proc(")
+	for i, v := range s.params {
+		if i > 0 {
+			_, _ = buf.WriteString(", ")
+		}
+		if v.ref {
+			_, _ = buf.WriteString("ref ")
+		}
+		_, _ = buf.WriteString(v.typ.String())
+	}
+	_, _ = buf.WriteString(")")
+	if s.result != nil {
+		_, _ = buf.WriteString(": " + s.result.String())
+	}
+	return buf.String()
+}
+
+// Sizeof returns the size of a value of type t in bytes. Integers and booleans
+// occupy four bytes, false is stored as 0 and true as 1. Arrays and records
+// are laid out contiguously without padding.
+func Sizeof(t Type) int64 {
+	switch t := t.(type) {
+	case *Basic:
+		return 4
+	case *Array:
+		return t.len * Sizeof(t.elem)
+	case *Record:
+		var size int64
+		for _, f := range t.fields {
+			size += Sizeof(f.typ)
+		}
+		return size
+	}
+	return 0
+}
+
+// IsInteger reports whether t is the integer type.
+func IsInteger(t Type) bool { return t == Typ[Int] }
+
+// IsBoolean reports whether t is the boolean type.
+func IsBoolean(t Type) bool { return t == Typ[Bool] }
+
+// IsBasic reports whether t is the integer or the boolean type. Values of
+// basic types can be assigned and passed by value.
+func IsBasic(t Type) bool { return IsInteger(t) || IsBoolean(t) }
@@ -0,0 +1,60 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/ast"
+)
+
+// predeclared contains the objects which are implicitly declared before all
+// user declarations. They are built from the table ast.Universe, which also
+// describes which of them are available to a program.
+var predeclared = make(map[string]Object)
+
+// testOnly contains the names of the library procedures which are only
+// available to tests, see Config.Test.
+var testOnly = make(map[string]bool)
+
+// typeNames maps the names of the types of predeclared objects to the types.
+var typeNames = map[string]Type{
+	"int":    Typ[Int],
+	"bool":   Typ[Bool],
+	"string": Typ[String],
+}
+
+func init() {
+	for _, p := range ast.Universe {
+		switch p.Kind {
+		case ast.Typ:
+			predeclared[p.Name] = &TypeName{object{name: p.Name, typ: typeNames[p.Name]}}
+		case ast.Con:
+			predeclared[p.Name] = &Const{object{name: p.Name, typ: typeNames[p.Type]}, p.Value}
+		case ast.Pro:
+			predeclared[p.Name] = libraryProc(p.Name, p.Params)
+		}
+		if p.Test {
+			testOnly[p.Name] = true
+		}
+	}
+}
+
+// libraryProc returns a library procedure with the parameters described like
+// in ast.Predeclared.
+func libraryProc(name string, params []string) *Proc {
+	vars := make([]*Var, 0, len(params))
+	for _, p := range params {
+		v := &Var{object: object{name: p, typ: Typ[Int]}, param: true}
+		if strings.HasPrefix(v.name, "ref ") {
+			v.name, v.ref = v.name[4:], true
+		}
+		if i := strings.IndexByte(v.name, ' '); i >= 0 {
+			v.name, v.typ = v.name[:i], typeNames[v.name[i+1:]]
+		}
+		vars = append(vars, v)
+	}
+	return &Proc{object: object{name: name, typ: &Signature{params: vars}}}
+}
+
+// Lookup returns the predeclared object with the given name or nil, if there is
+// no such object. The library procedures of tests are included.
+func Lookup(name string) Object { return predeclared[name] }
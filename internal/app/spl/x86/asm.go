@@ -0,0 +1,399 @@
+package x86
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Label identifies a position in the instruction stream. Labels are created
+// by NewLabel and bound to the current position by Bind.
+type Label int
+
+// Reloc describes a 32 bit RIP-relative reference to a symbol which lives
+// outside of the instruction stream. The reference must be patched once the
+// address of the symbol is known.
+type Reloc struct {
+	// Offset is the position of the 32 bit displacement in the instruction
+	// stream. The displacement is relative to Offset+4.
+	Offset int
+	// Sym identifies the symbol. Its meaning is defined by the user.
+	Sym int
+	// Add is added to the address of the symbol.
+	Add int32
+}
+
+// Assembler encodes instructions into an in-memory buffer.
+type Assembler struct {
+	buf    []byte
+	labels []int
+	fixups []fixup
+	relocs []Reloc
+}
+
+// fixup is a 32 bit relative reference to a label which is resolved when the
+// instruction stream is finished.
+type fixup struct {
+	offset int
+	label  Label
+}
+
+// Len returns the number of bytes emitted so far.
+func (a *Assembler) Len() int { return len(a.buf) }
+
+// NewLabel returns a new, unbound label.
+func (a *Assembler) NewLabel() Label {
+	a.labels = append(a.labels, -1)
+	return Label(len(a.labels) - 1)
+}
+
+// Bind binds the label to the current position. A label can only be bound
+// once.
+func (a *Assembler) Bind(l Label) {
+	if a.labels[l] >= 0 {
+		panic(fmt.Sprintf("x86: label %d bound twice", l))
+	}
+	a.labels[l] = len(a.buf)
+}
+
+// Offset returns the position a label is bound to or -1 if it is unbound.
+func (a *Assembler) Offset(l Label) int { return a.labels[l] }
+
+// Relocs returns the relocations recorded by LeaSym.
+func (a *Assembler) Relocs() []Reloc { return a.relocs }
+
+// Bytes resolves all label references and returns the encoded instructions.
+// The Assembler must not be used afterwards.
+func (a *Assembler) Bytes() ([]byte, error) {
+	for _, f := range a.fixups {
+		target := a.labels[f.label]
+		if target < 0 {
+			return nil, fmt.Errorf("x86: reference to unbound label %d", f.label)
+		}
+		binary.LittleEndian.PutUint32(a.buf[f.offset:], uint32(target-(f.offset+4)))
+	}
+	return a.buf, nil
+}
+
+// Patch overwrites the 32 bit value at the given offset. It is used to resolve
+// relocations.
+func Patch(b []byte, offset int, v int32) {
+	binary.LittleEndian.PutUint32(b[offset:], uint32(v))
+}
+
+// Data appends raw bytes to the instruction stream.
+func (a *Assembler) Data(b ...byte) { a.buf = append(a.buf, b...) }
+
+// -----------------------------------------------------------------------------
+// Instructions
+
+// Mov copies src to dst.
+func (a *Assembler) Mov(s Size, dst, src Operand) {
+	switch src := src.(type) {
+	case Imm:
+		if r, ok := dst.(Reg); ok && s == L {
+			a.rex(false, 0, r, false)
+			a.byte(0xB8 + r.low())
+			a.imm32(int32(src))
+			return
+		}
+		a.rm(s, []byte{0xC7 - sizeBit(s)}, 0, dst)
+		if s == B {
+			a.byte(byte(src))
+		} else {
+			a.imm32(int32(src))
+		}
+	case Reg:
+		a.rmReg(s, 0x89, src, dst)
+	case Mem:
+		a.rmReg(s, 0x8B, dst.(Reg), src)
+	}
+}
+
+// aluOp describes the encoding of a two operand arithmetic instruction: its
+// opcode in the "r/m, reg" form and its opcode extension for the immediate
+// forms.
+type aluOp struct {
+	op    byte
+	digit byte
+}
+
+var (
+	opAdd = aluOp{0x01, 0}
+	opOr  = aluOp{0x09, 1}
+	opAnd = aluOp{0x21, 4}
+	opSub = aluOp{0x29, 5}
+	opXor = aluOp{0x31, 6}
+	opCmp = aluOp{0x39, 7}
+)
+
+// Add adds src to dst.
+func (a *Assembler) Add(s Size, dst, src Operand) { a.alu(opAdd, s, dst, src) }
+
+// Or stores the bitwise or of dst and src in dst.
+func (a *Assembler) Or(s Size, dst, src Operand) { a.alu(opOr, s, dst, src) }
+
+// And stores the bitwise and of dst and src in dst.
+func (a *Assembler) And(s Size, dst, src Operand) { a.alu(opAnd, s, dst, src) }
+
+// Sub subtracts src from dst.
+func (a *Assembler) Sub(s Size, dst, src Operand) { a.alu(opSub, s, dst, src) }
+
+// Xor stores the bitwise exclusive or of dst and src in dst.
+func (a *Assembler) Xor(s Size, dst, src Operand) { a.alu(opXor, s, dst, src) }
+
+// Cmp compares dst with src and sets the flags accordingly.
+func (a *Assembler) Cmp(s Size, dst, src Operand) { a.alu(opCmp, s, dst, src) }
+
+func (a *Assembler) alu(op aluOp, s Size, dst, src Operand) {
+	switch src := src.(type) {
+	case Imm:
+		switch {
+		case s == B:
+			a.rm(s, []byte{0x80}, op.digit, dst)
+			a.byte(byte(src))
+		case int32(src) >= -128 && int32(src) <= 127:
+			a.rm(s, []byte{0x83}, op.digit, dst)
+			a.byte(byte(src))
+		default:
+			a.rm(s, []byte{0x81}, op.digit, dst)
+			a.imm32(int32(src))
+		}
+	case Reg:
+		a.rmReg(s, op.op, src, dst)
+	case Mem:
+		a.rmReg(s, op.op+2, dst.(Reg), src)
+	}
+}
+
+// Test computes the bitwise and of dst and src and sets the flags accordingly.
+func (a *Assembler) Test(s Size, dst Operand, src Reg) { a.rmReg(s, 0x85, src, dst) }
+
+// Imul multiplies dst with src (signed) and stores the result in dst.
+func (a *Assembler) Imul(s Size, dst Reg, src Operand) {
+	if imm, ok := src.(Imm); ok {
+		if int32(imm) >= -128 && int32(imm) <= 127 {
+			a.rmReg(s, 0x6B, dst, dst)
+			a.byte(byte(imm))
+		} else {
+			a.rmReg(s, 0x69, dst, dst)
+			a.imm32(int32(imm))
+		}
+		return
+	}
+	a.rmRegOp(s, []byte{0x0F, 0xAF}, dst, src)
+}
+
+// Neg negates dst (two's complement).
+func (a *Assembler) Neg(s Size, dst Operand) { a.rm(s, []byte{0xF7}, 3, dst) }
+
+// Div divides RDX:RAX by src (unsigned). The quotient is stored in RAX, the
+// remainder in RDX.
+func (a *Assembler) Div(s Size, src Operand) { a.rm(s, []byte{0xF7}, 6, src) }
+
+// Idiv divides RDX:RAX by src (signed). The quotient is stored in RAX, the
+// remainder in RDX.
+func (a *Assembler) Idiv(s Size, src Operand) { a.rm(s, []byte{0xF7}, 7, src) }
+
+// Cdq sign extends EAX into EDX:EAX.
+func (a *Assembler) Cdq() { a.byte(0x99) }
+
+// Lea loads the effective address of src into dst.
+func (a *Assembler) Lea(dst Reg, src Mem) { a.rmReg(Q, 0x8D, dst, src) }
+
+// LeaSym loads the address of a symbol outside of the instruction stream into
+// dst. The reference is recorded as relocation and must be patched by the
+// user.
+func (a *Assembler) LeaSym(dst Reg, sym int, add int32) {
+	a.rmReg(Q, 0x8D, dst, Mem{Base: RIP})
+	a.relocs = append(a.relocs, Reloc{Offset: len(a.buf) - 4, Sym: sym, Add: add})
+}
+
+// LeaLabel loads the address of the label into dst. It is used to address
+// constant data placed in the instruction stream.
+func (a *Assembler) LeaLabel(dst Reg, l Label) {
+	a.rmReg(Q, 0x8D, dst, Mem{Base: RIP})
+	a.fixups = append(a.fixups, fixup{offset: len(a.buf) - 4, label: l})
+}
+
+// Movsxd sign extends the 32 bit src into the 64 bit dst.
+func (a *Assembler) Movsxd(dst Reg, src Operand) { a.rmReg(Q, 0x63, dst, src) }
+
+// Movzxb zero extends the 8 bit src into the 32 bit dst.
+func (a *Assembler) Movzxb(dst Reg, src Operand) {
+	if r, ok := src.(Reg); ok {
+		a.rex(false, dst, r, r >= RSP)
+	} else {
+		a.rexMem(false, dst, src.(Mem))
+	}
+	a.buf = append(a.buf, 0x0F, 0xB6)
+	a.modrm(dst.low(), src)
+}
+
+// Push pushes a 64 bit register onto the stack.
+func (a *Assembler) Push(r Reg) {
+	a.rex(false, 0, r, false)
+	a.byte(0x50 + r.low())
+}
+
+// Pop pops a 64 bit register from the stack.
+func (a *Assembler) Pop(r Reg) {
+	a.rex(false, 0, r, false)
+	a.byte(0x58 + r.low())
+}
+
+// Call calls the procedure at the label.
+func (a *Assembler) Call(l Label) {
+	a.byte(0xE8)
+	a.ref(l)
+}
+
+// Jmp jumps to the label.
+func (a *Assembler) Jmp(l Label) {
+	a.byte(0xE9)
+	a.ref(l)
+}
+
+// Jcc jumps to the label if the condition holds.
+func (a *Assembler) Jcc(c Cond, l Label) {
+	a.buf = append(a.buf, 0x0F, 0x80+byte(c))
+	a.ref(l)
+}
+
+// Setcc sets the lower byte of dst to 1 if the condition holds and to 0
+// otherwise.
+func (a *Assembler) Setcc(c Cond, dst Reg) {
+	a.rex(false, 0, dst, dst >= RSP)
+	a.buf = append(a.buf, 0x0F, 0x90+byte(c))
+	a.modrm(0, dst)
+}
+
+// RepStosl stores EAX to the memory at RDI RCX times, advancing RDI.
+func (a *Assembler) RepStosl() { a.buf = append(a.buf, 0xF3, 0xAB) }
+
+// RepMovsl copies RCX doublewords from the memory at RSI to the memory at RDI,
+// advancing RSI and RDI.
+func (a *Assembler) RepMovsl() { a.buf = append(a.buf, 0xF3, 0xA5) }
+
+// Ret returns from a procedure.
+func (a *Assembler) Ret() { a.byte(0xC3) }
+
+// Leave restores the stack and frame pointer of the calling procedure.
+func (a *Assembler) Leave() { a.byte(0xC9) }
+
+// Syscall performs a system call.
+func (a *Assembler) Syscall() { a.buf = append(a.buf, 0x0F, 0x05) }
+
+// Ud2 raises an invalid opcode exception.
+func (a *Assembler) Ud2() { a.buf = append(a.buf, 0x0F, 0x0B) }
+
+// -----------------------------------------------------------------------------
+// Encoding
+
+func (a *Assembler) byte(b byte) { a.buf = append(a.buf, b) }
+
+func (a *Assembler) imm32(v int32) {
+	a.buf = append(a.buf, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+// ref emits a 32 bit relative reference to the label.
+func (a *Assembler) ref(l Label) {
+	a.fixups = append(a.fixups, fixup{offset: len(a.buf), label: l})
+	a.imm32(0)
+}
+
+// sizeBit returns the difference between the opcode of a byte sized
+// instruction and the opcode of its long or quad sized counterpart.
+func sizeBit(s Size) byte {
+	if s == B {
+		return 1
+	}
+	return 0
+}
+
+// rex emits a REX prefix if it is needed to encode the 64 bit operand size,
+// one of the extended registers or the lower byte of RSP, RBP, RSI or RDI.
+func (a *Assembler) rex(w bool, reg, rm Reg, force bool) {
+	var b byte
+	if w {
+		b |= 8
+	}
+	if reg.ext() {
+		b |= 4
+	}
+	if rm.ext() {
+		b |= 1
+	}
+	if b != 0 || force {
+		a.byte(0x40 | b)
+	}
+}
+
+func (a *Assembler) rexMem(w bool, reg Reg, m Mem) { a.rex(w, reg, m.Base, false) }
+
+// rm emits an instruction with a ModRM byte whose reg field holds the opcode
+// extension digit.
+func (a *Assembler) rm(s Size, op []byte, digit byte, dst Operand) {
+	switch dst := dst.(type) {
+	case Reg:
+		a.rex(s == Q, 0, dst, s == B && dst >= RSP)
+	case Mem:
+		a.rexMem(s == Q, 0, dst)
+	}
+	a.buf = append(a.buf, op...)
+	a.modrm(digit, dst)
+}
+
+// rmReg emits an instruction with a one byte opcode whose ModRM byte encodes a
+// register and a register or memory operand. The opcode is adjusted for byte
+// sized operands.
+func (a *Assembler) rmReg(s Size, op byte, reg Reg, rm Operand) {
+	a.rmRegOp(s, []byte{op - sizeBit(s)}, reg, rm)
+}
+
+func (a *Assembler) rmRegOp(s Size, op []byte, reg Reg, rm Operand) {
+	switch rm := rm.(type) {
+	case Reg:
+		a.rex(s == Q, reg, rm, s == B && (reg >= RSP || rm >= RSP))
+	case Mem:
+		a.rex(s == Q, reg, rm.Base, s == B && reg >= RSP)
+	}
+	a.buf = append(a.buf, op...)
+	a.modrm(reg.low(), rm)
+}
+
+// modrm emits the ModRM byte and, if needed, the SIB byte and displacement for
+// the register field reg and the operand rm.
+func (a *Assembler) modrm(reg byte, rm Operand) {
+	reg = (reg & 7) << 3
+	switch rm := rm.(type) {
+	case Reg:
+		a.byte(0xC0 | reg | rm.low())
+	case Mem:
+		if rm.Base == RIP {
+			a.byte(reg | 5)
+			a.imm32(rm.Disp)
+			return
+		}
+		var mod byte
+		switch {
+		case rm.Disp == 0 && rm.Base.low() != 5:
+			mod = 0x00
+		case rm.Disp >= -128 && rm.Disp <= 127:
+			mod = 0x40
+		default:
+			mod = 0x80
+		}
+		a.byte(mod | reg | rm.Base.low())
+		if rm.Base.low() == 4 {
+			a.byte(0x24)
+		}
+		switch mod {
+		case 0x40:
+			a.byte(byte(rm.Disp))
+		case 0x80:
+			a.imm32(rm.Disp)
+		}
+	default:
+		panic(fmt.Sprintf("x86: invalid ModRM operand %s", rm))
+	}
+}
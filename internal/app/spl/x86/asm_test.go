@@ -0,0 +1,110 @@
+package x86_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lukasmalkmus/spl/internal/app/spl/x86"
+)
+
+func TestAssembler(t *testing.T) {
+	tests := []struct {
+		name string
+		emit func(a *x86.Assembler)
+		want []byte
+	}{
+		{"mov eax, ecx", func(a *x86.Assembler) { a.Mov(x86.L, x86.RAX, x86.RCX) }, []byte{0x89, 0xC8}},
+		{"mov rax, rcx", func(a *x86.Assembler) { a.Mov(x86.Q, x86.RAX, x86.RCX) }, []byte{0x48, 0x89, 0xC8}},
+		{"mov eax, [rbp-8]", func(a *x86.Assembler) { a.Mov(x86.L, x86.RAX, x86.Mem{Base: x86.RBP, Disp: -8}) }, []byte{0x8B, 0x45, 0xF8}},
+		{"mov [rbp-8], eax", func(a *x86.Assembler) { a.Mov(x86.L, x86.Mem{Base: x86.RBP, Disp: -8}, x86.RAX) }, []byte{0x89, 0x45, 0xF8}},
+		{"mov rax, [rsp+8]", func(a *x86.Assembler) { a.Mov(x86.Q, x86.RAX, x86.Mem{Base: x86.RSP, Disp: 8}) }, []byte{0x48, 0x8B, 0x44, 0x24, 0x08}},
+		{"mov r8d, [r12]", func(a *x86.Assembler) { a.Mov(x86.L, x86.R8, x86.Mem{Base: x86.R12}) }, []byte{0x45, 0x8B, 0x04, 0x24}},
+		{"mov eax, [r13]", func(a *x86.Assembler) { a.Mov(x86.L, x86.RAX, x86.Mem{Base: x86.R13}) }, []byte{0x41, 0x8B, 0x45, 0x00}},
+		{"mov eax, [rbp-4096]", func(a *x86.Assembler) { a.Mov(x86.L, x86.RAX, x86.Mem{Base: x86.RBP, Disp: -4096}) }, []byte{0x8B, 0x85, 0x00, 0xF0, 0xFF, 0xFF}},
+		{"mov eax, 60", func(a *x86.Assembler) { a.Mov(x86.L, x86.RAX, x86.Imm(60)) }, []byte{0xB8, 0x3C, 0x00, 0x00, 0x00}},
+		{"mov r9d, 1", func(a *x86.Assembler) { a.Mov(x86.L, x86.R9, x86.Imm(1)) }, []byte{0x41, 0xB9, 0x01, 0x00, 0x00, 0x00}},
+		{"mov rax, -1", func(a *x86.Assembler) { a.Mov(x86.Q, x86.RAX, x86.Imm(-1)) }, []byte{0x48, 0xC7, 0xC0, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{"mov dword [rax], 0", func(a *x86.Assembler) { a.Mov(x86.L, x86.Mem{Base: x86.RAX}, x86.Imm(0)) }, []byte{0xC7, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{"mov byte [rsi], dl", func(a *x86.Assembler) { a.Mov(x86.B, x86.Mem{Base: x86.RSI}, x86.RDX) }, []byte{0x88, 0x16}},
+		{"mov byte [rax], sil", func(a *x86.Assembler) { a.Mov(x86.B, x86.Mem{Base: x86.RAX}, x86.RSI) }, []byte{0x40, 0x88, 0x30}},
+		{"mov byte [rsi], 45", func(a *x86.Assembler) { a.Mov(x86.B, x86.Mem{Base: x86.RSI}, x86.Imm(45)) }, []byte{0xC6, 0x06, 0x2D}},
+		{"add eax, 1", func(a *x86.Assembler) { a.Add(x86.L, x86.RAX, x86.Imm(1)) }, []byte{0x83, 0xC0, 0x01}},
+		{"add eax, ecx", func(a *x86.Assembler) { a.Add(x86.L, x86.RAX, x86.RCX) }, []byte{0x01, 0xC8}},
+		{"add rax, [rbp+16]", func(a *x86.Assembler) { a.Add(x86.Q, x86.RAX, x86.Mem{Base: x86.RBP, Disp: 16}) }, []byte{0x48, 0x03, 0x45, 0x10}},
+		{"sub rsp, 256", func(a *x86.Assembler) { a.Sub(x86.Q, x86.RSP, x86.Imm(256)) }, []byte{0x48, 0x81, 0xEC, 0x00, 0x01, 0x00, 0x00}},
+		{"xor edx, edx", func(a *x86.Assembler) { a.Xor(x86.L, x86.RDX, x86.RDX) }, []byte{0x31, 0xD2}},
+		{"cmp eax, ecx", func(a *x86.Assembler) { a.Cmp(x86.L, x86.RAX, x86.RCX) }, []byte{0x39, 0xC8}},
+		{"cmp al, 10", func(a *x86.Assembler) { a.Cmp(x86.B, x86.RAX, x86.Imm(10)) }, []byte{0x80, 0xF8, 0x0A}},
+		{"test eax, eax", func(a *x86.Assembler) { a.Test(x86.L, x86.RAX, x86.RAX) }, []byte{0x85, 0xC0}},
+		{"imul eax, ecx", func(a *x86.Assembler) { a.Imul(x86.L, x86.RAX, x86.RCX) }, []byte{0x0F, 0xAF, 0xC1}},
+		{"imul rax, rax, 40", func(a *x86.Assembler) { a.Imul(x86.Q, x86.RAX, x86.Imm(40)) }, []byte{0x48, 0x6B, 0xC0, 0x28}},
+		{"imul rax, rax, 4000", func(a *x86.Assembler) { a.Imul(x86.Q, x86.RAX, x86.Imm(4000)) }, []byte{0x48, 0x69, 0xC0, 0xA0, 0x0F, 0x00, 0x00}},
+		{"neg eax", func(a *x86.Assembler) { a.Neg(x86.L, x86.RAX) }, []byte{0xF7, 0xD8}},
+		{"div ecx", func(a *x86.Assembler) { a.Div(x86.L, x86.RCX) }, []byte{0xF7, 0xF1}},
+		{"idiv ecx", func(a *x86.Assembler) { a.Idiv(x86.L, x86.RCX) }, []byte{0xF7, 0xF9}},
+		{"cdq", func(a *x86.Assembler) { a.Cdq() }, []byte{0x99}},
+		{"lea rax, [rbp-16]", func(a *x86.Assembler) { a.Lea(x86.RAX, x86.Mem{Base: x86.RBP, Disp: -16}) }, []byte{0x48, 0x8D, 0x45, 0xF0}},
+		{"movsxd rax, eax", func(a *x86.Assembler) { a.Movsxd(x86.RAX, x86.RAX) }, []byte{0x48, 0x63, 0xC0}},
+		{"movzx eax, al", func(a *x86.Assembler) { a.Movzxb(x86.RAX, x86.RAX) }, []byte{0x0F, 0xB6, 0xC0}},
+		{"movzx eax, byte [rsi]", func(a *x86.Assembler) { a.Movzxb(x86.RAX, x86.Mem{Base: x86.RSI}) }, []byte{0x0F, 0xB6, 0x06}},
+		{"setl al", func(a *x86.Assembler) { a.Setcc(x86.CondL, x86.RAX) }, []byte{0x0F, 0x9C, 0xC0}},
+		{"setne sil", func(a *x86.Assembler) { a.Setcc(x86.CondNE, x86.RSI) }, []byte{0x40, 0x0F, 0x95, 0xC6}},
+		{"push rbp", func(a *x86.Assembler) { a.Push(x86.RBP) }, []byte{0x55}},
+		{"push r12", func(a *x86.Assembler) { a.Push(x86.R12) }, []byte{0x41, 0x54}},
+		{"pop rbp", func(a *x86.Assembler) { a.Pop(x86.RBP) }, []byte{0x5D}},
+		{"syscall", func(a *x86.Assembler) { a.Syscall() }, []byte{0x0F, 0x05}},
+		{"rep stosd", func(a *x86.Assembler) { a.RepStosl() }, []byte{0xF3, 0xAB}},
+		{"rep movsd", func(a *x86.Assembler) { a.RepMovsl() }, []byte{0xF3, 0xA5}},
+		{"leave; ret", func(a *x86.Assembler) { a.Leave(); a.Ret() }, []byte{0xC9, 0xC3}},
+		{"lea rsi, [rip+sym]", func(a *x86.Assembler) { a.LeaSym(x86.RSI, 0, 0) }, []byte{0x48, 0x8D, 0x35, 0x00, 0x00, 0x00, 0x00}},
+	}
+	for _, tt := range tests {
+		_ = t.Run(tt.name, func(t *testing.T) {
+			var a x86.Assembler
+			tt.emit(&a)
+			got, err := a.Bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			equals(t, got, tt.want)
+		})
+	}
+}
+
+func TestAssembler_Labels(t *testing.T) {
+	var a x86.Assembler
+	loop, done := a.NewLabel(), a.NewLabel()
+	a.Bind(loop)
+	a.Jcc(x86.CondE, done)
+	a.Jmp(loop)
+	a.Bind(done)
+	a.Call(loop)
+	a.LeaLabel(x86.RSI, done)
+
+	got, err := a.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, got, []byte{
+		0x0F, 0x84, 0x05, 0x00, 0x00, 0x00, // je done
+		0xE9, 0xF5, 0xFF, 0xFF, 0xFF, // jmp loop
+		0xE8, 0xF0, 0xFF, 0xFF, 0xFF, // call loop
+		0x48, 0x8D, 0x35, 0xF4, 0xFF, 0xFF, 0xFF, // lea rsi, [rip+done]
+	})
+}
+
+func TestAssembler_UnboundLabel(t *testing.T) {
+	var a x86.Assembler
+	a.Jmp(a.NewLabel())
+	if _, err := a.Bytes(); err == nil {
+		t.Error("expected error for unbound label")
+	}
+}
+
+// equals fails the test if got is not equal to want.
+func equals(tb testing.TB, got, want []byte) {
+	tb.Helper()
+	if !bytes.Equal(got, want) {
+		tb.Errorf("\033[31m\n\n\tgot: % x\n\n\twant: % x\033[39m\n\n", got, want)
+	}
+}
@@ -0,0 +1,5 @@
+// Package x86 implements an encoder for the subset of x86-64 machine
+// instructions needed by the native code generator. Instructions are appended
+// to an in-memory buffer by an Assembler which also resolves jumps and calls to
+// labels.
+package x86
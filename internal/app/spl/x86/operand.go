@@ -0,0 +1,125 @@
+package x86
+
+import "fmt"
+
+// Operand is an instruction operand: a register, a memory location or an
+// immediate value.
+type Operand interface {
+	// String returns the operand in Intel syntax.
+	String() string
+
+	// operand is unexported to make sure implementations of Operand can only
+	// originate in this package.
+	operand()
+}
+
+func (Reg) operand() {}
+func (Mem) operand() {}
+func (Imm) operand() {}
+
+// Reg is a general purpose register.
+type Reg uint8
+
+// All general purpose registers in encoding order. RIP is only valid as the
+// base of a memory operand.
+const (
+	RAX Reg = iota
+	RCX
+	RDX
+	RBX
+	RSP
+	RBP
+	RSI
+	RDI
+	R8
+	R9
+	R10
+	R11
+	R12
+	R13
+	R14
+	R15
+	RIP
+)
+
+var regs = [...]string{
+	RAX: "rax", RCX: "rcx", RDX: "rdx", RBX: "rbx",
+	RSP: "rsp", RBP: "rbp", RSI: "rsi", RDI: "rdi",
+	R8: "r8", R9: "r9", R10: "r10", R11: "r11",
+	R12: "r12", R13: "r13", R14: "r14", R15: "r15",
+	RIP: "rip",
+}
+
+// String implements the Operand interface.
+func (r Reg) String() string {
+	if int(r) < len(regs) {
+		return regs[r]
+	}
+	return fmt.Sprintf("reg(%d)", r)
+}
+
+// low returns the lower three bits of the register number which are encoded in
+// the ModRM, SIB or opcode byte.
+func (r Reg) low() byte { return byte(r) & 7 }
+
+// ext reports whether the register requires a REX prefix bit to be encoded.
+func (r Reg) ext() bool { return r >= R8 && r != RIP }
+
+// Mem is a memory operand addressing Base+Disp.
+type Mem struct {
+	Base Reg
+	Disp int32
+}
+
+// String implements the Operand interface.
+func (m Mem) String() string {
+	switch {
+	case m.Disp > 0:
+		return fmt.Sprintf("[%s+%d]", m.Base, m.Disp)
+	case m.Disp < 0:
+		return fmt.Sprintf("[%s%d]", m.Base, m.Disp)
+	}
+	return fmt.Sprintf("[%s]", m.Base)
+}
+
+// Imm is an immediate value.
+type Imm int32
+
+// String implements the Operand interface.
+func (i Imm) String() string { return fmt.Sprintf("%d", int32(i)) }
+
+// Size is the operand size of an instruction in bytes.
+type Size uint8
+
+// Supported operand sizes.
+const (
+	B Size = 1 // Byte
+	L Size = 4 // Long (doubleword)
+	Q Size = 8 // Quadword
+)
+
+// Cond is a condition code used by conditional jumps and set instructions.
+type Cond uint8
+
+// Condition codes in encoding order.
+const (
+	CondO  Cond = iota // Overflow
+	CondNO             // Not overflow
+	CondB              // Below (unsigned <)
+	CondAE             // Above or equal (unsigned >=)
+	CondE              // Equal
+	CondNE             // Not equal
+	CondBE             // Below or equal (unsigned <=)
+	CondA              // Above (unsigned >)
+	CondS              // Sign
+	CondNS             // Not sign
+	CondP              // Parity
+	CondNP             // Not parity
+	CondL              // Less (signed <)
+	CondGE             // Greater or equal (signed >=)
+	CondLE             // Less or equal (signed <=)
+	CondG              // Greater (signed >)
+)
+
+// Not returns the negated condition.
+func (c Cond) Not() Cond { return c ^ 1 }